@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/helpers"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/stream"
+)
+
+// pool state observed at a slot, with the config it was created from
+type PoolState struct {
+	Address solana.PublicKey
+	Pool    common.Pool
+	Config  common.PoolConfig
+	// slot at which the pool was observed
+	Slot uint64
+}
+
+type poolEntry struct {
+	pool common.Pool
+	slot uint64
+}
+
+type configEntry struct {
+	// closed once the config is loaded or failed to load
+	ready  chan struct{}
+	config common.PoolConfig
+	err    error
+}
+
+// Concurrency-safe cache of decoded pools and their configs. Pools are refreshed by
+// Update (e.g. fed from a stream.PoolStateSource) and fetched on a miss; configs are
+// loaded once and kept, as they do not change after creation.
+type PoolCache struct {
+	rpcClient instructions.AccountFetcher
+	program   *instructions.Program
+
+	mu      sync.RWMutex
+	pools   map[solana.PublicKey]poolEntry
+	configs map[solana.PublicKey]*configEntry
+}
+
+func NewPoolCache(rpcClient instructions.AccountFetcher) *PoolCache {
+	return NewProgramPoolCache(rpcClient, instructions.DefaultProgram)
+}
+
+// Creates a cache of the pools of the given deployment
+func NewProgramPoolCache(rpcClient instructions.AccountFetcher, program *instructions.Program) *PoolCache {
+	return &PoolCache{
+		rpcClient: rpcClient,
+		program:   program,
+		pools:     make(map[solana.PublicKey]poolEntry),
+		configs:   make(map[solana.PublicKey]*configEntry),
+	}
+}
+
+// Gets the pool with its config, fetching whichever is not cached yet. The returned
+// state is a copy, so it stays consistent while the cache is updated.
+func (c *PoolCache) Get(ctx context.Context, poolAddress solana.PublicKey) (*PoolState, error) {
+	c.mu.RLock()
+	entry, ok := c.pools[poolAddress]
+	c.mu.RUnlock()
+
+	if !ok {
+		pool, slot, err := c.fetchPool(ctx, poolAddress)
+		if err != nil {
+			return nil, err
+		}
+		c.Update(poolAddress, pool, slot)
+
+		c.mu.RLock()
+		entry = c.pools[poolAddress]
+		c.mu.RUnlock()
+	}
+
+	config, err := c.GetConfig(ctx, entry.pool.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PoolState{
+		Address: poolAddress,
+		Pool:    entry.pool,
+		Config:  *config,
+		Slot:    entry.slot,
+	}, nil
+}
+
+// Gets the config, loading it once; concurrent callers wait for the same load
+func (c *PoolCache) GetConfig(ctx context.Context, configAddress solana.PublicKey) (*common.PoolConfig, error) {
+	c.mu.Lock()
+	entry, ok := c.configs[configAddress]
+	if !ok {
+		entry = &configEntry{ready: make(chan struct{})}
+		c.configs[configAddress] = entry
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		config, err := c.program.GetPoolConfig(ctx, configAddress, c.rpcClient)
+		if err != nil {
+			entry.err = err
+			// forget failed loads so the next call retries
+			c.mu.Lock()
+			delete(c.configs, configAddress)
+			c.mu.Unlock()
+		} else {
+			entry.config = *config
+		}
+		close(entry.ready)
+	}
+
+	select {
+	case <-entry.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	config := entry.config
+	return &config, nil
+}
+
+// Stores the pool observed at the slot, unless a newer observation is cached.
+// Reports whether the cache was updated.
+func (c *PoolCache) Update(poolAddress solana.PublicKey, pool *common.Pool, slot uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.pools[poolAddress]; ok && entry.slot > slot {
+		return false
+	}
+	c.pools[poolAddress] = poolEntry{pool: *pool, slot: slot}
+	return true
+}
+
+// Removes the pool, e.g. once it migrated
+func (c *PoolCache) Evict(poolAddress solana.PublicKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pools, poolAddress)
+}
+
+// Keeps the pools up to date from the source until the context ends
+func (c *PoolCache) Watch(ctx context.Context, source stream.PoolStateSource, pools []solana.PublicKey) error {
+	snapshots, err := source.Subscribe(ctx, pools)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for snapshot := range snapshots {
+			c.Update(snapshot.Address, snapshot.Pool, snapshot.Slot)
+		}
+	}()
+	return nil
+}
+
+func (c *PoolCache) fetchPool(ctx context.Context, poolAddress solana.PublicKey) (*common.Pool, uint64, error) {
+	account, err := c.rpcClient.GetAccountInfo(ctx, poolAddress)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get pool account: %w", err)
+	}
+	if account == nil || account.Value == nil {
+		return nil, 0, fmt.Errorf("pool account not found: %s", poolAddress)
+	}
+	if !account.Value.Owner.Equals(c.program.Programs.Dbc) {
+		return nil, 0, fmt.Errorf("pool account is owned by %s, not the DBC program %s", account.Value.Owner, c.program.Programs.Dbc)
+	}
+
+	data := account.Value.Data.GetBinary()
+	if len(data) < 8 || !bytes.Equal(data[:8], instructions.PoolAccountDiscriminator[:]) {
+		return nil, 0, fmt.Errorf("invalid discriminator, not a pool account")
+	}
+
+	pool, err := helpers.DecodePool(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return pool, account.Context.Slot, nil
+}
@@ -0,0 +1,332 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/cache"
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+	"github.com/Luigi-1Combo/dbc-go/stream"
+)
+
+// fetcher counting the lookups of every account, held until the gate is closed
+type gatedFetcher struct {
+	*rpctest.AccountFetcher
+	gate chan struct{}
+
+	mu    sync.Mutex
+	calls map[solana.PublicKey]int
+}
+
+func newGatedFetcher() *gatedFetcher {
+	gate := make(chan struct{})
+	close(gate)
+	return &gatedFetcher{
+		AccountFetcher: rpctest.NewAccountFetcher(),
+		gate:           gate,
+		calls:          make(map[solana.PublicKey]int),
+	}
+}
+
+func (f *gatedFetcher) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*solRpc.GetAccountInfoResult, error) {
+	f.mu.Lock()
+	f.calls[account]++
+	f.mu.Unlock()
+	select {
+	case <-f.gate:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return f.AccountFetcher.GetAccountInfo(ctx, account)
+}
+
+func (f *gatedFetcher) Calls(account solana.PublicKey) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[account]
+}
+
+// fetcher holding the sample config and a pool of it
+func newSamplePool(t *testing.T) (*gatedFetcher, solana.PublicKey, solana.PublicKey) {
+	t.Helper()
+	fetcher := newGatedFetcher()
+	config := solana.NewWallet().PublicKey()
+	pool := solana.NewWallet().PublicKey()
+	if err := fetcher.SetPoolConfig(config, rpctest.SamplePoolConfig()); err != nil {
+		t.Fatal(err)
+	}
+	if err := fetcher.SetPool(pool, rpctest.SamplePool(config, solana.NewWallet().PublicKey())); err != nil {
+		t.Fatal(err)
+	}
+	return fetcher, config, pool
+}
+
+func TestPoolCacheGet(t *testing.T) {
+	otherProgram := solana.NewWallet().PublicKey()
+	tests := []struct {
+		name    string
+		modify  func(fetcher *gatedFetcher, config, pool solana.PublicKey)
+		program *instructions.Program
+		wantErr bool
+	}{
+		{name: "fetched pool", modify: func(*gatedFetcher, solana.PublicKey, solana.PublicKey) {}},
+		{
+			name:    "missing pool",
+			modify:  func(f *gatedFetcher, _, pool solana.PublicKey) { f.Delete(pool) },
+			wantErr: true,
+		},
+		{
+			name:    "missing config",
+			modify:  func(f *gatedFetcher, config, _ solana.PublicKey) { f.Delete(config) },
+			wantErr: true,
+		},
+		{
+			name: "not a pool account",
+			modify: func(f *gatedFetcher, _, pool solana.PublicKey) {
+				if err := f.SetPoolConfig(pool, rpctest.SamplePoolConfig()); err != nil {
+					panic(err)
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "owned by another program",
+			modify: func(f *gatedFetcher, _, pool solana.PublicKey) {
+				f.SetAccount(pool, otherProgram, []byte("not a pool"))
+			},
+			wantErr: true,
+		},
+		{
+			name:    "pools of another deployment",
+			modify:  func(*gatedFetcher, solana.PublicKey, solana.PublicKey) {},
+			program: instructions.MustNewProgram(common.ProgramSet{Dbc: otherProgram}),
+			wantErr: true,
+		},
+		{
+			name:    "node error",
+			modify:  func(f *gatedFetcher, _, pool solana.PublicKey) { f.SetError(pool, errors.New("node is behind")) },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		fetcher, config, pool := newSamplePool(t)
+		fetcher.SetSlot(42)
+		tt.modify(fetcher, config, pool)
+		c := cache.NewPoolCache(fetcher)
+		if tt.program != nil {
+			c = cache.NewProgramPoolCache(fetcher, tt.program)
+		}
+
+		state, err := c.Get(context.Background(), pool)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if state.Address != pool || state.Slot != 42 || state.Pool.Config != config ||
+			state.Config.MigrationQuoteThreshold != rpctest.SampleMigrationQuoteThreshold {
+			t.Errorf("%s: got %+v", tt.name, state)
+		}
+	}
+}
+
+func TestPoolCacheGetUsesCache(t *testing.T) {
+	fetcher, config, pool := newSamplePool(t)
+	c := cache.NewPoolCache(fetcher)
+
+	first, err := c.Get(context.Background(), pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the returned state is a copy
+	first.Pool.QuoteReserve = 1
+	first.Config.MigrationQuoteThreshold = 1
+
+	second, err := c.Get(context.Background(), pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Pool.QuoteReserve != 0 || second.Config.MigrationQuoteThreshold != rpctest.SampleMigrationQuoteThreshold {
+		t.Fatalf("cached state changed through a returned copy: %+v", second)
+	}
+	if fetcher.Calls(pool) != 1 || fetcher.Calls(config) != 1 {
+		t.Fatalf("got %d pool and %d config lookups, want 1 each", fetcher.Calls(pool), fetcher.Calls(config))
+	}
+
+	// an evicted pool is fetched again, its config stays cached
+	c.Evict(pool)
+	if _, err := c.Get(context.Background(), pool); err != nil {
+		t.Fatal(err)
+	}
+	if fetcher.Calls(pool) != 2 || fetcher.Calls(config) != 1 {
+		t.Fatalf("got %d pool and %d config lookups after eviction, want 2 and 1", fetcher.Calls(pool), fetcher.Calls(config))
+	}
+}
+
+func TestPoolCacheUpdate(t *testing.T) {
+	fetcher, config, pool := newSamplePool(t)
+	c := cache.NewPoolCache(fetcher)
+	tests := []struct {
+		slot         uint64
+		quoteReserve uint64
+		want         bool
+		// quote reserve cached afterwards
+		wantReserve uint64
+	}{
+		{slot: 100, quoteReserve: 1, want: true, wantReserve: 1},
+		{slot: 101, quoteReserve: 2, want: true, wantReserve: 2},
+		{slot: 101, quoteReserve: 3, want: true, wantReserve: 3},
+		{slot: 99, quoteReserve: 4, want: false, wantReserve: 3},
+		{slot: 200, quoteReserve: 5, want: true, wantReserve: 5},
+	}
+	for _, tt := range tests {
+		if got := c.Update(pool, &common.Pool{Config: config, QuoteReserve: tt.quoteReserve}, tt.slot); got != tt.want {
+			t.Errorf("slot %d: got %v, want %v", tt.slot, got, tt.want)
+		}
+		state, err := c.Get(context.Background(), pool)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.Pool.QuoteReserve != tt.wantReserve {
+			t.Errorf("slot %d: cached quote reserve %d, want %d", tt.slot, state.Pool.QuoteReserve, tt.wantReserve)
+		}
+	}
+	if fetcher.Calls(pool) != 0 {
+		t.Fatalf("updated pool was fetched %d times", fetcher.Calls(pool))
+	}
+}
+
+func TestPoolCacheGetConfigSingleFlight(t *testing.T) {
+	fetcher, config, _ := newSamplePool(t)
+	fetcher.gate = make(chan struct{})
+	c := cache.NewPoolCache(fetcher)
+
+	const callers = 16
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			loaded, err := c.GetConfig(context.Background(), config)
+			if err == nil && loaded.MigrationQuoteThreshold != rpctest.SampleMigrationQuoteThreshold {
+				err = errors.New("got another config")
+			}
+			errs <- err
+		}()
+	}
+	// hold the load until it started, so the other callers queue behind it
+	for fetcher.Calls(config) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(fetcher.gate)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls := fetcher.Calls(config); calls != 1 {
+		t.Fatalf("got %d config lookups for %d callers, want 1", calls, callers)
+	}
+}
+
+func TestPoolCacheGetConfigRetriesFailures(t *testing.T) {
+	fetcher, config, _ := newSamplePool(t)
+	c := cache.NewPoolCache(fetcher)
+
+	fetcher.SetError(config, errors.New("node is behind"))
+	if _, err := c.GetConfig(context.Background(), config); err == nil {
+		t.Fatal("expected the node error")
+	}
+	if err := fetcher.SetPoolConfig(config, rpctest.SamplePoolConfig()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetConfig(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	if calls := fetcher.Calls(config); calls != 2 {
+		t.Fatalf("got %d config lookups, want the failed one retried", calls)
+	}
+}
+
+func TestPoolCacheGetConfigCanceled(t *testing.T) {
+	fetcher, config, _ := newSamplePool(t)
+	fetcher.gate = make(chan struct{})
+	c := cache.NewPoolCache(fetcher)
+
+	// a first caller holds the load
+	loaded := make(chan error, 1)
+	go func() {
+		_, err := c.GetConfig(context.Background(), config)
+		loaded <- err
+	}()
+	for fetcher.Calls(config) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// a waiting caller gives up with its own context
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetConfig(ctx, config); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(fetcher.gate)
+	if err := <-loaded; err != nil {
+		t.Fatal(err)
+	}
+	if calls := fetcher.Calls(config); calls != 1 {
+		t.Fatalf("got %d config lookups, want 1", calls)
+	}
+}
+
+func TestPoolCacheWatch(t *testing.T) {
+	fetcher, config, pool := newSamplePool(t)
+	server := rpctest.NewServer(fetcher.AccountFetcher)
+	defer server.Close()
+	c := cache.NewPoolCache(fetcher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	source := &stream.PollingSource{RpcClient: server.Client(), Interval: 10 * time.Millisecond}
+	if err := c.Watch(ctx, source, []solana.PublicKey{pool}); err != nil {
+		t.Fatal(err)
+	}
+
+	fetcher.SetSlot(500)
+	updated := rpctest.SamplePool(config, solana.NewWallet().PublicKey())
+	updated.QuoteReserve = 1_000_000_000
+	if err := fetcher.SetPool(pool, updated); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		state, err := c.Get(ctx, pool)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.Slot == 500 && state.Pool.QuoteReserve == 1_000_000_000 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("watched pool not updated, cached %d at slot %d", state.Pool.QuoteReserve, state.Slot)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
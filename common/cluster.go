@@ -0,0 +1,77 @@
+package common
+
+import "github.com/gagliardetto/solana-go"
+
+// program ids the SDK builds instructions for and reads accounts of
+type ProgramSet struct {
+	Dbc      solana.PublicKey
+	DammV1   solana.PublicKey
+	DammV2   solana.PublicKey
+	Metadata solana.PublicKey
+}
+
+// rpc endpoints and program ids of a cluster
+type Cluster struct {
+	Name     string
+	RpcURL   string
+	WsURL    string
+	Programs ProgramSet
+}
+
+// program ids of the mainnet deployments, also used on devnet
+var MainnetPrograms = ProgramSet{
+	Dbc:      solana.MustPublicKeyFromBase58(DbcProgramID),
+	DammV1:   solana.MustPublicKeyFromBase58(DammV1ProgramID),
+	DammV2:   solana.MustPublicKeyFromBase58(DammV2ProgramID),
+	Metadata: solana.MustPublicKeyFromBase58(MetadataProgram),
+}
+
+var (
+	Mainnet = Cluster{
+		Name:     "mainnet",
+		RpcURL:   "https://api.mainnet-beta.solana.com",
+		WsURL:    "wss://api.mainnet-beta.solana.com",
+		Programs: MainnetPrograms,
+	}
+	Devnet = Cluster{
+		Name:     "devnet",
+		RpcURL:   "https://api.devnet.solana.com",
+		WsURL:    "wss://api.devnet.solana.com",
+		Programs: MainnetPrograms,
+	}
+	// local validator with the programs cloned at their mainnet addresses;
+	// use NewCustomCluster for programs deployed elsewhere
+	Localnet = Cluster{
+		Name:     "localnet",
+		RpcURL:   "http://127.0.0.1:8899",
+		WsURL:    "ws://127.0.0.1:8900",
+		Programs: MainnetPrograms,
+	}
+)
+
+// Creates a cluster with its own program deployments; zero program ids default to mainnet
+func NewCustomCluster(name, rpcURL, wsURL string, programs ProgramSet) Cluster {
+	return Cluster{
+		Name:     name,
+		RpcURL:   rpcURL,
+		WsURL:    wsURL,
+		Programs: programs.WithDefaults(),
+	}
+}
+
+// Fills the zero program ids with the mainnet ones
+func (p ProgramSet) WithDefaults() ProgramSet {
+	if p.Dbc.IsZero() {
+		p.Dbc = MainnetPrograms.Dbc
+	}
+	if p.DammV1.IsZero() {
+		p.DammV1 = MainnetPrograms.DammV1
+	}
+	if p.DammV2.IsZero() {
+		p.DammV2 = MainnetPrograms.DammV2
+	}
+	if p.Metadata.IsZero() {
+		p.Metadata = MainnetPrograms.Metadata
+	}
+	return p
+}
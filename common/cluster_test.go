@@ -0,0 +1,77 @@
+package common_test
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+)
+
+func TestProgramSetWithDefaults(t *testing.T) {
+	dbc := solana.NewWallet().PublicKey()
+	dammV2 := solana.NewWallet().PublicKey()
+	tests := []struct {
+		name     string
+		programs common.ProgramSet
+		want     common.ProgramSet
+	}{
+		{name: "empty", want: common.MainnetPrograms},
+		{name: "mainnet", programs: common.MainnetPrograms, want: common.MainnetPrograms},
+		{
+			name:     "custom dbc",
+			programs: common.ProgramSet{Dbc: dbc},
+			want: common.ProgramSet{
+				Dbc:      dbc,
+				DammV1:   common.MainnetPrograms.DammV1,
+				DammV2:   common.MainnetPrograms.DammV2,
+				Metadata: common.MainnetPrograms.Metadata,
+			},
+		},
+		{
+			name:     "custom dbc and DAMM V2",
+			programs: common.ProgramSet{Dbc: dbc, DammV2: dammV2},
+			want: common.ProgramSet{
+				Dbc:      dbc,
+				DammV1:   common.MainnetPrograms.DammV1,
+				DammV2:   dammV2,
+				Metadata: common.MainnetPrograms.Metadata,
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.programs.WithDefaults(); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClusters(t *testing.T) {
+	dbc := solana.NewWallet().PublicKey()
+	custom := common.NewCustomCluster("staging", "http://10.0.0.1:8899", "ws://10.0.0.1:8900", common.ProgramSet{Dbc: dbc})
+	tests := []struct {
+		cluster      common.Cluster
+		name         string
+		rpcURL       string
+		wsURL        string
+		dbcProgramID solana.PublicKey
+	}{
+		{common.Mainnet, "mainnet", "https://api.mainnet-beta.solana.com", "wss://api.mainnet-beta.solana.com", common.MainnetPrograms.Dbc},
+		{common.Devnet, "devnet", "https://api.devnet.solana.com", "wss://api.devnet.solana.com", common.MainnetPrograms.Dbc},
+		{common.Localnet, "localnet", "http://127.0.0.1:8899", "ws://127.0.0.1:8900", common.MainnetPrograms.Dbc},
+		{custom, "staging", "http://10.0.0.1:8899", "ws://10.0.0.1:8900", dbc},
+	}
+	for _, tt := range tests {
+		c := tt.cluster
+		if c.Name != tt.name || c.RpcURL != tt.rpcURL || c.WsURL != tt.wsURL || !c.Programs.Dbc.Equals(tt.dbcProgramID) {
+			t.Errorf("%s: got %+v", tt.name, c)
+		}
+		// every cluster has all its program ids set
+		if c.Programs != c.Programs.WithDefaults() {
+			t.Errorf("%s: missing program ids in %+v", tt.name, c.Programs)
+		}
+	}
+	if common.MainnetPrograms.Dbc.String() != common.DbcProgramID {
+		t.Errorf("got mainnet DBC program %s", common.MainnetPrograms.Dbc)
+	}
+}
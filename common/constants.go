@@ -14,4 +14,16 @@ const (
 	Resolution = 64
 
 	MaxCurvePoint = 16
+
+	FeeDenominator  = 1_000_000_000
+	MaxFeeNumerator = 990_000_000
+	BasisPointMax   = 10_000
+
+	// scaling of the variable fee: (volatility * bin step)^2 * fee control / 1e11
+	DynamicFeeScalingFactor = 100_000_000_000
+
+	// metaplex token metadata limits, in bytes
+	MaxNameLength   = 32
+	MaxSymbolLength = 10
+	MaxUriLength    = 200
 )
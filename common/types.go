@@ -12,6 +12,51 @@ const (
 	Down
 )
 
+type TradeDirection int
+
+const (
+	BaseToQuote TradeDirection = iota
+	QuoteToBase
+)
+
+// swap2 modes, encoded as a u8 in the instruction data
+type SwapMode uint8
+
+const (
+	// spends exactly the amount in, failing below the minimum amount out
+	SwapModeExactIn SwapMode = iota
+	// spends at most the amount in, stopping at the migration threshold
+	SwapModePartialFill
+	// receives exactly the amount out, failing above the maximum amount in
+	SwapModeExactOut
+)
+
+// BaseFeeConfig.FeeSchedulerMode values, i.e. the base fee mode
+const (
+	FeeSchedulerModeLinear uint8 = iota
+	FeeSchedulerModeExponential
+	// the fee rises with the input amount of buys during the limiter window
+	FeeSchedulerModeRateLimiter
+)
+
+// PoolConfig.ActivationType values, i.e. whether points are slots or unix timestamps
+const (
+	ActivationTypeSlot uint8 = iota
+	ActivationTypeTimestamp
+)
+
+// PoolConfig.TokenType values
+const (
+	TokenTypeSplToken uint8 = iota
+	TokenTypeToken2022
+)
+
+// PoolConfig.CollectFeeMode values
+const (
+	CollectFeeModeQuoteToken uint8 = iota
+	CollectFeeModeOutputToken
+)
+
 type BaseFeeConfig struct {
 	CliffFeeNumerator uint64
 	PeriodFrequency   uint64
@@ -21,6 +66,29 @@ type BaseFeeConfig struct {
 	Padding0          [5]uint8
 }
 
+// rate limiter parameters, stored in the fee scheduler fields of BaseFeeConfig
+type FeeRateLimiter struct {
+	CliffFeeNumerator uint64
+	// fee increase per ReferenceAmount of input above the first one
+	FeeIncrementBps uint16
+	// points after the activation point during which the limiter applies
+	MaxLimiterDuration uint64
+	ReferenceAmount    uint64
+}
+
+// Decodes the rate limiter parameters, false when the base fee is a fee scheduler
+func (b *BaseFeeConfig) RateLimiter() (FeeRateLimiter, bool) {
+	if b.FeeSchedulerMode != FeeSchedulerModeRateLimiter {
+		return FeeRateLimiter{}, false
+	}
+	return FeeRateLimiter{
+		CliffFeeNumerator:  b.CliffFeeNumerator,
+		FeeIncrementBps:    b.NumberOfPeriod,
+		MaxLimiterDuration: b.PeriodFrequency,
+		ReferenceAmount:    b.ReductionFactor,
+	}, true
+}
+
 type DynamicFeeConfig struct {
 	Initialized              uint8
 	Padding                  [7]uint8
@@ -147,3 +215,59 @@ type PoolFeeMetrics struct {
 		TotalTradingQuoteFee uint64
 	}
 }
+
+type FeeMode struct {
+	FeesOnInput     bool
+	FeesOnBaseToken bool
+	HasReferral     bool
+}
+
+type FeeOnAmountResult struct {
+	Amount      uint64
+	TradingFee  uint64
+	ProtocolFee uint64
+	ReferralFee uint64
+}
+
+type SwapResult struct {
+	// input consumed by the swap, fees included
+	InputAmount uint64
+	// input swapped on the curve, after fees on input
+	ActualInputAmount uint64
+	OutputAmount      uint64
+	NextSqrtPrice     uint128.Uint128
+	TradingFee        uint64
+	ProtocolFee       uint64
+	ReferralFee       uint64
+}
+
+// buy quote capped at the migration threshold
+type CappedSwapResult struct {
+	SwapResult
+	// input the pool can still absorb before the curve completes, fees included
+	MaxAmountIn uint64
+	// part of the requested amount that is not consumed
+	RefundAmount uint64
+	// whether the buy completes the curve at the migration sqrt price
+	CompletesCurve bool
+}
+
+type MetadataCreator struct {
+	Address  solana.PublicKey
+	Verified bool
+	// share of the royalties in percent
+	Share uint8
+}
+
+// metaplex token metadata, with the padding of the strings trimmed
+type TokenMetadata struct {
+	Mint                 solana.PublicKey
+	UpdateAuthority      solana.PublicKey
+	Name                 string
+	Symbol               string
+	URI                  string
+	SellerFeeBasisPoints uint16
+	Creators             []MetadataCreator
+	PrimarySaleHappened  bool
+	IsMutable            bool
+}
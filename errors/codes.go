@@ -0,0 +1,124 @@
+package errors
+
+import "fmt"
+
+// error returned by the DBC program (or the anchor framework) as a custom error code
+type ProgramError struct {
+	Code uint32
+	Name string
+	Msg  string
+}
+
+func (e *ProgramError) Error() string {
+	return fmt.Sprintf("%s (%d): %s", e.Name, e.Code, e.Msg)
+}
+
+// DBC program errors, in the order of the program's PoolError enum
+var (
+	ErrMathOverflow                             = &ProgramError{6000, "MathOverflow", "Math operation overflow"}
+	ErrInvalidFee                               = &ProgramError{6001, "InvalidFee", "Invalid fee setup"}
+	ErrExceededSlippage                         = &ProgramError{6002, "ExceededSlippage", "Exceeded slippage tolerance"}
+	ErrExceedMaxFeeBps                          = &ProgramError{6003, "ExceedMaxFeeBps", "Exceeded max fee bps"}
+	ErrInvalidAdmin                             = &ProgramError{6004, "InvalidAdmin", "Invalid admin"}
+	ErrAmountIsZero                             = &ProgramError{6005, "AmountIsZero", "Amount is zero"}
+	ErrTypeCastFailed                           = &ProgramError{6006, "TypeCastFailed", "Type cast error"}
+	ErrInvalidActivationType                    = &ProgramError{6007, "InvalidActivationType", "Invalid activation type"}
+	ErrInvalidQuoteMint                         = &ProgramError{6008, "InvalidQuoteMint", "Invalid quote mint"}
+	ErrInvalidCollectFeeMode                    = &ProgramError{6009, "InvalidCollectFeeMode", "Invalid collect fee mode"}
+	ErrInvalidMigrationFeeOption                = &ProgramError{6010, "InvalidMigrationFeeOption", "Invalid migration fee option"}
+	ErrInvalidInput                             = &ProgramError{6011, "InvalidInput", "Invalid input"}
+	ErrNotEnoughLiquidity                       = &ProgramError{6012, "NotEnoughLiquidity", "Not enough liquidity"}
+	ErrPoolIsCompleted                          = &ProgramError{6013, "PoolIsCompleted", "Pool is completed"}
+	ErrPoolIsIncompleted                        = &ProgramError{6014, "PoolIsIncompleted", "Pool is incompleted"}
+	ErrInvalidMigrationOption                   = &ProgramError{6015, "InvalidMigrationOption", "Invalid migration option"}
+	ErrInvalidTokenDecimals                     = &ProgramError{6016, "InvalidTokenDecimals", "Invalid token decimals"}
+	ErrInvalidTokenType                         = &ProgramError{6017, "InvalidTokenType", "Invalid token type"}
+	ErrInvalidFeePercentage                     = &ProgramError{6018, "InvalidFeePercentage", "Invalid fee percentage"}
+	ErrInvalidQuoteThreshold                    = &ProgramError{6019, "InvalidQuoteThreshold", "Invalid quote threshold"}
+	ErrInvalidTokenSupply                       = &ProgramError{6020, "InvalidTokenSupply", "Invalid token supply"}
+	ErrInvalidCurve                             = &ProgramError{6021, "InvalidCurve", "Invalid curve"}
+	ErrNotPermitToDoThisAction                  = &ProgramError{6022, "NotPermitToDoThisAction", "Not permit to do this action"}
+	ErrInvalidOwnerAccount                      = &ProgramError{6023, "InvalidOwnerAccount", "Invalid owner account"}
+	ErrInvalidConfigAccount                     = &ProgramError{6024, "InvalidConfigAccount", "Invalid config account"}
+	ErrSurplusHasBeenWithdraw                   = &ProgramError{6025, "SurplusHasBeenWithdraw", "Surplus has been withdraw"}
+	ErrLeftoverHasBeenWithdraw                  = &ProgramError{6026, "LeftoverHasBeenWithdraw", "Leftover has been withdraw"}
+	ErrTotalBaseTokenExceedMaxSupply            = &ProgramError{6027, "TotalBaseTokenExceedMaxSupply", "Total base token is exceeded max supply"}
+	ErrUnsupportNativeMintToken2022             = &ProgramError{6028, "UnsupportNativeMintToken2022", "Unsupport native mint token 2022"}
+	ErrInsufficientLiquidityForMigration        = &ProgramError{6029, "InsufficientLiquidityForMigration", "Insufficient liquidity for migration"}
+	ErrMissingPoolConfigInRemainingAccount      = &ProgramError{6030, "MissingPoolConfigInRemainingAccount", "Missing pool config in remaining account"}
+	ErrInvalidVestingParameters                 = &ProgramError{6031, "InvalidVestingParameters", "Invalid vesting parameters"}
+	ErrInvalidLeftoverAddress                   = &ProgramError{6032, "InvalidLeftoverAddress", "Invalid leftover address"}
+	ErrSwapAmountIsOverAThreshold               = &ProgramError{6033, "SwapAmountIsOverAThreshold", "Swap amount is over a threshold"}
+	ErrInvalidFeeScheduler                      = &ProgramError{6034, "InvalidFeeScheduler", "Invalid fee scheduler"}
+	ErrInvalidCreatorTradingFeePercentage       = &ProgramError{6035, "InvalidCreatorTradingFeePercentage", "Invalid creator trading fee percentage"}
+	ErrInvalidNewCreator                        = &ProgramError{6036, "InvalidNewCreator", "Invalid new creator"}
+	ErrInvalidTokenAuthorityOption              = &ProgramError{6037, "InvalidTokenAuthorityOption", "Invalid token authority option"}
+	ErrInvalidAccount                           = &ProgramError{6038, "InvalidAccount", "Invalid account for the instruction"}
+	ErrInvalidMigratorFeePercentage             = &ProgramError{6039, "InvalidMigratorFeePercentage", "Invalid migrator fee percentage"}
+	ErrMigrationFeeHasBeenWithdraw              = &ProgramError{6040, "MigrationFeeHasBeenWithdraw", "Migration fee has been withdraw"}
+	ErrInvalidBaseFeeMode                       = &ProgramError{6041, "InvalidBaseFeeMode", "Invalid base fee mode"}
+	ErrInvalidFeeRateLimiter                    = &ProgramError{6042, "InvalidFeeRateLimiter", "Invalid fee rate limiter"}
+	ErrFailToValidateSingleSwapInstruction      = &ProgramError{6043, "FailToValidateSingleSwapInstruction", "Fail to validate single swap instruction in rate limiter"}
+	ErrInvalidMigratedPoolFee                   = &ProgramError{6044, "InvalidMigratedPoolFee", "Invalid migrated pool fee params"}
+	ErrUndeterminedError                        = &ProgramError{6045, "UndeterminedError", "Undertermined error"}
+	ErrRateLimiterNotSupported                  = &ProgramError{6046, "RateLimiterNotSupported", "Rate limiter not supported"}
+	ErrAmountLeftIsNotZero                      = &ProgramError{6047, "AmountLeftIsNotZero", "Amount left is not zero"}
+	ErrNextSqrtPriceIsSmallerThanStartSqrtPrice = &ProgramError{6048, "NextSqrtPriceIsSmallerThanStartSqrtPrice", "Next sqrt price is smaller than start sqrt price"}
+)
+
+// anchor framework errors the DBC instructions commonly fail with
+var (
+	ErrInstructionMissing               = &ProgramError{100, "InstructionMissing", "8 byte instruction identifier not provided"}
+	ErrInstructionFallbackNotFound      = &ProgramError{101, "InstructionFallbackNotFound", "Fallback functions are not supported"}
+	ErrInstructionDidNotDeserialize     = &ProgramError{102, "InstructionDidNotDeserialize", "The program could not deserialize the given instruction"}
+	ErrConstraintMut                    = &ProgramError{2000, "ConstraintMut", "A mut constraint was violated"}
+	ErrConstraintHasOne                 = &ProgramError{2001, "ConstraintHasOne", "A has one constraint was violated"}
+	ErrConstraintSigner                 = &ProgramError{2002, "ConstraintSigner", "A signer constraint was violated"}
+	ErrConstraintRaw                    = &ProgramError{2003, "ConstraintRaw", "A raw constraint was violated"}
+	ErrConstraintSeeds                  = &ProgramError{2006, "ConstraintSeeds", "A seeds constraint was violated"}
+	ErrConstraintAddress                = &ProgramError{2012, "ConstraintAddress", "An address constraint was violated"}
+	ErrConstraintTokenMint              = &ProgramError{2014, "ConstraintTokenMint", "A token mint constraint was violated"}
+	ErrConstraintTokenOwner             = &ProgramError{2015, "ConstraintTokenOwner", "A token owner constraint was violated"}
+	ErrAccountDiscriminatorNotFound     = &ProgramError{3001, "AccountDiscriminatorNotFound", "No 8 byte discriminator was found on the account"}
+	ErrAccountDiscriminatorMismatch     = &ProgramError{3002, "AccountDiscriminatorMismatch", "8 byte discriminator did not match what was expected"}
+	ErrAccountDidNotDeserialize         = &ProgramError{3003, "AccountDidNotDeserialize", "Failed to deserialize the account"}
+	ErrAccountOwnedByWrongProgram       = &ProgramError{3007, "AccountOwnedByWrongProgram", "The given account is owned by a different program than expected"}
+	ErrAccountNotInitialized            = &ProgramError{3012, "AccountNotInitialized", "The program expected this account to be already initialized"}
+	ErrAccountNotAssociatedTokenAccount = &ProgramError{3014, "AccountNotAssociatedTokenAccount", "The given account is not the associated token account"}
+)
+
+var programErrors = map[uint32]*ProgramError{}
+
+func init() {
+	for _, err := range []*ProgramError{
+		ErrMathOverflow, ErrInvalidFee, ErrExceededSlippage, ErrExceedMaxFeeBps, ErrInvalidAdmin,
+		ErrAmountIsZero, ErrTypeCastFailed, ErrInvalidActivationType, ErrInvalidQuoteMint,
+		ErrInvalidCollectFeeMode, ErrInvalidMigrationFeeOption, ErrInvalidInput, ErrNotEnoughLiquidity,
+		ErrPoolIsCompleted, ErrPoolIsIncompleted, ErrInvalidMigrationOption, ErrInvalidTokenDecimals,
+		ErrInvalidTokenType, ErrInvalidFeePercentage, ErrInvalidQuoteThreshold, ErrInvalidTokenSupply,
+		ErrInvalidCurve, ErrNotPermitToDoThisAction, ErrInvalidOwnerAccount, ErrInvalidConfigAccount,
+		ErrSurplusHasBeenWithdraw, ErrLeftoverHasBeenWithdraw, ErrTotalBaseTokenExceedMaxSupply,
+		ErrUnsupportNativeMintToken2022, ErrInsufficientLiquidityForMigration,
+		ErrMissingPoolConfigInRemainingAccount, ErrInvalidVestingParameters, ErrInvalidLeftoverAddress,
+		ErrSwapAmountIsOverAThreshold, ErrInvalidFeeScheduler, ErrInvalidCreatorTradingFeePercentage,
+		ErrInvalidNewCreator, ErrInvalidTokenAuthorityOption, ErrInvalidAccount,
+		ErrInvalidMigratorFeePercentage, ErrMigrationFeeHasBeenWithdraw, ErrInvalidBaseFeeMode,
+		ErrInvalidFeeRateLimiter, ErrFailToValidateSingleSwapInstruction, ErrInvalidMigratedPoolFee,
+		ErrUndeterminedError, ErrRateLimiterNotSupported, ErrAmountLeftIsNotZero,
+		ErrNextSqrtPriceIsSmallerThanStartSqrtPrice,
+
+		ErrInstructionMissing, ErrInstructionFallbackNotFound, ErrInstructionDidNotDeserialize,
+		ErrConstraintMut, ErrConstraintHasOne, ErrConstraintSigner, ErrConstraintRaw, ErrConstraintSeeds,
+		ErrConstraintAddress, ErrConstraintTokenMint, ErrConstraintTokenOwner,
+		ErrAccountDiscriminatorNotFound, ErrAccountDiscriminatorMismatch, ErrAccountDidNotDeserialize,
+		ErrAccountOwnedByWrongProgram, ErrAccountNotInitialized, ErrAccountNotAssociatedTokenAccount,
+	} {
+		programErrors[err.Code] = err
+	}
+}
+
+// Gets the DBC program error for a custom error code
+func FromCode(code uint32) (*ProgramError, bool) {
+	err, ok := programErrors[code]
+	return err, ok
+}
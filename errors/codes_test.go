@@ -0,0 +1,26 @@
+package errors
+
+import "testing"
+
+func TestProgramErrorsRegistered(t *testing.T) {
+	// the program's PoolError enum numbers its variants from 6000 without gaps
+	for code := uint32(6000); code <= ErrNextSqrtPriceIsSmallerThanStartSqrtPrice.Code; code++ {
+		if _, ok := FromCode(code); !ok {
+			t.Errorf("program error %d is not registered", code)
+		}
+	}
+	if _, ok := FromCode(ErrNextSqrtPriceIsSmallerThanStartSqrtPrice.Code + 1); ok {
+		t.Error("registered a program error past the last variant")
+	}
+
+	names := map[string]uint32{}
+	for code, err := range programErrors {
+		if err.Code != code {
+			t.Errorf("%s registered under %d", err.Name, code)
+		}
+		if other, ok := names[err.Name]; ok {
+			t.Errorf("%s used by %d and %d", err.Name, other, code)
+		}
+		names[err.Name] = code
+	}
+}
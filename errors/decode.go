@@ -0,0 +1,139 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+)
+
+// failing instruction of a transaction, mapped back to the builder that produced it
+type InstructionError struct {
+	// index of the failing instruction in the transaction
+	Index int
+	// program the failing instruction was sent to, zero when unknown
+	ProgramID solana.PublicKey
+	// builder that produced the instruction, e.g. "Swap", empty when unknown
+	Instruction string
+	// *ProgramError for DBC custom errors, otherwise the raw rpc error detail
+	Err error
+}
+
+func (e *InstructionError) Error() string {
+	if e.Instruction != "" {
+		return fmt.Sprintf("instruction %d (%s) failed: %v", e.Index, e.Instruction, e.Err)
+	}
+	return fmt.Sprintf("instruction %d failed: %v", e.Index, e.Err)
+}
+
+func (e *InstructionError) Unwrap() error {
+	return e.Err
+}
+
+// Decodes the raw error of a failed transaction as returned by the rpc
+// (e.g. map[InstructionError:[5 map[Custom:6012]]]). The transaction is used to map
+// the failing instruction back to its program and builder and may be nil.
+func DecodeTransactionError(tx *solana.Transaction, raw interface{}) (*InstructionError, bool) {
+	return DecodeProgramTransactionError(tx, raw, solana.MustPublicKeyFromBase58(common.DbcProgramID))
+}
+
+// Like DecodeTransactionError for a DBC deployment at the given program id
+func DecodeProgramTransactionError(tx *solana.Transaction, raw interface{}, dbcProgramID solana.PublicKey) (*InstructionError, bool) {
+	index, detail, ok := ParseInstructionError(raw)
+	if !ok {
+		return nil, false
+	}
+
+	decoded := &InstructionError{Index: index}
+	isDbc := true
+	if tx != nil && index < len(tx.Message.Instructions) {
+		compiled := tx.Message.Instructions[index]
+		programID, err := tx.Message.ResolveProgramIDIndex(compiled.ProgramIDIndex)
+		if err == nil {
+			decoded.ProgramID = programID
+			isDbc = programID.Equals(dbcProgramID)
+		}
+		if isDbc {
+			decoded.Instruction, _ = instructions.InstructionName(compiled.Data)
+		}
+	}
+
+	code, isCustom := ParseCustomErrorCode(detail)
+	switch {
+	case isCustom && isDbc:
+		if programErr, ok := FromCode(code); ok {
+			decoded.Err = programErr
+		} else {
+			decoded.Err = &ProgramError{Code: code, Name: "Unknown", Msg: "unknown program error"}
+		}
+	case isCustom:
+		decoded.Err = fmt.Errorf("custom program error %d", code)
+	default:
+		decoded.Err = fmt.Errorf("%v", detail)
+	}
+
+	return decoded, true
+}
+
+// Parses {"InstructionError": [index, detail]} into the failing instruction index and the detail
+func ParseInstructionError(raw interface{}) (int, interface{}, bool) {
+	fields, ok := raw.(map[string]interface{})
+	if !ok {
+		return 0, nil, false
+	}
+	pair, ok := fields["InstructionError"].([]interface{})
+	if !ok || len(pair) != 2 {
+		return 0, nil, false
+	}
+	index, ok := toUint64(pair[0])
+	if !ok {
+		return 0, nil, false
+	}
+	return int(index), pair[1], true
+}
+
+// Parses {"Custom": code} into the custom program error code
+func ParseCustomErrorCode(detail interface{}) (uint32, bool) {
+	fields, ok := detail.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	code, ok := toUint64(fields["Custom"])
+	if !ok {
+		return 0, false
+	}
+	return uint32(code), true
+}
+
+func toUint64(v interface{}) (uint64, bool) {
+	switch n := v.(type) {
+	case float64:
+		if n < 0 {
+			return 0, false
+		}
+		return uint64(n), true
+	case json.Number:
+		i, err := n.Int64()
+		if err != nil || i < 0 {
+			return 0, false
+		}
+		return uint64(i), true
+	case int:
+		if n < 0 {
+			return 0, false
+		}
+		return uint64(n), true
+	case int64:
+		if n < 0 {
+			return 0, false
+		}
+		return uint64(n), true
+	case uint64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
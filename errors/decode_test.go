@@ -0,0 +1,169 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	dbcErrors "github.com/Luigi-1Combo/dbc-go/errors"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+)
+
+func TestFromCode(t *testing.T) {
+	tests := []struct {
+		code uint32
+		want *dbcErrors.ProgramError
+	}{
+		{code: 6000, want: dbcErrors.ErrMathOverflow},
+		{code: 6002, want: dbcErrors.ErrExceededSlippage},
+		{code: 6048, want: dbcErrors.ErrNextSqrtPriceIsSmallerThanStartSqrtPrice},
+		{code: 2003, want: dbcErrors.ErrConstraintRaw},
+		{code: 3012, want: dbcErrors.ErrAccountNotInitialized},
+		{code: 6049},
+		{code: 0},
+	}
+	for _, tt := range tests {
+		got, ok := dbcErrors.FromCode(tt.code)
+		if ok != (tt.want != nil) || got != tt.want {
+			t.Errorf("%d: got %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+// transaction calling the system program, then swapping
+func testTransaction(t *testing.T) *solana.Transaction {
+	t.Helper()
+	user := solana.NewWallet().PublicKey()
+	key := func() solana.PublicKey { return solana.NewWallet().PublicKey() }
+	transfer := solana.NewInstruction(solana.SystemProgramID, solana.AccountMetaSlice{
+		solana.Meta(user).WRITE().SIGNER(),
+		solana.Meta(key()).WRITE(),
+	}, []byte{2, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0})
+	swap := instructions.Swap(key(), key(), key(), key(), key(), key(), key(), solana.SolMint,
+		user, solana.PublicKey{}, 1_000_000, 1)
+	tx, err := solana.NewTransaction([]solana.Instruction{transfer, swap}, solana.Hash{1}, solana.TransactionPayer(user))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func instructionError(index interface{}, detail interface{}) map[string]interface{} {
+	return map[string]interface{}{"InstructionError": []interface{}{index, detail}}
+}
+
+func TestDecodeTransactionError(t *testing.T) {
+	tx := testTransaction(t)
+	dbcProgram := solana.MustPublicKeyFromBase58(common.DbcProgramID)
+	tests := []struct {
+		name string
+		tx   *solana.Transaction
+		raw  interface{}
+		// nil when the error is not an instruction error
+		want        *dbcErrors.InstructionError
+		programErr  *dbcErrors.ProgramError
+		unknownCode uint32
+	}{
+		{
+			name:       "dbc custom error",
+			tx:         tx,
+			raw:        instructionError(float64(1), map[string]interface{}{"Custom": float64(6002)}),
+			want:       &dbcErrors.InstructionError{Index: 1, ProgramID: dbcProgram, Instruction: "Swap"},
+			programErr: dbcErrors.ErrExceededSlippage,
+		},
+		{
+			name:       "json number code",
+			tx:         tx,
+			raw:        instructionError(json.Number("1"), map[string]interface{}{"Custom": json.Number("6012")}),
+			want:       &dbcErrors.InstructionError{Index: 1, ProgramID: dbcProgram, Instruction: "Swap"},
+			programErr: dbcErrors.ErrNotEnoughLiquidity,
+		},
+		{
+			name:        "unknown dbc code",
+			tx:          tx,
+			raw:         instructionError(1, map[string]interface{}{"Custom": 6999}),
+			want:        &dbcErrors.InstructionError{Index: 1, ProgramID: dbcProgram, Instruction: "Swap"},
+			unknownCode: 6999,
+		},
+		{
+			name: "custom error of another program",
+			tx:   tx,
+			raw:  instructionError(0, map[string]interface{}{"Custom": 1}),
+			want: &dbcErrors.InstructionError{Index: 0, ProgramID: solana.SystemProgramID},
+		},
+		{
+			name: "builtin error",
+			tx:   tx,
+			raw:  instructionError(1, "InvalidAccountData"),
+			want: &dbcErrors.InstructionError{Index: 1, ProgramID: dbcProgram, Instruction: "Swap"},
+		},
+		{
+			// without the transaction every custom code is assumed to come from the program
+			name:       "no transaction",
+			raw:        instructionError(3, map[string]interface{}{"Custom": 6002}),
+			want:       &dbcErrors.InstructionError{Index: 3},
+			programErr: dbcErrors.ErrExceededSlippage,
+		},
+		{
+			name:       "index past the instructions",
+			tx:         tx,
+			raw:        instructionError(5, map[string]interface{}{"Custom": 6002}),
+			want:       &dbcErrors.InstructionError{Index: 5},
+			programErr: dbcErrors.ErrExceededSlippage,
+		},
+		{name: "transaction error", tx: tx, raw: "AccountInUse"},
+		{name: "nil", tx: tx},
+		{name: "negative index", tx: tx, raw: instructionError(-1, "InvalidAccountData")},
+		{name: "missing detail", tx: tx, raw: map[string]interface{}{"InstructionError": []interface{}{1}}},
+	}
+	for _, tt := range tests {
+		got, ok := dbcErrors.DecodeTransactionError(tt.tx, tt.raw)
+		if tt.want == nil {
+			if ok {
+				t.Errorf("%s: decoded %v", tt.name, got)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%s: not decoded", tt.name)
+			continue
+		}
+		if got.Index != tt.want.Index || !got.ProgramID.Equals(tt.want.ProgramID) || got.Instruction != tt.want.Instruction {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+		if got.Err == nil || got.Error() == "" {
+			t.Errorf("%s: missing error detail", tt.name)
+		}
+
+		var programErr *dbcErrors.ProgramError
+		isProgramErr := errors.As(got, &programErr)
+		switch {
+		case tt.programErr != nil:
+			if !errors.Is(got, tt.programErr) {
+				t.Errorf("%s: %v is not %v", tt.name, got, tt.programErr)
+			}
+		case tt.unknownCode != 0:
+			if !isProgramErr || programErr.Code != tt.unknownCode || programErr.Name != "Unknown" {
+				t.Errorf("%s: got %v, want unknown program error %d", tt.name, got.Err, tt.unknownCode)
+			}
+		case isProgramErr:
+			t.Errorf("%s: got program error %v", tt.name, programErr)
+		}
+	}
+}
+
+func TestDecodeProgramTransactionError(t *testing.T) {
+	// a deployment at another address does not own the swap built for the default one
+	tx := testTransaction(t)
+	raw := instructionError(1, map[string]interface{}{"Custom": 6002})
+	got, ok := dbcErrors.DecodeProgramTransactionError(tx, raw, solana.NewWallet().PublicKey())
+	if !ok {
+		t.Fatal("not decoded")
+	}
+	if got.Instruction != "" || errors.Is(got, dbcErrors.ErrExceededSlippage) {
+		t.Errorf("got %v, want a custom error of another program", got)
+	}
+}
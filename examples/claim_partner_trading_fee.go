@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"time"
 
 	"github.com/gagliardetto/solana-go"
 	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
@@ -13,6 +12,7 @@ import (
 	"github.com/Luigi-1Combo/dbc-go/common"
 	"github.com/Luigi-1Combo/dbc-go/helpers"
 	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/transaction"
 )
 
 func ClaimPartnerTradingFee() {
@@ -83,11 +83,6 @@ func ClaimPartnerTradingFee() {
 	)
 
 	// 7) assemble transaction
-	bh, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
-	if err != nil {
-		log.Fatalf("GetLatestBlockhash: %v", err)
-	}
-
 	// create instructions slice
 	instructions := []solana.Instruction{ixClaim}
 
@@ -99,14 +94,18 @@ func ClaimPartnerTradingFee() {
 		instructions = append([]solana.Instruction{createTokenBAtaIx}, instructions...)
 	}
 
-	tx, err := solana.NewTransaction(
+	// prepend compute budget instructions (compute unit limit and priority fee)
+	built, err := transaction.BuildTransaction(
+		ctx,
+		client,
 		instructions,
-		bh.Value.Blockhash,
-		solana.TransactionPayer(payer.PublicKey()),
+		payer.PublicKey(),
+		nil,
 	)
 	if err != nil {
-		log.Fatalf("NewTransaction: %v", err)
+		log.Fatalf("BuildTransaction: %v", err)
 	}
+	tx := built.Transaction
 
 	// 8) sign with payer and fee claimer
 	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
@@ -124,30 +123,14 @@ func ClaimPartnerTradingFee() {
 	}
 
 	// 9) send & confirm
-	sig, err := client.SendTransaction(ctx, tx)
+	// rebroadcasts until the blockhash expires
+	sig, err := transaction.SendAndConfirm(ctx, client, tx, &transaction.SendOpts{
+		LastValidBlockHeight: built.LastValidBlockHeight,
+	})
 	if err != nil {
-		log.Fatalf("SendTransaction: %v", err)
-	}
-	fmt.Printf("Transaction sent: %s\n", sig)
-
-	// wait for confirmation by polling
-	for i := 0; i < 30; i++ { // try for 30 secs
-		time.Sleep(time.Second)
-		resp, err := client.GetTransaction(ctx, sig, &rpc.GetTransactionOpts{
-			Commitment: rpc.CommitmentFinalized,
-		})
-		if err != nil {
-			continue
-		}
-		if resp != nil {
-			if resp.Meta != nil && resp.Meta.Err != nil {
-				log.Fatalf("Transaction failed: %v", resp.Meta.Err)
-			}
-			fmt.Printf("Transaction confirmed: %s\n", `https://solscan.io/tx/`+sig.String())
-			return
-		}
+		log.Fatalf("SendAndConfirm: %v", err)
 	}
-	log.Fatalf("Transaction confirmation timeout")
+	fmt.Printf("Transaction confirmed: %s\n", `https://solscan.io/tx/`+sig.String())
 }
 
 // func main() {
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+)
+
+func DecodeTransaction() {
+	ctx := context.Background()
+	client := rpc.New("https://api.mainnet-beta.solana.com")
+
+	sig := solana.MustSignatureFromBase58("YOUR_TRANSACTION_SIGNATURE")
+
+	maxVersion := uint64(0)
+	res, err := client.GetTransaction(ctx, sig, &rpc.GetTransactionOpts{
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if err != nil {
+		log.Fatalf("GetTransaction: %v", err)
+	}
+	tx, err := res.Transaction.GetTransaction()
+	if err != nil {
+		log.Fatalf("GetTransaction: %v", err)
+	}
+	// versioned transactions need their address lookup tables set on the message first,
+	// see solana.Message.SetAddressTables
+
+	decoded, err := instructions.DecodeTransaction(tx)
+	if err != nil {
+		log.Fatalf("DecodeTransaction: %v", err)
+	}
+	// DBC instructions invoked through other programs, e.g. swaps routed by an aggregator
+	inner, err := instructions.DecodeInnerInstructions(tx, res.Meta.InnerInstructions)
+	if err != nil {
+		log.Fatalf("DecodeInnerInstructions: %v", err)
+	}
+	for _, ix := range append(decoded, inner...) {
+		if ix.Name == "" {
+			fmt.Printf("#%d unknown DBC instruction %x\n", ix.Index, ix.Data)
+			continue
+		}
+		fmt.Printf("#%d %s %+v\n", ix.Index, ix.Name, ix.Args)
+		for _, account := range ix.Accounts {
+			fmt.Printf("  %s: %s\n", account.Name, account.PublicKey)
+		}
+	}
+}
+
+// func main() {
+// 	DecodeTransaction()
+// }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func GetPoolPrice() {
+	rpcClient := rpc.New("https://api.mainnet-beta.solana.com")
+
+	poolAddress := solana.MustPublicKeyFromBase58("YOUR_POOL_ADDRESS")
+	quoteDecimal := uint8(9) // SOL (use 6 for USDC)
+
+	ctx := context.Background()
+
+	pool, err := instructions.GetPool(ctx, poolAddress, rpcClient)
+	if err != nil {
+		log.Fatalf("Failed to get pool: %v", err)
+	}
+
+	poolConfig, err := instructions.GetPoolConfig(ctx, pool.Config, rpcClient)
+	if err != nil {
+		log.Fatalf("Failed to get pool config: %v", err)
+	}
+
+	price := math.GetPoolPrice(pool, poolConfig, quoteDecimal, math.DefaultPricePrecision)
+	fmt.Printf("Price: %s\n", price.Text('g', 20))
+
+	totalSupply, ok := math.GetTotalSupply(poolConfig)
+	if !ok {
+		fmt.Println("Token supply is not fixed by the config, read it from the base mint")
+		return
+	}
+
+	fdv := math.GetFdv(pool, poolConfig, totalSupply, quoteDecimal, math.DefaultPricePrecision)
+	fmt.Printf("FDV: %s\n", fdv.Text('f', 6))
+
+	marketCap, err := math.GetMarketCap(pool, poolConfig, totalSupply, quoteDecimal, math.DefaultPricePrecision)
+	if err != nil {
+		log.Fatalf("Failed to get market cap: %v", err)
+	}
+	fmt.Printf("Market cap: %s\n", marketCap.Text('f', 6))
+}
+
+// func main() {
+// 	GetPoolPrice()
+// }
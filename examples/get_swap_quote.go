@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func GetSwapQuote() {
+	rpcClient := rpc.New("https://api.mainnet-beta.solana.com")
+
+	poolAddress := solana.MustPublicKeyFromBase58("YOUR_POOL_ADDRESS")
+	amountIn := uint64(1e7) // 0.01 SOL
+
+	ctx := context.Background()
+
+	pool, err := instructions.GetPool(ctx, poolAddress, rpcClient)
+	if err != nil {
+		log.Fatalf("Failed to get pool: %v", err)
+	}
+
+	poolConfig, err := instructions.GetPoolConfig(ctx, pool.Config, rpcClient)
+	if err != nil {
+		log.Fatalf("Failed to get pool config: %v", err)
+	}
+
+	// the current point is a slot or a timestamp depending on the config activation type
+	currentPoint, err := rpcClient.GetSlot(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		log.Fatalf("Failed to get slot: %v", err)
+	}
+
+	// SwapQuoteU128 returns the same result without heap allocations
+	quote, err := math.SwapQuote(pool, poolConfig, common.QuoteToBase, amountIn, false, currentPoint)
+	if err != nil {
+		log.Fatalf("Failed to quote swap: %v", err)
+	}
+
+	fmt.Printf("Amount out: %d\n", quote.OutputAmount)
+	fmt.Printf("Trading fee: %d, protocol fee: %d\n", quote.TradingFee, quote.ProtocolFee)
+	fmt.Printf("Next sqrt price: %s\n", quote.NextSqrtPrice.String())
+
+	// near the end of the curve only part of a buy fits below the migration threshold
+	capped, err := math.QuoteCappedBuy(pool, poolConfig, amountIn, false, currentPoint)
+	if err != nil {
+		log.Fatalf("Failed to quote capped buy: %v", err)
+	}
+	fmt.Printf("Max amount in: %d, filled: %d, refund: %d, completes curve: %t\n",
+		capped.MaxAmountIn, capped.InputAmount, capped.RefundAmount, capped.CompletesCurve)
+}
+
+// func main() {
+// 	GetSwapQuote()
+// }
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func GetTokenMetadata() {
+	rpcClient := rpc.New("https://api.mainnet-beta.solana.com")
+
+	poolAddress := solana.MustPublicKeyFromBase58("YOUR_POOL_ADDRESS")
+
+	ctx := context.Background()
+
+	pool, err := instructions.GetPool(ctx, poolAddress, rpcClient)
+	if err != nil {
+		log.Fatalf("Failed to get pool: %v", err)
+	}
+
+	metadata, err := instructions.GetTokenMetadata(ctx, rpcClient, pool.BaseMint)
+	if err != nil {
+		log.Fatalf("Failed to get token metadata: %v", err)
+	}
+
+	fmt.Printf("Name: %s, symbol: %s\n", metadata.Name, metadata.Symbol)
+	fmt.Printf("URI: %s\n", metadata.URI)
+	fmt.Printf("Update authority: %s, mutable: %t\n", metadata.UpdateAuthority, metadata.IsMutable)
+	for _, creator := range metadata.Creators {
+		fmt.Printf("Creator: %s, share: %d%%, verified: %t\n", creator.Address, creator.Share, creator.Verified)
+	}
+
+	// GetTokenMetadatas fetches many mints at once, nil for mints without metadata
+	metadatas, err := instructions.GetTokenMetadatas(ctx, rpcClient, []solana.PublicKey{pool.BaseMint})
+	if err != nil {
+		log.Fatalf("Failed to get token metadatas: %v", err)
+	}
+	fmt.Printf("Fetched %d metadata accounts\n", len(metadatas))
+}
+
+// func main() {
+// 	GetTokenMetadata()
+// }
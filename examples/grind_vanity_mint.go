@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Luigi-1Combo/dbc-go/vanity"
+)
+
+func GrindVanityMint() {
+	// give up after 10 minutes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	opts := &vanity.Options{
+		Suffix: "dbc",
+		OnProgress: func(p vanity.Progress) {
+			fmt.Printf("%d attempts (%.0f/s), expected ~%.0f\n", p.Attempts, p.Rate(), p.ExpectedAttempts)
+		},
+	}
+
+	baseMint, err := vanity.Grind(ctx, opts)
+	if err != nil {
+		log.Fatalf("Grind: %v", err)
+	}
+
+	// pass as transaction.CreatePoolParams.BaseMint
+	fmt.Println("Base mint:", baseMint.PublicKey())
+}
+
+// func main() {
+// 	GrindVanityMint()
+// }
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/transaction"
+)
+
+func SimulateSwap() {
+	ctx := context.Background()
+	client := rpc.New("https://api.mainnet-beta.solana.com")
+
+	user := solana.MustPublicKeyFromBase58("YOUR_WALLET_PUBLIC_KEY")
+	poolAddress := solana.MustPublicKeyFromBase58("YOUR_POOL_ADDRESS")
+	amountIn := uint64(1_000_000) // 1 USDC
+
+	pool, err := instructions.GetPool(ctx, poolAddress, client)
+	if err != nil {
+		log.Fatalf("Failed to get pool: %v", err)
+	}
+	poolConfig, err := instructions.GetPoolConfig(ctx, pool.Config, client)
+	if err != nil {
+		log.Fatalf("Failed to get pool config: %v", err)
+	}
+
+	// buy base with quote, both token accounts must already exist
+	userInputTokenAccount, _, _ := solana.FindAssociatedTokenAddress(user, poolConfig.QuoteMint)
+	userOutputTokenAccount, _, _ := solana.FindAssociatedTokenAddress(user, pool.BaseMint)
+
+	ixSwap := instructions.Swap(
+		pool.Config,
+		poolAddress,
+		userInputTokenAccount,
+		userOutputTokenAccount,
+		pool.BaseVault,
+		pool.QuoteVault,
+		pool.BaseMint,
+		poolConfig.QuoteMint,
+		user,
+		solana.PublicKey{}, // no referral
+		amountIn,
+		1, // minOut
+	)
+
+	built, err := transaction.BuildTransaction(ctx, client, []solana.Instruction{ixSwap}, user, nil)
+	if err != nil {
+		log.Fatalf("BuildTransaction: %v", err)
+	}
+
+	// no signature needed, the simulation skips signature verification
+	sim, err := transaction.SimulateSwap(ctx, client, built.Transaction)
+	if err != nil {
+		log.Fatalf("SimulateSwap: %v", err)
+	}
+
+	diff, _ := sim.AmountOutDiff()
+	fmt.Printf("Simulated amount out: %d (quoted %d, diff %d)\n", sim.ActualAmountOut, sim.Quote.OutputAmount, diff)
+	fmt.Printf("Simulated trading fee: %d (quoted %d)\n", sim.TradingFee, sim.Quote.TradingFee)
+	fmt.Printf("Simulated protocol fee: %d (quoted %d)\n", sim.ProtocolFee, sim.Quote.ProtocolFee)
+	fmt.Printf("Next sqrt price: %s (quoted %s)\n", sim.PostPool.SqrtPrice.String(), sim.Quote.NextSqrtPrice.String())
+	fmt.Printf("Compute units: %d\n", sim.UnitsConsumed)
+}
+
+// func main() {
+// 	SimulateSwap()
+// }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/math"
+	"github.com/Luigi-1Combo/dbc-go/stream"
+)
+
+func SubscribePool() {
+	ctx := context.Background()
+	rpcClient := rpc.New("https://api.mainnet-beta.solana.com")
+	wsURL := "wss://api.mainnet-beta.solana.com"
+
+	poolAddress := solana.MustPublicKeyFromBase58("YOUR_POOL_ADDRESS")
+	quoteDecimal := uint8(9) // SOL
+
+	pool, err := instructions.GetPool(ctx, poolAddress, rpcClient)
+	if err != nil {
+		log.Fatalf("Failed to get pool: %v", err)
+	}
+	poolConfig, err := instructions.GetPoolConfig(ctx, pool.Config, rpcClient)
+	if err != nil {
+		log.Fatalf("Failed to get pool config: %v", err)
+	}
+
+	// use stream.SubscribeConfigPools to follow every pool of the config
+	snapshots, err := stream.SubscribePool(ctx, wsURL, poolAddress, &stream.SubscribeOpts{
+		Config: poolConfig,
+		OnError: func(err error) {
+			log.Printf("Subscription error: %v", err)
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	for snapshot := range snapshots {
+		price := math.GetPoolPrice(snapshot.Pool, poolConfig, quoteDecimal, math.DefaultPricePrecision)
+		fmt.Printf("slot %d: price %s, quote reserve %d, progress %.2f%%\n",
+			snapshot.Slot, price.Text('g', 10), snapshot.QuoteReserve, snapshot.Progress*100)
+	}
+}
+
+// func main() {
+// 	SubscribePool()
+// }
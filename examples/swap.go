@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/transaction"
+)
+
+func SwapOnPool() {
+	ctx := context.Background()
+	client := rpc.New("https://api.mainnet-beta.solana.com")
+
+	user := solana.MustPrivateKeyFromBase58("YOUR_PRIVATE_KEY")
+	pool := solana.MustPublicKeyFromBase58("YOUR_POOL_ADDRESS")
+
+	// buy base with 0.01 SOL, accepting 1% less than the quoted amount out
+	swapTx, err := transaction.BuildSwapTransaction(ctx, client, &transaction.SwapParams{
+		Pool:           pool,
+		Owner:          user.PublicKey(),
+		TradeDirection: common.QuoteToBase,
+		AmountIn:       uint64(1e7),
+		SlippageBps:    100,
+	})
+	if err != nil {
+		log.Fatalf("BuildSwapTransaction: %v", err)
+	}
+	fmt.Printf("Quoted amount out: %d, minimum amount out: %d\n", swapTx.Quote.OutputAmount, swapTx.MinimumAmountOut)
+
+	tx := swapTx.Transaction
+	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(user.PublicKey()) {
+			return &user
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Sign: %v", err)
+	}
+
+	sig, err := transaction.SendAndConfirm(ctx, client, tx, &transaction.SendOpts{
+		LastValidBlockHeight: swapTx.LastValidBlockHeight,
+	})
+	if err != nil {
+		log.Fatalf("SendAndConfirm: %v", err)
+	}
+	fmt.Printf("Transaction confirmed: %s\n", `https://solscan.io/tx/`+sig.String())
+}
+
+// func main() {
+// 	SwapOnPool()
+// }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/transaction"
+)
+
+func SwapExactOutOnPool() {
+	ctx := context.Background()
+	client := rpc.New("https://api.mainnet-beta.solana.com")
+
+	user := solana.MustPrivateKeyFromBase58("YOUR_PRIVATE_KEY")
+	pool := solana.MustPublicKeyFromBase58("YOUR_POOL_ADDRESS")
+
+	// buy exactly 1,000 base tokens (6 decimals), paying at most 1% more than the quoted amount in;
+	// use common.SwapModePartialFill with AmountIn instead to buy what is left below the migration threshold
+	swapTx, err := transaction.BuildSwapTransaction(ctx, client, &transaction.SwapParams{
+		Pool:           pool,
+		Owner:          user.PublicKey(),
+		TradeDirection: common.QuoteToBase,
+		SwapMode:       common.SwapModeExactOut,
+		AmountOut:      uint64(1_000_000_000),
+		SlippageBps:    100,
+	})
+	if err != nil {
+		log.Fatalf("BuildSwapTransaction: %v", err)
+	}
+	fmt.Printf("Quoted amount in: %d, maximum amount in: %d\n", swapTx.Quote.InputAmount, swapTx.MaximumAmountIn)
+
+	tx := swapTx.Transaction
+	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(user.PublicKey()) {
+			return &user
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Sign: %v", err)
+	}
+
+	sig, err := transaction.SendAndConfirm(ctx, client, tx, &transaction.SendOpts{
+		LastValidBlockHeight: swapTx.LastValidBlockHeight,
+	})
+	if err != nil {
+		log.Fatalf("SendAndConfirm: %v", err)
+	}
+	fmt.Printf("Transaction confirmed: %s\n", `https://solscan.io/tx/`+sig.String())
+}
+
+// func main() {
+// 	SwapExactOutOnPool()
+// }
@@ -4,13 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"time"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 
 	"github.com/Luigi-1Combo/dbc-go/helpers"
 	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/transaction"
 )
 
 func TransferPoolCreator() {
@@ -47,19 +47,18 @@ func TransferPoolCreator() {
 	)
 
 	// 6) assemble transaction
-	bh, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
-	if err != nil {
-		log.Fatalf("GetLatestBlockhash: %v", err)
-	}
-
-	tx, err := solana.NewTransaction(
+	// prepend compute budget instructions (compute unit limit and priority fee)
+	built, err := transaction.BuildTransaction(
+		ctx,
+		client,
 		[]solana.Instruction{ixTransfer},
-		bh.Value.Blockhash,
-		solana.TransactionPayer(payer.PublicKey()),
+		payer.PublicKey(),
+		nil,
 	)
 	if err != nil {
-		log.Fatalf("NewTransaction: %v", err)
+		log.Fatalf("BuildTransaction: %v", err)
 	}
+	tx := built.Transaction
 
 	// 7) sign with payer and creator
 	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
@@ -77,30 +76,14 @@ func TransferPoolCreator() {
 	}
 
 	// 8) send & confirm
-	sig, err := client.SendTransaction(ctx, tx)
+	// rebroadcasts until the blockhash expires
+	sig, err := transaction.SendAndConfirm(ctx, client, tx, &transaction.SendOpts{
+		LastValidBlockHeight: built.LastValidBlockHeight,
+	})
 	if err != nil {
-		log.Fatalf("SendTransaction: %v", err)
-	}
-	fmt.Printf("Transaction sent: %s\n", sig)
-
-	// wait for confirmation by polling
-	for i := 0; i < 30; i++ { // try for 30 secs
-		time.Sleep(time.Second)
-		resp, err := client.GetTransaction(ctx, sig, &rpc.GetTransactionOpts{
-			Commitment: rpc.CommitmentFinalized,
-		})
-		if err != nil {
-			continue
-		}
-		if resp != nil {
-			if resp.Meta != nil && resp.Meta.Err != nil {
-				log.Fatalf("Transaction failed: %v", resp.Meta.Err)
-			}
-			fmt.Printf("Transaction confirmed: %s\n", `https://solscan.io/tx/`+sig.String())
-			return
-		}
+		log.Fatalf("SendAndConfirm: %v", err)
 	}
-	log.Fatalf("Transaction confirmation timeout")
+	fmt.Printf("Transaction confirmed: %s\n", `https://solscan.io/tx/`+sig.String())
 }
 
 // func main() {
@@ -0,0 +1,251 @@
+package geyser
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/Luigi-1Combo/dbc-go/stream"
+)
+
+const (
+	// full name of the Yellowstone Subscribe call
+	SubscribeMethod = "/geyser.Geyser/Subscribe"
+	// name of the accounts filter of the subscriptions, listed in the updates
+	AccountsFilterName = "dbc"
+
+	DefaultPingInterval = 10 * time.Second
+	// http/2 keepalive pings of the connection, as in the Yellowstone examples
+	DefaultKeepaliveTime    = 10 * time.Second
+	DefaultKeepaliveTimeout = time.Second
+	// account updates can carry up to 10 MiB of data
+	DefaultMaxRecvMsgSize = 64 << 20
+)
+
+// descriptor of the bidirectional Subscribe stream
+var SubscribeStreamDesc = grpc.StreamDesc{
+	StreamName:    "Subscribe",
+	ServerStreams: true,
+	ClientStreams: true,
+}
+
+type ClientOpts struct {
+	// sent in the x-token header of every call, not sent when empty
+	Token string
+	// commitment of the streamed updates, confirmed when empty
+	Commitment solRpc.CommitmentType
+	// how often the stream is pinged so load balancers keep it open while the accounts
+	// are idle, DefaultPingInterval when zero
+	PingInterval time.Duration
+	// connect without TLS, e.g. to a local endpoint
+	Insecure bool
+	// extra dial options, e.g. a custom dialer
+	DialOptions []grpc.DialOption
+}
+
+// Yellowstone gRPC geyser client streaming account updates, its Subscribe method is a
+// stream.GeyserDialer
+type Client struct {
+	conn       *grpc.ClientConn
+	opts       *ClientOpts
+	commitment CommitmentLevel
+}
+
+var _ stream.GeyserDialer = (*Client)(nil).Subscribe
+
+type subscription struct {
+	clientStream grpc.ClientStream
+	cancel       context.CancelFunc
+	// pings are sent from the keepalive loop and from Recv, streams allow one sender
+	sendMu sync.Mutex
+}
+
+func DefaultClientOpts() *ClientOpts {
+	return &ClientOpts{
+		Commitment:   solRpc.CommitmentConfirmed,
+		PingInterval: DefaultPingInterval,
+	}
+}
+
+// Creates a client of the endpoint, e.g. "my-endpoint.rpcpool.com:443". The connection
+// is established by the first subscription.
+func NewClient(target string, opts *ClientOpts) (*Client, error) {
+	opts = withClientDefaults(opts)
+	commitment, err := commitmentLevel(opts.Commitment)
+	if err != nil {
+		return nil, err
+	}
+
+	var creds credentials.TransportCredentials
+	if opts.Insecure {
+		creds = insecure.NewCredentials()
+	} else {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                DefaultKeepaliveTime,
+			Timeout:             DefaultKeepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(DefaultMaxRecvMsgSize)),
+	}, opts.DialOptions...)
+
+	conn, err := grpc.NewClient(target, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create geyser client: %w", err)
+	}
+	return &Client{conn: conn, opts: opts, commitment: commitment}, nil
+}
+
+// Opens a Subscribe stream filtering the accounts. Returns once the endpoint accepted
+// the subscription, so a rejected token or an unreachable endpoint fails here.
+func (c *Client) Subscribe(ctx context.Context, accounts []solana.PublicKey) (stream.GeyserStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	if c.opts.Token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-token", c.opts.Token)
+	}
+
+	clientStream, err := c.conn.NewStream(ctx, &SubscribeStreamDesc, SubscribeMethod, grpc.ForceCodec(Codec{}))
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open subscribe stream: %w", err)
+	}
+
+	addresses := make([]string, len(accounts))
+	for i, account := range accounts {
+		addresses[i] = account.String()
+	}
+	commitment := c.commitment
+	req := &SubscribeRequest{
+		Accounts:   map[string]*SubscribeRequestFilterAccounts{AccountsFilterName: {Account: addresses}},
+		Commitment: &commitment,
+	}
+	// io.EOF means the stream already ended, its status is read below
+	if err := clientStream.SendMsg(req); err != nil && err != io.EOF {
+		cancel()
+		return nil, fmt.Errorf("failed to send subscribe request: %w", err)
+	}
+
+	header, err := clientStream.Header()
+	if err == nil && header == nil {
+		// the stream ended without headers, the status comes with the next message
+		err = clientStream.RecvMsg(new(SubscribeUpdate))
+		if err == nil || err == io.EOF {
+			err = fmt.Errorf("stream ended without headers")
+		}
+	}
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	s := &subscription{clientStream: clientStream, cancel: cancel}
+	go s.keepAlive(ctx, c.opts.PingInterval)
+	return s, nil
+}
+
+// Closes the connection, ending the open subscriptions
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Receives the next account update, answering the pings of the endpoint in between
+func (s *subscription) Recv() (*stream.GeyserAccountUpdate, error) {
+	for {
+		var update SubscribeUpdate
+		if err := s.clientStream.RecvMsg(&update); err != nil {
+			// the stream ended, stop pinging it
+			s.cancel()
+			return nil, err
+		}
+
+		switch {
+		case update.Account != nil && update.Account.Account != nil:
+			info := update.Account.Account
+			if len(info.Pubkey) != solana.PublicKeyLength {
+				return nil, fmt.Errorf("invalid account pubkey length %d", len(info.Pubkey))
+			}
+			return &stream.GeyserAccountUpdate{
+				Pubkey: solana.PublicKeyFromBytes(info.Pubkey),
+				Slot:   update.Account.Slot,
+				Data:   info.Data,
+			}, nil
+		case update.Ping != nil:
+			if err := s.ping(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (s *subscription) Close() error {
+	s.cancel()
+	return nil
+}
+
+func (s *subscription) keepAlive(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// a failed ping means the stream ended, Recv reports why
+			if s.ping() != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *subscription) ping() error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	err := s.clientStream.SendMsg(&SubscribeRequest{Ping: &SubscribeRequestPing{ID: 1}})
+	if err != nil {
+		return fmt.Errorf("failed to ping geyser stream: %w", err)
+	}
+	return nil
+}
+
+func commitmentLevel(commitment solRpc.CommitmentType) (CommitmentLevel, error) {
+	switch commitment {
+	case solRpc.CommitmentProcessed:
+		return CommitmentProcessed, nil
+	case solRpc.CommitmentConfirmed:
+		return CommitmentConfirmed, nil
+	case solRpc.CommitmentFinalized:
+		return CommitmentFinalized, nil
+	}
+	return 0, fmt.Errorf("unsupported commitment %q", commitment)
+}
+
+func withClientDefaults(opts *ClientOpts) *ClientOpts {
+	defaults := DefaultClientOpts()
+	if opts == nil {
+		return defaults
+	}
+
+	merged := *opts
+	if merged.Commitment == "" {
+		merged.Commitment = defaults.Commitment
+	}
+	if merged.PingInterval == 0 {
+		merged.PingInterval = defaults.PingInterval
+	}
+	return &merged
+}
@@ -0,0 +1,282 @@
+package geyser_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/geyser"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+	"github.com/Luigi-1Combo/dbc-go/stream"
+)
+
+const testTimeout = 5 * time.Second
+
+// in-process endpoint with a client connected to it
+func newEndpoint(t *testing.T, token string, opts *geyser.ClientOpts) (*rpctest.GeyserServer, *geyser.Client) {
+	t.Helper()
+	server := rpctest.NewGeyserServer(rpctest.NewGeyserFeed(rpctest.NewAccountFetcher()), token)
+	t.Cleanup(server.Close)
+
+	if opts == nil {
+		opts = &geyser.ClientOpts{}
+	}
+	opts.Insecure = true
+	opts.DialOptions = server.DialOptions()
+	client, err := geyser.NewClient(server.Target(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func setAccounts(t *testing.T, fetcher *rpctest.AccountFetcher, accounts ...solana.PublicKey) {
+	t.Helper()
+	for _, account := range accounts {
+		if err := fetcher.SetPool(account, &common.Pool{QuoteReserve: 1_000_000_000}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+type received struct {
+	update *stream.GeyserAccountUpdate
+	err    error
+}
+
+// receives the updates of the stream in the background
+func receive(geyserStream stream.GeyserStream) <-chan received {
+	updates := make(chan received, 16)
+	go func() {
+		for {
+			update, err := geyserStream.Recv()
+			updates <- received{update, err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return updates
+}
+
+func nextUpdate(t *testing.T, updates <-chan received) *stream.GeyserAccountUpdate {
+	t.Helper()
+	select {
+	case r := <-updates:
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		return r.update
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for an account update")
+	}
+	return nil
+}
+
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestClientSubscribe(t *testing.T) {
+	tests := []struct {
+		name           string
+		token          string
+		opts           *geyser.ClientOpts
+		wantCommitment geyser.CommitmentLevel
+	}{
+		{name: "default options", wantCommitment: geyser.CommitmentConfirmed},
+		{name: "token", token: "secret", opts: &geyser.ClientOpts{Token: "secret"}, wantCommitment: geyser.CommitmentConfirmed},
+		{name: "processed", opts: &geyser.ClientOpts{Commitment: solRpc.CommitmentProcessed}, wantCommitment: geyser.CommitmentProcessed},
+		{name: "finalized", opts: &geyser.ClientOpts{Commitment: solRpc.CommitmentFinalized}, wantCommitment: geyser.CommitmentFinalized},
+	}
+	for _, tt := range tests {
+		server, client := newEndpoint(t, tt.token, tt.opts)
+		fetcher := server.Feed.Fetcher
+		accounts := []solana.PublicKey{solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()}
+		other := solana.NewWallet().PublicKey()
+		fetcher.SetSlot(300)
+		setAccounts(t, fetcher, append(accounts, other)...)
+
+		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+		geyserStream, err := client.Subscribe(ctx, accounts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		requests := server.Requests()
+		if len(requests) != 1 {
+			t.Fatalf("%s: got %d requests, want the subscription", tt.name, len(requests))
+		}
+		filter := requests[0].Accounts[geyser.AccountsFilterName]
+		if filter == nil || len(filter.Account) != 2 || filter.Account[0] != accounts[0].String() || filter.Account[1] != accounts[1].String() {
+			t.Errorf("%s: got accounts filter %+v", tt.name, requests[0].Accounts)
+		}
+		if c := requests[0].Commitment; c == nil || *c != tt.wantCommitment {
+			t.Errorf("%s: got commitment %v, want %d", tt.name, c, tt.wantCommitment)
+		}
+
+		// accounts outside the filter are not streamed
+		updates := receive(geyserStream)
+		for _, account := range []solana.PublicKey{other, accounts[1]} {
+			if err := server.Feed.Publish(account); err != nil {
+				t.Fatal(err)
+			}
+		}
+		update := nextUpdate(t, updates)
+		want, err := fetcher.GetAccountInfo(ctx, accounts[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if update.Pubkey != accounts[1] || update.Slot != 300 || string(update.Data) != string(want.Value.Data.GetBinary()) {
+			t.Errorf("%s: got update of %s at slot %d, want %s at 300", tt.name, update.Pubkey, update.Slot, accounts[1])
+		}
+
+		// closing the stream ends the call on the endpoint
+		geyserStream.Close()
+		if r := <-updates; status.Code(r.err) != codes.Canceled {
+			t.Errorf("%s: got %v after closing, want canceled", tt.name, r.err)
+		}
+		waitFor(t, "the feed stream to close", func() bool { return server.Feed.Streams() == 0 })
+		cancel()
+	}
+}
+
+func TestClientSubscribeFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		opts    *geyser.ClientOpts
+		dialErr error
+		want    codes.Code
+	}{
+		{name: "missing token", token: "secret", want: codes.Unauthenticated},
+		{name: "wrong token", token: "secret", opts: &geyser.ClientOpts{Token: "guess"}, want: codes.Unauthenticated},
+		{name: "unavailable", dialErr: errors.New("overloaded"), want: codes.Unavailable},
+	}
+	for _, tt := range tests {
+		server, client := newEndpoint(t, tt.token, tt.opts)
+		server.Feed.SetDialError(tt.dialErr)
+
+		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+		_, err := client.Subscribe(ctx, []solana.PublicKey{solana.NewWallet().PublicKey()})
+		cancel()
+		if status.Code(err) != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.name, err, tt.want)
+		}
+	}
+
+	if _, err := geyser.NewClient("localhost:10000", &geyser.ClientOpts{Commitment: "recent"}); err == nil {
+		t.Error("expected an error for an unsupported commitment")
+	}
+}
+
+func TestClientPings(t *testing.T) {
+	tests := []struct {
+		name         string
+		pingInterval time.Duration
+		// pings sent by the endpoint
+		serverPings int
+		wantPings   int
+	}{
+		{name: "answers the endpoint", pingInterval: time.Hour, serverPings: 2, wantPings: 2},
+		{name: "keeps idle streams open", pingInterval: 10 * time.Millisecond, wantPings: 3},
+	}
+	for _, tt := range tests {
+		server, client := newEndpoint(t, "", &geyser.ClientOpts{PingInterval: tt.pingInterval})
+		account := solana.NewWallet().PublicKey()
+		setAccounts(t, server.Feed.Fetcher, account)
+
+		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+		geyserStream, err := client.Subscribe(ctx, []solana.PublicKey{account})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		updates := receive(geyserStream)
+		for i := 0; i < tt.serverPings; i++ {
+			server.Ping()
+			waitFor(t, "the ping to be answered", func() bool { return server.Pings() > i })
+		}
+		waitFor(t, "client pings", func() bool { return server.Pings() >= tt.wantPings })
+
+		// pings and pongs are not surfaced, the stream still delivers updates
+		if err := server.Feed.Publish(account); err != nil {
+			t.Fatal(err)
+		}
+		if update := nextUpdate(t, updates); update.Pubkey != account {
+			t.Errorf("%s: got update of %s", tt.name, update.Pubkey)
+		}
+		// pings only carry the ping, they must not replace the subscription filters
+		for _, req := range server.Requests()[1:] {
+			if req.Ping == nil || req.Accounts != nil || req.Commitment != nil {
+				t.Errorf("%s: got request %+v, want a ping only", tt.name, req)
+			}
+		}
+		geyserStream.Close()
+		cancel()
+	}
+}
+
+func TestGeyserSourceOverGrpc(t *testing.T) {
+	server, client := newEndpoint(t, "", nil)
+	fetcher := server.Feed.Fetcher
+	pools := []solana.PublicKey{solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()}
+	fetcher.SetSlot(400)
+	setAccounts(t, fetcher, pools...)
+
+	source := &stream.GeyserSource{Dial: client.Subscribe, ReconnectDelay: 10 * time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	snapshots, err := source.Subscribe(ctx, pools)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func() stream.PoolSnapshot {
+		t.Helper()
+		select {
+		case snapshot := <-snapshots:
+			return snapshot
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for a snapshot")
+		}
+		return stream.PoolSnapshot{}
+	}
+	if err := server.Feed.Publish(pools[0]); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot := next(); snapshot.Address != pools[0] || snapshot.Slot != 400 || snapshot.QuoteReserve != 1_000_000_000 {
+		t.Fatalf("got %s at slot %d", snapshot.Address, snapshot.Slot)
+	}
+
+	// the endpoint ending the call makes the source subscribe again
+	server.Feed.DropStreams()
+	waitFor(t, "the source to subscribe again", func() bool { return server.Feed.Dials() >= 2 && server.Feed.Streams() == 1 })
+	fetcher.SetSlot(401)
+	if err := fetcher.SetPool(pools[1], &common.Pool{QuoteReserve: 2_000_000_000}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Feed.Publish(pools[1]); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot := next(); snapshot.Address != pools[1] || snapshot.Slot != 401 || snapshot.QuoteReserve != 2_000_000_000 {
+		t.Fatalf("got %s at slot %d after resubscribing", snapshot.Address, snapshot.Slot)
+	}
+
+	cancel()
+	waitFor(t, "the streams to close", func() bool { return server.Feed.Streams() == 0 })
+}
@@ -0,0 +1,42 @@
+package geyser
+
+import "fmt"
+
+// message of the Subscribe call
+type Message interface {
+	Marshal() []byte
+	Unmarshal(data []byte) error
+}
+
+var (
+	_ Message = (*SubscribeRequest)(nil)
+	_ Message = (*SubscribeUpdate)(nil)
+)
+
+// grpc codec of the Subscribe messages, passed with grpc.ForceCodec on the client and
+// grpc.ForceServerCodec on a server
+type Codec struct{}
+
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(Message)
+	if !ok {
+		return nil, fmt.Errorf("failed to marshal %T: not a geyser message", v)
+	}
+	return m.Marshal(), nil
+}
+
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(Message)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal %T: not a geyser message", v)
+	}
+	if err := m.Unmarshal(data); err != nil {
+		return fmt.Errorf("failed to unmarshal %T: %w", v, err)
+	}
+	return nil
+}
+
+// the messages are protobuf encoded
+func (Codec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,419 @@
+package geyser
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Messages of the Yellowstone geyser.proto used by the account subscription. Only the
+// fields the SDK reads or sets are modelled, unknown fields are skipped when decoding.
+// Field numbers follow rpcpool/yellowstone-grpc's geyser.proto.
+
+// commitment level of the streamed updates
+type CommitmentLevel int32
+
+const (
+	CommitmentProcessed CommitmentLevel = 0
+	CommitmentConfirmed CommitmentLevel = 1
+	CommitmentFinalized CommitmentLevel = 2
+)
+
+type SubscribeRequest struct {
+	// account filters by name, an update lists the names of the filters it matched
+	Accounts   map[string]*SubscribeRequestFilterAccounts // 1
+	Commitment *CommitmentLevel                           // 6
+	// a request with only a ping keeps the stream open without changing the filters
+	Ping *SubscribeRequestPing // 9
+}
+
+type SubscribeRequestFilterAccounts struct {
+	// base58 addresses
+	Account []string // 2
+	// base58 owner program ids
+	Owner []string // 3
+}
+
+type SubscribeRequestPing struct {
+	ID int32 // 1
+}
+
+type SubscribeUpdate struct {
+	Filters []string                // 1
+	Account *SubscribeUpdateAccount // 2
+	Ping    *SubscribeUpdatePing    // 6
+	Pong    *SubscribeUpdatePong    // 9
+}
+
+type SubscribeUpdateAccount struct {
+	Account   *SubscribeUpdateAccountInfo // 1
+	Slot      uint64                      // 2
+	IsStartup bool                        // 3
+}
+
+type SubscribeUpdateAccountInfo struct {
+	Pubkey       []byte // 1
+	Lamports     uint64 // 2
+	Owner        []byte // 3
+	Executable   bool   // 4
+	RentEpoch    uint64 // 5
+	Data         []byte // 6
+	WriteVersion uint64 // 7
+}
+
+// sent by the endpoint to idle streams
+type SubscribeUpdatePing struct{}
+
+// answer to a SubscribeRequestPing
+type SubscribeUpdatePong struct {
+	ID int32 // 1
+}
+
+var errWireType = errors.New("unexpected wire type")
+
+func (m *SubscribeRequest) Marshal() []byte {
+	var b []byte
+	// map entries are sorted so the encoding is deterministic
+	names := make([]string, 0, len(m.Accounts))
+	for name := range m.Accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var entry []byte
+		entry = appendString(entry, 1, name)
+		if filter := m.Accounts[name]; filter != nil {
+			entry = appendMessage(entry, 2, filter.Marshal())
+		}
+		b = appendMessage(b, 1, entry)
+	}
+	if m.Commitment != nil {
+		// an optional field, processed is written even though it is the zero value
+		b = protowire.AppendTag(b, 6, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(*m.Commitment))
+	}
+	if m.Ping != nil {
+		b = appendMessage(b, 9, m.Ping.Marshal())
+	}
+	return b
+}
+
+func (m *SubscribeRequest) Unmarshal(b []byte) error {
+	*m = SubscribeRequest{}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			entry, n, err := consumeBytes(typ, b)
+			if err != nil {
+				return 0, err
+			}
+			name, filter := "", new(SubscribeRequestFilterAccounts)
+			err = consumeFields(entry, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+				switch num {
+				case 1:
+					v, n, err := consumeBytes(typ, b)
+					name = string(v)
+					return n, err
+				case 2:
+					v, n, err := consumeBytes(typ, b)
+					if err != nil {
+						return 0, err
+					}
+					return n, filter.Unmarshal(v)
+				}
+				return 0, nil
+			})
+			if err != nil {
+				return 0, fmt.Errorf("accounts filter: %w", err)
+			}
+			if m.Accounts == nil {
+				m.Accounts = make(map[string]*SubscribeRequestFilterAccounts)
+			}
+			m.Accounts[name] = filter
+			return n, nil
+		case 6:
+			v, n, err := consumeVarint(typ, b)
+			commitment := CommitmentLevel(v)
+			m.Commitment = &commitment
+			return n, err
+		case 9:
+			v, n, err := consumeBytes(typ, b)
+			if err != nil {
+				return 0, err
+			}
+			m.Ping = new(SubscribeRequestPing)
+			return n, m.Ping.Unmarshal(v)
+		}
+		return 0, nil
+	})
+}
+
+func (m *SubscribeRequestFilterAccounts) Marshal() []byte {
+	var b []byte
+	for _, account := range m.Account {
+		b = appendString(b, 2, account)
+	}
+	for _, owner := range m.Owner {
+		b = appendString(b, 3, owner)
+	}
+	return b
+}
+
+func (m *SubscribeRequestFilterAccounts) Unmarshal(b []byte) error {
+	*m = SubscribeRequestFilterAccounts{}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 2:
+			v, n, err := consumeBytes(typ, b)
+			m.Account = append(m.Account, string(v))
+			return n, err
+		case 3:
+			v, n, err := consumeBytes(typ, b)
+			m.Owner = append(m.Owner, string(v))
+			return n, err
+		}
+		return 0, nil
+	})
+}
+
+func (m *SubscribeRequestPing) Marshal() []byte {
+	return appendVarint(nil, 1, uint64(m.ID))
+}
+
+func (m *SubscribeRequestPing) Unmarshal(b []byte) error {
+	*m = SubscribeRequestPing{}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num != 1 {
+			return 0, nil
+		}
+		v, n, err := consumeVarint(typ, b)
+		m.ID = int32(v)
+		return n, err
+	})
+}
+
+func (m *SubscribeUpdate) Marshal() []byte {
+	var b []byte
+	for _, filter := range m.Filters {
+		b = appendString(b, 1, filter)
+	}
+	if m.Account != nil {
+		b = appendMessage(b, 2, m.Account.Marshal())
+	}
+	if m.Ping != nil {
+		b = appendMessage(b, 6, nil)
+	}
+	if m.Pong != nil {
+		b = appendMessage(b, 9, m.Pong.Marshal())
+	}
+	return b
+}
+
+func (m *SubscribeUpdate) Unmarshal(b []byte) error {
+	*m = SubscribeUpdate{}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			v, n, err := consumeBytes(typ, b)
+			m.Filters = append(m.Filters, string(v))
+			return n, err
+		case 2:
+			v, n, err := consumeBytes(typ, b)
+			if err != nil {
+				return 0, err
+			}
+			m.Account = new(SubscribeUpdateAccount)
+			return n, m.Account.Unmarshal(v)
+		case 6:
+			_, n, err := consumeBytes(typ, b)
+			m.Ping = new(SubscribeUpdatePing)
+			return n, err
+		case 9:
+			v, n, err := consumeBytes(typ, b)
+			if err != nil {
+				return 0, err
+			}
+			m.Pong = new(SubscribeUpdatePong)
+			return n, m.Pong.Unmarshal(v)
+		}
+		return 0, nil
+	})
+}
+
+func (m *SubscribeUpdateAccount) Marshal() []byte {
+	var b []byte
+	if m.Account != nil {
+		b = appendMessage(b, 1, m.Account.Marshal())
+	}
+	b = appendVarint(b, 2, m.Slot)
+	return appendVarint(b, 3, boolVarint(m.IsStartup))
+}
+
+func (m *SubscribeUpdateAccount) Unmarshal(b []byte) error {
+	*m = SubscribeUpdateAccount{}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			v, n, err := consumeBytes(typ, b)
+			if err != nil {
+				return 0, err
+			}
+			m.Account = new(SubscribeUpdateAccountInfo)
+			return n, m.Account.Unmarshal(v)
+		case 2:
+			v, n, err := consumeVarint(typ, b)
+			m.Slot = v
+			return n, err
+		case 3:
+			v, n, err := consumeVarint(typ, b)
+			m.IsStartup = v != 0
+			return n, err
+		}
+		return 0, nil
+	})
+}
+
+func (m *SubscribeUpdateAccountInfo) Marshal() []byte {
+	var b []byte
+	b = appendBytes(b, 1, m.Pubkey)
+	b = appendVarint(b, 2, m.Lamports)
+	b = appendBytes(b, 3, m.Owner)
+	b = appendVarint(b, 4, boolVarint(m.Executable))
+	b = appendVarint(b, 5, m.RentEpoch)
+	b = appendBytes(b, 6, m.Data)
+	return appendVarint(b, 7, m.WriteVersion)
+}
+
+func (m *SubscribeUpdateAccountInfo) Unmarshal(b []byte) error {
+	*m = SubscribeUpdateAccountInfo{}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1, 3, 6:
+			v, n, err := consumeBytes(typ, b)
+			// the decoded message must not alias the receive buffer
+			v = append([]byte{}, v...)
+			switch num {
+			case 1:
+				m.Pubkey = v
+			case 3:
+				m.Owner = v
+			default:
+				m.Data = v
+			}
+			return n, err
+		case 2, 4, 5, 7:
+			v, n, err := consumeVarint(typ, b)
+			switch num {
+			case 2:
+				m.Lamports = v
+			case 4:
+				m.Executable = v != 0
+			case 5:
+				m.RentEpoch = v
+			default:
+				m.WriteVersion = v
+			}
+			return n, err
+		}
+		return 0, nil
+	})
+}
+
+func (m *SubscribeUpdatePong) Marshal() []byte {
+	return appendVarint(nil, 1, uint64(m.ID))
+}
+
+func (m *SubscribeUpdatePong) Unmarshal(b []byte) error {
+	*m = SubscribeUpdatePong{}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num != 1 {
+			return 0, nil
+		}
+		v, n, err := consumeVarint(typ, b)
+		m.ID = int32(v)
+		return n, err
+	})
+}
+
+// proto3 omits scalar fields holding their zero value
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	return appendBytes(b, num, []byte(v))
+}
+
+// embedded messages are written even when empty, their presence is meaningful
+func appendMessage(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func boolVarint(v bool) uint64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// calls field with the value of every field of the message, field returns the length
+// it consumed or zero to skip an unknown field
+func consumeFields(b []byte, field func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		n, err := field(num, typ, b)
+		if err != nil {
+			return fmt.Errorf("field %d: %w", num, err)
+		}
+		if n == 0 {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return fmt.Errorf("field %d: %w", num, protowire.ParseError(n))
+			}
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func consumeBytes(typ protowire.Type, b []byte) ([]byte, int, error) {
+	if typ != protowire.BytesType {
+		return nil, 0, errWireType
+	}
+	v, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return nil, 0, protowire.ParseError(n)
+	}
+	return v, n, nil
+}
+
+func consumeVarint(typ protowire.Type, b []byte) (uint64, int, error) {
+	if typ != protowire.VarintType {
+		return 0, 0, errWireType
+	}
+	v, n := protowire.ConsumeVarint(b)
+	if n < 0 {
+		return 0, 0, protowire.ParseError(n)
+	}
+	return v, n, nil
+}
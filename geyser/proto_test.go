@@ -0,0 +1,154 @@
+package geyser_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/Luigi-1Combo/dbc-go/geyser"
+)
+
+func commitment(level geyser.CommitmentLevel) *geyser.CommitmentLevel {
+	return &level
+}
+
+func TestSubscribeRequestEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *geyser.SubscribeRequest
+		// protobuf encoding, as protoc-generated code writes it
+		want []byte
+	}{
+		{
+			name: "accounts filter",
+			req: &geyser.SubscribeRequest{
+				Accounts:   map[string]*geyser.SubscribeRequestFilterAccounts{"a": {Account: []string{"x"}}},
+				Commitment: commitment(geyser.CommitmentConfirmed),
+			},
+			// accounts entry {1: "a", 2: {2: "x"}}, commitment 1
+			want: []byte{0x0a, 0x08, 0x0a, 0x01, 'a', 0x12, 0x03, 0x12, 0x01, 'x', 0x30, 0x01},
+		},
+		{
+			name: "processed commitment is written",
+			req:  &geyser.SubscribeRequest{Commitment: commitment(geyser.CommitmentProcessed)},
+			want: []byte{0x30, 0x00},
+		},
+		{
+			name: "ping",
+			req:  &geyser.SubscribeRequest{Ping: &geyser.SubscribeRequestPing{ID: 1}},
+			want: []byte{0x4a, 0x02, 0x08, 0x01},
+		},
+		{
+			name: "owner filter",
+			req: &geyser.SubscribeRequest{
+				Accounts: map[string]*geyser.SubscribeRequestFilterAccounts{"b": {Owner: []string{"yz"}}},
+			},
+			want: []byte{0x0a, 0x09, 0x0a, 0x01, 'b', 0x12, 0x04, 0x1a, 0x02, 'y', 'z'},
+		},
+	}
+	for _, tt := range tests {
+		got := tt.req.Marshal()
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: got %x, want %x", tt.name, got, tt.want)
+		}
+		var decoded geyser.SubscribeRequest
+		if err := decoded.Unmarshal(got); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(&decoded, tt.req) {
+			t.Errorf("%s: decoded %+v, want %+v", tt.name, decoded, tt.req)
+		}
+	}
+}
+
+func TestSubscribeUpdateEncoding(t *testing.T) {
+	account := &geyser.SubscribeUpdate{
+		Filters: []string{"dbc"},
+		Account: &geyser.SubscribeUpdateAccount{
+			Account: &geyser.SubscribeUpdateAccountInfo{
+				Pubkey:       bytes.Repeat([]byte{1}, 32),
+				Lamports:     1_000_000,
+				Owner:        bytes.Repeat([]byte{2}, 32),
+				RentEpoch:    18446744073709551615,
+				Data:         []byte{3, 4, 5},
+				WriteVersion: 7,
+			},
+			Slot:      300,
+			IsStartup: true,
+		},
+	}
+	tests := []struct {
+		name   string
+		update *geyser.SubscribeUpdate
+		want   []byte
+	}{
+		{name: "account", update: account},
+		{name: "ping", update: &geyser.SubscribeUpdate{Filters: []string{"dbc"}, Ping: &geyser.SubscribeUpdatePing{}}, want: []byte{0x0a, 0x03, 'd', 'b', 'c', 0x32, 0x00}},
+		{name: "pong", update: &geyser.SubscribeUpdate{Pong: &geyser.SubscribeUpdatePong{ID: 1}}, want: []byte{0x4a, 0x02, 0x08, 0x01}},
+	}
+	for _, tt := range tests {
+		got := tt.update.Marshal()
+		if tt.want != nil && !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: got %x, want %x", tt.name, got, tt.want)
+		}
+		var decoded geyser.SubscribeUpdate
+		if err := decoded.Unmarshal(got); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(&decoded, tt.update) {
+			t.Errorf("%s: decoded %+v, want %+v", tt.name, decoded, tt.update)
+		}
+	}
+}
+
+func TestSubscribeUpdateDecoding(t *testing.T) {
+	slot := []byte{0x12, 0x02, 0x10, 0x05}
+	tests := []struct {
+		name    string
+		data    []byte
+		want    *geyser.SubscribeUpdate
+		wantErr bool
+	}{
+		{name: "slot of an account update", data: slot, want: &geyser.SubscribeUpdate{Account: &geyser.SubscribeUpdateAccount{Slot: 5}}},
+		// a slot update {3: {1: 9}} and the created_at timestamp {11: {1: 1}} are skipped
+		{name: "unknown fields", data: append([]byte{0x1a, 0x02, 0x08, 0x09, 0x5a, 0x02, 0x08, 0x01}, slot...), want: &geyser.SubscribeUpdate{Account: &geyser.SubscribeUpdateAccount{Slot: 5}}},
+		{name: "empty", data: nil, want: &geyser.SubscribeUpdate{}},
+		{name: "truncated message", data: slot[:3], wantErr: true},
+		{name: "truncated tag", data: []byte{0x80}, wantErr: true},
+		{name: "wrong wire type", data: []byte{0x10, 0x01}, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got geyser.SubscribeUpdate
+		err := got.Unmarshal(tt.data)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %+v", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(&got, tt.want) {
+			t.Errorf("%s: got %+v, %v, want %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestCodec(t *testing.T) {
+	codec := geyser.Codec{}
+	if codec.Name() != "proto" {
+		t.Fatalf("got codec name %q", codec.Name())
+	}
+	data, err := codec.Marshal(&geyser.SubscribeRequest{Ping: &geyser.SubscribeRequestPing{ID: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req geyser.SubscribeRequest
+	if err := codec.Unmarshal(data, &req); err != nil || req.Ping == nil || req.Ping.ID != 3 {
+		t.Fatalf("got %+v, %v", req, err)
+	}
+	if _, err := codec.Marshal("not a message"); err == nil {
+		t.Fatal("expected an error for a value that is not a geyser message")
+	}
+	if err := codec.Unmarshal([]byte{0x80}, &req); err == nil {
+		t.Fatal("expected an error for a truncated message")
+	}
+}
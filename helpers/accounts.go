@@ -2,142 +2,163 @@ package helpers
 
 import (
 	"bytes"
-	"log"
 
 	"github.com/Luigi-1Combo/dbc-go/common"
 	"github.com/gagliardetto/solana-go"
 )
 
-// Derives the dbc pool address
-func DeriveDbcPoolPDA(quoteMint, baseMint, config solana.PublicKey) solana.PublicKey {
-	// pda order: the larger public key bytes goes first
-	var mintA, mintB solana.PublicKey
-	if bytes.Compare(quoteMint.Bytes(), baseMint.Bytes()) > 0 {
-		mintA = quoteMint
-		mintB = baseMint
-	} else {
-		mintA = baseMint
-		mintB = quoteMint
+// static PDAs of the DBC program, derived once
+var (
+	PoolAuthority      solana.PublicKey
+	PoolAuthorityBump  uint8
+	EventAuthority     solana.PublicKey
+	EventAuthorityBump uint8
+)
+
+func init() {
+	programID := solana.MustPublicKeyFromBase58(common.DbcProgramID)
+
+	var err error
+	PoolAuthority, PoolAuthorityBump, err = FindPoolAuthorityPDA(programID)
+	if err != nil {
+		panic(err)
+	}
+	EventAuthority, EventAuthorityBump, err = FindEventAuthorityPDA(programID)
+	if err != nil {
+		panic(err)
 	}
+}
+
+// Finds the dbc pool address and bump
+func FindDbcPoolPDA(programID, quoteMint, baseMint, config solana.PublicKey) (solana.PublicKey, uint8, error) {
+	// pda order: the larger public key bytes goes first
+	mintA, mintB := sortMints(quoteMint, baseMint)
 	seeds := [][]byte{
 		[]byte("pool"),
 		config.Bytes(),
 		mintA.Bytes(),
 		mintB.Bytes(),
 	}
-	pda, _, err := solana.FindProgramAddress(seeds, solana.MustPublicKeyFromBase58(common.DbcProgramID))
-	if err != nil {
-		log.Fatalf("find pool PDA: %v", err)
-	}
-	return pda
+	return FindProgramAddressCached(seeds, programID)
 }
 
-// Derives the DAMM V1 pool address
-func DeriveDammV1PoolPDA(config, tokenAMint, tokenBMint solana.PublicKey) solana.PublicKey {
-	// Get the first and second keys based on byte comparison
-	var firstKey, secondKey solana.PublicKey
-	if bytes.Compare(tokenAMint.Bytes(), tokenBMint.Bytes()) > 0 {
-		firstKey = tokenAMint
-		secondKey = tokenBMint
-	} else {
-		firstKey = tokenBMint
-		secondKey = tokenAMint
-	}
-
+// Finds the DAMM V1 pool address and bump
+func FindDammV1PoolPDA(programID, config, tokenAMint, tokenBMint solana.PublicKey) (solana.PublicKey, uint8, error) {
+	firstKey, secondKey := sortMints(tokenAMint, tokenBMint)
 	seeds := [][]byte{
 		firstKey.Bytes(),
 		secondKey.Bytes(),
 		config.Bytes(),
 	}
-	pda, _, err := solana.FindProgramAddress(seeds, solana.MustPublicKeyFromBase58(common.DammV1ProgramID))
-	if err != nil {
-		log.Fatalf("find DAMM V1 pool PDA: %v", err)
-	}
-	return pda
+	return FindProgramAddressCached(seeds, programID)
 }
 
-// Derives the DAMM V2 pool address
-func DeriveDammV2PoolPDA(config, tokenAMint, tokenBMint solana.PublicKey) solana.PublicKey {
-	// Get the first and second keys based on byte comparison
-	var firstKey, secondKey solana.PublicKey
-	if bytes.Compare(tokenAMint.Bytes(), tokenBMint.Bytes()) > 0 {
-		firstKey = tokenAMint
-		secondKey = tokenBMint
-	} else {
-		firstKey = tokenBMint
-		secondKey = tokenAMint
-	}
-
+// Finds the DAMM V2 pool address and bump
+func FindDammV2PoolPDA(programID, config, tokenAMint, tokenBMint solana.PublicKey) (solana.PublicKey, uint8, error) {
+	firstKey, secondKey := sortMints(tokenAMint, tokenBMint)
 	seeds := [][]byte{
 		[]byte("pool"),
 		config.Bytes(),
 		firstKey.Bytes(),
 		secondKey.Bytes(),
 	}
-	pda, _, err := solana.FindProgramAddress(seeds, solana.MustPublicKeyFromBase58(common.DammV2ProgramID))
-	if err != nil {
-		log.Fatalf("find DAMM V2 pool PDA: %v", err)
-	}
-	return pda
+	return FindProgramAddressCached(seeds, programID)
 }
 
-// Derives the dbc token vault address
-func DeriveTokenVaultPDA(pool, mint solana.PublicKey) solana.PublicKey {
-	seed := [][]byte{
+// Finds the dbc token vault address and bump
+func FindTokenVaultPDA(programID, pool, mint solana.PublicKey) (solana.PublicKey, uint8, error) {
+	seeds := [][]byte{
 		[]byte("token_vault"),
 		mint.Bytes(),
 		pool.Bytes(),
 	}
-	pda, _, err := solana.FindProgramAddress(seed, solana.MustPublicKeyFromBase58(common.DbcProgramID))
-	if err != nil {
-		log.Fatalf("find vault PDA: %v", err)
+	return FindProgramAddressCached(seeds, programID)
+}
+
+// Finds the event authority address and bump
+func FindEventAuthorityPDA(programID solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return FindProgramAddressCached([][]byte{[]byte("__event_authority")}, programID)
+}
+
+// Finds the pool authority address and bump
+func FindPoolAuthorityPDA(programID solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return FindProgramAddressCached([][]byte{[]byte("pool_authority")}, programID)
+}
+
+// Finds the mint metadata address and bump
+func FindMintMetadataPDA(metadataProgramID, mint solana.PublicKey) (solana.PublicKey, uint8, error) {
+	seeds := [][]byte{
+		[]byte("metadata"),
+		metadataProgramID.Bytes(),
+		mint.Bytes(),
 	}
-	return pda
+	return FindProgramAddressCached(seeds, metadataProgramID)
+}
+
+// Finds the DAMM V1 migration metadata address and bump
+func FindDammV1MigrationMetadataPDA(programID, pool solana.PublicKey) (solana.PublicKey, uint8, error) {
+	seeds := [][]byte{
+		[]byte("meteora"),
+		pool.Bytes(),
+	}
+	return FindProgramAddressCached(seeds, programID)
+}
+
+// The Derive functions below use the mainnet program ids and panic when no address
+// is found, which does not happen for valid seeds; use the Find variants to get the
+// error and bump instead.
+
+// Derives the dbc pool address
+func DeriveDbcPoolPDA(quoteMint, baseMint, config solana.PublicKey) solana.PublicKey {
+	return mustPDA(FindDbcPoolPDA(solana.MustPublicKeyFromBase58(common.DbcProgramID), quoteMint, baseMint, config))
+}
+
+// Derives the DAMM V1 pool address
+func DeriveDammV1PoolPDA(config, tokenAMint, tokenBMint solana.PublicKey) solana.PublicKey {
+	return mustPDA(FindDammV1PoolPDA(solana.MustPublicKeyFromBase58(common.DammV1ProgramID), config, tokenAMint, tokenBMint))
+}
+
+// Derives the DAMM V2 pool address
+func DeriveDammV2PoolPDA(config, tokenAMint, tokenBMint solana.PublicKey) solana.PublicKey {
+	return mustPDA(FindDammV2PoolPDA(solana.MustPublicKeyFromBase58(common.DammV2ProgramID), config, tokenAMint, tokenBMint))
+}
+
+// Derives the dbc token vault address
+func DeriveTokenVaultPDA(pool, mint solana.PublicKey) solana.PublicKey {
+	return mustPDA(FindTokenVaultPDA(solana.MustPublicKeyFromBase58(common.DbcProgramID), pool, mint))
 }
 
 // Derives the event authority PDA
 func DeriveEventAuthorityPDA() solana.PublicKey {
-	seeds := [][]byte{[]byte("__event_authority")}
-	address, _, err := solana.FindProgramAddress(seeds, solana.MustPublicKeyFromBase58(common.DbcProgramID))
-	if err != nil {
-		panic(err)
-	}
-	return address
+	return EventAuthority
 }
 
 // Derives the pool authority PDA
 func DerivePoolAuthorityPDA() solana.PublicKey {
-	seeds := [][]byte{[]byte("pool_authority")}
-	address, _, err := solana.FindProgramAddress(seeds, solana.MustPublicKeyFromBase58(common.DbcProgramID))
-	if err != nil {
-		panic(err)
-	}
-	return address
+	return PoolAuthority
 }
 
 // Derives the mint metadata address
 func DeriveMintMetadataPDA(mint solana.PublicKey) solana.PublicKey {
-	seeds := [][]byte{
-		[]byte("metadata"),
-		solana.MustPublicKeyFromBase58(common.MetadataProgram).Bytes(),
-		mint.Bytes(),
-	}
-	pda, _, err := solana.FindProgramAddress(seeds, solana.MustPublicKeyFromBase58(common.MetadataProgram))
-	if err != nil {
-		log.Fatalf("find mint metadata PDA: %v", err)
-	}
-	return pda
+	return mustPDA(FindMintMetadataPDA(solana.MustPublicKeyFromBase58(common.MetadataProgram), mint))
 }
 
 // Derives the DAMM V1 migration metadata PDA
 func DeriveDammV1MigrationMetadataPda(pool solana.PublicKey) solana.PublicKey {
-	seeds := [][]byte{
-		[]byte("meteora"),
-		pool.Bytes(),
+	return mustPDA(FindDammV1MigrationMetadataPDA(solana.MustPublicKeyFromBase58(common.DbcProgramID), pool))
+}
+
+// orders the mints with the larger public key bytes first
+func sortMints(a, b solana.PublicKey) (solana.PublicKey, solana.PublicKey) {
+	if bytes.Compare(a.Bytes(), b.Bytes()) > 0 {
+		return a, b
 	}
-	pda, _, err := solana.FindProgramAddress(seeds, solana.MustPublicKeyFromBase58(common.DbcProgramID))
+	return b, a
+}
+
+func mustPDA(pda solana.PublicKey, _ uint8, err error) solana.PublicKey {
 	if err != nil {
-		log.Fatalf("find DAMM V1 migration metadata PDA: %v", err)
+		panic(err)
 	}
 	return pda
 }
@@ -0,0 +1,147 @@
+package helpers_test
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/helpers"
+)
+
+func TestFindPDAs(t *testing.T) {
+	dbc := solana.MustPublicKeyFromBase58(common.DbcProgramID)
+	dammV1 := solana.MustPublicKeyFromBase58(common.DammV1ProgramID)
+	dammV2 := solana.MustPublicKeyFromBase58(common.DammV2ProgramID)
+	metadata := solana.MustPublicKeyFromBase58(common.MetadataProgram)
+	config := solana.NewWallet().PublicKey()
+	pool := solana.NewWallet().PublicKey()
+	mint := solana.NewWallet().PublicKey()
+	// mints in both orders, the pool seeds put the larger one first
+	small, large := solana.PublicKey{1}, solana.PublicKey{2}
+
+	tests := []struct {
+		name      string
+		find      func() (solana.PublicKey, uint8, error)
+		derive    func() solana.PublicKey
+		seeds     [][]byte
+		programID solana.PublicKey
+	}{
+		{
+			name:      "dbc pool",
+			find:      func() (solana.PublicKey, uint8, error) { return helpers.FindDbcPoolPDA(dbc, small, large, config) },
+			derive:    func() solana.PublicKey { return helpers.DeriveDbcPoolPDA(large, small, config) },
+			seeds:     [][]byte{[]byte("pool"), config[:], large[:], small[:]},
+			programID: dbc,
+		},
+		{
+			name: "DAMM V1 pool",
+			find: func() (solana.PublicKey, uint8, error) {
+				return helpers.FindDammV1PoolPDA(dammV1, config, small, large)
+			},
+			derive:    func() solana.PublicKey { return helpers.DeriveDammV1PoolPDA(config, large, small) },
+			seeds:     [][]byte{large[:], small[:], config[:]},
+			programID: dammV1,
+		},
+		{
+			name: "DAMM V2 pool",
+			find: func() (solana.PublicKey, uint8, error) {
+				return helpers.FindDammV2PoolPDA(dammV2, config, small, large)
+			},
+			derive:    func() solana.PublicKey { return helpers.DeriveDammV2PoolPDA(config, large, small) },
+			seeds:     [][]byte{[]byte("pool"), config[:], large[:], small[:]},
+			programID: dammV2,
+		},
+		{
+			name:      "token vault",
+			find:      func() (solana.PublicKey, uint8, error) { return helpers.FindTokenVaultPDA(dbc, pool, mint) },
+			derive:    func() solana.PublicKey { return helpers.DeriveTokenVaultPDA(pool, mint) },
+			seeds:     [][]byte{[]byte("token_vault"), mint[:], pool[:]},
+			programID: dbc,
+		},
+		{
+			name:      "event authority",
+			find:      func() (solana.PublicKey, uint8, error) { return helpers.FindEventAuthorityPDA(dbc) },
+			derive:    helpers.DeriveEventAuthorityPDA,
+			seeds:     [][]byte{[]byte("__event_authority")},
+			programID: dbc,
+		},
+		{
+			name:      "pool authority",
+			find:      func() (solana.PublicKey, uint8, error) { return helpers.FindPoolAuthorityPDA(dbc) },
+			derive:    helpers.DerivePoolAuthorityPDA,
+			seeds:     [][]byte{[]byte("pool_authority")},
+			programID: dbc,
+		},
+		{
+			name:      "mint metadata",
+			find:      func() (solana.PublicKey, uint8, error) { return helpers.FindMintMetadataPDA(metadata, mint) },
+			derive:    func() solana.PublicKey { return helpers.DeriveMintMetadataPDA(mint) },
+			seeds:     [][]byte{[]byte("metadata"), metadata[:], mint[:]},
+			programID: metadata,
+		},
+		{
+			name:      "DAMM V1 migration metadata",
+			find:      func() (solana.PublicKey, uint8, error) { return helpers.FindDammV1MigrationMetadataPDA(dbc, pool) },
+			derive:    func() solana.PublicKey { return helpers.DeriveDammV1MigrationMetadataPda(pool) },
+			seeds:     [][]byte{[]byte("meteora"), pool[:]},
+			programID: dbc,
+		},
+	}
+	for _, tt := range tests {
+		want, wantBump, err := solana.FindProgramAddress(tt.seeds, tt.programID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the second lookup is served from the cache
+		for i := 0; i < 2; i++ {
+			got, bump, err := tt.find()
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if !got.Equals(want) || bump != wantBump {
+				t.Errorf("%s: got %s bump %d, want %s bump %d", tt.name, got, bump, want, wantBump)
+			}
+		}
+		if got := tt.derive(); !got.Equals(want) {
+			t.Errorf("%s: derived %s, want %s", tt.name, got, want)
+		}
+		// the bump recreates the address
+		recreated, err := solana.CreateProgramAddress(append(tt.seeds, []byte{wantBump}), tt.programID)
+		if err != nil || !recreated.Equals(want) {
+			t.Errorf("%s: bump %d recreates %s, %v", tt.name, wantBump, recreated, err)
+		}
+	}
+}
+
+func TestStaticPDAs(t *testing.T) {
+	dbc := solana.MustPublicKeyFromBase58(common.DbcProgramID)
+	poolAuthority, poolBump, _ := solana.FindProgramAddress([][]byte{[]byte("pool_authority")}, dbc)
+	eventAuthority, eventBump, _ := solana.FindProgramAddress([][]byte{[]byte("__event_authority")}, dbc)
+	if !helpers.PoolAuthority.Equals(poolAuthority) || helpers.PoolAuthorityBump != poolBump {
+		t.Errorf("got pool authority %s bump %d", helpers.PoolAuthority, helpers.PoolAuthorityBump)
+	}
+	if !helpers.EventAuthority.Equals(eventAuthority) || helpers.EventAuthorityBump != eventBump {
+		t.Errorf("got event authority %s bump %d", helpers.EventAuthority, helpers.EventAuthorityBump)
+	}
+}
+
+func TestFindProgramAddressCachedDisabled(t *testing.T) {
+	helpers.SetPdaCacheSize(0)
+	t.Cleanup(func() { helpers.SetPdaCacheSize(helpers.DefaultPdaCacheSize) })
+
+	programID := solana.NewWallet().PublicKey()
+	seeds := [][]byte{[]byte("seed")}
+	want, wantBump, _ := solana.FindProgramAddress(seeds, programID)
+	for i := 0; i < 2; i++ {
+		got, bump, err := helpers.FindProgramAddressCached(seeds, programID)
+		if err != nil || !got.Equals(want) || bump != wantBump {
+			t.Fatalf("got %s bump %d, %v, want %s bump %d", got, bump, err, want, wantBump)
+		}
+	}
+
+	// seeds longer than allowed fail like the uncached derivation
+	if _, _, err := helpers.FindProgramAddressCached([][]byte{make([]byte, 33)}, programID); err == nil {
+		t.Fatal("expected an error for a seed over 32 bytes")
+	}
+}
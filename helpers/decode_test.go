@@ -0,0 +1,282 @@
+package helpers_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"lukechampine.com/uint128"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/helpers"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+)
+
+// loads the data of an account dump, owned by the program
+func loadFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	dump, err := rpctest.LoadAccountDump("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump.Owner.String() != common.DbcProgramID {
+		t.Fatalf("%s: owned by %s", name, dump.Owner)
+	}
+	return dump.Data
+}
+
+func u128(s string) uint128.Uint128 {
+	v, err := uint128.FromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// reports the fields of got that differ from want, both structs of the same type
+func checkFields(t *testing.T, name string, got, want interface{}) {
+	t.Helper()
+	g, w := reflect.ValueOf(got), reflect.ValueOf(want)
+	for i := 0; i < w.NumField(); i++ {
+		if !reflect.DeepEqual(g.Field(i).Interface(), w.Field(i).Interface()) {
+			t.Errorf("%s: %s: got %+v, want %+v", name, w.Type().Field(i).Name, g.Field(i).Interface(), w.Field(i).Interface())
+		}
+	}
+}
+
+// the expected values are those written at the IDL offsets by testdata/gen_fixtures.go
+func TestDecodePoolConfigFixtures(t *testing.T) {
+	linear := common.PoolConfig{
+		QuoteMint:        solana.SolMint,
+		FeeClaimer:       solana.MustPublicKeyFromBase58("8LDtoeKGk7AZHWpScdwLgWCShxAuGXDq671MZ3nLpN21"),
+		LeftoverReceiver: solana.MustPublicKeyFromBase58("7dVPJAG6g5U1AEwabkiktt8FDKXkJE8esukNP3NFna3X"),
+		PoolFees: common.PoolFeesConfig{
+			BaseFee: common.BaseFeeConfig{
+				CliffFeeNumerator: 25_000_000, PeriodFrequency: 10, ReductionFactor: 500_000, NumberOfPeriod: 30, FeeSchedulerMode: common.FeeSchedulerModeLinear,
+			},
+			ProtocolFeePercent: 20,
+			ReferralFeePercent: 20,
+		},
+		CollectFeeMode:            common.CollectFeeModeQuoteToken,
+		ActivationType:            common.ActivationTypeSlot,
+		TokenDecimal:              9,
+		Version:                   0,
+		TokenType:                 common.TokenTypeSplToken,
+		PartnerLockedLpPercentage: 100,
+		SwapBaseAmount:            800_000_000_000_000_000,
+		MigrationQuoteThreshold:   80_000_000_000,
+		MigrationBaseThreshold:    200_000_000_000_000_000,
+		MigrationSqrtPrice:        u128("1165074669665358066"),
+		SqrtStartPrice:            u128("58333726687135158"),
+	}
+	linear.Curve[0] = common.LiquidityDistributionConfig{SqrtPrice: u128("233334906748540631"), Liquidity: u128("1622226400000000000000000000000")}
+	linear.Curve[1] = common.LiquidityDistributionConfig{SqrtPrice: u128("583337266871351581"), Liquidity: u128("2433339600000000000000000000000")}
+	linear.Curve[2] = common.LiquidityDistributionConfig{SqrtPrice: u128("1165074669665358066"), Liquidity: u128("3244452800000000000000000000000")}
+
+	exponential := common.PoolConfig{
+		QuoteMint:        solana.SolMint,
+		FeeClaimer:       solana.MustPublicKeyFromBase58("5unTfT2kssBuNvHPY6LbJfJpLqEcdMxGYLWHwShaeTLi"),
+		LeftoverReceiver: solana.MustPublicKeyFromBase58("8aQZ3Ph4XQXhZHSLtZYtNvYTyyVzTg9KkVGMEunqnNn2"),
+		PoolFees: common.PoolFeesConfig{
+			BaseFee: common.BaseFeeConfig{
+				CliffFeeNumerator: 500_000_000, PeriodFrequency: 60, ReductionFactor: 277, NumberOfPeriod: 120, FeeSchedulerMode: common.FeeSchedulerModeExponential,
+			},
+			DynamicFee: common.DynamicFeeConfig{
+				Initialized:              1,
+				MaxVolatilityAccumulator: 14_460_000,
+				VariableFeeControl:       1_788,
+				BinStep:                  1,
+				FilterPeriod:             10,
+				DecayPeriod:              120,
+				ReductionFactor:          5_000,
+				BinStepU128:              u128("1844674407370955"),
+			},
+			ProtocolFeePercent: 20,
+			ReferralFeePercent: 20,
+		},
+		CollectFeeMode:              common.CollectFeeModeOutputToken,
+		MigrationOption:             1,
+		ActivationType:              common.ActivationTypeTimestamp,
+		TokenDecimal:                6,
+		Version:                     1,
+		TokenType:                   common.TokenTypeToken2022,
+		PartnerLockedLpPercentage:   50,
+		CreatorLockedLpPercentage:   50,
+		MigrationFeeOption:          2,
+		FixedTokenSupplyFlag:        1,
+		CreatorTradingFeePercentage: 50,
+		SwapBaseAmount:              781_426_025_471_513,
+		MigrationQuoteThreshold:     85_000_000_000,
+		MigrationBaseThreshold:      189_774_891_900_224,
+		MigrationSqrtPrice:          u128("390400000000000000"),
+		LockedVestingConfig: common.LockedVestingConfig{
+			AmountPerPeriod: 1_000_000_000_000, CliffDurationFromMigrationTime: 86_400, Frequency: 3_600, NumberOfPeriod: 24, CliffUnlockAmount: 5_000_000_000_000,
+		},
+		PreMigrationTokenSupply:  1_000_000_000_000_000,
+		PostMigrationTokenSupply: 1_000_000_000_000_000,
+		SqrtStartPrice:           u128("97600000000000000"),
+	}
+	exponential.Curve[0] = common.LiquidityDistributionConfig{SqrtPrice: u128("195200000000000000"), Liquidity: u128("104594989832255675244889735890912")}
+	exponential.Curve[1] = common.LiquidityDistributionConfig{SqrtPrice: u128("390400000000000000"), Liquidity: u128("95878740679567702307815591233336")}
+
+	rateLimiter := common.PoolConfig{
+		QuoteMint:        solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"),
+		FeeClaimer:       solana.MustPublicKeyFromBase58("9z1ApuHVsv7aBm4wVURFUh7kvMB6Lc1TDVTdk6cAsBiW"),
+		LeftoverReceiver: solana.MustPublicKeyFromBase58("BsWRyYHShzFQEGwvPrRUY2txCT2BNzNzFZ3rVN3bywfS"),
+		PoolFees: common.PoolFeesConfig{
+			BaseFee: common.BaseFeeConfig{
+				CliffFeeNumerator: 10_000_000, PeriodFrequency: 150, ReductionFactor: 1_000_000_000, NumberOfPeriod: 10, FeeSchedulerMode: common.FeeSchedulerModeRateLimiter,
+			},
+			ProtocolFeePercent: 20,
+			ReferralFeePercent: 20,
+		},
+		CollectFeeMode:              common.CollectFeeModeQuoteToken,
+		MigrationOption:             1,
+		ActivationType:              common.ActivationTypeSlot,
+		TokenDecimal:                6,
+		Version:                     1,
+		TokenType:                   common.TokenTypeSplToken,
+		PartnerLockedLpPercentage:   40,
+		PartnerLpPercentage:         10,
+		CreatorLockedLpPercentage:   40,
+		CreatorLpPercentage:         10,
+		MigrationFeeOption:          3,
+		CreatorTradingFeePercentage: 25,
+		SwapBaseAmount:              700_000_000_000_000,
+		MigrationQuoteThreshold:     50_000_000_000,
+		MigrationBaseThreshold:      250_000_000_000_000,
+		MigrationSqrtPrice:          u128("82496443491455209"),
+		SqrtStartPrice:              u128("18446744073709551"),
+	}
+	rateLimiter.Curve[0] = common.LiquidityDistributionConfig{SqrtPrice: u128("82496443491455209"), Liquidity: u128("12397393120460284102049015186048")}
+
+	tests := []struct {
+		name string
+		file string
+		want common.PoolConfig
+	}{
+		{name: "linear fee scheduler, slot activation, version 0", file: "config_linear_slot.json", want: linear},
+		{name: "exponential fee scheduler, output token fees, timestamp activation, version 1", file: "config_exponential_timestamp.json", want: exponential},
+		{name: "rate limiter, version 1", file: "config_rate_limiter_slot.json", want: rateLimiter},
+	}
+	for _, tt := range tests {
+		data := loadFixture(t, tt.file)
+		if len(data) != helpers.PoolConfigAccountSize {
+			t.Errorf("%s: got %d bytes, want %d", tt.name, len(data), helpers.PoolConfigAccountSize)
+		}
+		config, err := helpers.DecodePoolConfig(data)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		checkFields(t, tt.name, *config, tt.want)
+	}
+
+	// the rate limiter parameters share the fee scheduler fields
+	config, err := helpers.DecodePoolConfig(loadFixture(t, "config_rate_limiter_slot.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter, ok := config.PoolFees.BaseFee.RateLimiter()
+	want := common.FeeRateLimiter{CliffFeeNumerator: 10_000_000, FeeIncrementBps: 10, MaxLimiterDuration: 150, ReferenceAmount: 1_000_000_000}
+	if !ok || limiter != want {
+		t.Errorf("got rate limiter %+v, %t, want %+v", limiter, ok, want)
+	}
+}
+
+func TestDecodePoolFixtures(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want common.Pool
+	}{
+		{
+			name: "part way along the curve",
+			file: "pool_curve.json",
+			want: common.Pool{
+				VolatilityTracker: common.VolatilityTracker{
+					LastUpdateTimestamp:   1_760_834_112,
+					SqrtPriceReference:    uint128.From64(118_902_455_391_284_730),
+					VolatilityAccumulator: uint128.From64(2_310_000),
+					VolatilityReference:   uint128.From64(1_155_000),
+				},
+				Config:           solana.MustPublicKeyFromBase58("FbKf76ucsQssF7XZBuzScdJfugtsSKwZFYztKsMEhWZM"),
+				Creator:          solana.MustPublicKeyFromBase58("3hxSMxrnD9Lbx9e6dJ7y8yvZFTm6ojvGjCtnhzbBDAqE"),
+				BaseMint:         solana.MustPublicKeyFromBase58("9QnHb2YZW3kVhvWrYx6MDmd8XH2xKc8VvmB2uNdvbonk"),
+				BaseVault:        solana.MustPublicKeyFromBase58("7YWdcXZfuyQJA5X9QAHrRXZa9KdwLQ5aHMXmvVYFD5aK"),
+				QuoteVault:       solana.MustPublicKeyFromBase58("AUa9j4jpm2rjwoq4uf3aoGJsgXSyQoWQkqmGvJPs6yo6"),
+				BaseReserve:      805_512_233_846_017,
+				QuoteReserve:     12_375_000_000,
+				ProtocolQuoteFee: 27_500_000,
+				PartnerQuoteFee:  55_000_000,
+				SqrtPrice:        uint128.From64(120_716_034_118_210_337),
+				ActivationPoint:  1_760_833_980,
+				Metrics:          common.PoolMetrics{TotalProtocolQuoteFee: 27_500_000, TotalTradingQuoteFee: 137_500_000},
+				CreatorQuoteFee:  55_000_000,
+			},
+		},
+		{
+			name: "migrated",
+			file: "pool_migrated.json",
+			want: common.Pool{
+				Config:                     solana.MustPublicKeyFromBase58("45XdFFzzZBvjFEBYT7WM9iq9RWNZYWkPtpgH68BrkaMf"),
+				Creator:                    solana.MustPublicKeyFromBase58("CM1ckdew83532TS9JbK1d36jPomkx1Uii9ebTzDbscTK"),
+				BaseMint:                   solana.MustPublicKeyFromBase58("3eu2SysweenQza5f6buCNC3f9fNf2cJgMBNxUxRMv2zX"),
+				BaseVault:                  solana.MustPublicKeyFromBase58("4q7FhZfDafzXG7gam2oCnNy5yZ5XZ4LS5oEgTwppCWj1"),
+				QuoteVault:                 solana.MustPublicKeyFromBase58("GqpxrPjRkSWa5BNuMufYHw67SmQQi4m6sUshovLLs4ia"),
+				ProtocolBaseFee:            1_200_000,
+				PartnerBaseFee:             3_600_000,
+				SqrtPrice:                  uint128.From64(82_496_443_491_455_209),
+				ActivationPoint:            371_204_117,
+				PoolType:                   1,
+				IsMigrated:                 1,
+				IsPartnerWithdrawSurplus:   1,
+				IsProtocolWithdrawSurplus:  1,
+				MigrationProgress:          3,
+				IsWithdrawLeftover:         1,
+				MigrationFeeWithdrawStatus: 3,
+				Metrics: common.PoolMetrics{
+					TotalProtocolBaseFee: 1_200_000, TotalProtocolQuoteFee: 250_000_000, TotalTradingBaseFee: 6_000_000, TotalTradingQuoteFee: 1_250_000_000,
+				},
+				FinishCurveTimestamp: 1_760_901_344,
+				CreatorBaseFee:       1_200_000,
+			},
+		},
+	}
+	for _, tt := range tests {
+		data := loadFixture(t, tt.file)
+		if len(data) != helpers.PoolAccountSize {
+			t.Errorf("%s: got %d bytes, want %d", tt.name, len(data), helpers.PoolAccountSize)
+		}
+		pool, err := helpers.DecodePool(data)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		checkFields(t, tt.name, *pool, tt.want)
+	}
+}
+
+// every prefix of an account must fail to decode rather than panic or decode garbage
+func TestDecodeTruncatedAccounts(t *testing.T) {
+	config := loadFixture(t, "config_exponential_timestamp.json")
+	pool := loadFixture(t, "pool_curve.json")
+
+	for n := 0; n < len(config); n++ {
+		if _, err := helpers.DecodePoolConfig(config[:n]); !errors.Is(err, helpers.ErrUnsupportedLayout) {
+			t.Fatalf("DecodePoolConfig of %d bytes: got %v, want ErrUnsupportedLayout", n, err)
+		}
+		if _, err := helpers.DeserializePoolConfig(config[:n]); err == nil {
+			t.Fatalf("DeserializePoolConfig of %d bytes succeeded", n)
+		}
+	}
+	for n := 0; n < len(pool); n++ {
+		if _, err := helpers.DecodePool(pool[:n]); !errors.Is(err, helpers.ErrUnsupportedLayout) {
+			t.Fatalf("DecodePool of %d bytes: got %v, want ErrUnsupportedLayout", n, err)
+		}
+		if _, err := helpers.DeserializePool(pool[:n]); err == nil {
+			t.Fatalf("DeserializePool of %d bytes succeeded", n)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package helpers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+)
+
+// account sizes of the current layouts, including the 8-byte discriminator
+var (
+	PoolAccountSize       = 8 + binary.Size(common.Pool{})
+	PoolConfigAccountSize = 8 + binary.Size(common.PoolConfig{})
+)
+
+// offset of PoolConfig.Version: discriminator, 3 public keys, pool fees, then 4 u8 fields
+var poolConfigVersionOffset = 8 + 3*32 + binary.Size(common.PoolFeesConfig{}) + 4
+
+// returned when no registered decoder matches the account version and size
+var ErrUnsupportedLayout = errors.New("unsupported account layout")
+
+type PoolDecoder func(data []byte) (*common.Pool, error)
+type PoolConfigDecoder func(data []byte) (*common.PoolConfig, error)
+
+type poolConfigLayoutKey struct {
+	version uint8
+	size    int
+}
+
+var (
+	layoutsMu         sync.RWMutex
+	poolLayouts       = map[int]PoolDecoder{}
+	poolConfigLayouts = map[poolConfigLayoutKey]PoolConfigDecoder{}
+)
+
+// The program has no legacy layouts: new fields take over padding or are appended, so
+// accounts of at least the current size decode with the current layout, trailing bytes
+// ignored, as DeserializePool and DeserializePoolConfig always did. Decoders registered
+// for an exact size take precedence over it.
+
+// Registers a decoder for pool accounts of the given size, including the discriminator
+func RegisterPoolLayout(size int, decoder PoolDecoder) {
+	layoutsMu.Lock()
+	defer layoutsMu.Unlock()
+	poolLayouts[size] = decoder
+}
+
+// Registers a decoder for pool config accounts of the given version and size,
+// including the discriminator; it takes precedence over the current layout
+func RegisterPoolConfigLayout(version uint8, size int, decoder PoolConfigDecoder) {
+	layoutsMu.Lock()
+	defer layoutsMu.Unlock()
+	poolConfigLayouts[poolConfigLayoutKey{version: version, size: size}] = decoder
+}
+
+// Decodes pool account data with the decoder registered for its size, or the current
+// layout when it is at least PoolAccountSize bytes
+func DecodePool(data []byte) (*common.Pool, error) {
+	layoutsMu.RLock()
+	decoder, ok := poolLayouts[len(data)]
+	layoutsMu.RUnlock()
+	if ok {
+		return decoder(data)
+	}
+	if len(data) < PoolAccountSize {
+		return nil, fmt.Errorf("%w: pool account of %d bytes, expected at least %d", ErrUnsupportedLayout, len(data), PoolAccountSize)
+	}
+	return DeserializePool(data)
+}
+
+// Decodes pool config account data with the decoder registered for its version and size,
+// or the current layout when it is at least PoolConfigAccountSize bytes
+func DecodePoolConfig(data []byte) (*common.PoolConfig, error) {
+	if len(data) <= poolConfigVersionOffset {
+		return nil, fmt.Errorf("%w: pool config account of %d bytes, expected at least %d", ErrUnsupportedLayout, len(data), PoolConfigAccountSize)
+	}
+	version := data[poolConfigVersionOffset]
+
+	layoutsMu.RLock()
+	decoder, ok := poolConfigLayouts[poolConfigLayoutKey{version: version, size: len(data)}]
+	layoutsMu.RUnlock()
+	if ok {
+		return decoder(data)
+	}
+	if len(data) < PoolConfigAccountSize {
+		return nil, fmt.Errorf("%w: pool config version %d of %d bytes, expected at least %d", ErrUnsupportedLayout, version, len(data), PoolConfigAccountSize)
+	}
+	return DeserializePoolConfig(data)
+}
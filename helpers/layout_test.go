@@ -0,0 +1,120 @@
+package helpers_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/helpers"
+)
+
+func TestLayoutSizes(t *testing.T) {
+	if helpers.PoolAccountSize != 424 {
+		t.Errorf("got pool account size %d, want 424", helpers.PoolAccountSize)
+	}
+	if helpers.PoolConfigAccountSize != 1048 {
+		t.Errorf("got pool config account size %d, want 1048", helpers.PoolConfigAccountSize)
+	}
+}
+
+func TestDecodePoolSizes(t *testing.T) {
+	pool := loadFixture(t, "pool_curve.json")
+	tests := []struct {
+		name      string
+		data      []byte
+		supported bool
+	}{
+		{name: "current layout", data: pool, supported: true},
+		{name: "one field appended", data: append(append([]byte(nil), pool...), make([]byte, 8)...), supported: true},
+		{name: "trailing data", data: append(append([]byte(nil), pool...), make([]byte, 1000)...), supported: true},
+		{name: "one field removed", data: pool[:len(pool)-8]},
+		{name: "discriminator only", data: pool[:8]},
+		{name: "empty", data: nil},
+	}
+	want, err := helpers.DeserializePool(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		got, err := helpers.DecodePool(tt.data)
+		if tt.supported && (err != nil || *got != *want) {
+			t.Errorf("%s: got %+v, %v, want the current layout", tt.name, got, err)
+		}
+		if !tt.supported && !errors.Is(err, helpers.ErrUnsupportedLayout) {
+			t.Errorf("%s: got %v, want ErrUnsupportedLayout", tt.name, err)
+		}
+	}
+}
+
+func TestDecodePoolConfigSizes(t *testing.T) {
+	config := loadFixture(t, "config_exponential_timestamp.json")
+	tests := []struct {
+		name      string
+		data      []byte
+		supported bool
+	}{
+		{name: "current layout", data: config, supported: true},
+		{name: "one field appended", data: append(append([]byte(nil), config...), make([]byte, 16)...), supported: true},
+		{name: "trailing data", data: append(append([]byte(nil), config...), make([]byte, 1000)...), supported: true},
+		{name: "one field removed", data: config[:len(config)-16]},
+		{name: "up to the version", data: config[:8+3*32]},
+		{name: "empty", data: nil},
+	}
+	want, err := helpers.DeserializePoolConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		got, err := helpers.DecodePoolConfig(tt.data)
+		if tt.supported && (err != nil || !reflect.DeepEqual(got, want)) {
+			t.Errorf("%s: got %+v, %v, want the current layout", tt.name, got, err)
+		}
+		if !tt.supported && !errors.Is(err, helpers.ErrUnsupportedLayout) {
+			t.Errorf("%s: got %v, want ErrUnsupportedLayout", tt.name, err)
+		}
+	}
+}
+
+// registered layouts take precedence over the current one for their own size, and config
+// layouts for their own version
+func TestRegisterLayouts(t *testing.T) {
+	pool := loadFixture(t, "pool_curve.json")
+	const extendedPoolSize = 432
+	extendedMarker := errors.New("extended layout")
+	helpers.RegisterPoolLayout(extendedPoolSize, func([]byte) (*common.Pool, error) {
+		return nil, extendedMarker
+	})
+	extended := append(append([]byte(nil), pool...), make([]byte, extendedPoolSize-len(pool))...)
+	if _, err := helpers.DecodePool(extended); !errors.Is(err, extendedMarker) {
+		t.Errorf("extended pool: got %v, want the registered decoder", err)
+	}
+	decoded, err := helpers.DecodePool(extended[:extendedPoolSize-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.BaseReserve != 805_512_233_846_017 {
+		t.Errorf("got base reserve %d from the current layout", decoded.BaseReserve)
+	}
+
+	config := loadFixture(t, "config_linear_slot.json")
+	// discriminator, 3 public keys, pool fees, then 4 u8 fields
+	versionOffset := 8 + 3*32 + binary.Size(common.PoolFeesConfig{}) + 4
+	version := config[versionOffset]
+	if version != 0 {
+		t.Fatalf("fixture has config version %d, want 0", version)
+	}
+	marker := errors.New("version 7 layout")
+	helpers.RegisterPoolConfigLayout(7, helpers.PoolConfigAccountSize, func([]byte) (*common.PoolConfig, error) {
+		return nil, marker
+	})
+	if _, err := helpers.DecodePoolConfig(config); err != nil {
+		t.Fatalf("version 0 config: %v", err)
+	}
+	versioned := append([]byte(nil), config...)
+	versioned[versionOffset] = 7
+	if _, err := helpers.DecodePoolConfig(versioned); !errors.Is(err, marker) {
+		t.Fatalf("version 7 config: got %v, want the registered decoder", err)
+	}
+}
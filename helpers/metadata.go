@@ -0,0 +1,247 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+)
+
+// schemes ValidateTokenMetadataPolicy accepts for the metadata uri, and ValidatePolicy
+// for the links of the off-chain metadata: where metadata is commonly hosted, not a
+// program rule
+var metadataURISchemes = []string{"https", "http", "ipfs", "ar"}
+
+// hosts ValidatePolicy accepts for the socials of the off-chain metadata, so launchpads
+// and explorers can render them as profile links
+var (
+	twitterHosts  = []string{"x.com", "twitter.com"}
+	telegramHosts = []string{"t.me", "telegram.me"}
+)
+
+// Validates the token metadata against the limits the program enforces, before it is
+// sent to InitializeVirtualPoolWithSplToken: a utf-8 name, symbol and uri of at most
+// MaxNameLength, MaxSymbolLength and MaxUriLength bytes. Metadata over them fails the
+// transaction on-chain after its fees are paid.
+func ValidateTokenMetadata(name, symbol, uri string) error {
+	fields := []struct {
+		field     string
+		value     string
+		maxLength int
+	}{
+		{"name", name, common.MaxNameLength},
+		{"symbol", symbol, common.MaxSymbolLength},
+		{"uri", uri, common.MaxUriLength},
+	}
+	for _, f := range fields {
+		if len(f.value) > f.maxLength {
+			return fmt.Errorf("%s must be at most %d bytes, got %d", f.field, f.maxLength, len(f.value))
+		}
+		if !utf8.ValidString(f.value) {
+			return fmt.Errorf("%s is not valid utf-8", f.field)
+		}
+	}
+	return nil
+}
+
+// Opt-in checks on top of ValidateTokenMetadata, for metadata wallets and explorers
+// display well: a name and symbol without control characters or surrounding
+// whitespace, and a uri with one of metadataURISchemes. The program does not require
+// any of them.
+func ValidateTokenMetadataPolicy(name, symbol, uri string) error {
+	if err := ValidateTokenMetadata(name, symbol, uri); err != nil {
+		return err
+	}
+	if err := validateMetadataString("name", name); err != nil {
+		return err
+	}
+	if err := validateMetadataString("symbol", symbol); err != nil {
+		return err
+	}
+	if uri != "" {
+		if err := validateMetadataURI("uri", uri); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checks a name or symbol is set, without control characters or surrounding whitespace
+func validateMetadataString(field, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is required", field)
+	}
+	for _, r := range value {
+		// readers trim the null padding of the stored strings, so control characters
+		// would not survive a round trip
+		if unicode.IsControl(r) {
+			return fmt.Errorf("%s contains the control character %U", field, r)
+		}
+	}
+	if strings.TrimSpace(value) != value {
+		return fmt.Errorf("%s has leading or trailing whitespace", field)
+	}
+	return nil
+}
+
+func validateMetadataURI(field, value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid uri: %w", field, err)
+	}
+	for _, scheme := range metadataURISchemes {
+		if parsed.Scheme == scheme {
+			if parsed.Host == "" && parsed.Opaque == "" {
+				return fmt.Errorf("%s %q has no host", field, value)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%s %q must use one of the schemes %s", field, value, strings.Join(metadataURISchemes, ", "))
+}
+
+// off-chain token metadata, the json document the metadata uri points to. Socials follow
+// the top-level twitter, telegram and website fields read by launchpads and explorers.
+type OffChainMetadata struct {
+	Name        string `json:"name"`
+	Symbol      string `json:"symbol"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image"`
+	ExternalURL string `json:"external_url,omitempty"`
+	Twitter     string `json:"twitter,omitempty"`
+	Telegram    string `json:"telegram,omitempty"`
+	Website     string `json:"website,omitempty"`
+}
+
+// max bytes of the off-chain description accepted by ValidatePolicy, well above what
+// wallets and explorers display
+const MaxDescriptionLength = 1000
+
+// Validates the off-chain metadata: a name and symbol within the on-chain limits, since
+// they must match the pool initialization, and utf-8 text throughout
+func (m *OffChainMetadata) Validate() error {
+	if err := ValidateTokenMetadata(m.Name, m.Symbol, ""); err != nil {
+		return err
+	}
+	fields := []struct {
+		field string
+		value string
+	}{
+		{"description", m.Description},
+		{"image", m.Image},
+		{"external_url", m.ExternalURL},
+		{"twitter", m.Twitter},
+		{"telegram", m.Telegram},
+		{"website", m.Website},
+	}
+	for _, f := range fields {
+		if !utf8.ValidString(f.value) {
+			return fmt.Errorf("%s is not valid utf-8", f.field)
+		}
+	}
+	return nil
+}
+
+// Opt-in checks on top of Validate, for documents launchpads and explorers render well:
+// the name and symbol rules of ValidateTokenMetadataPolicy, a description of at most
+// MaxDescriptionLength bytes, an image uri, well-formed links and socials on their
+// expected hosts
+func (m *OffChainMetadata) ValidatePolicy() error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	if err := validateMetadataString("name", m.Name); err != nil {
+		return err
+	}
+	if err := validateMetadataString("symbol", m.Symbol); err != nil {
+		return err
+	}
+	if len(m.Description) > MaxDescriptionLength {
+		return fmt.Errorf("description must be at most %d bytes, got %d", MaxDescriptionLength, len(m.Description))
+	}
+	if m.Image == "" {
+		return fmt.Errorf("image is required")
+	}
+
+	links := []struct {
+		field string
+		value string
+	}{
+		{"image", m.Image},
+		{"external_url", m.ExternalURL},
+	}
+	for _, link := range links {
+		if link.value == "" {
+			continue
+		}
+		if err := validateMetadataURI(link.field, link.value); err != nil {
+			return err
+		}
+	}
+
+	socials := []struct {
+		field string
+		value string
+		// nil for any host
+		hosts []string
+	}{
+		{"twitter", m.Twitter, twitterHosts},
+		{"telegram", m.Telegram, telegramHosts},
+		{"website", m.Website, nil},
+	}
+	for _, social := range socials {
+		if social.value == "" {
+			continue
+		}
+		if err := validateSocialURL(social.field, social.value, social.hosts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checks a social link is an absolute http(s) url, on one of the hosts when given,
+// with a path naming the profile or post
+func validateSocialURL(field, value string, hosts []string) error {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid url: %w", field, err)
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return fmt.Errorf("%s %q must be an http or https url", field, value)
+	}
+	if parsed.Hostname() == "" {
+		return fmt.Errorf("%s %q has no host", field, value)
+	}
+	if hosts == nil {
+		return nil
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	for _, allowed := range hosts {
+		if host == allowed {
+			if strings.Trim(parsed.Path, "/") == "" {
+				return fmt.Errorf("%s %q does not link to a profile", field, value)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%s %q must be on %s", field, value, strings.Join(hosts, " or "))
+}
+
+// Validates the off-chain metadata and encodes it as the json document to upload
+// before creating the pool with its uri
+func BuildMetadataJSON(metadata *OffChainMetadata) ([]byte, error) {
+	if err := metadata.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid off-chain metadata: %w", err)
+	}
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode off-chain metadata: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,225 @@
+package helpers_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Luigi-1Combo/dbc-go/helpers"
+)
+
+func TestValidateTokenMetadata(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		symbol string
+		uri    string
+		valid  bool
+	}{
+		{name: "https uri", token: "Dynamic Bonding", symbol: "DBC", uri: "https://arweave.net/abc123", valid: true},
+		{name: "no uri", token: "Dynamic Bonding", symbol: "DBC", valid: true},
+		{name: "multi-byte name at the limit", token: strings.Repeat("é", 16), symbol: "DBC", valid: true},
+		{name: "uri at the limit", token: "Dynamic Bonding", symbol: "DBC", uri: "https://arweave.net/" + strings.Repeat("a", 180), valid: true},
+		// the program stores these as given, only ValidateTokenMetadataPolicy rejects them
+		{name: "empty name and symbol", valid: true},
+		{name: "null byte", token: "Dynamic\x00", symbol: "DBC", valid: true},
+		{name: "trailing whitespace", token: "Dynamic Bonding", symbol: "DBC ", valid: true},
+		{name: "unsupported scheme", token: "Dynamic Bonding", symbol: "DBC", uri: "ftp://example.com/meta.json", valid: true},
+		{name: "relative uri", token: "Dynamic Bonding", symbol: "DBC", uri: "meta.json", valid: true},
+		{name: "name over 32 bytes", token: strings.Repeat("a", 33), symbol: "DBC"},
+		{name: "multi-byte name over 32 bytes", token: strings.Repeat("é", 17), symbol: "DBC"},
+		{name: "symbol over 10 bytes", token: "Dynamic Bonding", symbol: "ABCDEFGHIJK"},
+		{name: "uri over 200 bytes", token: "Dynamic Bonding", symbol: "DBC", uri: "https://arweave.net/" + strings.Repeat("a", 181)},
+		{name: "invalid utf-8 name", token: "Dynamic \xff", symbol: "DBC"},
+		{name: "invalid utf-8 symbol", token: "Dynamic Bonding", symbol: "DB\xc3"},
+		{name: "invalid utf-8 uri", token: "Dynamic Bonding", symbol: "DBC", uri: "https://arweave.net/\xff"},
+	}
+	for _, tt := range tests {
+		err := helpers.ValidateTokenMetadata(tt.token, tt.symbol, tt.uri)
+		if tt.valid && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestValidateTokenMetadataPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		symbol string
+		uri    string
+		valid  bool
+	}{
+		{name: "https uri", token: "Dynamic Bonding", symbol: "DBC", uri: "https://arweave.net/abc123", valid: true},
+		{name: "ipfs uri", token: "Dynamic Bonding", symbol: "DBC", uri: "ipfs://bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi", valid: true},
+		{name: "ar uri", token: "Dynamic Bonding", symbol: "DBC", uri: "ar://abc123", valid: true},
+		{name: "no uri", token: "Dynamic Bonding", symbol: "DBC", valid: true},
+		{name: "name over 32 bytes", token: strings.Repeat("a", 33), symbol: "DBC"},
+		{name: "invalid utf-8", token: "Dynamic \xff", symbol: "DBC"},
+		{name: "empty name", token: "", symbol: "DBC"},
+		{name: "empty symbol", token: "Dynamic Bonding", symbol: ""},
+		{name: "null byte", token: "Dynamic\x00", symbol: "DBC"},
+		{name: "newline", token: "Dynamic\nBonding", symbol: "DBC"},
+		{name: "leading whitespace", token: " Dynamic", symbol: "DBC"},
+		{name: "trailing whitespace", token: "Dynamic Bonding", symbol: "DBC "},
+		{name: "unsupported scheme", token: "Dynamic Bonding", symbol: "DBC", uri: "ftp://example.com/meta.json"},
+		{name: "relative uri", token: "Dynamic Bonding", symbol: "DBC", uri: "meta.json"},
+		{name: "uri without host", token: "Dynamic Bonding", symbol: "DBC", uri: "https:///meta.json"},
+	}
+	for _, tt := range tests {
+		err := helpers.ValidateTokenMetadataPolicy(tt.token, tt.symbol, tt.uri)
+		if tt.valid && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func validOffChainMetadata() helpers.OffChainMetadata {
+	return helpers.OffChainMetadata{
+		Name:        "Dynamic Bonding",
+		Symbol:      "DBC",
+		Description: "A token launched on a dynamic bonding curve",
+		Image:       "https://arweave.net/image123",
+		ExternalURL: "https://example.com",
+		Twitter:     "https://x.com/example",
+		Telegram:    "https://t.me/example",
+		Website:     "https://example.com",
+	}
+}
+
+func TestOffChainMetadataValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(m *helpers.OffChainMetadata)
+		valid  bool
+	}{
+		{name: "complete", modify: func(m *helpers.OffChainMetadata) {}, valid: true},
+		// links and socials are only checked by ValidatePolicy
+		{name: "missing image", modify: func(m *helpers.OffChainMetadata) { m.Image = "" }, valid: true},
+		{name: "twitter handle", modify: func(m *helpers.OffChainMetadata) { m.Twitter = "@example" }, valid: true},
+		{name: "twitter on another host", modify: func(m *helpers.OffChainMetadata) { m.Twitter = "https://example.com/example" }, valid: true},
+		{name: "long description", modify: func(m *helpers.OffChainMetadata) {
+			m.Description = strings.Repeat("a", helpers.MaxDescriptionLength+1)
+		}, valid: true},
+		{name: "name over 32 bytes", modify: func(m *helpers.OffChainMetadata) { m.Name = strings.Repeat("a", 33) }},
+		{name: "symbol over 10 bytes", modify: func(m *helpers.OffChainMetadata) { m.Symbol = "ABCDEFGHIJK" }},
+		{name: "invalid utf-8 description", modify: func(m *helpers.OffChainMetadata) { m.Description = "\xff" }},
+		{name: "invalid utf-8 website", modify: func(m *helpers.OffChainMetadata) { m.Website = "https://example.com/\xff" }},
+	}
+	for _, tt := range tests {
+		m := validOffChainMetadata()
+		tt.modify(&m)
+		err := m.Validate()
+		if tt.valid && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestOffChainMetadataValidatePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(m *helpers.OffChainMetadata)
+		valid  bool
+	}{
+		{name: "complete", modify: func(m *helpers.OffChainMetadata) {}, valid: true},
+		{name: "no optional fields", modify: func(m *helpers.OffChainMetadata) {
+			m.Description, m.ExternalURL, m.Twitter, m.Telegram, m.Website = "", "", "", "", ""
+		}, valid: true},
+		{name: "ipfs image", modify: func(m *helpers.OffChainMetadata) { m.Image = "ipfs://bafyimage" }, valid: true},
+		{name: "twitter.com post", modify: func(m *helpers.OffChainMetadata) { m.Twitter = "https://twitter.com/example/status/1" }, valid: true},
+		{name: "www host", modify: func(m *helpers.OffChainMetadata) { m.Twitter = "https://www.x.com/example" }, valid: true},
+		{name: "telegram.me", modify: func(m *helpers.OffChainMetadata) { m.Telegram = "https://telegram.me/example" }, valid: true},
+		{name: "http website", modify: func(m *helpers.OffChainMetadata) { m.Website = "http://example.com/token" }, valid: true},
+		{name: "missing image", modify: func(m *helpers.OffChainMetadata) { m.Image = "" }},
+		{name: "image without scheme", modify: func(m *helpers.OffChainMetadata) { m.Image = "arweave.net/image123" }},
+		{name: "name over 32 bytes", modify: func(m *helpers.OffChainMetadata) { m.Name = strings.Repeat("a", 33) }},
+		{name: "symbol with whitespace", modify: func(m *helpers.OffChainMetadata) { m.Symbol = "DBC\t" }},
+		{name: "long description", modify: func(m *helpers.OffChainMetadata) {
+			m.Description = strings.Repeat("a", helpers.MaxDescriptionLength+1)
+		}},
+		{name: "invalid external url", modify: func(m *helpers.OffChainMetadata) { m.ExternalURL = "javascript:alert(1)" }},
+		{name: "twitter handle", modify: func(m *helpers.OffChainMetadata) { m.Twitter = "@example" }},
+		{name: "twitter on another host", modify: func(m *helpers.OffChainMetadata) { m.Twitter = "https://example.com/example" }},
+		{name: "twitter lookalike host", modify: func(m *helpers.OffChainMetadata) { m.Twitter = "https://x.com.example.com/example" }},
+		{name: "twitter without profile", modify: func(m *helpers.OffChainMetadata) { m.Twitter = "https://x.com/" }},
+		{name: "telegram on another host", modify: func(m *helpers.OffChainMetadata) { m.Telegram = "https://x.com/example" }},
+		{name: "telegram without scheme", modify: func(m *helpers.OffChainMetadata) { m.Telegram = "t.me/example" }},
+		{name: "ipfs website", modify: func(m *helpers.OffChainMetadata) { m.Website = "ipfs://bafysite" }},
+		{name: "website without host", modify: func(m *helpers.OffChainMetadata) { m.Website = "https://" }},
+	}
+	for _, tt := range tests {
+		m := validOffChainMetadata()
+		tt.modify(&m)
+		err := m.ValidatePolicy()
+		if tt.valid && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestBuildMetadataJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(m *helpers.OffChainMetadata)
+		// keys expected in the document, nil when it must fail
+		keys []string
+	}{
+		{
+			name:   "complete",
+			modify: func(m *helpers.OffChainMetadata) {},
+			keys:   []string{"name", "symbol", "description", "image", "external_url", "twitter", "telegram", "website"},
+		},
+		{
+			name: "optional fields omitted",
+			modify: func(m *helpers.OffChainMetadata) {
+				m.Description, m.ExternalURL, m.Twitter, m.Telegram, m.Website = "", "", "", "", ""
+			},
+			keys: []string{"name", "symbol", "image"},
+		},
+		{name: "invalid", modify: func(m *helpers.OffChainMetadata) { m.Name = strings.Repeat("a", 33) }},
+	}
+	for _, tt := range tests {
+		m := validOffChainMetadata()
+		tt.modify(&m)
+		data, err := helpers.BuildMetadataJSON(&m)
+		if tt.keys == nil {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		var document map[string]string
+		if err := json.Unmarshal(data, &document); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(document) != len(tt.keys) {
+			t.Errorf("%s: got keys %v, want %v", tt.name, document, tt.keys)
+		}
+		for _, key := range tt.keys {
+			if _, ok := document[key]; !ok {
+				t.Errorf("%s: missing key %s", tt.name, key)
+			}
+		}
+		if document["name"] != m.Name || document["image"] != m.Image {
+			t.Errorf("%s: got %v", tt.name, document)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package helpers
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const DefaultPdaCacheSize = 4096
+
+type pdaCacheEntry struct {
+	key  string
+	pda  solana.PublicKey
+	bump uint8
+}
+
+// LRU cache of program derived addresses keyed by program id and seeds
+type pdaCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+var pdas = newPdaCache(DefaultPdaCacheSize)
+
+func newPdaCache(capacity int) *pdaCache {
+	return &pdaCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Sets the number of cached derivations, 0 or less disables caching
+func SetPdaCacheSize(capacity int) {
+	if capacity < 0 {
+		capacity = 0
+	}
+	pdas.mu.Lock()
+	defer pdas.mu.Unlock()
+	pdas.capacity = capacity
+	pdas.evict()
+}
+
+// Finds the program address for the seeds, reusing earlier derivations
+func FindProgramAddressCached(seeds [][]byte, programID solana.PublicKey) (solana.PublicKey, uint8, error) {
+	key := pdaCacheKey(seeds, programID)
+	if pda, bump, ok := pdas.get(key); ok {
+		return pda, bump, nil
+	}
+
+	pda, bump, err := solana.FindProgramAddress(seeds, programID)
+	if err != nil {
+		return solana.PublicKey{}, 0, err
+	}
+	pdas.put(key, pda, bump)
+	return pda, bump, nil
+}
+
+func (c *pdaCache) get(key string) (solana.PublicKey, uint8, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return solana.PublicKey{}, 0, false
+	}
+	c.order.MoveToFront(elem)
+	entry := elem.Value.(*pdaCacheEntry)
+	return entry.pda, entry.bump, true
+}
+
+func (c *pdaCache) put(key string, pda solana.PublicKey, bump uint8) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.capacity <= 0 {
+		return
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&pdaCacheEntry{key: key, pda: pda, bump: bump})
+	c.evict()
+}
+
+// drops the least recently used entries above capacity, the caller holds the lock
+func (c *pdaCache) evict() {
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*pdaCacheEntry).key)
+	}
+}
+
+// program id followed by length-prefixed seeds, so different splits never collide
+func pdaCacheKey(seeds [][]byte, programID solana.PublicKey) string {
+	size := len(programID)
+	for _, seed := range seeds {
+		size += 1 + len(seed)
+	}
+	key := make([]byte, 0, size)
+	key = append(key, programID[:]...)
+	for _, seed := range seeds {
+		key = append(key, byte(len(seed)))
+		key = append(key, seed...)
+	}
+	return string(key)
+}
@@ -0,0 +1,110 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestPdaCacheEviction(t *testing.T) {
+	c := newPdaCache(2)
+	c.put("a", solana.PublicKey{1}, 1)
+	c.put("b", solana.PublicKey{2}, 2)
+	// reading a makes b the least recently used
+	if _, bump, ok := c.get("a"); !ok || bump != 1 {
+		t.Fatalf("got bump %d, %v", bump, ok)
+	}
+	c.put("c", solana.PublicKey{3}, 3)
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "a", want: true},
+		{key: "b", want: false},
+		{key: "c", want: true},
+	}
+	for _, tt := range tests {
+		if _, _, ok := c.get(tt.key); ok != tt.want {
+			t.Errorf("%s: cached %v, want %v", tt.key, ok, tt.want)
+		}
+	}
+
+	// shrinking drops the least recently used entry, c was read last
+	c.capacity = 1
+	c.evict()
+	if _, _, ok := c.get("a"); ok {
+		t.Error("a kept after shrinking")
+	}
+	if _, _, ok := c.get("c"); !ok {
+		t.Error("c dropped after shrinking")
+	}
+
+	disabled := newPdaCache(0)
+	disabled.put("a", solana.PublicKey{1}, 1)
+	if _, _, ok := disabled.get("a"); ok {
+		t.Error("cached with capacity 0")
+	}
+}
+
+func TestSetPdaCacheSize(t *testing.T) {
+	defer SetPdaCacheSize(DefaultPdaCacheSize)
+	programID := solana.PublicKey{1}
+	seeds := [][]byte{[]byte("pool")}
+	want, wantBump, err := solana.FindProgramAddress(seeds, programID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		capacity   int
+		wantCached int
+	}{
+		{name: "default", capacity: DefaultPdaCacheSize, wantCached: 1},
+		{name: "disabled", capacity: 0, wantCached: 0},
+		{name: "negative", capacity: -1, wantCached: 0},
+	}
+	for _, tt := range tests {
+		// start from an empty cache holding only this derivation
+		SetPdaCacheSize(0)
+		SetPdaCacheSize(DefaultPdaCacheSize)
+		if _, _, err := FindProgramAddressCached(seeds, programID); err != nil {
+			t.Fatal(err)
+		}
+		// shrinking evicts the cached derivation instead of panicking on an empty list
+		SetPdaCacheSize(tt.capacity)
+		pda, bump, err := FindProgramAddressCached(seeds, programID)
+		if err != nil || pda != want || bump != wantBump {
+			t.Errorf("%s: got %s, %d, %v, want %s, %d", tt.name, pda, bump, err, want, wantBump)
+		}
+		if got := pdas.order.Len(); got != tt.wantCached {
+			t.Errorf("%s: got %d cached derivations, want %d", tt.name, got, tt.wantCached)
+		}
+	}
+}
+
+func TestPdaCacheKey(t *testing.T) {
+	programID := solana.PublicKey{1}
+	tests := []struct {
+		name      string
+		a, b      [][]byte
+		programID solana.PublicKey
+	}{
+		{name: "different split", a: [][]byte{[]byte("ab"), []byte("c")}, b: [][]byte{[]byte("a"), []byte("bc")}},
+		{name: "empty seed", a: [][]byte{[]byte("a")}, b: [][]byte{[]byte("a"), {}}},
+		{name: "other program", a: [][]byte{[]byte("a")}, b: [][]byte{[]byte("a")}, programID: solana.PublicKey{2}},
+	}
+	for _, tt := range tests {
+		other := programID
+		if !tt.programID.IsZero() {
+			other = tt.programID
+		}
+		if pdaCacheKey(tt.a, programID) == pdaCacheKey(tt.b, other) {
+			t.Errorf("%s: keys collide", tt.name)
+		}
+	}
+	if pdaCacheKey([][]byte{[]byte("a")}, programID) != pdaCacheKey([][]byte{[]byte("a")}, programID) {
+		t.Error("equal seeds give different keys")
+	}
+}
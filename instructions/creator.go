@@ -6,10 +6,9 @@ import (
 	"github.com/gagliardetto/solana-go"
 
 	"github.com/Luigi-1Combo/dbc-go/common"
-	"github.com/Luigi-1Combo/dbc-go/helpers"
 )
 
-func ClaimCreatorTradingFee(
+func (p *Program) ClaimCreatorTradingFee(
 	pool solana.PublicKey,
 	tokenAAccount solana.PublicKey,
 	tokenBAccount solana.PublicKey,
@@ -21,16 +20,15 @@ func ClaimCreatorTradingFee(
 	maxBaseAmount uint64,
 	maxQuoteAmount uint64,
 ) solana.Instruction {
-	disc := []byte{82, 220, 250, 189, 3, 85, 107, 45}
 	buf := make([]byte, 8+8+8)
-	copy(buf, disc)
+	copy(buf, ClaimCreatorTradingFeeDiscriminator[:])
 	binary.LittleEndian.PutUint64(buf[8:], maxBaseAmount)
 	binary.LittleEndian.PutUint64(buf[16:], maxQuoteAmount)
 
 	tokenBaseProgram := solana.MustPublicKeyFromBase58(common.TokenProgram)
 	tokenQuoteProgram := solana.MustPublicKeyFromBase58(common.TokenProgram)
-	poolAuthority := helpers.DerivePoolAuthorityPDA()
-	eventAuthority := helpers.DeriveEventAuthorityPDA()
+	poolAuthority := p.PoolAuthority
+	eventAuthority := p.EventAuthority
 
 	acctMeta := solana.AccountMetaSlice{
 		// 1. pool_authority
@@ -58,25 +56,25 @@ func ClaimCreatorTradingFee(
 		// 12. event_authority
 		{PublicKey: eventAuthority, IsSigner: false, IsWritable: false},
 		// 13. program
-		{PublicKey: solana.MustPublicKeyFromBase58(common.DbcProgramID), IsSigner: false, IsWritable: false},
+		{PublicKey: p.Programs.Dbc, IsSigner: false, IsWritable: false},
 	}
 
 	return solana.NewInstruction(
-		solana.MustPublicKeyFromBase58(common.DbcProgramID),
+		p.Programs.Dbc,
 		acctMeta,
 		buf,
 	)
 }
 
-func TransferPoolCreator(
+func (p *Program) TransferPoolCreator(
 	virtualPool solana.PublicKey,
 	config solana.PublicKey,
 	creator solana.PublicKey,
 	newCreator solana.PublicKey,
 	migrationMetadata solana.PublicKey,
 ) solana.Instruction {
-	disc := []byte{20, 7, 169, 33, 58, 147, 166, 33}
-	eventAuthority := helpers.DeriveEventAuthorityPDA()
+	disc := append([]byte{}, TransferPoolCreatorDiscriminator[:]...)
+	eventAuthority := p.EventAuthority
 
 	acctMeta := solana.AccountMetaSlice{
 		// 1. virtual_pool (writable)
@@ -90,7 +88,7 @@ func TransferPoolCreator(
 		// 5. event_authority
 		{PublicKey: eventAuthority, IsSigner: false, IsWritable: false},
 		// 6. program
-		{PublicKey: solana.MustPublicKeyFromBase58(common.DbcProgramID), IsSigner: false, IsWritable: false},
+		{PublicKey: p.Programs.Dbc, IsSigner: false, IsWritable: false},
 	}
 
 	acctMeta = append(acctMeta, &solana.AccountMeta{
@@ -100,7 +98,7 @@ func TransferPoolCreator(
 	})
 
 	return solana.NewInstruction(
-		solana.MustPublicKeyFromBase58(common.DbcProgramID),
+		p.Programs.Dbc,
 		acctMeta,
 		disc,
 	)
@@ -0,0 +1,358 @@
+package instructions
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+)
+
+// returned when the instruction data does not start with a known DBC discriminator
+var ErrUnknownInstruction = errors.New("unknown DBC instruction")
+
+var errDataTooShort = errors.New("instruction data too short")
+
+type InitializeVirtualPoolWithSplTokenArgs struct {
+	Name   string
+	Symbol string
+	URI    string
+}
+
+type SwapArgs struct {
+	AmountIn         uint64
+	MinimumAmountOut uint64
+}
+
+type Swap2Args struct {
+	// amount in for ExactIn and PartialFill, amount out for ExactOut
+	Amount0 uint64
+	// minimum amount out for ExactIn and PartialFill, maximum amount in for ExactOut
+	Amount1  uint64
+	SwapMode common.SwapMode
+}
+
+type ClaimCreatorTradingFeeArgs struct {
+	MaxBaseAmount  uint64
+	MaxQuoteAmount uint64
+}
+
+type ClaimPartnerTradingFeeArgs struct {
+	MaxAmountA uint64
+	MaxAmountB uint64
+}
+
+type NamedAccount struct {
+	// account name in the program IDL, e.g. pool or input_token_account
+	Name      string
+	PublicKey solana.PublicKey
+}
+
+// DBC instruction decoded back into the arguments and accounts of its builder
+type DecodedInstruction struct {
+	// index of the instruction in its transaction, zero when decoded on its own; for inner
+	// instructions the index of the top-level instruction that invoked them
+	Index int
+	// set for instructions invoked by another program, InnerIndex is then the position
+	// among the inner instructions of Index
+	Inner      bool
+	InnerIndex int
+	// empty for DBC instructions without a known layout, which keep all their accounts as
+	// remaining accounts
+	Name      string
+	ProgramID solana.PublicKey
+	// raw instruction data, including the discriminator
+	Data []byte
+	// one of the *Args types of this file, nil for instructions without arguments
+	Args     interface{}
+	Accounts []NamedAccount
+	// accounts passed after the named ones, e.g. the instructions sysvar of rate limited swaps
+	RemainingAccounts []solana.PublicKey
+}
+
+// Gets the named account, e.g. Account("pool")
+func (d *DecodedInstruction) Account(name string) (solana.PublicKey, bool) {
+	for _, account := range d.Accounts {
+		if account.Name == name {
+			return account.PublicKey, true
+		}
+	}
+	return solana.PublicKey{}, false
+}
+
+type instructionLayout struct {
+	discriminator [8]byte
+	name          string
+	accounts      []string
+	decodeArgs    func(data []byte) (interface{}, error)
+}
+
+var swapAccountNames = []string{
+	"pool_authority", "config", "pool", "input_token_account", "output_token_account",
+	"base_vault", "quote_vault", "base_mint", "quote_mint", "payer",
+	"token_base_program", "token_quote_program", "referral_token_account", "event_authority", "program",
+}
+
+// layouts of the instructions built by this package, in the account order of the builders
+var instructionLayouts = []instructionLayout{
+	{
+		discriminator: InitializeVirtualPoolWithSplTokenDiscriminator,
+		name:          "InitializeVirtualPoolWithSplToken",
+		accounts: []string{
+			"config", "pool_authority", "creator", "base_mint", "quote_mint", "pool",
+			"base_vault", "quote_vault", "mint_metadata", "metadata_program", "payer",
+			"token_quote_program", "token_program", "system_program", "event_authority", "program",
+		},
+		decodeArgs: func(data []byte) (interface{}, error) {
+			args := &InitializeVirtualPoolWithSplTokenArgs{}
+			var err error
+			if args.Name, data, err = readString(data); err != nil {
+				return nil, fmt.Errorf("failed to decode name: %w", err)
+			}
+			if args.Symbol, data, err = readString(data); err != nil {
+				return nil, fmt.Errorf("failed to decode symbol: %w", err)
+			}
+			if args.URI, _, err = readString(data); err != nil {
+				return nil, fmt.Errorf("failed to decode uri: %w", err)
+			}
+			return args, nil
+		},
+	},
+	{
+		discriminator: SwapDiscriminator,
+		name:          "Swap",
+		accounts:      swapAccountNames,
+		decodeArgs: func(data []byte) (interface{}, error) {
+			if len(data) < 16 {
+				return nil, errDataTooShort
+			}
+			return &SwapArgs{
+				AmountIn:         binary.LittleEndian.Uint64(data[0:8]),
+				MinimumAmountOut: binary.LittleEndian.Uint64(data[8:16]),
+			}, nil
+		},
+	},
+	{
+		discriminator: Swap2Discriminator,
+		name:          "Swap2",
+		accounts:      swapAccountNames,
+		decodeArgs: func(data []byte) (interface{}, error) {
+			if len(data) < 17 {
+				return nil, errDataTooShort
+			}
+			return &Swap2Args{
+				Amount0:  binary.LittleEndian.Uint64(data[0:8]),
+				Amount1:  binary.LittleEndian.Uint64(data[8:16]),
+				SwapMode: common.SwapMode(data[16]),
+			}, nil
+		},
+	},
+	{
+		discriminator: ClaimCreatorTradingFeeDiscriminator,
+		name:          "ClaimCreatorTradingFee",
+		accounts: []string{
+			"pool_authority", "pool", "token_a_account", "token_b_account", "base_vault", "quote_vault",
+			"base_mint", "quote_mint", "creator", "token_base_program", "token_quote_program",
+			"event_authority", "program",
+		},
+		decodeArgs: func(data []byte) (interface{}, error) {
+			if len(data) < 16 {
+				return nil, errDataTooShort
+			}
+			return &ClaimCreatorTradingFeeArgs{
+				MaxBaseAmount:  binary.LittleEndian.Uint64(data[0:8]),
+				MaxQuoteAmount: binary.LittleEndian.Uint64(data[8:16]),
+			}, nil
+		},
+	},
+	{
+		discriminator: ClaimPartnerTradingFeeDiscriminator,
+		name:          "ClaimPartnerTradingFee",
+		accounts: []string{
+			"pool_authority", "config", "pool", "token_a_account", "token_b_account", "base_vault", "quote_vault",
+			"base_mint", "quote_mint", "fee_claimer", "token_base_program", "token_quote_program",
+			"event_authority", "program",
+		},
+		decodeArgs: func(data []byte) (interface{}, error) {
+			if len(data) < 16 {
+				return nil, errDataTooShort
+			}
+			return &ClaimPartnerTradingFeeArgs{
+				MaxAmountA: binary.LittleEndian.Uint64(data[0:8]),
+				MaxAmountB: binary.LittleEndian.Uint64(data[8:16]),
+			}, nil
+		},
+	},
+	{
+		discriminator: TransferPoolCreatorDiscriminator,
+		name:          "TransferPoolCreator",
+		// the migration metadata follows as a remaining account
+		accounts: []string{"virtual_pool", "config", "creator", "new_creator", "event_authority", "program"},
+	},
+}
+
+// Decodes a DBC instruction from its program, resolved accounts and data
+func DecodeInstruction(programID solana.PublicKey, accounts []solana.PublicKey, data []byte) (*DecodedInstruction, error) {
+	layout, ok := findInstructionLayout(data)
+	if !ok {
+		return nil, ErrUnknownInstruction
+	}
+	if len(accounts) < len(layout.accounts) {
+		return nil, fmt.Errorf("%s has %d accounts, expected %d", layout.name, len(accounts), len(layout.accounts))
+	}
+
+	decoded := &DecodedInstruction{
+		Name:      layout.name,
+		ProgramID: programID,
+		Data:      data,
+		Accounts:  make([]NamedAccount, len(layout.accounts)),
+	}
+	for i, name := range layout.accounts {
+		decoded.Accounts[i] = NamedAccount{Name: name, PublicKey: accounts[i]}
+	}
+	decoded.RemainingAccounts = append(decoded.RemainingAccounts, accounts[len(layout.accounts):]...)
+
+	if layout.decodeArgs != nil {
+		args, err := layout.decodeArgs(data[8:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s args: %w", layout.name, err)
+		}
+		decoded.Args = args
+	}
+	return decoded, nil
+}
+
+// Decodes a compiled instruction of the message, which must be a DBC instruction of the deployment.
+// Address table lookups must be set on versioned messages, see solana.Message.SetAddressTables.
+func (p *Program) DecodeCompiledInstruction(message *solana.Message, ix solana.CompiledInstruction) (*DecodedInstruction, error) {
+	programID, accounts, err := p.resolveCompiledInstruction(message, ix)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeInstruction(programID, accounts, ix.Data)
+}
+
+// Decodes the top-level DBC instructions of the transaction, skipping other programs.
+// DBC instructions without a known layout are returned unnamed with their raw data.
+func (p *Program) DecodeTransaction(tx *solana.Transaction) ([]*DecodedInstruction, error) {
+	var decoded []*DecodedInstruction
+	for i, ix := range tx.Message.Instructions {
+		instruction, ok, err := p.decodeTransactionInstruction(&tx.Message, ix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode instruction %d: %w", i, err)
+		}
+		if !ok {
+			continue
+		}
+		instruction.Index = i
+		decoded = append(decoded, instruction)
+	}
+	return decoded, nil
+}
+
+// Decodes the DBC instructions invoked through other programs, e.g. swaps routed by an
+// aggregator, from the inner instructions of the transaction meta. DBC instructions
+// without a known layout are returned unnamed with their raw data.
+func (p *Program) DecodeInnerInstructions(tx *solana.Transaction, inner []solRpc.InnerInstruction) ([]*DecodedInstruction, error) {
+	var decoded []*DecodedInstruction
+	for _, group := range inner {
+		for j, ix := range group.Instructions {
+			instruction, ok, err := p.decodeTransactionInstruction(&tx.Message, ix)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode inner instruction %d of instruction %d: %w", j, group.Index, err)
+			}
+			if !ok {
+				continue
+			}
+			instruction.Index = int(group.Index)
+			instruction.Inner = true
+			instruction.InnerIndex = j
+			decoded = append(decoded, instruction)
+		}
+	}
+	return decoded, nil
+}
+
+func DecodeCompiledInstruction(message *solana.Message, ix solana.CompiledInstruction) (*DecodedInstruction, error) {
+	return DefaultProgram.DecodeCompiledInstruction(message, ix)
+}
+
+func DecodeTransaction(tx *solana.Transaction) ([]*DecodedInstruction, error) {
+	return DefaultProgram.DecodeTransaction(tx)
+}
+
+func DecodeInnerInstructions(tx *solana.Transaction, inner []solRpc.InnerInstruction) ([]*DecodedInstruction, error) {
+	return DefaultProgram.DecodeInnerInstructions(tx, inner)
+}
+
+// decodes a DBC instruction of the deployment, false for other programs
+func (p *Program) decodeTransactionInstruction(message *solana.Message, ix solana.CompiledInstruction) (*DecodedInstruction, bool, error) {
+	programID, err := message.ResolveProgramIDIndex(ix.ProgramIDIndex)
+	if err != nil || !programID.Equals(p.Programs.Dbc) {
+		return nil, false, nil
+	}
+	programID, accounts, err := p.resolveCompiledInstruction(message, ix)
+	if err != nil {
+		return nil, false, err
+	}
+	instruction, err := DecodeInstruction(programID, accounts, ix.Data)
+	if errors.Is(err, ErrUnknownInstruction) {
+		return &DecodedInstruction{ProgramID: programID, Data: ix.Data, RemainingAccounts: accounts}, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return instruction, true, nil
+}
+
+// resolves the program and accounts of a DBC instruction of the deployment
+func (p *Program) resolveCompiledInstruction(message *solana.Message, ix solana.CompiledInstruction) (solana.PublicKey, []solana.PublicKey, error) {
+	keys, err := message.GetAllKeys()
+	if err != nil {
+		return solana.PublicKey{}, nil, fmt.Errorf("failed to resolve message accounts: %w", err)
+	}
+	if int(ix.ProgramIDIndex) >= len(keys) {
+		return solana.PublicKey{}, nil, fmt.Errorf("program id index %d out of range", ix.ProgramIDIndex)
+	}
+	programID := keys[ix.ProgramIDIndex]
+	if !programID.Equals(p.Programs.Dbc) {
+		return solana.PublicKey{}, nil, fmt.Errorf("instruction of program %s, not the DBC program %s", programID, p.Programs.Dbc)
+	}
+
+	accounts := make([]solana.PublicKey, len(ix.Accounts))
+	for i, index := range ix.Accounts {
+		if int(index) >= len(keys) {
+			return solana.PublicKey{}, nil, fmt.Errorf("account index %d out of range", index)
+		}
+		accounts[i] = keys[index]
+	}
+	return programID, accounts, nil
+}
+
+func findInstructionLayout(data []byte) (*instructionLayout, bool) {
+	if len(data) < 8 {
+		return nil, false
+	}
+	for i := range instructionLayouts {
+		if bytes.Equal(data[:8], instructionLayouts[i].discriminator[:]) {
+			return &instructionLayouts[i], true
+		}
+	}
+	return nil, false
+}
+
+// reads a borsh string: u32 length followed by the bytes
+func readString(data []byte) (string, []byte, error) {
+	if len(data) < 4 {
+		return "", nil, errDataTooShort
+	}
+	length := binary.LittleEndian.Uint32(data[:4])
+	if uint64(len(data)-4) < uint64(length) {
+		return "", nil, errDataTooShort
+	}
+	return string(data[4 : 4+length]), data[4+length:], nil
+}
@@ -0,0 +1,379 @@
+package instructions_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/system"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+)
+
+// anchor discriminators are the first bytes of the hash of the namespaced name
+func TestDiscriminators(t *testing.T) {
+	tests := []struct {
+		preimage string
+		got      [8]byte
+	}{
+		{"global:initialize_virtual_pool_with_spl_token", instructions.InitializeVirtualPoolWithSplTokenDiscriminator},
+		{"global:swap", instructions.SwapDiscriminator},
+		{"global:swap2", instructions.Swap2Discriminator},
+		{"global:claim_creator_trading_fee", instructions.ClaimCreatorTradingFeeDiscriminator},
+		{"global:claim_trading_fee", instructions.ClaimPartnerTradingFeeDiscriminator},
+		{"global:transfer_pool_creator", instructions.TransferPoolCreatorDiscriminator},
+		{"account:PoolConfig", instructions.PoolConfigAccountDiscriminator},
+		{"account:VirtualPool", instructions.PoolAccountDiscriminator},
+	}
+	for _, tt := range tests {
+		hash := sha256.Sum256([]byte(tt.preimage))
+		if string(hash[:8]) != string(tt.got[:]) {
+			t.Errorf("%s: got %v, want %v", tt.preimage, tt.got, hash[:8])
+		}
+	}
+}
+
+func TestInstructionName(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want string
+		ok   bool
+	}{
+		{data: instructions.SwapDiscriminator[:], want: "Swap", ok: true},
+		{data: append(instructions.Swap2Discriminator[:], 1, 2, 3), want: "Swap2", ok: true},
+		{data: instructions.TransferPoolCreatorDiscriminator[:], want: "TransferPoolCreator", ok: true},
+		{data: instructions.PoolAccountDiscriminator[:]},
+		{data: instructions.SwapDiscriminator[:7]},
+		{data: nil},
+	}
+	for _, tt := range tests {
+		got, ok := instructions.InstructionName(tt.data)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%v: got %q, %v, want %q, %v", tt.data, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDecodeInstruction(t *testing.T) {
+	keys := make([]solana.PublicKey, 11)
+	for i := range keys {
+		keys[i] = solana.NewWallet().PublicKey()
+	}
+	referral := solana.NewWallet().PublicKey()
+	rateLimited, err := instructions.WithInstructionsSysvar(instructions.Swap2(keys[0], keys[1], keys[2], keys[3], keys[4], keys[5],
+		keys[6], keys[7], keys[8], solana.PublicKey{}, 5, 6, common.SwapModePartialFill))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		ix       solana.Instruction
+		wantName string
+		wantArgs interface{}
+		// named accounts checked, with the key they should hold
+		accounts  map[string]solana.PublicKey
+		remaining []solana.PublicKey
+	}{
+		{
+			name: "initialize pool",
+			ix: instructions.InitializeVirtualPoolWithSplToken(keys[0], keys[1], keys[2], keys[3], keys[4], keys[5],
+				keys[6], keys[7], keys[8], "Dynamic Bonding", "DBC", "https://arweave.net/abc123"),
+			wantName: "InitializeVirtualPoolWithSplToken",
+			wantArgs: &instructions.InitializeVirtualPoolWithSplTokenArgs{Name: "Dynamic Bonding", Symbol: "DBC", URI: "https://arweave.net/abc123"},
+			accounts: map[string]solana.PublicKey{"config": keys[0], "creator": keys[1], "base_mint": keys[2], "pool": keys[4], "payer": keys[8]},
+		},
+		{
+			name: "swap with referral",
+			ix: instructions.Swap(keys[0], keys[1], keys[2], keys[3], keys[4], keys[5], keys[6], keys[7], keys[8],
+				referral, 1_000, 990),
+			wantName: "Swap",
+			wantArgs: &instructions.SwapArgs{AmountIn: 1_000, MinimumAmountOut: 990},
+			accounts: map[string]solana.PublicKey{
+				"pool_authority": instructions.DefaultProgram.PoolAuthority, "config": keys[0], "pool": keys[1],
+				"input_token_account": keys[2], "payer": keys[8], "referral_token_account": referral,
+			},
+		},
+		{
+			name: "exact out swap2",
+			ix: instructions.Swap2(keys[0], keys[1], keys[2], keys[3], keys[4], keys[5], keys[6], keys[7], keys[8],
+				solana.PublicKey{}, 3, 4, common.SwapModeExactOut),
+			wantName: "Swap2",
+			wantArgs: &instructions.Swap2Args{Amount0: 3, Amount1: 4, SwapMode: common.SwapModeExactOut},
+			accounts: map[string]solana.PublicKey{"referral_token_account": instructions.DefaultProgram.Programs.Dbc},
+		},
+		{
+			name:      "rate limited swap2",
+			ix:        rateLimited,
+			wantName:  "Swap2",
+			wantArgs:  &instructions.Swap2Args{Amount0: 5, Amount1: 6, SwapMode: common.SwapModePartialFill},
+			accounts:  map[string]solana.PublicKey{"event_authority": instructions.DefaultProgram.EventAuthority},
+			remaining: []solana.PublicKey{solana.SysVarInstructionsPubkey},
+		},
+		{
+			name: "claim creator fee",
+			ix: instructions.ClaimCreatorTradingFee(keys[0], keys[1], keys[2], keys[3], keys[4], keys[5], keys[6],
+				keys[7], 7, 8),
+			wantName: "ClaimCreatorTradingFee",
+			wantArgs: &instructions.ClaimCreatorTradingFeeArgs{MaxBaseAmount: 7, MaxQuoteAmount: 8},
+			accounts: map[string]solana.PublicKey{"pool": keys[0], "creator": keys[7]},
+		},
+		{
+			name: "claim partner fee",
+			ix: instructions.ClaimPartnerTradingFee(keys[0], keys[1], keys[2], keys[3], keys[4], keys[5], keys[6],
+				keys[7], keys[8], 9, 10),
+			wantName: "ClaimPartnerTradingFee",
+			wantArgs: &instructions.ClaimPartnerTradingFeeArgs{MaxAmountA: 9, MaxAmountB: 10},
+			accounts: map[string]solana.PublicKey{"config": keys[0], "pool": keys[1], "fee_claimer": keys[8]},
+		},
+		{
+			name:      "transfer creator",
+			ix:        instructions.TransferPoolCreator(keys[0], keys[1], keys[2], keys[3], keys[4]),
+			wantName:  "TransferPoolCreator",
+			accounts:  map[string]solana.PublicKey{"virtual_pool": keys[0], "creator": keys[2], "new_creator": keys[3]},
+			remaining: []solana.PublicKey{keys[4]},
+		},
+	}
+	for _, tt := range tests {
+		data, err := tt.ix.Data()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := instructions.DecodeInstruction(tt.ix.ProgramID(), accountKeys(tt.ix), data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if decoded.Name != tt.wantName || !decoded.ProgramID.Equals(tt.ix.ProgramID()) || decoded.Index != 0 {
+			t.Errorf("%s: got %s of %s at %d", tt.name, decoded.Name, decoded.ProgramID, decoded.Index)
+		}
+		if !reflect.DeepEqual(decoded.Args, tt.wantArgs) {
+			t.Errorf("%s: got args %+v, want %+v", tt.name, decoded.Args, tt.wantArgs)
+		}
+		for name, want := range tt.accounts {
+			if got, ok := decoded.Account(name); !ok || !got.Equals(want) {
+				t.Errorf("%s: got %s %s, want %s", tt.name, name, got, want)
+			}
+		}
+		if len(decoded.Accounts)+len(decoded.RemainingAccounts) != len(tt.ix.Accounts()) {
+			t.Errorf("%s: decoded %d accounts of %d", tt.name, len(decoded.Accounts)+len(decoded.RemainingAccounts), len(tt.ix.Accounts()))
+		}
+		if len(decoded.RemainingAccounts) != len(tt.remaining) ||
+			(len(tt.remaining) != 0 && !reflect.DeepEqual(decoded.RemainingAccounts, tt.remaining)) {
+			t.Errorf("%s: got remaining accounts %v, want %v", tt.name, decoded.RemainingAccounts, tt.remaining)
+		}
+		if _, ok := decoded.Account("unknown"); ok {
+			t.Errorf("%s: found an unknown account", tt.name)
+		}
+	}
+}
+
+func TestDecodeInstructionErrors(t *testing.T) {
+	swap := instructions.Swap(solana.PublicKey{1}, solana.PublicKey{2}, solana.PublicKey{3}, solana.PublicKey{4}, solana.PublicKey{5},
+		solana.PublicKey{6}, solana.PublicKey{7}, solana.PublicKey{8}, solana.PublicKey{9}, solana.PublicKey{}, 1, 1)
+	swapData, _ := swap.Data()
+	swapAccounts := accountKeys(swap)
+	initialize := instructions.InitializeVirtualPoolWithSplToken(solana.PublicKey{1}, solana.PublicKey{2}, solana.PublicKey{3},
+		solana.PublicKey{4}, solana.PublicKey{5}, solana.PublicKey{6}, solana.PublicKey{7}, solana.PublicKey{8}, solana.PublicKey{9},
+		"Dynamic Bonding", "DBC", "")
+	initializeData, _ := initialize.Data()
+
+	tests := []struct {
+		name     string
+		accounts []solana.PublicKey
+		data     []byte
+		unknown  bool
+	}{
+		{name: "unknown discriminator", accounts: swapAccounts, data: make([]byte, 24), unknown: true},
+		{name: "no discriminator", accounts: swapAccounts, data: swapData[:4], unknown: true},
+		{name: "missing accounts", accounts: swapAccounts[:14], data: swapData},
+		{name: "truncated args", accounts: swapAccounts, data: swapData[:20]},
+		// the name length still claims 15 bytes
+		{name: "truncated string", accounts: accountKeys(initialize), data: initializeData[:20]},
+	}
+	for _, tt := range tests {
+		decoded, err := instructions.DecodeInstruction(swap.ProgramID(), tt.accounts, tt.data)
+		if err == nil || decoded != nil {
+			t.Errorf("%s: expected an error, got %+v", tt.name, decoded)
+			continue
+		}
+		if unknown := errors.Is(err, instructions.ErrUnknownInstruction); unknown != tt.unknown {
+			t.Errorf("%s: got %v", tt.name, err)
+		}
+	}
+}
+
+func TestDecodeTransaction(t *testing.T) {
+	payer := solana.NewWallet().PublicKey()
+	key := func() solana.PublicKey { return solana.NewWallet().PublicKey() }
+	pool := key()
+	custom := instructions.MustNewProgram(common.ProgramSet{Dbc: key()})
+	unknownAccount := key()
+	unknownData := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		computebudget.NewSetComputeUnitLimitInstruction(200_000).Build(),
+		instructions.Swap(key(), pool, key(), key(), key(), key(), key(), solana.SolMint, payer, solana.PublicKey{}, 1_000, 1),
+		system.NewTransferInstruction(1, payer, key()).Build(),
+		// another deployment is skipped like any other program
+		custom.TransferPoolCreator(key(), key(), payer, key(), key()),
+		instructions.ClaimCreatorTradingFee(pool, key(), key(), key(), key(), key(), solana.SolMint, payer, 1, 2),
+		// DBC instructions without a layout in this package are kept raw
+		solana.NewInstruction(instructions.DefaultProgram.Programs.Dbc, solana.AccountMetaSlice{solana.Meta(unknownAccount)}, unknownData),
+	}, solana.Hash{}, solana.TransactionPayer(payer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		program *instructions.Program
+		want    map[int]string
+	}{
+		{name: "mainnet", program: instructions.DefaultProgram, want: map[int]string{1: "Swap", 4: "ClaimCreatorTradingFee", 5: ""}},
+		{name: "other deployment", program: custom, want: map[int]string{3: "TransferPoolCreator"}},
+	}
+	for _, tt := range tests {
+		decoded, err := tt.program.DecodeTransaction(tx)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(decoded) != len(tt.want) {
+			t.Fatalf("%s: decoded %d instructions, want %d", tt.name, len(decoded), len(tt.want))
+		}
+		for _, ix := range decoded {
+			if tt.want[ix.Index] != ix.Name {
+				t.Errorf("%s: got %s at %d", tt.name, ix.Name, ix.Index)
+			}
+		}
+	}
+
+	decoded, err := instructions.DecodeTransaction(tx)
+	if err != nil || len(decoded) != 3 {
+		t.Fatalf("got %d instructions, %v", len(decoded), err)
+	}
+	if got, _ := decoded[0].Account("pool"); !got.Equals(pool) {
+		t.Errorf("got pool %s, want %s", got, pool)
+	}
+	unknown := decoded[2]
+	if string(unknown.Data) != string(unknownData) || unknown.Args != nil || len(unknown.Accounts) != 0 ||
+		len(unknown.RemainingAccounts) != 1 || !unknown.RemainingAccounts[0].Equals(unknownAccount) {
+		t.Errorf("got unknown instruction %+v", unknown)
+	}
+
+	// compiled instructions of other programs are rejected
+	if _, err := instructions.DecodeCompiledInstruction(&tx.Message, tx.Message.Instructions[0]); err == nil {
+		t.Error("decoded a compute budget instruction")
+	}
+	broken := tx.Message.Instructions[1]
+	broken.Accounts = append([]uint16{uint16(len(tx.Message.AccountKeys))}, broken.Accounts[1:]...)
+	if _, err := instructions.DecodeCompiledInstruction(&tx.Message, broken); err == nil {
+		t.Error("decoded an instruction with an account index out of range")
+	}
+}
+
+// compiles the instruction against the accounts of the message, as the runtime records
+// the instructions invoked through other programs
+func compileInner(t *testing.T, message *solana.Message, ix solana.Instruction) solana.CompiledInstruction {
+	t.Helper()
+	index := func(key solana.PublicKey) uint16 {
+		for i, account := range message.AccountKeys {
+			if account.Equals(key) {
+				return uint16(i)
+			}
+		}
+		t.Fatalf("%s is not an account of the message", key)
+		return 0
+	}
+	data, err := ix.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiled := solana.CompiledInstruction{ProgramIDIndex: index(ix.ProgramID()), Data: data}
+	for _, account := range ix.Accounts() {
+		compiled.Accounts = append(compiled.Accounts, index(account.PublicKey))
+	}
+	return compiled
+}
+
+func TestDecodeInnerInstructions(t *testing.T) {
+	payer := solana.NewWallet().PublicKey()
+	key := func() solana.PublicKey { return solana.NewWallet().PublicKey() }
+	pool := key()
+	swap := instructions.Swap(key(), pool, key(), key(), key(), key(), key(), solana.SolMint, payer, solana.PublicKey{}, 1_000, 1)
+	unknown := solana.NewInstruction(instructions.DefaultProgram.Programs.Dbc, solana.AccountMetaSlice{solana.Meta(pool)}, make([]byte, 16))
+	transfer := system.NewTransferInstruction(1, payer, pool).Build()
+
+	// an aggregator routing through DBC passes it every account of the swap
+	aggregator := key()
+	routed := append(solana.AccountMetaSlice{solana.Meta(instructions.DefaultProgram.Programs.Dbc)}, swap.Accounts()...)
+	routed = append(routed, solana.Meta(solana.SystemProgramID))
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		computebudget.NewSetComputeUnitLimitInstruction(200_000).Build(),
+		solana.NewInstruction(aggregator, routed, []byte{0}),
+	}, solana.Hash{}, solana.TransactionPayer(payer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		inner []solRpc.InnerInstruction
+		// name of the decoded instructions by inner index
+		want map[int]string
+	}{
+		{name: "no inner instructions"},
+		{
+			name: "routed swap",
+			inner: []solRpc.InnerInstruction{{Index: 1, Instructions: []solana.CompiledInstruction{
+				compileInner(t, &tx.Message, transfer),
+				compileInner(t, &tx.Message, swap),
+			}}},
+			want: map[int]string{1: "Swap"},
+		},
+		{
+			name: "unknown DBC instruction",
+			inner: []solRpc.InnerInstruction{{Index: 1, Instructions: []solana.CompiledInstruction{
+				compileInner(t, &tx.Message, unknown),
+			}}},
+			want: map[int]string{0: ""},
+		},
+	}
+	for _, tt := range tests {
+		decoded, err := instructions.DecodeInnerInstructions(tx, tt.inner)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(decoded) != len(tt.want) {
+			t.Fatalf("%s: decoded %d instructions, want %d", tt.name, len(decoded), len(tt.want))
+		}
+		for _, ix := range decoded {
+			if name, ok := tt.want[ix.InnerIndex]; !ok || name != ix.Name || !ix.Inner || ix.Index != 1 {
+				t.Errorf("%s: got %q at %d.%d, inner %v", tt.name, ix.Name, ix.Index, ix.InnerIndex, ix.Inner)
+			}
+		}
+	}
+
+	// the routed swap decodes like a top-level one
+	inner := []solRpc.InnerInstruction{{Index: 1, Instructions: []solana.CompiledInstruction{compileInner(t, &tx.Message, swap)}}}
+	decoded, err := instructions.DecodeInnerInstructions(tx, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := decoded[0].Account("pool"); !got.Equals(pool) {
+		t.Errorf("got pool %s, want %s", got, pool)
+	}
+	if args, ok := decoded[0].Args.(*instructions.SwapArgs); !ok || args.AmountIn != 1_000 || args.MinimumAmountOut != 1 {
+		t.Errorf("got args %+v", decoded[0].Args)
+	}
+
+	// a routed DBC instruction with missing accounts is an error, not skipped
+	broken := compileInner(t, &tx.Message, swap)
+	broken.Accounts = broken.Accounts[:3]
+	if _, err := instructions.DecodeInnerInstructions(tx, []solRpc.InnerInstruction{{Index: 1, Instructions: []solana.CompiledInstruction{broken}}}); err == nil {
+		t.Error("decoded a swap with missing accounts")
+	}
+}
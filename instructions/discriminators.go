@@ -0,0 +1,26 @@
+package instructions
+
+// 8-byte anchor discriminators of the DBC instructions built by this package
+var (
+	InitializeVirtualPoolWithSplTokenDiscriminator = [8]byte{140, 85, 215, 176, 102, 54, 104, 79}
+	SwapDiscriminator                              = [8]byte{248, 198, 158, 145, 225, 117, 135, 200}
+	Swap2Discriminator                             = [8]byte{65, 75, 63, 76, 235, 91, 91, 136}
+	ClaimCreatorTradingFeeDiscriminator            = [8]byte{82, 220, 250, 189, 3, 85, 107, 45}
+	ClaimPartnerTradingFeeDiscriminator            = [8]byte{8, 236, 89, 49, 152, 125, 177, 81}
+	TransferPoolCreatorDiscriminator               = [8]byte{20, 7, 169, 33, 58, 147, 166, 33}
+)
+
+// 8-byte anchor discriminators of the DBC accounts read by this package
+var (
+	PoolConfigAccountDiscriminator = [8]byte{26, 108, 14, 123, 116, 230, 129, 43}
+	PoolAccountDiscriminator       = [8]byte{213, 224, 5, 209, 98, 69, 119, 92}
+)
+
+// Gets the name of the builder that produced the DBC instruction data
+func InstructionName(data []byte) (string, bool) {
+	layout, ok := findInstructionLayout(data)
+	if !ok {
+		return "", false
+	}
+	return layout.name, true
+}
@@ -0,0 +1,170 @@
+package instructions
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+)
+
+// metaplex account key of MetadataV1 accounts
+const metadataV1Key = 4
+
+// accounts per getMultipleAccounts request, the rpc limit
+const maxMultipleAccounts = 100
+
+var errMetadataTooShort = errors.New("metadata account data too short")
+
+// reads accounts in batches, implemented by *solRpc.Client and by rpctest.AccountFetcher
+type MultipleAccountsFetcher interface {
+	GetMultipleAccounts(ctx context.Context, accounts ...solana.PublicKey) (*solRpc.GetMultipleAccountsResult, error)
+}
+
+// Gets the metaplex metadata of the mint
+func (p *Program) GetTokenMetadata(ctx context.Context, rpcClient AccountFetcher, mint solana.PublicKey) (*common.TokenMetadata, error) {
+	metadataAddress, err := p.DeriveMintMetadata(mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive mint metadata: %w", err)
+	}
+
+	account, err := rpcClient.GetAccountInfo(ctx, metadataAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token metadata account: %w", err)
+	}
+	if account == nil || account.Value == nil {
+		return nil, fmt.Errorf("token metadata account not found")
+	}
+	if !account.Value.Owner.Equals(p.Programs.Metadata) {
+		return nil, fmt.Errorf("token metadata account is owned by %s, not the metadata program %s", account.Value.Owner, p.Programs.Metadata)
+	}
+
+	return DecodeTokenMetadata(account.Value.Data.GetBinary())
+}
+
+// Gets the metaplex metadata of the mints, in order, batching the requests; mints
+// without a metadata account get nil
+func (p *Program) GetTokenMetadatas(ctx context.Context, rpcClient MultipleAccountsFetcher, mints []solana.PublicKey) ([]*common.TokenMetadata, error) {
+	addresses := make([]solana.PublicKey, len(mints))
+	for i, mint := range mints {
+		address, err := p.DeriveMintMetadata(mint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive mint metadata of %s: %w", mint, err)
+		}
+		addresses[i] = address
+	}
+
+	metadatas := make([]*common.TokenMetadata, len(mints))
+	for start := 0; start < len(addresses); start += maxMultipleAccounts {
+		end := start + maxMultipleAccounts
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+
+		res, err := rpcClient.GetMultipleAccounts(ctx, addresses[start:end]...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token metadata accounts: %w", err)
+		}
+		if res == nil || len(res.Value) != end-start {
+			return nil, fmt.Errorf("unexpected token metadata accounts response")
+		}
+
+		for i, account := range res.Value {
+			if account == nil || !account.Owner.Equals(p.Programs.Metadata) {
+				continue
+			}
+			metadata, err := DecodeTokenMetadata(account.Data.GetBinary())
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode token metadata of %s: %w", mints[start+i], err)
+			}
+			metadatas[start+i] = metadata
+		}
+	}
+	return metadatas, nil
+}
+
+func GetTokenMetadata(ctx context.Context, rpcClient AccountFetcher, mint solana.PublicKey) (*common.TokenMetadata, error) {
+	return DefaultProgram.GetTokenMetadata(ctx, rpcClient, mint)
+}
+
+func GetTokenMetadatas(ctx context.Context, rpcClient MultipleAccountsFetcher, mints []solana.PublicKey) ([]*common.TokenMetadata, error) {
+	return DefaultProgram.GetTokenMetadatas(ctx, rpcClient, mints)
+}
+
+// Decodes a metaplex MetadataV1 account: key (1), update authority (32), mint (32),
+// name, symbol, uri, seller fee (2), optional creators, primary sale (1), mutable (1), ...
+func DecodeTokenMetadata(data []byte) (*common.TokenMetadata, error) {
+	if len(data) < 1+32+32 {
+		return nil, errMetadataTooShort
+	}
+	if data[0] != metadataV1Key {
+		return nil, fmt.Errorf("invalid key %d, not a metadata account", data[0])
+	}
+
+	metadata := &common.TokenMetadata{
+		UpdateAuthority: solana.PublicKeyFromBytes(data[1:33]),
+		Mint:            solana.PublicKeyFromBytes(data[33:65]),
+	}
+	rest := data[65:]
+
+	var err error
+	if metadata.Name, rest, err = readPaddedString(rest); err != nil {
+		return nil, fmt.Errorf("failed to decode name: %w", err)
+	}
+	if metadata.Symbol, rest, err = readPaddedString(rest); err != nil {
+		return nil, fmt.Errorf("failed to decode symbol: %w", err)
+	}
+	if metadata.URI, rest, err = readPaddedString(rest); err != nil {
+		return nil, fmt.Errorf("failed to decode uri: %w", err)
+	}
+
+	if len(rest) < 2+1 {
+		return nil, errMetadataTooShort
+	}
+	metadata.SellerFeeBasisPoints = binary.LittleEndian.Uint16(rest[:2])
+	hasCreators := rest[2] == 1
+	rest = rest[3:]
+
+	if hasCreators {
+		if len(rest) < 4 {
+			return nil, errMetadataTooShort
+		}
+		count := binary.LittleEndian.Uint32(rest[:4])
+		rest = rest[4:]
+		// address (32), verified (1), share (1)
+		if uint64(len(rest)) < uint64(count)*34 {
+			return nil, errMetadataTooShort
+		}
+		metadata.Creators = make([]common.MetadataCreator, count)
+		for i := range metadata.Creators {
+			metadata.Creators[i] = common.MetadataCreator{
+				Address:  solana.PublicKeyFromBytes(rest[:32]),
+				Verified: rest[32] == 1,
+				Share:    rest[33],
+			}
+			rest = rest[34:]
+		}
+	}
+
+	if len(rest) < 2 {
+		return nil, errMetadataTooShort
+	}
+	metadata.PrimarySaleHappened = rest[0] == 1
+	metadata.IsMutable = rest[1] == 1
+
+	return metadata, nil
+}
+
+// reads a borsh string and trims the null padding metaplex stores it with
+func readPaddedString(data []byte) (string, []byte, error) {
+	s, rest, err := readString(data)
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.TrimRight(s, "\x00"), rest, nil
+}
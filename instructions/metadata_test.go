@@ -0,0 +1,200 @@
+package instructions_test
+
+import (
+	"context"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+)
+
+// encodes a metaplex MetadataV1 account, padding the strings with nulls to the
+// lengths metaplex reserves for them
+func encodeMetadata(m *common.TokenMetadata, padded bool) []byte {
+	data := []byte{4}
+	data = append(data, m.UpdateAuthority[:]...)
+	data = append(data, m.Mint[:]...)
+	for i, s := range []string{m.Name, m.Symbol, m.URI} {
+		if padded {
+			pad := []int{32, 10, 200}[i]
+			for len(s) < pad {
+				s += "\x00"
+			}
+		}
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(s)))
+		data = append(data, s...)
+	}
+	data = binary.LittleEndian.AppendUint16(data, m.SellerFeeBasisPoints)
+	if m.Creators == nil {
+		data = append(data, 0)
+	} else {
+		data = append(data, 1)
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(m.Creators)))
+		for _, c := range m.Creators {
+			data = append(data, c.Address[:]...)
+			data = append(data, boolByte(c.Verified), c.Share)
+		}
+	}
+	return append(data, boolByte(m.PrimarySaleHappened), boolByte(m.IsMutable))
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func sampleMetadata(mint solana.PublicKey) *common.TokenMetadata {
+	return &common.TokenMetadata{
+		Mint:                 mint,
+		UpdateAuthority:      solana.PublicKey{9},
+		Name:                 "Dynamic Bonding",
+		Symbol:               "DBC",
+		URI:                  "https://arweave.net/abc123",
+		SellerFeeBasisPoints: 500,
+		IsMutable:            true,
+	}
+}
+
+func TestDecodeTokenMetadata(t *testing.T) {
+	withCreators := sampleMetadata(solana.PublicKey{1})
+	withCreators.Creators = []common.MetadataCreator{
+		{Address: solana.PublicKey{2}, Verified: true, Share: 70},
+		{Address: solana.PublicKey{3}, Share: 30},
+	}
+	withCreators.PrimarySaleHappened = true
+	immutable := sampleMetadata(solana.PublicKey{1})
+	immutable.IsMutable = false
+	empty := &common.TokenMetadata{Mint: solana.PublicKey{1}}
+
+	tests := []struct {
+		name string
+		data []byte
+		want *common.TokenMetadata
+	}{
+		{name: "plain strings", data: encodeMetadata(sampleMetadata(solana.PublicKey{1}), false), want: sampleMetadata(solana.PublicKey{1})},
+		{name: "null padded strings", data: encodeMetadata(sampleMetadata(solana.PublicKey{1}), true), want: sampleMetadata(solana.PublicKey{1})},
+		{name: "creators", data: encodeMetadata(withCreators, true), want: withCreators},
+		{name: "immutable", data: encodeMetadata(immutable, true), want: immutable},
+		{name: "empty strings", data: encodeMetadata(empty, false), want: empty},
+		// edition nonce, token standard and the other trailing fields are ignored
+		{name: "trailing fields", data: append(encodeMetadata(immutable, true), 1, 255, 0, 0, 0), want: immutable},
+	}
+	for _, tt := range tests {
+		got, err := instructions.DecodeTokenMetadata(tt.data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeTokenMetadataErrors(t *testing.T) {
+	valid := encodeMetadata(sampleMetadata(solana.PublicKey{1}), true)
+	withCreators := sampleMetadata(solana.PublicKey{1})
+	withCreators.Creators = []common.MetadataCreator{{Address: solana.PublicKey{2}, Share: 100}}
+	creators := encodeMetadata(withCreators, false)
+	wrongKey := append([]byte{}, valid...)
+	wrongKey[0] = 6
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "no mint", data: valid[:40]},
+		{name: "wrong key", data: wrongKey},
+		{name: "truncated name", data: valid[:70]},
+		{name: "truncated uri", data: valid[:len(valid)-10]},
+		{name: "no flags", data: valid[:len(valid)-2]},
+		{name: "truncated creators", data: creators[:len(creators)-10]},
+	}
+	for _, tt := range tests {
+		if got, err := instructions.DecodeTokenMetadata(tt.data); err == nil {
+			t.Errorf("%s: expected an error, got %+v", tt.name, got)
+		}
+	}
+}
+
+// fetcher counting the batches requested
+type batchCounter struct {
+	*rpctest.AccountFetcher
+	batches []int
+}
+
+func (b *batchCounter) GetMultipleAccounts(ctx context.Context, accounts ...solana.PublicKey) (*solRpc.GetMultipleAccountsResult, error) {
+	b.batches = append(b.batches, len(accounts))
+	return b.AccountFetcher.GetMultipleAccounts(ctx, accounts...)
+}
+
+func TestGetTokenMetadatas(t *testing.T) {
+	metadataProgram := solana.MustPublicKeyFromBase58(common.MetadataProgram)
+	fetcher := &batchCounter{AccountFetcher: rpctest.NewAccountFetcher()}
+	mints := make([]solana.PublicKey, 150)
+	for i := range mints {
+		mints[i] = solana.NewWallet().PublicKey()
+		address, err := instructions.DefaultProgram.DeriveMintMetadata(mints[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case i%10 == 3:
+			// no metadata account
+		case i%10 == 7:
+			// an account at the address that the metadata program does not own
+			fetcher.SetAccount(address, solana.SystemProgramID, encodeMetadata(sampleMetadata(mints[i]), true))
+		default:
+			fetcher.SetAccount(address, metadataProgram, encodeMetadata(sampleMetadata(mints[i]), true))
+		}
+	}
+
+	metadatas, err := instructions.GetTokenMetadatas(context.Background(), fetcher, mints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fetcher.batches, []int{100, 50}) {
+		t.Fatalf("got batches %v, want [100 50]", fetcher.batches)
+	}
+	for i, metadata := range metadatas {
+		found := i%10 != 3 && i%10 != 7
+		if found != (metadata != nil) {
+			t.Fatalf("mint %d: got %+v", i, metadata)
+		}
+		if found && !metadata.Mint.Equals(mints[i]) {
+			t.Fatalf("mint %d: got metadata of %s", i, metadata.Mint)
+		}
+	}
+
+	// the single lookup rejects what the batch skips
+	tests := []struct {
+		name  string
+		index int
+		valid bool
+	}{
+		{name: "metadata", index: 0, valid: true},
+		{name: "missing", index: 3},
+		{name: "wrong owner", index: 7},
+	}
+	for _, tt := range tests {
+		metadata, err := instructions.GetTokenMetadata(context.Background(), fetcher, mints[tt.index])
+		if tt.valid != (err == nil) {
+			t.Errorf("%s: got %+v, %v", tt.name, metadata, err)
+		}
+	}
+
+	// a corrupt account fails the batch
+	address, _ := instructions.DefaultProgram.DeriveMintMetadata(mints[0])
+	fetcher.SetAccount(address, metadataProgram, []byte{4, 1, 2})
+	if _, err := instructions.GetTokenMetadatas(context.Background(), fetcher, mints[:1]); err == nil {
+		t.Fatal("expected an error for a corrupt metadata account")
+	}
+}
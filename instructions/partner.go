@@ -6,10 +6,9 @@ import (
 	"github.com/gagliardetto/solana-go"
 
 	"github.com/Luigi-1Combo/dbc-go/common"
-	"github.com/Luigi-1Combo/dbc-go/helpers"
 )
 
-func ClaimPartnerTradingFee(
+func (p *Program) ClaimPartnerTradingFee(
 	config solana.PublicKey,
 	pool solana.PublicKey,
 	tokenAAccount solana.PublicKey,
@@ -22,16 +21,15 @@ func ClaimPartnerTradingFee(
 	maxAmountA uint64,
 	maxAmountB uint64,
 ) solana.Instruction {
-	disc := []byte{8, 236, 89, 49, 152, 125, 177, 81}
 	buf := make([]byte, 8+8+8)
-	copy(buf, disc)
+	copy(buf, ClaimPartnerTradingFeeDiscriminator[:])
 	binary.LittleEndian.PutUint64(buf[8:], maxAmountA)
 	binary.LittleEndian.PutUint64(buf[16:], maxAmountB)
 
 	tokenBaseProgram := solana.MustPublicKeyFromBase58(common.TokenProgram)
 	tokenQuoteProgram := solana.MustPublicKeyFromBase58(common.TokenProgram)
-	poolAuthority := helpers.DerivePoolAuthorityPDA()
-	eventAuthority := helpers.DeriveEventAuthorityPDA()
+	poolAuthority := p.PoolAuthority
+	eventAuthority := p.EventAuthority
 
 	acctMeta := solana.AccountMetaSlice{
 		// 1. pool_authority
@@ -61,11 +59,11 @@ func ClaimPartnerTradingFee(
 		// 13. event_authority
 		{PublicKey: eventAuthority, IsSigner: false, IsWritable: false},
 		// 14. program
-		{PublicKey: solana.MustPublicKeyFromBase58(common.DbcProgramID), IsSigner: false, IsWritable: false},
+		{PublicKey: p.Programs.Dbc, IsSigner: false, IsWritable: false},
 	}
 
 	return solana.NewInstruction(
-		solana.MustPublicKeyFromBase58(common.DbcProgramID),
+		p.Programs.Dbc,
 		acctMeta,
 		buf,
 	)
@@ -2,6 +2,7 @@ package instructions
 
 import (
 	"encoding/binary"
+	"fmt"
 
 	"github.com/gagliardetto/solana-go"
 
@@ -9,7 +10,29 @@ import (
 	"github.com/Luigi-1Combo/dbc-go/helpers"
 )
 
-func InitializeVirtualPoolWithSplToken(
+// Like InitializeVirtualPoolWithSplToken, failing on metadata the program would reject,
+// see helpers.ValidateTokenMetadata
+func (p *Program) InitializeVirtualPoolWithSplTokenChecked(
+	config solana.PublicKey,
+	poolCreator solana.PublicKey,
+	baseMint solana.PublicKey,
+	quoteMint solana.PublicKey,
+	pool solana.PublicKey,
+	baseVault solana.PublicKey,
+	quoteVault solana.PublicKey,
+	mintMetadata solana.PublicKey,
+	payer solana.PublicKey,
+	name string,
+	symbol string,
+	uri string,
+) (solana.Instruction, error) {
+	if err := helpers.ValidateTokenMetadata(name, symbol, uri); err != nil {
+		return nil, fmt.Errorf("invalid token metadata: %w", err)
+	}
+	return p.InitializeVirtualPoolWithSplToken(config, poolCreator, baseMint, quoteMint, pool, baseVault, quoteVault, mintMetadata, payer, name, symbol, uri), nil
+}
+
+func (p *Program) InitializeVirtualPoolWithSplToken(
 	config solana.PublicKey,
 	poolCreator solana.PublicKey,
 	baseMint solana.PublicKey,
@@ -23,7 +46,7 @@ func InitializeVirtualPoolWithSplToken(
 	symbol string,
 	uri string,
 ) solana.Instruction {
-	disc := []byte{140, 85, 215, 176, 102, 54, 104, 79}
+	disc := append([]byte{}, InitializeVirtualPoolWithSplTokenDiscriminator[:]...)
 
 	packString := func(s string) []byte {
 		b := make([]byte, 4+len(s))
@@ -35,8 +58,8 @@ func InitializeVirtualPoolWithSplToken(
 
 	tokenQuoteProgram := solana.MustPublicKeyFromBase58(common.TokenProgram)
 	tokenProgram := solana.MustPublicKeyFromBase58(common.TokenProgram)
-	poolAuthority := helpers.DerivePoolAuthorityPDA()
-	eventAuthority := helpers.DeriveEventAuthorityPDA()
+	poolAuthority := p.PoolAuthority
+	eventAuthority := p.EventAuthority
 
 	acctMeta := solana.AccountMetaSlice{
 		// 1. config
@@ -58,7 +81,7 @@ func InitializeVirtualPoolWithSplToken(
 		// 9. mint_metadata (writable)
 		{PublicKey: mintMetadata, IsSigner: false, IsWritable: true},
 		// 10. metadata_program
-		{PublicKey: solana.MustPublicKeyFromBase58(common.MetadataProgram), IsSigner: false, IsWritable: false},
+		{PublicKey: p.Programs.Metadata, IsSigner: false, IsWritable: false},
 		// 11. payer (signer, writable)
 		{PublicKey: payer, IsSigner: true, IsWritable: true},
 		// 12. token_quote_program
@@ -70,17 +93,17 @@ func InitializeVirtualPoolWithSplToken(
 		// 15. event_authority (PDA, same as pool_authority)
 		{PublicKey: eventAuthority, IsSigner: false, IsWritable: false},
 		// 16. program (ProgramID)
-		{PublicKey: solana.MustPublicKeyFromBase58(common.DbcProgramID), IsSigner: false, IsWritable: false},
+		{PublicKey: p.Programs.Dbc, IsSigner: false, IsWritable: false},
 	}
 
 	return solana.NewInstruction(
-		solana.MustPublicKeyFromBase58(common.DbcProgramID),
+		p.Programs.Dbc,
 		acctMeta,
 		data,
 	)
 }
 
-func Swap(
+func (p *Program) Swap(
 	config solana.PublicKey,
 	pool solana.PublicKey,
 	userInputTokenAccount solana.PublicKey,
@@ -94,18 +117,75 @@ func Swap(
 	amountIn uint64,
 	minOut uint64,
 ) solana.Instruction {
-	swapDisc := []byte{248, 198, 158, 145, 225, 117, 135, 200}
 	buf := make([]byte, 8+8+8)
-	copy(buf, swapDisc)
+	copy(buf, SwapDiscriminator[:])
 	binary.LittleEndian.PutUint64(buf[8:], amountIn)
 	binary.LittleEndian.PutUint64(buf[16:], minOut)
 
-	poolAuthority := helpers.DerivePoolAuthorityPDA()
+	return solana.NewInstruction(
+		p.Programs.Dbc,
+		p.swapAccounts(config, pool, userInputTokenAccount, userOutputTokenAccount, baseVault, quoteVault, baseMint, quoteMint, payer, referralTokenAccount),
+		buf,
+	)
+}
+
+// Builds a swap2: amount0 is the amount in and amount1 the minimum amount out for
+// ExactIn and PartialFill, amount0 the amount out and amount1 the maximum amount in
+// for ExactOut
+func (p *Program) Swap2(
+	config solana.PublicKey,
+	pool solana.PublicKey,
+	userInputTokenAccount solana.PublicKey,
+	userOutputTokenAccount solana.PublicKey,
+	baseVault solana.PublicKey,
+	quoteVault solana.PublicKey,
+	baseMint solana.PublicKey,
+	quoteMint solana.PublicKey,
+	payer solana.PublicKey,
+	referralTokenAccount solana.PublicKey,
+	amount0 uint64,
+	amount1 uint64,
+	swapMode common.SwapMode,
+) solana.Instruction {
+	buf := make([]byte, 8+8+8+1)
+	copy(buf, Swap2Discriminator[:])
+	binary.LittleEndian.PutUint64(buf[8:], amount0)
+	binary.LittleEndian.PutUint64(buf[16:], amount1)
+	buf[24] = byte(swapMode)
+
+	return solana.NewInstruction(
+		p.Programs.Dbc,
+		p.swapAccounts(config, pool, userInputTokenAccount, userOutputTokenAccount, baseVault, quoteVault, baseMint, quoteMint, payer, referralTokenAccount),
+		buf,
+	)
+}
+
+// accounts shared by swap and swap2
+func (p *Program) swapAccounts(
+	config solana.PublicKey,
+	pool solana.PublicKey,
+	userInputTokenAccount solana.PublicKey,
+	userOutputTokenAccount solana.PublicKey,
+	baseVault solana.PublicKey,
+	quoteVault solana.PublicKey,
+	baseMint solana.PublicKey,
+	quoteMint solana.PublicKey,
+	payer solana.PublicKey,
+	referralTokenAccount solana.PublicKey,
+) solana.AccountMetaSlice {
+	// anchor expects the program id in place of an omitted optional account
+	programID := p.Programs.Dbc
+	referral := &solana.AccountMeta{PublicKey: programID, IsSigner: false, IsWritable: false}
+	if !referralTokenAccount.IsZero() {
+		referral = &solana.AccountMeta{PublicKey: referralTokenAccount, IsSigner: false, IsWritable: true}
+	}
+
+	poolAuthority := p.PoolAuthority
 	tokenBaseProgram := solana.MustPublicKeyFromBase58(common.TokenProgram)
 	tokenQuoteProgram := solana.MustPublicKeyFromBase58(common.TokenProgram)
-	eventAuthority := helpers.DeriveEventAuthorityPDA()
+	eventAuthority := p.EventAuthority
 
-	acctMetaSwap := solana.AccountMetaSlice{
+	return solana.AccountMetaSlice{
 		// 1. pool_authority
 		{PublicKey: poolAuthority, IsSigner: false, IsWritable: false},
 		// 2. config
@@ -130,17 +210,23 @@ func Swap(
 		{PublicKey: tokenBaseProgram, IsSigner: false, IsWritable: false},
 		// 12. token_quote_program
 		{PublicKey: tokenQuoteProgram, IsSigner: false, IsWritable: false},
-		// 13. referral_token_account (optional; use a valid token account)
-		{PublicKey: referralTokenAccount, IsSigner: false, IsWritable: true},
+		// 13. referral_token_account (optional; the program id, readonly, when omitted)
+		referral,
 		// 14. event_authority
 		{PublicKey: eventAuthority, IsSigner: false, IsWritable: false},
 		// 15. program
-		{PublicKey: solana.MustPublicKeyFromBase58(common.DbcProgramID), IsSigner: false, IsWritable: false},
+		{PublicKey: programID, IsSigner: false, IsWritable: false},
 	}
+}
 
-	return solana.NewInstruction(
-		solana.MustPublicKeyFromBase58(common.DbcProgramID),
-		acctMetaSwap,
-		buf,
-	)
+// Appends the instructions sysvar to a swap or swap2, which the program reads while the
+// rate limiter applies to check the swap is the only one of its transaction
+func WithInstructionsSysvar(ix solana.Instruction) (solana.Instruction, error) {
+	data, err := ix.Data()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instruction data: %w", err)
+	}
+	accounts := append(solana.AccountMetaSlice{}, ix.Accounts()...)
+	accounts = append(accounts, &solana.AccountMeta{PublicKey: solana.SysVarInstructionsPubkey, IsSigner: false, IsWritable: false})
+	return solana.NewInstruction(ix.ProgramID(), accounts, data), nil
 }
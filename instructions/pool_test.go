@@ -0,0 +1,144 @@
+package instructions_test
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+)
+
+func TestInitializeVirtualPoolWithSplTokenChecked(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		symbol string
+		uri    string
+		valid  bool
+	}{
+		{name: "valid", token: "Dynamic Bonding", symbol: "DBC", uri: "https://arweave.net/abc123", valid: true},
+		{name: "symbol too long", token: "Dynamic Bonding", symbol: "DYNAMICBOND", uri: "https://arweave.net/abc123"},
+		{name: "invalid utf-8", token: "Dynamic \xff", symbol: "DBC"},
+		// SDK policies are opt-in, the program accepts these
+		{name: "empty symbol", token: "Dynamic Bonding", valid: true},
+		{name: "ftp uri", token: "Dynamic Bonding", symbol: "DBC", uri: "ftp://example.com/meta.json", valid: true},
+	}
+	keys := make([]solana.PublicKey, 9)
+	for i := range keys {
+		keys[i] = solana.NewWallet().PublicKey()
+	}
+
+	for _, tt := range tests {
+		ix, err := instructions.InitializeVirtualPoolWithSplTokenChecked(
+			keys[0], keys[1], keys[2], keys[3], keys[4], keys[5], keys[6], keys[7], keys[8], tt.token, tt.symbol, tt.uri,
+		)
+		if !tt.valid {
+			if err == nil || ix != nil {
+				t.Errorf("%s: expected an error and no instruction", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		// the checked builder produces the same instruction as the unchecked one
+		data, err := ix.Data()
+		if err != nil {
+			t.Fatal(err)
+		}
+		unchecked := instructions.InitializeVirtualPoolWithSplToken(
+			keys[0], keys[1], keys[2], keys[3], keys[4], keys[5], keys[6], keys[7], keys[8], tt.token, tt.symbol, tt.uri,
+		)
+		uncheckedData, _ := unchecked.Data()
+		if string(data) != string(uncheckedData) || len(ix.Accounts()) != len(unchecked.Accounts()) {
+			t.Fatalf("%s: checked instruction differs from the unchecked one", tt.name)
+		}
+
+		decoded, err := instructions.DecodeInstruction(ix.ProgramID(), accountKeys(ix), data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		args, ok := decoded.Args.(*instructions.InitializeVirtualPoolWithSplTokenArgs)
+		if !ok || args.Name != tt.token || args.Symbol != tt.symbol || args.URI != tt.uri {
+			t.Fatalf("%s: decoded %+v", tt.name, decoded.Args)
+		}
+	}
+}
+
+func accountKeys(ix solana.Instruction) []solana.PublicKey {
+	keys := make([]solana.PublicKey, len(ix.Accounts()))
+	for i, account := range ix.Accounts() {
+		keys[i] = account.PublicKey
+	}
+	return keys
+}
+
+func TestSwapReferralAccount(t *testing.T) {
+	custom := instructions.MustNewProgram(common.ProgramSet{Dbc: solana.NewWallet().PublicKey()})
+	referral := solana.NewWallet().PublicKey()
+	keys := make([]solana.PublicKey, 9)
+	for i := range keys {
+		keys[i] = solana.NewWallet().PublicKey()
+	}
+	build := func(program *instructions.Program, swap2 bool, referral solana.PublicKey) solana.Instruction {
+		if swap2 {
+			return program.Swap2(keys[0], keys[1], keys[2], keys[3], keys[4], keys[5], keys[6], keys[7], keys[8],
+				referral, 1_000, 1, common.SwapModeExactOut)
+		}
+		return program.Swap(keys[0], keys[1], keys[2], keys[3], keys[4], keys[5], keys[6], keys[7], keys[8],
+			referral, 1_000, 1)
+	}
+
+	tests := []struct {
+		name     string
+		program  *instructions.Program
+		swap2    bool
+		referral solana.PublicKey
+		// account 13, the program id in place of an omitted referral
+		want     solana.PublicKey
+		writable bool
+	}{
+		{name: "swap without referral", program: instructions.DefaultProgram, want: instructions.DefaultProgram.Programs.Dbc},
+		{name: "swap with referral", program: instructions.DefaultProgram, referral: referral, want: referral, writable: true},
+		{name: "swap2 without referral", program: instructions.DefaultProgram, swap2: true, want: instructions.DefaultProgram.Programs.Dbc},
+		{name: "swap2 with referral", program: instructions.DefaultProgram, swap2: true, referral: referral, want: referral, writable: true},
+		{name: "other deployment without referral", program: custom, want: custom.Programs.Dbc},
+	}
+	for _, tt := range tests {
+		accounts := build(tt.program, tt.swap2, tt.referral).Accounts()
+		if len(accounts) != 15 {
+			t.Fatalf("%s: got %d accounts", tt.name, len(accounts))
+		}
+		got := accounts[12]
+		if !got.PublicKey.Equals(tt.want) || got.IsWritable != tt.writable || got.IsSigner {
+			t.Errorf("%s: got referral account %s (writable %v), want %s (writable %v)",
+				tt.name, got.PublicKey, got.IsWritable, tt.want, tt.writable)
+		}
+	}
+}
+
+func TestWithInstructionsSysvar(t *testing.T) {
+	key := func() solana.PublicKey { return solana.NewWallet().PublicKey() }
+	swap := instructions.Swap2(key(), key(), key(), key(), key(), key(), key(), solana.SolMint, key(),
+		solana.PublicKey{}, 1_000, 1, common.SwapModeExactIn)
+
+	ix, err := instructions.WithInstructionsSysvar(swap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts := ix.Accounts()
+	if len(accounts) != len(swap.Accounts())+1 || len(swap.Accounts()) != 15 {
+		t.Fatalf("got %d accounts from %d", len(accounts), len(swap.Accounts()))
+	}
+	last := accounts[len(accounts)-1]
+	if !last.PublicKey.Equals(solana.SysVarInstructionsPubkey) || last.IsWritable || last.IsSigner {
+		t.Fatalf("got last account %+v", last)
+	}
+	data, _ := ix.Data()
+	swapData, _ := swap.Data()
+	if !ix.ProgramID().Equals(swap.ProgramID()) || string(data) != string(swapData) {
+		t.Fatal("the swap itself changed")
+	}
+}
@@ -0,0 +1,193 @@
+package instructions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/helpers"
+)
+
+// builds instructions for and reads accounts of one DBC deployment
+type Program struct {
+	Programs       common.ProgramSet
+	PoolAuthority  solana.PublicKey
+	EventAuthority solana.PublicKey
+}
+
+// mainnet deployment, used by the package-level builders and fetchers
+var DefaultProgram = MustNewProgram(common.MainnetPrograms)
+
+// Creates a builder for the programs, deriving their authority PDAs; zero program ids default to mainnet
+func NewProgram(programs common.ProgramSet) (*Program, error) {
+	programs = programs.WithDefaults()
+	poolAuthority, _, err := helpers.FindPoolAuthorityPDA(programs.Dbc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive pool authority: %w", err)
+	}
+	eventAuthority, _, err := helpers.FindEventAuthorityPDA(programs.Dbc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive event authority: %w", err)
+	}
+	return &Program{
+		Programs:       programs,
+		PoolAuthority:  poolAuthority,
+		EventAuthority: eventAuthority,
+	}, nil
+}
+
+// Like NewProgram but panics on error
+func MustNewProgram(programs common.ProgramSet) *Program {
+	p, err := NewProgram(programs)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Creates a builder for the programs of the cluster
+func NewClusterProgram(cluster common.Cluster) (*Program, error) {
+	return NewProgram(cluster.Programs)
+}
+
+// Derives the pool address of the deployment
+func (p *Program) DerivePool(quoteMint, baseMint, config solana.PublicKey) (solana.PublicKey, error) {
+	pool, _, err := helpers.FindDbcPoolPDA(p.Programs.Dbc, quoteMint, baseMint, config)
+	return pool, err
+}
+
+// Derives the token vault of the pool for the mint
+func (p *Program) DeriveTokenVault(pool, mint solana.PublicKey) (solana.PublicKey, error) {
+	vault, _, err := helpers.FindTokenVaultPDA(p.Programs.Dbc, pool, mint)
+	return vault, err
+}
+
+// Derives the metaplex metadata account of the mint
+func (p *Program) DeriveMintMetadata(mint solana.PublicKey) (solana.PublicKey, error) {
+	metadata, _, err := helpers.FindMintMetadataPDA(p.Programs.Metadata, mint)
+	return metadata, err
+}
+
+func InitializeVirtualPoolWithSplTokenChecked(
+	config solana.PublicKey,
+	poolCreator solana.PublicKey,
+	baseMint solana.PublicKey,
+	quoteMint solana.PublicKey,
+	pool solana.PublicKey,
+	baseVault solana.PublicKey,
+	quoteVault solana.PublicKey,
+	mintMetadata solana.PublicKey,
+	payer solana.PublicKey,
+	name string,
+	symbol string,
+	uri string,
+) (solana.Instruction, error) {
+	return DefaultProgram.InitializeVirtualPoolWithSplTokenChecked(config, poolCreator, baseMint, quoteMint, pool, baseVault, quoteVault, mintMetadata, payer, name, symbol, uri)
+}
+
+func InitializeVirtualPoolWithSplToken(
+	config solana.PublicKey,
+	poolCreator solana.PublicKey,
+	baseMint solana.PublicKey,
+	quoteMint solana.PublicKey,
+	pool solana.PublicKey,
+	baseVault solana.PublicKey,
+	quoteVault solana.PublicKey,
+	mintMetadata solana.PublicKey,
+	payer solana.PublicKey,
+	name string,
+	symbol string,
+	uri string,
+) solana.Instruction {
+	return DefaultProgram.InitializeVirtualPoolWithSplToken(config, poolCreator, baseMint, quoteMint, pool, baseVault, quoteVault, mintMetadata, payer, name, symbol, uri)
+}
+
+func Swap(
+	config solana.PublicKey,
+	pool solana.PublicKey,
+	userInputTokenAccount solana.PublicKey,
+	userOutputTokenAccount solana.PublicKey,
+	baseVault solana.PublicKey,
+	quoteVault solana.PublicKey,
+	baseMint solana.PublicKey,
+	quoteMint solana.PublicKey,
+	payer solana.PublicKey,
+	referralTokenAccount solana.PublicKey,
+	amountIn uint64,
+	minOut uint64,
+) solana.Instruction {
+	return DefaultProgram.Swap(config, pool, userInputTokenAccount, userOutputTokenAccount, baseVault, quoteVault, baseMint, quoteMint, payer, referralTokenAccount, amountIn, minOut)
+}
+
+func Swap2(
+	config solana.PublicKey,
+	pool solana.PublicKey,
+	userInputTokenAccount solana.PublicKey,
+	userOutputTokenAccount solana.PublicKey,
+	baseVault solana.PublicKey,
+	quoteVault solana.PublicKey,
+	baseMint solana.PublicKey,
+	quoteMint solana.PublicKey,
+	payer solana.PublicKey,
+	referralTokenAccount solana.PublicKey,
+	amount0 uint64,
+	amount1 uint64,
+	swapMode common.SwapMode,
+) solana.Instruction {
+	return DefaultProgram.Swap2(config, pool, userInputTokenAccount, userOutputTokenAccount, baseVault, quoteVault, baseMint, quoteMint, payer, referralTokenAccount, amount0, amount1, swapMode)
+}
+
+func ClaimCreatorTradingFee(
+	pool solana.PublicKey,
+	tokenAAccount solana.PublicKey,
+	tokenBAccount solana.PublicKey,
+	baseVault solana.PublicKey,
+	quoteVault solana.PublicKey,
+	baseMint solana.PublicKey,
+	quoteMint solana.PublicKey,
+	creator solana.PublicKey,
+	maxBaseAmount uint64,
+	maxQuoteAmount uint64,
+) solana.Instruction {
+	return DefaultProgram.ClaimCreatorTradingFee(pool, tokenAAccount, tokenBAccount, baseVault, quoteVault, baseMint, quoteMint, creator, maxBaseAmount, maxQuoteAmount)
+}
+
+func TransferPoolCreator(
+	virtualPool solana.PublicKey,
+	config solana.PublicKey,
+	creator solana.PublicKey,
+	newCreator solana.PublicKey,
+	migrationMetadata solana.PublicKey,
+) solana.Instruction {
+	return DefaultProgram.TransferPoolCreator(virtualPool, config, creator, newCreator, migrationMetadata)
+}
+
+func ClaimPartnerTradingFee(
+	config solana.PublicKey,
+	pool solana.PublicKey,
+	tokenAAccount solana.PublicKey,
+	tokenBAccount solana.PublicKey,
+	baseVault solana.PublicKey,
+	quoteVault solana.PublicKey,
+	baseMint solana.PublicKey,
+	quoteMint solana.PublicKey,
+	feeClaimer solana.PublicKey,
+	maxAmountA uint64,
+	maxAmountB uint64,
+) solana.Instruction {
+	return DefaultProgram.ClaimPartnerTradingFee(config, pool, tokenAAccount, tokenBAccount, baseVault, quoteVault, baseMint, quoteMint, feeClaimer, maxAmountA, maxAmountB)
+}
+
+func GetPoolConfig(ctx context.Context, configAddress solana.PublicKey, rpcClient AccountFetcher) (*common.PoolConfig, error) {
+	return DefaultProgram.GetPoolConfig(ctx, configAddress, rpcClient)
+}
+
+func GetPoolFeeMetrics(ctx context.Context, poolAddress solana.PublicKey, rpcClient AccountFetcher) (*common.PoolFeeMetrics, error) {
+	return DefaultProgram.GetPoolFeeMetrics(ctx, poolAddress, rpcClient)
+}
+
+func GetPool(ctx context.Context, poolAddress solana.PublicKey, rpcClient AccountFetcher) (*common.Pool, error) {
+	return DefaultProgram.GetPool(ctx, poolAddress, rpcClient)
+}
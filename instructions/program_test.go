@@ -0,0 +1,124 @@
+package instructions_test
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/helpers"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+)
+
+func TestNewProgram(t *testing.T) {
+	dbc := solana.NewWallet().PublicKey()
+	metadata := solana.NewWallet().PublicKey()
+	tests := []struct {
+		name    string
+		program *instructions.Program
+		want    common.ProgramSet
+	}{
+		{name: "default", program: instructions.DefaultProgram, want: common.MainnetPrograms},
+		{name: "empty set", program: instructions.MustNewProgram(common.ProgramSet{}), want: common.MainnetPrograms},
+		{
+			name:    "custom dbc",
+			program: instructions.MustNewProgram(common.ProgramSet{Dbc: dbc}),
+			want:    common.ProgramSet{Dbc: dbc}.WithDefaults(),
+		},
+		{
+			name:    "custom cluster",
+			program: mustClusterProgram(common.NewCustomCluster("staging", "", "", common.ProgramSet{Dbc: dbc, Metadata: metadata})),
+			want:    common.ProgramSet{Dbc: dbc, Metadata: metadata}.WithDefaults(),
+		},
+	}
+	for _, tt := range tests {
+		p := tt.program
+		if p.Programs != tt.want {
+			t.Errorf("%s: got programs %+v, want %+v", tt.name, p.Programs, tt.want)
+		}
+		poolAuthority, _, _ := solana.FindProgramAddress([][]byte{[]byte("pool_authority")}, tt.want.Dbc)
+		eventAuthority, _, _ := solana.FindProgramAddress([][]byte{[]byte("__event_authority")}, tt.want.Dbc)
+		if !p.PoolAuthority.Equals(poolAuthority) || !p.EventAuthority.Equals(eventAuthority) {
+			t.Errorf("%s: got authorities %s and %s, want %s and %s", tt.name, p.PoolAuthority, p.EventAuthority, poolAuthority, eventAuthority)
+		}
+
+		quoteMint, baseMint, config := solana.SolMint, solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+		pool, err := p.DerivePool(quoteMint, baseMint, config)
+		wantPool, _, _ := helpers.FindDbcPoolPDA(tt.want.Dbc, quoteMint, baseMint, config)
+		if err != nil || !pool.Equals(wantPool) {
+			t.Errorf("%s: derived pool %s, %v, want %s", tt.name, pool, err, wantPool)
+		}
+		vault, err := p.DeriveTokenVault(pool, baseMint)
+		wantVault, _, _ := helpers.FindTokenVaultPDA(tt.want.Dbc, pool, baseMint)
+		if err != nil || !vault.Equals(wantVault) {
+			t.Errorf("%s: derived vault %s, %v, want %s", tt.name, vault, err, wantVault)
+		}
+		mintMetadata, err := p.DeriveMintMetadata(baseMint)
+		wantMetadata, _, _ := helpers.FindMintMetadataPDA(tt.want.Metadata, baseMint)
+		if err != nil || !mintMetadata.Equals(wantMetadata) {
+			t.Errorf("%s: derived metadata %s, %v, want %s", tt.name, mintMetadata, err, wantMetadata)
+		}
+	}
+}
+
+func mustClusterProgram(cluster common.Cluster) *instructions.Program {
+	p, err := instructions.NewClusterProgram(cluster)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// instructions built by a program of another deployment never reference the mainnet one
+func TestProgramInstructionsUseDeployment(t *testing.T) {
+	p := instructions.MustNewProgram(common.ProgramSet{Dbc: solana.NewWallet().PublicKey()})
+	key := func() solana.PublicKey { return solana.NewWallet().PublicKey() }
+	tests := []struct {
+		name string
+		ix   solana.Instruction
+	}{
+		{
+			name: "InitializeVirtualPoolWithSplToken",
+			ix: p.InitializeVirtualPoolWithSplToken(key(), key(), key(), solana.SolMint, key(), key(), key(), key(), key(),
+				"Dynamic Bonding", "DBC", ""),
+		},
+		{name: "Swap", ix: p.Swap(key(), key(), key(), key(), key(), key(), key(), solana.SolMint, key(), solana.PublicKey{}, 1, 1)},
+		{
+			name: "Swap2",
+			ix: p.Swap2(key(), key(), key(), key(), key(), key(), key(), solana.SolMint, key(), key(), 1, 1,
+				common.SwapModeExactOut),
+		},
+		{
+			name: "ClaimCreatorTradingFee",
+			ix:   p.ClaimCreatorTradingFee(key(), key(), key(), key(), key(), key(), solana.SolMint, key(), 1, 1),
+		},
+		{
+			name: "ClaimPartnerTradingFee",
+			ix:   p.ClaimPartnerTradingFee(key(), key(), key(), key(), key(), key(), key(), solana.SolMint, key(), 1, 1),
+		},
+		{name: "TransferPoolCreator", ix: p.TransferPoolCreator(key(), key(), key(), key(), key())},
+	}
+	mainnet := instructions.DefaultProgram
+	for _, tt := range tests {
+		if !tt.ix.ProgramID().Equals(p.Programs.Dbc) {
+			t.Errorf("%s: built for program %s", tt.name, tt.ix.ProgramID())
+		}
+		var hasEventAuthority bool
+		for _, account := range tt.ix.Accounts() {
+			switch account.PublicKey {
+			case mainnet.Programs.Dbc, mainnet.PoolAuthority, mainnet.EventAuthority:
+				t.Errorf("%s: references the mainnet account %s", tt.name, account.PublicKey)
+			case p.EventAuthority:
+				hasEventAuthority = true
+			}
+		}
+		if !hasEventAuthority {
+			t.Errorf("%s: missing the event authority of the deployment", tt.name)
+		}
+
+		data, _ := tt.ix.Data()
+		if name, ok := instructions.InstructionName(data); !ok || name != tt.name {
+			t.Errorf("%s: built %s", tt.name, name)
+		}
+	}
+}
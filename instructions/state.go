@@ -11,7 +11,12 @@ import (
 	solRpc "github.com/gagliardetto/solana-go/rpc"
 )
 
-func GetPoolConfig(ctx context.Context, configAddress solana.PublicKey, rpcClient *solRpc.Client) (*common.PoolConfig, error) {
+// reads accounts, implemented by *solRpc.Client and by rpctest.AccountFetcher for offline tests
+type AccountFetcher interface {
+	GetAccountInfo(ctx context.Context, account solana.PublicKey) (*solRpc.GetAccountInfoResult, error)
+}
+
+func (p *Program) GetPoolConfig(ctx context.Context, configAddress solana.PublicKey, rpcClient AccountFetcher) (*common.PoolConfig, error) {
 	account, err := rpcClient.GetAccountInfo(ctx, configAddress)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pool config account: %w", err)
@@ -21,22 +26,25 @@ func GetPoolConfig(ctx context.Context, configAddress solana.PublicKey, rpcClien
 		return nil, fmt.Errorf("pool config account not found")
 	}
 
+	if !account.Value.Owner.Equals(p.Programs.Dbc) {
+		return nil, fmt.Errorf("pool config account is owned by %s, not the DBC program %s", account.Value.Owner, p.Programs.Dbc)
+	}
+
 	data := account.Value.Data.GetBinary()
 
 	if len(data) < 8 {
 		return nil, fmt.Errorf("data too short")
 	}
 
-	expectedDiscriminator := []byte{26, 108, 14, 123, 116, 230, 129, 43}
-	if !bytes.Equal(data[:8], expectedDiscriminator) {
+	if !bytes.Equal(data[:8], PoolConfigAccountDiscriminator[:]) {
 		return nil, fmt.Errorf("invalid discriminator, not a pool config account")
 	}
 
-	return helpers.DeserializePoolConfig(data)
+	return helpers.DecodePoolConfig(data)
 }
 
-func GetPoolFeeMetrics(ctx context.Context, poolAddress solana.PublicKey, rpcClient *solRpc.Client) (*common.PoolFeeMetrics, error) {
-	pool, err := GetPool(ctx, poolAddress, rpcClient)
+func (p *Program) GetPoolFeeMetrics(ctx context.Context, poolAddress solana.PublicKey, rpcClient AccountFetcher) (*common.PoolFeeMetrics, error) {
+	pool, err := p.GetPool(ctx, poolAddress, rpcClient)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pool: %w", err)
 	}
@@ -56,7 +64,7 @@ func GetPoolFeeMetrics(ctx context.Context, poolAddress solana.PublicKey, rpcCli
 	return metrics, nil
 }
 
-func GetPool(ctx context.Context, poolAddress solana.PublicKey, rpcClient *solRpc.Client) (*common.Pool, error) {
+func (p *Program) GetPool(ctx context.Context, poolAddress solana.PublicKey, rpcClient AccountFetcher) (*common.Pool, error) {
 	account, err := rpcClient.GetAccountInfo(ctx, poolAddress)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pool account: %w", err)
@@ -66,16 +74,19 @@ func GetPool(ctx context.Context, poolAddress solana.PublicKey, rpcClient *solRp
 		return nil, fmt.Errorf("pool account not found")
 	}
 
+	if !account.Value.Owner.Equals(p.Programs.Dbc) {
+		return nil, fmt.Errorf("pool account is owned by %s, not the DBC program %s", account.Value.Owner, p.Programs.Dbc)
+	}
+
 	data := account.Value.Data.GetBinary()
 
 	if len(data) < 8 {
 		return nil, fmt.Errorf("data too short")
 	}
 
-	expectedDiscriminator := []byte{213, 224, 5, 209, 98, 69, 119, 92}
-	if !bytes.Equal(data[:8], expectedDiscriminator) {
+	if !bytes.Equal(data[:8], PoolAccountDiscriminator[:]) {
 		return nil, fmt.Errorf("invalid discriminator, not a pool account")
 	}
 
-	return helpers.DeserializePool(data)
+	return helpers.DecodePool(data)
 }
@@ -0,0 +1,145 @@
+package instructions_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+)
+
+func TestGetPoolAndConfig(t *testing.T) {
+	otherProgram := solana.NewWallet().PublicKey()
+	nodeErr := errors.New("node is behind")
+	tests := []struct {
+		name string
+		// sets the account at the address, nothing is stored when nil
+		set     func(f *rpctest.AccountFetcher, address solana.PublicKey)
+		program *instructions.Program
+		// whether the address holds a pool, a config or neither once decoded
+		wantPool, wantConfig bool
+		target               error
+	}{
+		{
+			name:     "pool",
+			set:      func(f *rpctest.AccountFetcher, a solana.PublicKey) { mustSet(f.SetPool(a, samplePool())) },
+			wantPool: true,
+		},
+		{
+			name: "config",
+			set: func(f *rpctest.AccountFetcher, a solana.PublicKey) {
+				mustSet(f.SetPoolConfig(a, rpctest.SamplePoolConfig()))
+			},
+			wantConfig: true,
+		},
+		{name: "missing", target: solRpc.ErrNotFound},
+		{
+			name:   "node error",
+			set:    func(f *rpctest.AccountFetcher, a solana.PublicKey) { f.SetError(a, nodeErr) },
+			target: nodeErr,
+		},
+		{
+			name: "owned by another program",
+			set: func(f *rpctest.AccountFetcher, a solana.PublicKey) {
+				data, err := rpctest.EncodeAccount(instructions.PoolAccountDiscriminator, samplePool())
+				mustSet(err)
+				f.SetAccount(a, otherProgram, data)
+			},
+		},
+		{
+			name:    "pool of another deployment",
+			set:     func(f *rpctest.AccountFetcher, a solana.PublicKey) { mustSet(f.SetPool(a, samplePool())) },
+			program: instructions.MustNewProgram(common.ProgramSet{Dbc: otherProgram}),
+		},
+		{
+			name: "data too short",
+			set: func(f *rpctest.AccountFetcher, a solana.PublicKey) {
+				f.SetAccount(a, solana.MustPublicKeyFromBase58(common.DbcProgramID), []byte{1, 2, 3})
+			},
+		},
+		{
+			name: "truncated pool",
+			set: func(f *rpctest.AccountFetcher, a solana.PublicKey) {
+				f.SetAccount(a, solana.MustPublicKeyFromBase58(common.DbcProgramID), instructions.PoolAccountDiscriminator[:])
+			},
+		},
+	}
+	for _, tt := range tests {
+		fetcher := rpctest.NewAccountFetcher()
+		address := solana.NewWallet().PublicKey()
+		if tt.set != nil {
+			tt.set(fetcher, address)
+		}
+		program := instructions.DefaultProgram
+		if tt.program != nil {
+			program = tt.program
+		}
+
+		pool, err := program.GetPool(context.Background(), address, fetcher)
+		if tt.wantPool != (err == nil) {
+			t.Errorf("%s: GetPool got error %v", tt.name, err)
+		}
+		if tt.target != nil && !errors.Is(err, tt.target) {
+			t.Errorf("%s: GetPool got %v, want %v", tt.name, err, tt.target)
+		}
+		if tt.wantPool && (pool.QuoteReserve != samplePool().QuoteReserve || pool.SqrtPrice != samplePool().SqrtPrice) {
+			t.Errorf("%s: got pool %+v", tt.name, pool)
+		}
+
+		config, err := program.GetPoolConfig(context.Background(), address, fetcher)
+		if tt.wantConfig != (err == nil) {
+			t.Errorf("%s: GetPoolConfig got error %v", tt.name, err)
+		}
+		if tt.target != nil && !errors.Is(err, tt.target) {
+			t.Errorf("%s: GetPoolConfig got %v, want %v", tt.name, err, tt.target)
+		}
+		if tt.wantConfig && *config != *rpctest.SamplePoolConfig() {
+			t.Errorf("%s: got config %+v", tt.name, config)
+		}
+	}
+}
+
+func TestGetPoolFeeMetrics(t *testing.T) {
+	fetcher := rpctest.NewAccountFetcher()
+	address := solana.NewWallet().PublicKey()
+	pool := samplePool()
+	pool.PartnerBaseFee = 1
+	pool.PartnerQuoteFee = 2
+	pool.CreatorBaseFee = 3
+	pool.CreatorQuoteFee = 4
+	pool.Metrics.TotalTradingBaseFee = 5
+	pool.Metrics.TotalTradingQuoteFee = 6
+	mustSet(fetcher.SetPool(address, pool))
+
+	metrics, err := instructions.GetPoolFeeMetrics(context.Background(), address, fetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.Current.PartnerBaseFee != 1 || metrics.Current.PartnerQuoteFee != 2 ||
+		metrics.Current.CreatorBaseFee != 3 || metrics.Current.CreatorQuoteFee != 4 ||
+		metrics.Total.TotalTradingBaseFee != 5 || metrics.Total.TotalTradingQuoteFee != 6 {
+		t.Fatalf("got %+v", metrics)
+	}
+
+	if _, err := instructions.GetPoolFeeMetrics(context.Background(), solana.NewWallet().PublicKey(), fetcher); err == nil {
+		t.Fatal("expected an error for a missing pool")
+	}
+}
+
+func samplePool() *common.Pool {
+	pool := rpctest.SamplePool(solana.PublicKey{1}, solana.PublicKey{2})
+	pool.QuoteReserve = 1_000_000_000
+	return pool
+}
+
+// fixtures are built from valid state, encoding cannot fail
+func mustSet(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
@@ -1,11 +1,15 @@
 package math
 
 import (
+	"errors"
 	"math/big"
 
 	"github.com/Luigi-1Combo/dbc-go/common"
+	"lukechampine.com/uint128"
 )
 
+var errZeroSqrtPriceOrLiquidity = errors.New("sqrt price and liquidity must be greater than zero")
+
 // gets the delta amount_quote for given liquidity and price range
 // Formula: Δb = L (√P_upper - √P_lower)
 func GetDeltaAmountQuoteUnsigned(
@@ -42,19 +46,154 @@ func GetDeltaAmountQuoteUnsigned(
 	}
 }
 
+// gets the delta amount_base for given liquidity and price range
+// Formula: Δa = L * (1 / √P_lower - 1 / √P_upper) = L (√P_upper - √P_lower) / (√P_upper * √P_lower)
+func GetDeltaAmountBaseUnsigned(
+	lowerSqrtPrice *big.Int,
+	upperSqrtPrice *big.Int,
+	liquidity *big.Int,
+	round common.Rounding,
+) (*big.Int, error) {
+	if liquidity.Sign() == 0 {
+		return big.NewInt(0), nil
+	}
+
+	// delta sqrt price: (√P_upper - √P_lower)
+	deltaSqrtPrice, err := Sub(upperSqrtPrice, lowerSqrtPrice)
+	if err != nil {
+		return nil, err
+	}
+
+	// √P_upper * √P_lower
+	denominator := Mul(upperSqrtPrice, lowerSqrtPrice)
+
+	return MulDiv(liquidity, deltaSqrtPrice, denominator, round)
+}
+
+// gets the next sqrt price given an input amount of base or quote
+func GetNextSqrtPriceFromInput(
+	sqrtPrice *big.Int,
+	liquidity *big.Int,
+	amountIn *big.Int,
+	baseForQuote bool,
+) (*big.Int, error) {
+	if sqrtPrice.Sign() == 0 || liquidity.Sign() == 0 {
+		return nil, errZeroSqrtPriceOrLiquidity
+	}
+
+	if baseForQuote {
+		return GetNextSqrtPriceFromAmountBaseRoundingUp(sqrtPrice, liquidity, amountIn)
+	}
+	return GetNextSqrtPriceFromAmountQuoteRoundingDown(sqrtPrice, liquidity, amountIn)
+}
+
+// gets the next sqrt price from an amount of base token, rounded up
+// Formula: √P' = √P * L / (L + Δx * √P)
+func GetNextSqrtPriceFromAmountBaseRoundingUp(sqrtPrice, liquidity, amount *big.Int) (*big.Int, error) {
+	if amount.Sign() == 0 {
+		return new(big.Int).Set(sqrtPrice), nil
+	}
+
+	product := Mul(amount, sqrtPrice)
+	denominator := Add(liquidity, product)
+
+	return MulDiv(liquidity, sqrtPrice, denominator, common.Up)
+}
+
+// gets the next sqrt price from an amount of quote token, rounded down
+// Formula: √P' = √P + Δy / L
+func GetNextSqrtPriceFromAmountQuoteRoundingDown(sqrtPrice, liquidity, amount *big.Int) (*big.Int, error) {
+	quotient, err := Div(Shl(amount, uint(common.Resolution*2)), liquidity)
+	if err != nil {
+		return nil, err
+	}
+
+	return Add(sqrtPrice, quotient), nil
+}
+
+// gets the next sqrt price given an output amount of quote or base
+func GetNextSqrtPriceFromOutput(
+	sqrtPrice *big.Int,
+	liquidity *big.Int,
+	amountOut *big.Int,
+	baseForQuote bool,
+) (*big.Int, error) {
+	if sqrtPrice.Sign() == 0 || liquidity.Sign() == 0 {
+		return nil, errZeroSqrtPriceOrLiquidity
+	}
+
+	if baseForQuote {
+		return GetNextSqrtPriceFromOutputQuoteRoundingDown(sqrtPrice, liquidity, amountOut)
+	}
+	return GetNextSqrtPriceFromOutputBaseRoundingUp(sqrtPrice, liquidity, amountOut)
+}
+
+// gets the next sqrt price after removing an amount of base token, rounded up
+// Formula: √P' = √P * L / (L - Δx * √P)
+func GetNextSqrtPriceFromOutputBaseRoundingUp(sqrtPrice, liquidity, amount *big.Int) (*big.Int, error) {
+	if amount.Sign() == 0 {
+		return new(big.Int).Set(sqrtPrice), nil
+	}
+
+	product := Mul(amount, sqrtPrice)
+	denominator, err := Sub(liquidity, product)
+	if err != nil || denominator.Sign() == 0 {
+		return nil, ErrNotEnoughLiquidity
+	}
+
+	return MulDiv(liquidity, sqrtPrice, denominator, common.Up)
+}
+
+// gets the next sqrt price after removing an amount of quote token, rounded down
+// Formula: √P' = √P - Δy / L
+func GetNextSqrtPriceFromOutputQuoteRoundingDown(sqrtPrice, liquidity, amount *big.Int) (*big.Int, error) {
+	quotient, err := MulDiv(amount, new(big.Int).Lsh(big.NewInt(1), uint(common.Resolution*2)), liquidity, common.Up)
+	if err != nil {
+		return nil, err
+	}
+
+	nextSqrtPrice, err := Sub(sqrtPrice, quotient)
+	if err != nil {
+		return nil, ErrNotEnoughLiquidity
+	}
+	return nextSqrtPrice, nil
+}
+
+// u128 variant of GetDeltaAmountQuoteUnsigned that avoids big.Int allocations
+// Formula: Δb = L (√P_upper - √P_lower)
+func GetDeltaAmountQuoteUnsignedU128(
+	lowerSqrtPrice uint128.Uint128,
+	upperSqrtPrice uint128.Uint128,
+	liquidity uint128.Uint128,
+	round common.Rounding,
+) (uint128.Uint128, error) {
+	if liquidity.IsZero() {
+		return uint128.Zero, nil
+	}
+
+	// delta sqrt price: (√P_upper - √P_lower)
+	if lowerSqrtPrice.Cmp(upperSqrtPrice) > 0 {
+		return uint128.Zero, errSubOverflow
+	}
+	deltaSqrtPrice := upperSqrtPrice.Sub(lowerSqrtPrice)
+
+	// L * (√P_upper - √P_lower) >> 128
+	return MulShrU128(liquidity, deltaSqrtPrice, common.Resolution*2, round)
+}
+
 func GetQuoteReserveFromNextSqrtPrice(nextSqrtPrice *big.Int, config *common.PoolConfig) (*big.Int, error) {
 	totalAmount := big.NewInt(0)
 
 	for i := 0; i < common.MaxCurvePoint; i++ {
 		var lowerSqrtPrice *big.Int
 		if i == 0 {
-			lowerSqrtPrice = u128ToBig(config.SqrtStartPrice)
+			lowerSqrtPrice = U128ToBig(config.SqrtStartPrice)
 		} else {
-			lowerSqrtPrice = u128ToBig(config.Curve[i-1].SqrtPrice)
+			lowerSqrtPrice = U128ToBig(config.Curve[i-1].SqrtPrice)
 		}
 
 		if nextSqrtPrice.Cmp(lowerSqrtPrice) > 0 {
-			curveUpperSqrtPrice := u128ToBig(config.Curve[i].SqrtPrice)
+			curveUpperSqrtPrice := U128ToBig(config.Curve[i].SqrtPrice)
 
 			var upperSqrtPrice *big.Int
 			if nextSqrtPrice.Cmp(curveUpperSqrtPrice) < 0 {
@@ -63,7 +202,7 @@ func GetQuoteReserveFromNextSqrtPrice(nextSqrtPrice *big.Int, config *common.Poo
 				upperSqrtPrice = curveUpperSqrtPrice
 			}
 
-			liquidity := u128ToBig(config.Curve[i].Liquidity)
+			liquidity := U128ToBig(config.Curve[i].Liquidity)
 
 			maxAmountIn, err := GetDeltaAmountQuoteUnsigned(
 				lowerSqrtPrice,
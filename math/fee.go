@@ -0,0 +1,212 @@
+package math
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+)
+
+var (
+	errUnsupportedFeeSchedulerMode = errors.New("unsupported fee scheduler mode")
+	errUnsupportedCollectFeeMode   = errors.New("unsupported collect fee mode")
+)
+
+// gets the base fee numerator at the current point from the fee scheduler
+func GetCurrentBaseFeeNumerator(baseFee *common.BaseFeeConfig, currentPoint, activationPoint uint64) (*big.Int, error) {
+	cliffFeeNumerator := new(big.Int).SetUint64(baseFee.CliffFeeNumerator)
+	if baseFee.PeriodFrequency == 0 {
+		return cliffFeeNumerator, nil
+	}
+
+	// trading before the activation point uses the minimum fee
+	period := uint64(baseFee.NumberOfPeriod)
+	if currentPoint >= activationPoint {
+		period = (currentPoint - activationPoint) / baseFee.PeriodFrequency
+		if period > uint64(baseFee.NumberOfPeriod) {
+			period = uint64(baseFee.NumberOfPeriod)
+		}
+	}
+
+	reductionFactor := new(big.Int).SetUint64(baseFee.ReductionFactor)
+
+	switch baseFee.FeeSchedulerMode {
+	case common.FeeSchedulerModeLinear:
+		// Formula: cliff - period * reductionFactor
+		return Sub(cliffFeeNumerator, Mul(reductionFactor, new(big.Int).SetUint64(period)))
+	case common.FeeSchedulerModeExponential:
+		return GetFeeInPeriod(cliffFeeNumerator, reductionFactor, period)
+	case common.FeeSchedulerModeRateLimiter:
+		// the rate limiter depends on the input amount, see GetBaseFeeNumeratorFromInput
+		return cliffFeeNumerator, nil
+	default:
+		return nil, errUnsupportedFeeSchedulerMode
+	}
+}
+
+// gets the exponentially decayed fee numerator after the given number of periods
+// Formula: cliff * (1 - reductionFactor / BASIS_POINT_MAX)^period
+func GetFeeInPeriod(cliffFeeNumerator, reductionFactor *big.Int, period uint64) (*big.Int, error) {
+	one := Shl(big.NewInt(1), uint(common.Resolution))
+
+	// reduction factor in Q64.64
+	bps, err := Div(Shl(reductionFactor, uint(common.Resolution)), big.NewInt(common.BasisPointMax))
+	if err != nil {
+		return nil, err
+	}
+	base, err := Sub(one, bps)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := Pow(base, new(big.Int).SetUint64(period), true)
+	if err != nil {
+		return nil, err
+	}
+
+	return Shr(Mul(result, cliffFeeNumerator), uint(common.Resolution)), nil
+}
+
+// gets the variable fee numerator from the volatility accumulator
+// Formula: ceil((volatilityAccumulator * binStep)^2 * variableFeeControl / 1e11)
+func GetVariableFee(dynamicFee *common.DynamicFeeConfig, volatilityAccumulator *big.Int) (*big.Int, error) {
+	if dynamicFee.Initialized == 0 {
+		return big.NewInt(0), nil
+	}
+
+	volatilityBin := Mul(volatilityAccumulator, big.NewInt(int64(dynamicFee.BinStep)))
+	squareVolatilityBin := Mul(volatilityBin, volatilityBin)
+	if squareVolatilityBin.BitLen() > 128 {
+		return nil, errors.New("SafeMath: multiplication overflow")
+	}
+	variableFee := Mul(squareVolatilityBin, big.NewInt(int64(dynamicFee.VariableFeeControl)))
+	if variableFee.BitLen() > 128 {
+		return nil, errors.New("SafeMath: multiplication overflow")
+	}
+
+	return MulDiv(variableFee, big.NewInt(1), big.NewInt(common.DynamicFeeScalingFactor), common.Up)
+}
+
+// gets the total trading fee numerator, capped at MAX_FEE_NUMERATOR
+func GetTotalTradingFeeNumerator(
+	poolFees *common.PoolFeesConfig,
+	volatilityTracker *common.VolatilityTracker,
+	currentPoint uint64,
+	activationPoint uint64,
+) (*big.Int, error) {
+	baseFeeNumerator, err := GetCurrentBaseFeeNumerator(&poolFees.BaseFee, currentPoint, activationPoint)
+	if err != nil {
+		return nil, err
+	}
+	return addVariableFee(poolFees, volatilityTracker, baseFeeNumerator)
+}
+
+// adds the variable fee to the base fee numerator, capped at MAX_FEE_NUMERATOR
+func addVariableFee(
+	poolFees *common.PoolFeesConfig,
+	volatilityTracker *common.VolatilityTracker,
+	baseFeeNumerator *big.Int,
+) (*big.Int, error) {
+	variableFee, err := GetVariableFee(&poolFees.DynamicFee, U128ToBig(volatilityTracker.VolatilityAccumulator))
+	if err != nil {
+		return nil, err
+	}
+
+	totalFeeNumerator := Add(baseFeeNumerator, variableFee)
+	maxFeeNumerator := big.NewInt(common.MaxFeeNumerator)
+	if totalFeeNumerator.Cmp(maxFeeNumerator) > 0 {
+		return maxFeeNumerator, nil
+	}
+	return totalFeeNumerator, nil
+}
+
+// splits the trading fee off an amount into trading, protocol and referral fees
+func GetFeeOnAmount(
+	poolFees *common.PoolFeesConfig,
+	volatilityTracker *common.VolatilityTracker,
+	amount uint64,
+	hasReferral bool,
+	currentPoint uint64,
+	activationPoint uint64,
+) (*common.FeeOnAmountResult, error) {
+	tradeFeeNumerator, err := GetTotalTradingFeeNumerator(poolFees, volatilityTracker, currentPoint, activationPoint)
+	if err != nil {
+		return nil, err
+	}
+	return splitFeeOnAmount(poolFees, amount, tradeFeeNumerator, hasReferral)
+}
+
+// splits the fee at the given trading fee numerator off an amount
+func splitFeeOnAmount(
+	poolFees *common.PoolFeesConfig,
+	amount uint64,
+	tradeFeeNumerator *big.Int,
+	hasReferral bool,
+) (*common.FeeOnAmountResult, error) {
+	amountBig := new(big.Int).SetUint64(amount)
+	tradingFee, err := MulDiv(amountBig, tradeFeeNumerator, big.NewInt(common.FeeDenominator), common.Up)
+	if err != nil {
+		return nil, err
+	}
+	amountAfterFee, err := Sub(amountBig, tradingFee)
+	if err != nil {
+		return nil, err
+	}
+
+	protocolFee, err := MulDiv(tradingFee, big.NewInt(int64(poolFees.ProtocolFeePercent)), big.NewInt(100), common.Down)
+	if err != nil {
+		return nil, err
+	}
+	tradingFee, err = Sub(tradingFee, protocolFee)
+	if err != nil {
+		return nil, err
+	}
+
+	referralFee := big.NewInt(0)
+	if hasReferral {
+		referralFee, err = GetReferralFee(poolFees, protocolFee)
+		if err != nil {
+			return nil, err
+		}
+	}
+	protocolFee, err = Sub(protocolFee, referralFee)
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.FeeOnAmountResult{
+		Amount:      amountAfterFee.Uint64(),
+		TradingFee:  tradingFee.Uint64(),
+		ProtocolFee: protocolFee.Uint64(),
+		ReferralFee: referralFee.Uint64(),
+	}, nil
+}
+
+// gets the share of the protocol fee paid to the referral account
+func GetReferralFee(poolFees *common.PoolFeesConfig, protocolFee *big.Int) (*big.Int, error) {
+	return MulDiv(protocolFee, big.NewInt(int64(poolFees.ReferralFeePercent)), big.NewInt(100), common.Down)
+}
+
+// gets which side of the swap the fee is charged on
+func GetFeeMode(collectFeeMode uint8, tradeDirection common.TradeDirection, hasReferral bool) (*common.FeeMode, error) {
+	feeMode, err := getFeeMode(collectFeeMode, tradeDirection, hasReferral)
+	if err != nil {
+		return nil, err
+	}
+	return &feeMode, nil
+}
+
+func getFeeMode(collectFeeMode uint8, tradeDirection common.TradeDirection, hasReferral bool) (common.FeeMode, error) {
+	feeMode := common.FeeMode{HasReferral: hasReferral}
+
+	switch collectFeeMode {
+	case common.CollectFeeModeQuoteToken:
+		feeMode.FeesOnInput = tradeDirection == common.QuoteToBase
+	case common.CollectFeeModeOutputToken:
+		feeMode.FeesOnBaseToken = tradeDirection == common.QuoteToBase
+	default:
+		return common.FeeMode{}, errUnsupportedCollectFeeMode
+	}
+
+	return feeMode, nil
+}
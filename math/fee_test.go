@@ -0,0 +1,66 @@
+package math_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/math"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+)
+
+func TestGetReferralFee(t *testing.T) {
+	tests := []struct {
+		name        string
+		percent     uint8
+		protocolFee uint64
+		want        uint64
+	}{
+		{name: "no protocol fee", percent: 20, protocolFee: 0, want: 0},
+		{name: "rounded down", percent: 20, protocolFee: 999, want: 199},
+		{name: "no referral share", percent: 0, protocolFee: 1_000_000, want: 0},
+		{name: "whole protocol fee", percent: 100, protocolFee: 1_000_000, want: 1_000_000},
+		{name: "u64 max", percent: 50, protocolFee: ^uint64(0), want: ^uint64(0) / 2},
+	}
+	for _, tt := range tests {
+		fees := &common.PoolFeesConfig{ReferralFeePercent: tt.percent}
+		got, err := math.GetReferralFee(fees, new(big.Int).SetUint64(tt.protocolFee))
+		if err != nil || !got.IsUint64() || got.Uint64() != tt.want {
+			t.Errorf("%s: got %v (%v), want %d", tt.name, got, err, tt.want)
+		}
+		gotU64, err := math.GetReferralFeeU64(fees, tt.protocolFee)
+		if err != nil || gotU64 != tt.want {
+			t.Errorf("%s: u64 variant got %d (%v), want %d", tt.name, gotU64, err, tt.want)
+		}
+	}
+}
+
+func TestGetFeeOnAmountReferral(t *testing.T) {
+	// 1% fee of which 20% goes to the protocol, and 20% of that to the referral
+	fees := &rpctest.SamplePoolConfig().PoolFees
+	tests := []struct {
+		name        string
+		hasReferral bool
+		want        common.FeeOnAmountResult
+	}{
+		{
+			name: "without referral",
+			want: common.FeeOnAmountResult{Amount: 990_000_000, TradingFee: 8_000_000, ProtocolFee: 2_000_000},
+		},
+		{
+			name:        "with referral",
+			hasReferral: true,
+			want:        common.FeeOnAmountResult{Amount: 990_000_000, TradingFee: 8_000_000, ProtocolFee: 1_600_000, ReferralFee: 400_000},
+		},
+	}
+	for _, tt := range tests {
+		got, err := math.GetFeeOnAmount(fees, &common.VolatilityTracker{}, 1_000_000_000, tt.hasReferral, 0, 0)
+		if err != nil || *got != tt.want {
+			t.Errorf("%s: got %+v (%v), want %+v", tt.name, got, err, tt.want)
+		}
+		gotU64, err := math.GetFeeOnAmountU64(fees, &common.VolatilityTracker{}, 1_000_000_000, tt.hasReferral, 0, 0)
+		if err != nil || gotU64 != tt.want {
+			t.Errorf("%s: u64 variant got %+v (%v), want %+v", tt.name, gotU64, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package math
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+)
+
+var errNoMigrationSqrtPrice = errors.New("config has no migration sqrt price")
+
+// Gets the maximum quote amount, fees included, a buy can still spend before the
+// curve completes at the migration sqrt price
+func GetMaxBuyAmountIn(
+	pool *common.Pool,
+	config *common.PoolConfig,
+	hasReferral bool,
+	currentPoint uint64,
+) (uint64, error) {
+	if config.MigrationSqrtPrice.IsZero() {
+		return 0, errNoMigrationSqrtPrice
+	}
+	if IsRateLimiterApplied(&config.PoolFees.BaseFee, currentPoint, pool.ActivationPoint, common.QuoteToBase) {
+		return 0, ErrRateLimiterExactInOnly
+	}
+
+	amount, err := GetQuoteAmountToSqrtPrice(pool, config, U128ToBig(config.MigrationSqrtPrice))
+	if err != nil {
+		return 0, err
+	}
+	maxAmountIn, err := BigToU64(amount)
+	if err != nil {
+		return 0, err
+	}
+
+	feeMode, err := GetFeeMode(config.CollectFeeMode, common.QuoteToBase, hasReferral)
+	if err != nil {
+		return 0, err
+	}
+	if !feeMode.FeesOnInput || maxAmountIn == 0 {
+		return maxAmountIn, nil
+	}
+	return getIncludedFeeAmount(pool, config, maxAmountIn, currentPoint)
+}
+
+// gets the quote amount swapped on the curve to move the pool up to the target sqrt price
+// Formula: Δb = Σ L_i (√P_upper - √P_lower) over the segments between both prices
+func GetQuoteAmountToSqrtPrice(pool *common.Pool, config *common.PoolConfig, targetSqrtPrice *big.Int) (*big.Int, error) {
+	totalAmount := big.NewInt(0)
+	sqrtPrice := U128ToBig(pool.SqrtPrice)
+
+	for i := 0; i < common.MaxCurvePoint; i++ {
+		if sqrtPrice.Cmp(targetSqrtPrice) >= 0 {
+			break
+		}
+		if config.Curve[i].SqrtPrice.IsZero() || config.Curve[i].Liquidity.IsZero() {
+			break
+		}
+
+		upperSqrtPrice := U128ToBig(config.Curve[i].SqrtPrice)
+		if upperSqrtPrice.Cmp(targetSqrtPrice) > 0 {
+			upperSqrtPrice = targetSqrtPrice
+		}
+		if upperSqrtPrice.Cmp(sqrtPrice) <= 0 {
+			continue
+		}
+
+		amount, err := GetDeltaAmountQuoteUnsigned(sqrtPrice, upperSqrtPrice, U128ToBig(config.Curve[i].Liquidity), common.Up)
+		if err != nil {
+			return nil, err
+		}
+		totalAmount = Add(totalAmount, amount)
+		sqrtPrice = upperSqrtPrice
+	}
+
+	return totalAmount, nil
+}
+
+// Quotes a buy capped at the migration threshold: the part of amountIn the curve can
+// still absorb is filled and the rest refunded, like a partial fill swap2 on-chain
+func QuoteCappedBuy(
+	pool *common.Pool,
+	config *common.PoolConfig,
+	amountIn uint64,
+	hasReferral bool,
+	currentPoint uint64,
+) (*common.CappedSwapResult, error) {
+	maxAmountIn, err := GetMaxBuyAmountIn(pool, config, hasReferral, currentPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	feeMode, err := GetFeeMode(config.CollectFeeMode, common.QuoteToBase, hasReferral)
+	if err != nil {
+		return nil, err
+	}
+	swap, err := GetSwapResultPartialFill(pool, config, amountIn, feeMode, common.QuoteToBase, currentPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.CappedSwapResult{
+		SwapResult:     *swap,
+		MaxAmountIn:    maxAmountIn,
+		RefundAmount:   amountIn - swap.InputAmount,
+		CompletesCurve: swap.NextSqrtPrice.Cmp(config.MigrationSqrtPrice) >= 0,
+	}, nil
+}
@@ -0,0 +1,151 @@
+package math_test
+
+import (
+	"errors"
+	"testing"
+
+	"lukechampine.com/uint128"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/math"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+)
+
+// whether got is within tolerance of want
+func near(got, want, tolerance uint64) bool {
+	if got > want {
+		return got-want <= tolerance
+	}
+	return want-got <= tolerance
+}
+
+func TestGetQuoteAmountToSqrtPrice(t *testing.T) {
+	config := rpctest.SamplePoolConfig()
+	tests := []struct {
+		name    string
+		quoteIn uint64
+		target  uint128.Uint128
+		want    uint64
+	}{
+		{name: "first segment", target: rpctest.SampleSqrtMiddlePrice, want: 30_000_000_000},
+		{name: "whole curve", target: rpctest.SampleSqrtMigrationPrice, want: rpctest.SampleMigrationQuoteThreshold},
+		{name: "target at the price", target: rpctest.SampleSqrtStartPrice, want: 0},
+		// the 40 SOL buy puts 39.6 SOL on the curve after the 1% fee
+		{name: "from the second segment", quoteIn: 40_000_000_000, target: rpctest.SampleSqrtMigrationPrice, want: 45_400_000_000},
+		{name: "target below the price", quoteIn: 40_000_000_000, target: rpctest.SampleSqrtMiddlePrice, want: 0},
+	}
+	for _, tt := range tests {
+		pool := tradedSamplePool(t, config, tt.quoteIn)
+		got, err := math.GetQuoteAmountToSqrtPrice(pool, config, math.U128ToBig(tt.target))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		// the sample liquidities are rounded, a few lamports off the round amounts
+		if !got.IsUint64() || !near(got.Uint64(), tt.want, 10) {
+			t.Errorf("%s: got %s, want about %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetMaxBuyAmountIn(t *testing.T) {
+	tests := []struct {
+		name    string
+		quoteIn uint64
+		modify  func(config *common.PoolConfig)
+		want    uint64
+		wantErr bool
+		target  error
+	}{
+		// 85 SOL on the curve plus the 1% fee on input
+		{name: "fresh pool", want: 85_858_585_859},
+		{name: "fees on output", modify: func(c *common.PoolConfig) { c.CollectFeeMode = common.CollectFeeModeOutputToken }, want: 85_000_000_000},
+		{name: "traded pool", quoteIn: 40_000_000_000, want: 45_858_585_859},
+		{name: "no migration price", modify: func(c *common.PoolConfig) { c.MigrationSqrtPrice = uint128.Zero }, wantErr: true},
+		{
+			name: "rate limiter",
+			modify: func(c *common.PoolConfig) {
+				c.PoolFees.BaseFee = common.BaseFeeConfig{
+					CliffFeeNumerator: 10_000_000,
+					FeeSchedulerMode:  common.FeeSchedulerModeRateLimiter,
+					PeriodFrequency:   100,
+					NumberOfPeriod:    10,
+					ReductionFactor:   1_000_000_000,
+				}
+			},
+			wantErr: true,
+			target:  math.ErrRateLimiterExactInOnly,
+		},
+	}
+	for _, tt := range tests {
+		config := rpctest.SamplePoolConfig()
+		pool := tradedSamplePool(t, config, tt.quoteIn)
+		if tt.modify != nil {
+			tt.modify(config)
+		}
+
+		got, err := math.GetMaxBuyAmountIn(pool, config, false, 0)
+		if tt.wantErr {
+			if err == nil || (tt.target != nil && !errors.Is(err, tt.target)) {
+				t.Errorf("%s: got %d, %v, want %v", tt.name, got, err, tt.target)
+			}
+			continue
+		}
+		if err != nil || !near(got, tt.want, 20) {
+			t.Errorf("%s: got %d, %v, want about %d", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestQuoteCappedBuy(t *testing.T) {
+	config := rpctest.SamplePoolConfig()
+	pool := tradedSamplePool(t, config, 40_000_000_000)
+	maxAmountIn, err := math.GetMaxBuyAmountIn(pool, config, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		amountIn uint64
+		referral bool
+		// refund expected, within a lamport of rounding
+		wantRefund uint64
+		completes  bool
+	}{
+		{name: "small buy", amountIn: 1_000_000_000},
+		{name: "just below the maximum", amountIn: maxAmountIn - 1_000},
+		{name: "exactly the maximum", amountIn: maxAmountIn, completes: true},
+		{name: "over the maximum", amountIn: maxAmountIn + 5_000_000_000, wantRefund: 5_000_000_000, completes: true},
+		{name: "over the maximum with referral", amountIn: maxAmountIn + 1, referral: true, wantRefund: 1, completes: true},
+		{name: "far over the maximum", amountIn: 1_000_000_000_000, wantRefund: 1_000_000_000_000 - maxAmountIn, completes: true},
+	}
+	for _, tt := range tests {
+		capped, err := math.QuoteCappedBuy(pool, config, tt.amountIn, tt.referral, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if capped.MaxAmountIn != maxAmountIn {
+			t.Errorf("%s: got max amount in %d, want %d", tt.name, capped.MaxAmountIn, maxAmountIn)
+		}
+		if !near(capped.RefundAmount, tt.wantRefund, 1) || capped.CompletesCurve != tt.completes {
+			t.Errorf("%s: got refund %d, completes %v, want %d and %v", tt.name, capped.RefundAmount, capped.CompletesCurve, tt.wantRefund, tt.completes)
+		}
+		if capped.InputAmount+capped.RefundAmount != tt.amountIn {
+			t.Errorf("%s: consumed %d and refunded %d of %d", tt.name, capped.InputAmount, capped.RefundAmount, tt.amountIn)
+		}
+		if tt.completes && capped.NextSqrtPrice != config.MigrationSqrtPrice {
+			t.Errorf("%s: ended at %s, want the migration price", tt.name, capped.NextSqrtPrice)
+		}
+
+		// below the cap the quote is a plain exact in buy
+		if !tt.completes {
+			exactIn, err := math.SwapQuote(pool, config, common.QuoteToBase, tt.amountIn, tt.referral, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if capped.SwapResult != *exactIn {
+				t.Errorf("%s: got %+v, want %+v", tt.name, capped.SwapResult, exactIn)
+			}
+		}
+	}
+}
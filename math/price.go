@@ -0,0 +1,139 @@
+package math
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+)
+
+// default precision (in bits) used for big.Float prices
+const DefaultPricePrecision uint = 128
+
+// gets the decimal adjustment 10^(baseDecimal - quoteDecimal) as an exact ratio
+func decimalAdjustment(baseDecimal, quoteDecimal uint8) *big.Rat {
+	ten := big.NewInt(10)
+	if baseDecimal >= quoteDecimal {
+		exp := new(big.Int).Exp(ten, big.NewInt(int64(baseDecimal-quoteDecimal)), nil)
+		return new(big.Rat).SetInt(exp)
+	}
+	exp := new(big.Int).Exp(ten, big.NewInt(int64(quoteDecimal-baseDecimal)), nil)
+	return new(big.Rat).SetFrac(big.NewInt(1), exp)
+}
+
+// converts a Q64.64 sqrt price to the exact human price (quote per base)
+// Formula: P = (√P / 2^64)^2 * 10^(baseDecimal - quoteDecimal)
+func SqrtPriceToPriceRat(sqrtPrice *big.Int, baseDecimal, quoteDecimal uint8) *big.Rat {
+	numerator := Mul(sqrtPrice, sqrtPrice)
+	denominator := Shl(big.NewInt(1), uint(common.Resolution*2))
+
+	price := new(big.Rat).SetFrac(numerator, denominator)
+	return price.Mul(price, decimalAdjustment(baseDecimal, quoteDecimal))
+}
+
+// converts a Q64.64 sqrt price to the human price (quote per base) with the given precision in bits
+func SqrtPriceToPrice(sqrtPrice *big.Int, baseDecimal, quoteDecimal uint8, prec uint) *big.Float {
+	price := SqrtPriceToPriceRat(sqrtPrice, baseDecimal, quoteDecimal)
+	return new(big.Float).SetPrec(prec).SetRat(price)
+}
+
+// converts an exact human price (quote per base) to a Q64.64 sqrt price, rounded down
+// Formula: √P = floor(sqrt(P * 10^(quoteDecimal - baseDecimal) * 2^128))
+func PriceRatToSqrtPrice(price *big.Rat, baseDecimal, quoteDecimal uint8) (*big.Int, error) {
+	if price.Sign() < 0 {
+		return nil, errors.New("price must not be negative")
+	}
+
+	// raw price in lamport units: P / 10^(baseDecimal - quoteDecimal)
+	rawPrice := new(big.Rat).Quo(price, decimalAdjustment(baseDecimal, quoteDecimal))
+
+	// floor(sqrt(x)) == isqrt(floor(x)) for x >= 0
+	scaled := Mul(rawPrice.Num(), Shl(big.NewInt(1), uint(common.Resolution*2)))
+	floor, err := Div(scaled, rawPrice.Denom())
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).Sqrt(floor), nil
+}
+
+// converts a human price (quote per base) to a Q64.64 sqrt price, rounded down
+func PriceToSqrtPrice(price *big.Float, baseDecimal, quoteDecimal uint8) (*big.Int, error) {
+	if price.IsInf() {
+		return nil, errors.New("price must be finite")
+	}
+	priceRat, _ := price.Rat(nil)
+	return PriceRatToSqrtPrice(priceRat, baseDecimal, quoteDecimal)
+}
+
+// gets the total base token supply of a pool config, in raw units
+// returns false when the supply is not fixed by the config and must be read from the mint
+func GetTotalSupply(config *common.PoolConfig) (uint64, bool) {
+	if config.FixedTokenSupplyFlag == 0 {
+		return 0, false
+	}
+	return config.PostMigrationTokenSupply, true
+}
+
+// gets the current price of the pool (quote per base) as an exact ratio
+func GetPoolPriceRat(pool *common.Pool, config *common.PoolConfig, quoteDecimal uint8) *big.Rat {
+	return SqrtPriceToPriceRat(U128ToBig(pool.SqrtPrice), config.TokenDecimal, quoteDecimal)
+}
+
+// gets the current price of the pool (quote per base) with the given precision in bits
+func GetPoolPrice(pool *common.Pool, config *common.PoolConfig, quoteDecimal uint8, prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec).SetRat(GetPoolPriceRat(pool, config, quoteDecimal))
+}
+
+// gets the fully diluted valuation of the pool in quote tokens
+// Formula: FDV = P * totalSupply / 10^baseDecimal
+func GetFdvRat(pool *common.Pool, config *common.PoolConfig, totalSupply uint64, quoteDecimal uint8) *big.Rat {
+	price := GetPoolPriceRat(pool, config, quoteDecimal)
+	return price.Mul(price, tokenAmountRat(totalSupply, config.TokenDecimal))
+}
+
+// gets the fully diluted valuation of the pool in quote tokens with the given precision in bits
+func GetFdv(pool *common.Pool, config *common.PoolConfig, totalSupply uint64, quoteDecimal uint8, prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec).SetRat(GetFdvRat(pool, config, totalSupply, quoteDecimal))
+}
+
+// gets the market cap of the pool in quote tokens
+// the circulating supply is every base token outside the curve reserve: locked vesting,
+// the leftover and the migration base are counted as circulating. For another definition
+// of the circulating supply, price it with GetFdvRat instead
+// Formula: MC = P * (totalSupply - baseReserve) / 10^baseDecimal
+func GetMarketCapRat(pool *common.Pool, config *common.PoolConfig, totalSupply uint64, quoteDecimal uint8) (*big.Rat, error) {
+	circulating, err := Sub(new(big.Int).SetUint64(totalSupply), new(big.Int).SetUint64(pool.BaseReserve))
+	if err != nil {
+		return nil, err
+	}
+	price := GetPoolPriceRat(pool, config, quoteDecimal)
+	return price.Mul(price, tokenAmountRat(circulating.Uint64(), config.TokenDecimal)), nil
+}
+
+// gets the market cap of the pool in quote tokens with the given precision in bits, see
+// GetMarketCapRat for the circulating supply it assumes
+func GetMarketCap(pool *common.Pool, config *common.PoolConfig, totalSupply uint64, quoteDecimal uint8, prec uint) (*big.Float, error) {
+	marketCap, err := GetMarketCapRat(pool, config, totalSupply, quoteDecimal)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Float).SetPrec(prec).SetRat(marketCap), nil
+}
+
+// converts a raw token amount to a decimal amount
+func tokenAmountRat(amount uint64, decimal uint8) *big.Rat {
+	exp := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimal)), nil)
+	return new(big.Rat).SetFrac(new(big.Int).SetUint64(amount), exp)
+}
+
+// Gets the share (0 to 1) of the migration quote threshold the pool has raised
+func GetCurveProgress(pool *common.Pool, config *common.PoolConfig) float64 {
+	if config.MigrationQuoteThreshold == 0 {
+		return 0
+	}
+	progress := float64(pool.QuoteReserve) / float64(config.MigrationQuoteThreshold)
+	if progress > 1 {
+		return 1
+	}
+	return progress
+}
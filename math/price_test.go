@@ -0,0 +1,162 @@
+package math_test
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"lukechampine.com/uint128"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/math"
+)
+
+// 2^64, a raw price of one quote unit per base unit
+var sqrtPriceOne = new(big.Int).Lsh(big.NewInt(1), 64)
+
+func TestSqrtPriceToPriceRat(t *testing.T) {
+	tests := []struct {
+		name         string
+		sqrtPrice    *big.Int
+		baseDecimal  uint8
+		quoteDecimal uint8
+		want         *big.Rat
+	}{
+		{name: "same decimals", sqrtPrice: sqrtPriceOne, baseDecimal: 9, quoteDecimal: 9, want: big.NewRat(1, 1)},
+		{name: "6 decimal token against SOL", sqrtPrice: sqrtPriceOne, baseDecimal: 6, quoteDecimal: 9, want: big.NewRat(1, 1_000)},
+		{name: "9 decimal token against USDC", sqrtPrice: sqrtPriceOne, baseDecimal: 9, quoteDecimal: 6, want: big.NewRat(1_000, 1)},
+		{name: "double sqrt price", sqrtPrice: new(big.Int).Lsh(sqrtPriceOne, 1), baseDecimal: 6, quoteDecimal: 9, want: big.NewRat(4, 1_000)},
+		{name: "half sqrt price", sqrtPrice: new(big.Int).Rsh(sqrtPriceOne, 1), baseDecimal: 9, quoteDecimal: 9, want: big.NewRat(1, 4)},
+		{name: "zero", sqrtPrice: big.NewInt(0), baseDecimal: 6, quoteDecimal: 9, want: new(big.Rat)},
+	}
+	for _, tt := range tests {
+		got := math.SqrtPriceToPriceRat(tt.sqrtPrice, tt.baseDecimal, tt.quoteDecimal)
+		if got.Cmp(tt.want) != 0 {
+			t.Errorf("%s: got %s, want %s", tt.name, got.RatString(), tt.want.RatString())
+		}
+	}
+}
+
+func TestPriceRatToSqrtPrice(t *testing.T) {
+	sqrtTwo := new(big.Int).Sqrt(new(big.Int).Lsh(big.NewInt(2), 128))
+	tests := []struct {
+		name         string
+		price        *big.Rat
+		baseDecimal  uint8
+		quoteDecimal uint8
+		want         *big.Int
+	}{
+		{name: "one", price: big.NewRat(1, 1), baseDecimal: 9, quoteDecimal: 9, want: sqrtPriceOne},
+		{name: "6 decimal token against SOL", price: big.NewRat(1, 1_000), baseDecimal: 6, quoteDecimal: 9, want: sqrtPriceOne},
+		{name: "9 decimal token against USDC", price: big.NewRat(1_000, 1), baseDecimal: 9, quoteDecimal: 6, want: sqrtPriceOne},
+		{name: "rounded down", price: big.NewRat(2, 1), baseDecimal: 9, quoteDecimal: 9, want: sqrtTwo},
+		{name: "zero", price: new(big.Rat), baseDecimal: 6, quoteDecimal: 9, want: big.NewInt(0)},
+		{name: "negative", price: big.NewRat(-1, 1), baseDecimal: 6, quoteDecimal: 9},
+	}
+	for _, tt := range tests {
+		got, err := math.PriceRatToSqrtPrice(tt.price, tt.baseDecimal, tt.quoteDecimal)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got.Cmp(tt.want) != 0 {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPriceToSqrtPrice(t *testing.T) {
+	// 0.25 is exact in binary
+	got, err := math.PriceToSqrtPrice(big.NewFloat(0.25), 9, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := new(big.Int).Rsh(sqrtPriceOne, 1); got.Cmp(want) != 0 {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// 0.001 is not, so a 256-bit float lands within a unit of 2^64
+	price := new(big.Float).SetPrec(256).SetRat(big.NewRat(1, 1_000))
+	got, err = math.PriceToSqrtPrice(price, 6, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := new(big.Int).Sub(got, sqrtPriceOne); diff.CmpAbs(big.NewInt(1)) > 0 {
+		t.Errorf("got %s, want %s", got, sqrtPriceOne)
+	}
+
+	if _, err := math.PriceToSqrtPrice(new(big.Float).SetInf(false), 6, 9); err == nil {
+		t.Error("expected an error for an infinite price")
+	}
+}
+
+func TestSqrtPriceRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	decimals := []uint8{0, 6, 9}
+	for i := 0; i < 1_000; i++ {
+		sqrtPrice := new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), 128))
+		baseDecimal := decimals[rng.Intn(len(decimals))]
+		quoteDecimal := decimals[rng.Intn(len(decimals))]
+
+		price := math.SqrtPriceToPriceRat(sqrtPrice, baseDecimal, quoteDecimal)
+		got, err := math.PriceRatToSqrtPrice(price, baseDecimal, quoteDecimal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Cmp(sqrtPrice) != 0 {
+			t.Fatalf("sqrt price %s with decimals %d/%d: got %s back", sqrtPrice, baseDecimal, quoteDecimal, got)
+		}
+	}
+}
+
+func TestGetTotalSupply(t *testing.T) {
+	config := &common.PoolConfig{PostMigrationTokenSupply: 1_000_000_000_000_000}
+	if _, ok := math.GetTotalSupply(config); ok {
+		t.Error("supply reported for a config without a fixed supply")
+	}
+
+	config.FixedTokenSupplyFlag = 1
+	supply, ok := math.GetTotalSupply(config)
+	if !ok || supply != config.PostMigrationTokenSupply {
+		t.Errorf("got %d, %v, want %d", supply, ok, config.PostMigrationTokenSupply)
+	}
+}
+
+func TestValuation(t *testing.T) {
+	// 0.001 SOL per token, a billion tokens of which 400 million are still in the curve
+	config := &common.PoolConfig{TokenDecimal: 6}
+	pool := &common.Pool{
+		SqrtPrice:   uint128.FromBig(new(big.Int).Set(sqrtPriceOne)),
+		BaseReserve: 400_000_000_000_000,
+	}
+	const totalSupply = 1_000_000_000_000_000
+
+	if got := math.GetPoolPriceRat(pool, config, 9); got.Cmp(big.NewRat(1, 1_000)) != 0 {
+		t.Errorf("price: got %s, want 1/1000", got.RatString())
+	}
+	if got := math.GetFdvRat(pool, config, totalSupply, 9); got.Cmp(big.NewRat(1_000_000, 1)) != 0 {
+		t.Errorf("fdv: got %s, want 1000000", got.RatString())
+	}
+	marketCap, err := math.GetMarketCapRat(pool, config, totalSupply, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if marketCap.Cmp(big.NewRat(600_000, 1)) != 0 {
+		t.Errorf("market cap: got %s, want 600000", marketCap.RatString())
+	}
+	// the market cap counts locked tokens as circulating, pricing the unlocked supply excludes them
+	const locked = 100_000_000_000_000
+	if got := math.GetFdvRat(pool, config, totalSupply-pool.BaseReserve-locked, 9); got.Cmp(big.NewRat(500_000, 1)) != 0 {
+		t.Errorf("unlocked market cap: got %s, want 500000", got.RatString())
+	}
+
+	if _, err := math.GetMarketCapRat(pool, config, pool.BaseReserve-1, 9); err == nil {
+		t.Error("expected an error for a supply below the base reserve")
+	}
+}
@@ -0,0 +1,512 @@
+package math
+
+import (
+	"math/bits"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"lukechampine.com/uint128"
+)
+
+// Fixed-width variants of the swap quote in swap.go, fee.go and curve.go.
+// They mirror the big.Int implementation step by step but work on uint64,
+// uint128 and an internal 256-bit type, so quoting does not touch the heap.
+
+var oneQ64 = uint128.New(0, 1)
+
+// (x * y) / denominator with rounding for u64 values
+func mulDivU64(x, y, denominator uint64, round common.Rounding) (uint64, error) {
+	if denominator == 0 {
+		return 0, errDivisionByZero
+	}
+	hi, lo := bits.Mul64(x, y)
+	if hi >= denominator {
+		return 0, errU64Overflow
+	}
+	quotient, remainder := bits.Div64(hi, lo, denominator)
+	if round == common.Up && remainder != 0 {
+		if quotient == ^uint64(0) {
+			return 0, errU64Overflow
+		}
+		quotient++
+	}
+	return quotient, nil
+}
+
+// u128 variant of GetDeltaAmountBaseUnsigned
+// Formula: Δa = L (√P_upper - √P_lower) / (√P_upper * √P_lower)
+func GetDeltaAmountBaseUnsignedU128(
+	lowerSqrtPrice uint128.Uint128,
+	upperSqrtPrice uint128.Uint128,
+	liquidity uint128.Uint128,
+	round common.Rounding,
+) (uint128.Uint128, error) {
+	result, err := getDeltaAmountBaseUnsigned256(lowerSqrtPrice, upperSqrtPrice, liquidity, round)
+	if err != nil {
+		return uint128.Zero, err
+	}
+	return result.toU128()
+}
+
+func getDeltaAmountBaseUnsigned256(
+	lowerSqrtPrice uint128.Uint128,
+	upperSqrtPrice uint128.Uint128,
+	liquidity uint128.Uint128,
+	round common.Rounding,
+) (u256, error) {
+	if liquidity.IsZero() {
+		return u256{}, nil
+	}
+	if lowerSqrtPrice.Cmp(upperSqrtPrice) > 0 {
+		return u256{}, errSubOverflow
+	}
+	deltaSqrtPrice := upperSqrtPrice.Sub(lowerSqrtPrice)
+	denominator := mul128(upperSqrtPrice, lowerSqrtPrice)
+
+	return mulDiv256(liquidity, deltaSqrtPrice, denominator, round)
+}
+
+// u128 variant of GetNextSqrtPriceFromInput
+func GetNextSqrtPriceFromInputU128(
+	sqrtPrice uint128.Uint128,
+	liquidity uint128.Uint128,
+	amountIn uint64,
+	baseForQuote bool,
+) (uint128.Uint128, error) {
+	if sqrtPrice.IsZero() || liquidity.IsZero() {
+		return uint128.Zero, errZeroSqrtPriceOrLiquidity
+	}
+
+	if baseForQuote {
+		// Formula: √P' = √P * L / (L + Δx * √P)
+		if amountIn == 0 {
+			return sqrtPrice, nil
+		}
+		denominator, _ := u256FromU128(liquidity).add(mul128(uint128.From64(amountIn), sqrtPrice))
+		result, err := mulDiv256(liquidity, sqrtPrice, denominator, common.Up)
+		if err != nil {
+			return uint128.Zero, err
+		}
+		return result.toU128()
+	}
+
+	// Formula: √P' = √P + Δy / L
+	quotient, _ := u256{0, 0, amountIn, 0}.quoRem128(liquidity)
+	result, overflow := u256FromU128(sqrtPrice).add(quotient)
+	if overflow {
+		return uint128.Zero, errU128Overflow
+	}
+	return result.toU128()
+}
+
+// u64 variant of GetCurrentBaseFeeNumerator
+func GetCurrentBaseFeeNumeratorU64(baseFee *common.BaseFeeConfig, currentPoint, activationPoint uint64) (uint64, error) {
+	if baseFee.PeriodFrequency == 0 {
+		return baseFee.CliffFeeNumerator, nil
+	}
+
+	// trading before the activation point uses the minimum fee
+	period := uint64(baseFee.NumberOfPeriod)
+	if currentPoint >= activationPoint {
+		period = (currentPoint - activationPoint) / baseFee.PeriodFrequency
+		if period > uint64(baseFee.NumberOfPeriod) {
+			period = uint64(baseFee.NumberOfPeriod)
+		}
+	}
+
+	switch baseFee.FeeSchedulerMode {
+	case common.FeeSchedulerModeLinear:
+		// Formula: cliff - period * reductionFactor
+		hi, reduction := bits.Mul64(baseFee.ReductionFactor, period)
+		if hi != 0 || reduction > baseFee.CliffFeeNumerator {
+			return 0, errSubOverflow
+		}
+		return baseFee.CliffFeeNumerator - reduction, nil
+	case common.FeeSchedulerModeExponential:
+		return GetFeeInPeriodU64(baseFee.CliffFeeNumerator, baseFee.ReductionFactor, period)
+	case common.FeeSchedulerModeRateLimiter:
+		return baseFee.CliffFeeNumerator, nil
+	default:
+		return 0, errUnsupportedFeeSchedulerMode
+	}
+}
+
+// u64 variant of GetFeeInPeriod
+// Formula: cliff * (1 - reductionFactor / BASIS_POINT_MAX)^period
+func GetFeeInPeriodU64(cliffFeeNumerator, reductionFactor, period uint64) (uint64, error) {
+	bps, err := ShlDivU128(uint128.From64(reductionFactor), uint128.From64(common.BasisPointMax), common.Resolution, common.Down)
+	if err != nil {
+		return 0, err
+	}
+	if bps.Cmp(oneQ64) > 0 {
+		return 0, errSubOverflow
+	}
+	base := oneQ64.Sub(bps)
+
+	result, err := powQ64(base, period)
+	if err != nil {
+		return 0, err
+	}
+
+	fee, err := MulShrU128(result, uint128.From64(cliffFeeNumerator), common.Resolution, common.Down)
+	if err != nil {
+		return 0, err
+	}
+	if fee.Hi != 0 {
+		return 0, errU64Overflow
+	}
+	return fee.Lo, nil
+}
+
+// u128 variant of Pow with scaling for a non-negative exponent
+func powQ64(base uint128.Uint128, exponent uint64) (uint128.Uint128, error) {
+	if exponent == 0 {
+		return oneQ64, nil
+	}
+	if base.IsZero() {
+		return uint128.Zero, nil
+	}
+	if base.Equals(oneQ64) {
+		return oneQ64, nil
+	}
+
+	result := oneQ64
+	currentBase := base
+	var err error
+	for exp := exponent; exp != 0; exp >>= 1 {
+		if exp&1 == 1 {
+			result, err = MulShrU128(result, currentBase, common.Resolution, common.Down)
+			if err != nil {
+				return uint128.Zero, err
+			}
+		}
+		currentBase, err = MulShrU128(currentBase, currentBase, common.Resolution, common.Down)
+		if err != nil {
+			return uint128.Zero, err
+		}
+	}
+	return result, nil
+}
+
+// u128 variant of GetVariableFee
+// Formula: ceil((volatilityAccumulator * binStep)^2 * variableFeeControl / 1e11)
+func GetVariableFeeU128(dynamicFee *common.DynamicFeeConfig, volatilityAccumulator uint128.Uint128) (uint128.Uint128, error) {
+	if dynamicFee.Initialized == 0 {
+		return uint128.Zero, nil
+	}
+
+	volatilityBin, err := mul128(volatilityAccumulator, uint128.From64(uint64(dynamicFee.BinStep))).toU128()
+	if err != nil {
+		return uint128.Zero, err
+	}
+	squareVolatilityBin, err := mul128(volatilityBin, volatilityBin).toU128()
+	if err != nil {
+		return uint128.Zero, err
+	}
+	variableFee, err := mul128(squareVolatilityBin, uint128.From64(uint64(dynamicFee.VariableFeeControl))).toU128()
+	if err != nil {
+		return uint128.Zero, err
+	}
+
+	return MulDivU128(variableFee, uint128.From64(1), uint128.From64(common.DynamicFeeScalingFactor), common.Up)
+}
+
+// u64 variant of GetTotalTradingFeeNumerator
+func GetTotalTradingFeeNumeratorU64(
+	poolFees *common.PoolFeesConfig,
+	volatilityTracker *common.VolatilityTracker,
+	currentPoint uint64,
+	activationPoint uint64,
+) (uint64, error) {
+	baseFeeNumerator, err := GetCurrentBaseFeeNumeratorU64(&poolFees.BaseFee, currentPoint, activationPoint)
+	if err != nil {
+		return 0, err
+	}
+	return addVariableFeeU64(poolFees, volatilityTracker, baseFeeNumerator)
+}
+
+// u64 variant of addVariableFee
+func addVariableFeeU64(
+	poolFees *common.PoolFeesConfig,
+	volatilityTracker *common.VolatilityTracker,
+	baseFeeNumerator uint64,
+) (uint64, error) {
+	variableFee, err := GetVariableFeeU128(&poolFees.DynamicFee, volatilityTracker.VolatilityAccumulator)
+	if err != nil {
+		return 0, err
+	}
+
+	// anything at or above 2^64 is capped anyway, so saturate instead of overflowing
+	totalFeeNumerator := uint64(common.MaxFeeNumerator)
+	if variableFee.Hi == 0 {
+		sum, carry := bits.Add64(baseFeeNumerator, variableFee.Lo, 0)
+		if carry == 0 && sum < totalFeeNumerator {
+			totalFeeNumerator = sum
+		}
+	}
+	return totalFeeNumerator, nil
+}
+
+// fixed-width variant of GetFeeOnAmount
+func GetFeeOnAmountU64(
+	poolFees *common.PoolFeesConfig,
+	volatilityTracker *common.VolatilityTracker,
+	amount uint64,
+	hasReferral bool,
+	currentPoint uint64,
+	activationPoint uint64,
+) (common.FeeOnAmountResult, error) {
+	tradeFeeNumerator, err := GetTotalTradingFeeNumeratorU64(poolFees, volatilityTracker, currentPoint, activationPoint)
+	if err != nil {
+		return common.FeeOnAmountResult{}, err
+	}
+	return splitFeeOnAmountU64(poolFees, amount, tradeFeeNumerator, hasReferral)
+}
+
+// u64 variant of splitFeeOnAmount
+func splitFeeOnAmountU64(
+	poolFees *common.PoolFeesConfig,
+	amount uint64,
+	tradeFeeNumerator uint64,
+	hasReferral bool,
+) (common.FeeOnAmountResult, error) {
+	tradingFee, err := mulDivU64(amount, tradeFeeNumerator, common.FeeDenominator, common.Up)
+	if err != nil {
+		return common.FeeOnAmountResult{}, err
+	}
+	if tradingFee > amount {
+		return common.FeeOnAmountResult{}, errSubOverflow
+	}
+
+	protocolFee, err := mulDivU64(tradingFee, uint64(poolFees.ProtocolFeePercent), 100, common.Down)
+	if err != nil {
+		return common.FeeOnAmountResult{}, err
+	}
+	if protocolFee > tradingFee {
+		return common.FeeOnAmountResult{}, errSubOverflow
+	}
+
+	var referralFee uint64
+	if hasReferral {
+		referralFee, err = GetReferralFeeU64(poolFees, protocolFee)
+		if err != nil {
+			return common.FeeOnAmountResult{}, err
+		}
+		if referralFee > protocolFee {
+			return common.FeeOnAmountResult{}, errSubOverflow
+		}
+	}
+
+	return common.FeeOnAmountResult{
+		Amount:      amount - tradingFee,
+		TradingFee:  tradingFee - protocolFee,
+		ProtocolFee: protocolFee - referralFee,
+		ReferralFee: referralFee,
+	}, nil
+}
+
+// u64 variant of GetReferralFee
+func GetReferralFeeU64(poolFees *common.PoolFeesConfig, protocolFee uint64) (uint64, error) {
+	return mulDivU64(protocolFee, uint64(poolFees.ReferralFeePercent), 100, common.Down)
+}
+
+// fixed-width variant of SwapQuote
+func SwapQuoteU128(
+	pool *common.Pool,
+	config *common.PoolConfig,
+	tradeDirection common.TradeDirection,
+	amountIn uint64,
+	hasReferral bool,
+	currentPoint uint64,
+) (common.SwapResult, error) {
+	feeMode, err := getFeeMode(config.CollectFeeMode, tradeDirection, hasReferral)
+	if err != nil {
+		return common.SwapResult{}, err
+	}
+	return GetSwapResultU128(pool, config, amountIn, feeMode, tradeDirection, currentPoint)
+}
+
+// fixed-width variant of GetSwapResult
+func GetSwapResultU128(
+	pool *common.Pool,
+	config *common.PoolConfig,
+	amountIn uint64,
+	feeMode common.FeeMode,
+	tradeDirection common.TradeDirection,
+	currentPoint uint64,
+) (common.SwapResult, error) {
+	var result common.SwapResult
+
+	actualAmountIn := amountIn
+	if feeMode.FeesOnInput {
+		fee, err := GetFeeOnInputAmountU64(&config.PoolFees, &pool.VolatilityTracker, amountIn, feeMode.HasReferral, tradeDirection, currentPoint, pool.ActivationPoint)
+		if err != nil {
+			return common.SwapResult{}, err
+		}
+		result.TradingFee = fee.TradingFee
+		result.ProtocolFee = fee.ProtocolFee
+		result.ReferralFee = fee.ReferralFee
+		actualAmountIn = fee.Amount
+	}
+
+	var outputAmount uint64
+	var nextSqrtPrice uint128.Uint128
+	var err error
+	if tradeDirection == common.BaseToQuote {
+		outputAmount, nextSqrtPrice, err = GetSwapAmountFromBaseToQuoteU128(pool, config, actualAmountIn)
+	} else {
+		outputAmount, nextSqrtPrice, err = GetSwapAmountFromQuoteToBaseU128(pool, config, actualAmountIn)
+	}
+	if err != nil {
+		return common.SwapResult{}, err
+	}
+
+	if !feeMode.FeesOnInput {
+		fee, err := GetFeeOnAmountU64(&config.PoolFees, &pool.VolatilityTracker, outputAmount, feeMode.HasReferral, currentPoint, pool.ActivationPoint)
+		if err != nil {
+			return common.SwapResult{}, err
+		}
+		result.TradingFee = fee.TradingFee
+		result.ProtocolFee = fee.ProtocolFee
+		result.ReferralFee = fee.ReferralFee
+		outputAmount = fee.Amount
+	}
+
+	result.InputAmount = amountIn
+	result.ActualInputAmount = actualAmountIn
+	result.OutputAmount = outputAmount
+	result.NextSqrtPrice = nextSqrtPrice
+
+	return result, nil
+}
+
+// fixed-width variant of GetSwapAmountFromBaseToQuote
+func GetSwapAmountFromBaseToQuoteU128(pool *common.Pool, config *common.PoolConfig, amountIn uint64) (uint64, uint128.Uint128, error) {
+	var totalOutputAmount u256
+	sqrtPrice := pool.SqrtPrice
+	amountLeft := amountIn
+
+	for i := common.MaxCurvePoint - 1; i >= 0; i-- {
+		if config.Curve[i].SqrtPrice.IsZero() || config.Curve[i].Liquidity.IsZero() {
+			continue
+		}
+
+		lowerSqrtPrice := config.Curve[i].SqrtPrice
+		if lowerSqrtPrice.Cmp(sqrtPrice) >= 0 {
+			continue
+		}
+
+		// the segment below the current price is priced with the next point's liquidity
+		liquidity := config.Curve[i+1].Liquidity
+		if liquidity.IsZero() {
+			continue
+		}
+
+		maxAmountIn, err := getDeltaAmountBaseUnsigned256(lowerSqrtPrice, sqrtPrice, liquidity, common.Up)
+		if err != nil {
+			return 0, uint128.Zero, err
+		}
+
+		if maxAmountIn.cmp(u256{amountLeft}) > 0 {
+			nextSqrtPrice, err := GetNextSqrtPriceFromInputU128(sqrtPrice, liquidity, amountLeft, true)
+			if err != nil {
+				return 0, uint128.Zero, err
+			}
+			outputAmount, err := GetDeltaAmountQuoteUnsignedU128(nextSqrtPrice, sqrtPrice, liquidity, common.Down)
+			if err != nil {
+				return 0, uint128.Zero, err
+			}
+			totalOutputAmount, _ = totalOutputAmount.add(u256FromU128(outputAmount))
+			sqrtPrice = nextSqrtPrice
+			amountLeft = 0
+			break
+		}
+
+		outputAmount, err := GetDeltaAmountQuoteUnsignedU128(lowerSqrtPrice, sqrtPrice, liquidity, common.Down)
+		if err != nil {
+			return 0, uint128.Zero, err
+		}
+		totalOutputAmount, _ = totalOutputAmount.add(u256FromU128(outputAmount))
+		sqrtPrice = lowerSqrtPrice
+		amountLeft -= maxAmountIn[0]
+	}
+
+	if amountLeft != 0 {
+		liquidity := config.Curve[0].Liquidity
+		nextSqrtPrice, err := GetNextSqrtPriceFromInputU128(sqrtPrice, liquidity, amountLeft, true)
+		if err != nil {
+			return 0, uint128.Zero, err
+		}
+		if nextSqrtPrice.Cmp(config.SqrtStartPrice) < 0 {
+			return 0, uint128.Zero, ErrNotEnoughLiquidity
+		}
+		outputAmount, err := GetDeltaAmountQuoteUnsignedU128(nextSqrtPrice, sqrtPrice, liquidity, common.Down)
+		if err != nil {
+			return 0, uint128.Zero, err
+		}
+		totalOutputAmount, _ = totalOutputAmount.add(u256FromU128(outputAmount))
+		sqrtPrice = nextSqrtPrice
+	}
+
+	total, err := totalOutputAmount.toU64()
+	if err != nil {
+		return 0, uint128.Zero, err
+	}
+	return total, sqrtPrice, nil
+}
+
+// fixed-width variant of GetSwapAmountFromQuoteToBase
+func GetSwapAmountFromQuoteToBaseU128(pool *common.Pool, config *common.PoolConfig, amountIn uint64) (uint64, uint128.Uint128, error) {
+	var totalOutputAmount u256
+	sqrtPrice := pool.SqrtPrice
+	amountLeft := amountIn
+
+	for i := 0; i < common.MaxCurvePoint; i++ {
+		if config.Curve[i].SqrtPrice.IsZero() || config.Curve[i].Liquidity.IsZero() {
+			break
+		}
+
+		upperSqrtPrice := config.Curve[i].SqrtPrice
+		if upperSqrtPrice.Cmp(sqrtPrice) <= 0 {
+			continue
+		}
+
+		liquidity := config.Curve[i].Liquidity
+		maxAmountIn, err := GetDeltaAmountQuoteUnsignedU128(sqrtPrice, upperSqrtPrice, liquidity, common.Up)
+		if err != nil {
+			return 0, uint128.Zero, err
+		}
+
+		if maxAmountIn.Cmp64(amountLeft) > 0 {
+			nextSqrtPrice, err := GetNextSqrtPriceFromInputU128(sqrtPrice, liquidity, amountLeft, false)
+			if err != nil {
+				return 0, uint128.Zero, err
+			}
+			outputAmount, err := getDeltaAmountBaseUnsigned256(sqrtPrice, nextSqrtPrice, liquidity, common.Down)
+			if err != nil {
+				return 0, uint128.Zero, err
+			}
+			totalOutputAmount, _ = totalOutputAmount.add(outputAmount)
+			sqrtPrice = nextSqrtPrice
+			amountLeft = 0
+			break
+		}
+
+		outputAmount, err := getDeltaAmountBaseUnsigned256(sqrtPrice, upperSqrtPrice, liquidity, common.Down)
+		if err != nil {
+			return 0, uint128.Zero, err
+		}
+		totalOutputAmount, _ = totalOutputAmount.add(outputAmount)
+		sqrtPrice = upperSqrtPrice
+		amountLeft -= maxAmountIn.Lo
+	}
+
+	if amountLeft != 0 {
+		return 0, uint128.Zero, ErrNotEnoughLiquidity
+	}
+
+	total, err := totalOutputAmount.toU64()
+	if err != nil {
+		return 0, uint128.Zero, err
+	}
+	return total, sqrtPrice, nil
+}
@@ -0,0 +1,202 @@
+package math_test
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"lukechampine.com/uint128"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/math"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+)
+
+// random curve of 1 to 6 segments, each raising 0.1 to 100 SOL between sqrt prices
+// 1.2 to 4 times apart
+func randomCurve(rng *rand.Rand, config *common.PoolConfig) {
+	// uint128.FromBig shifts its argument, so prices are copied before conversion
+	q128 := new(big.Int).Lsh(big.NewInt(1), 128)
+	sqrtPrice := new(big.Int).SetUint64(1e15 + uint64(rng.Int63n(9_000_000_000_000_000_000)))
+	config.SqrtStartPrice = uint128.FromBig(new(big.Int).Set(sqrtPrice))
+	config.Curve = [len(config.Curve)]common.LiquidityDistributionConfig{}
+
+	segments := 1 + rng.Intn(6)
+	for i := 0; i < segments; i++ {
+		next := new(big.Int).Mul(sqrtPrice, big.NewInt(int64(120+rng.Intn(280))))
+		next.Div(next, big.NewInt(100))
+		if next.BitLen() > 100 {
+			break
+		}
+		quote := big.NewInt(1e8 + rng.Int63n(1e11))
+		liquidity := new(big.Int).Mul(quote, q128)
+		liquidity.Div(liquidity, new(big.Int).Sub(next, sqrtPrice))
+		config.Curve[i] = common.LiquidityDistributionConfig{
+			SqrtPrice: uint128.FromBig(new(big.Int).Set(next)),
+			Liquidity: uint128.FromBig(liquidity),
+		}
+		config.MigrationSqrtPrice = config.Curve[i].SqrtPrice
+		sqrtPrice = next
+	}
+}
+
+// random fees: either collect mode, a linear, exponential or rate limiter base fee,
+// and a dynamic fee half of the time
+func randomFees(rng *rand.Rand, config *common.PoolConfig, pool *common.Pool) {
+	config.CollectFeeMode = uint8(rng.Intn(2))
+	fees := &config.PoolFees
+	*fees = common.PoolFeesConfig{
+		ProtocolFeePercent: uint8(rng.Intn(51)),
+		ReferralFeePercent: uint8(rng.Intn(51)),
+	}
+
+	cliff := uint64(2_500_000 + rng.Int63n(500_000_000))
+	switch rng.Intn(3) {
+	case 0:
+		periods := uint16(1 + rng.Intn(200))
+		fees.BaseFee = common.BaseFeeConfig{
+			CliffFeeNumerator: cliff,
+			PeriodFrequency:   uint64(1 + rng.Intn(600)),
+			ReductionFactor:   uint64(rng.Int63n(int64(cliff/uint64(periods)) + 1)),
+			NumberOfPeriod:    periods,
+			FeeSchedulerMode:  common.FeeSchedulerModeLinear,
+		}
+	case 1:
+		fees.BaseFee = common.BaseFeeConfig{
+			CliffFeeNumerator: cliff,
+			PeriodFrequency:   uint64(1 + rng.Intn(600)),
+			ReductionFactor:   uint64(rng.Intn(5_000)),
+			NumberOfPeriod:    uint16(1 + rng.Intn(200)),
+			FeeSchedulerMode:  common.FeeSchedulerModeExponential,
+		}
+	default:
+		fees.BaseFee = common.BaseFeeConfig{
+			CliffFeeNumerator: cliff,
+			// max limiter duration, fee increment bps and reference amount
+			PeriodFrequency:  uint64(rng.Intn(1_000)),
+			NumberOfPeriod:   uint16(1 + rng.Intn(100)),
+			ReductionFactor:  uint64(1e8 + rng.Int63n(1e10)),
+			FeeSchedulerMode: common.FeeSchedulerModeRateLimiter,
+		}
+	}
+
+	if rng.Intn(2) == 0 {
+		fees.DynamicFee = common.DynamicFeeConfig{
+			Initialized:              1,
+			MaxVolatilityAccumulator: 14_460_000,
+			VariableFeeControl:       uint32(rng.Intn(5_000)),
+			BinStep:                  1,
+			BinStepU128:              uint128.From64(1_844_674_407_370_955),
+		}
+		pool.VolatilityTracker.VolatilityAccumulator = uint128.From64(uint64(rng.Int63n(14_460_000)))
+	}
+}
+
+// pool at a random price on the curve
+func randomPool(rng *rand.Rand, config *common.PoolConfig) *common.Pool {
+	pool := rpctest.SamplePool(solana.PublicKey{}, solana.PublicKey{})
+	lower := math.U128ToBig(config.SqrtStartPrice)
+	upper := math.U128ToBig(config.MigrationSqrtPrice)
+	span := new(big.Int).Sub(upper, lower)
+	offset := new(big.Int).Rand(rng, new(big.Int).Add(span, big.NewInt(1)))
+	pool.SqrtPrice = uint128.FromBig(offset.Add(offset, lower))
+	pool.ActivationPoint = uint64(1_000 + rng.Intn(2_000))
+	return pool
+}
+
+// amount spread over magnitudes from a single unit to 10^15
+func randomAmount(rng *rand.Rand) uint64 {
+	magnitude := rng.Intn(15)
+	scale := uint64(1)
+	for i := 0; i < magnitude; i++ {
+		scale *= 10
+	}
+	return 1 + uint64(rng.Int63n(int64(scale*10)))
+}
+
+func TestSwapQuoteU128MatchesSwapQuote(t *testing.T) {
+	iterations := 20_000
+	if testing.Short() {
+		iterations = 2_000
+	}
+	rng := rand.New(rand.NewSource(1))
+
+	quoted := 0
+	for i := 0; i < iterations; i++ {
+		config := rpctest.SamplePoolConfig()
+		if i%4 != 0 {
+			randomCurve(rng, config)
+		}
+		pool := randomPool(rng, config)
+		randomFees(rng, config, pool)
+
+		direction := common.TradeDirection(rng.Intn(2))
+		amountIn := randomAmount(rng)
+		hasReferral := rng.Intn(2) == 0
+		currentPoint := uint64(rng.Intn(5_000))
+
+		want, wantErr := math.SwapQuote(pool, config, direction, amountIn, hasReferral, currentPoint)
+		got, gotErr := math.SwapQuoteU128(pool, config, direction, amountIn, hasReferral, currentPoint)
+		if (wantErr == nil) != (gotErr == nil) {
+			t.Fatalf("case %d (direction %v, amount %d, fee mode %d): SwapQuote error %v, SwapQuoteU128 error %v",
+				i, direction, amountIn, config.PoolFees.BaseFee.FeeSchedulerMode, wantErr, gotErr)
+		}
+		if wantErr != nil {
+			continue
+		}
+		if got != *want {
+			t.Fatalf("case %d (direction %v, amount %d, fee mode %d): SwapQuote %+v, SwapQuoteU128 %+v",
+				i, direction, amountIn, config.PoolFees.BaseFee.FeeSchedulerMode, *want, got)
+		}
+		quoted++
+	}
+
+	// most cases must quote, or the comparison only covered errors
+	if quoted < iterations/2 {
+		t.Fatalf("only %d of %d cases quoted", quoted, iterations)
+	}
+}
+
+// sample pool after 20 SOL of buys, quoting a 1 SOL buy and the sale of its output
+func benchmarkQuote(b *testing.B, quote func(*common.Pool, *common.PoolConfig, common.TradeDirection, uint64) error) {
+	config := rpctest.SamplePoolConfig()
+	config.PoolFees.DynamicFee = common.DynamicFeeConfig{
+		Initialized:              1,
+		MaxVolatilityAccumulator: 14_460_000,
+		VariableFeeControl:       1_788,
+		BinStep:                  1,
+		BinStepU128:              uint128.From64(1_844_674_407_370_955),
+	}
+	pool := rpctest.SamplePool(solana.PublicKey{}, solana.PublicKey{})
+	buy, err := math.SwapQuote(pool, config, common.QuoteToBase, 20_000_000_000, false, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	pool.SqrtPrice = buy.NextSqrtPrice
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := quote(pool, config, common.QuoteToBase, 1_000_000_000); err != nil {
+			b.Fatal(err)
+		}
+		if err := quote(pool, config, common.BaseToQuote, 20_000_000_000_000); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSwapQuote(b *testing.B) {
+	benchmarkQuote(b, func(pool *common.Pool, config *common.PoolConfig, direction common.TradeDirection, amountIn uint64) error {
+		_, err := math.SwapQuote(pool, config, direction, amountIn, true, 0)
+		return err
+	})
+}
+
+func BenchmarkSwapQuoteU128(b *testing.B) {
+	benchmarkQuote(b, func(pool *common.Pool, config *common.PoolConfig, direction common.TradeDirection, amountIn uint64) error {
+		_, err := math.SwapQuoteU128(pool, config, direction, amountIn, true, 0)
+		return err
+	})
+}
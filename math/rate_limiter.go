@@ -0,0 +1,146 @@
+package math
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"lukechampine.com/uint128"
+)
+
+// returned by quotes that cannot invert the amount-dependent fee of the rate limiter
+var ErrRateLimiterExactInOnly = errors.New("rate limited swaps only support ExactIn")
+
+// Whether the rate limiter of the base fee applies to the swap: only buys within
+// MaxLimiterDuration points of the activation point are rate limited
+func IsRateLimiterApplied(
+	baseFee *common.BaseFeeConfig,
+	currentPoint uint64,
+	activationPoint uint64,
+	tradeDirection common.TradeDirection,
+) bool {
+	limiter, ok := baseFee.RateLimiter()
+	if !ok || tradeDirection == common.BaseToQuote {
+		return false
+	}
+	if limiter.ReferenceAmount == 0 && limiter.MaxLimiterDuration == 0 && limiter.FeeIncrementBps == 0 {
+		return false
+	}
+	return currentPoint < activationPoint || currentPoint-activationPoint <= limiter.MaxLimiterDuration
+}
+
+// gets the rate limiter fee numerator for an input amount, fees included. Every
+// ReferenceAmount above the first one is charged FeeIncrementBps more, up to MAX_FEE_NUMERATOR.
+// Formula: fee = x0 * (c + c*a + i*a*(a+1)/2) + b * (c + i*(a+1)), numerator = fee / amount
+// where a, b = (amount - x0) divmod x0
+func GetRateLimiterFeeNumerator(limiter *common.FeeRateLimiter, inputAmount uint64) (uint64, error) {
+	cliffFeeNumerator := limiter.CliffFeeNumerator
+	if cliffFeeNumerator >= common.MaxFeeNumerator {
+		return common.MaxFeeNumerator, nil
+	}
+	feeIncrementNumerator := uint64(limiter.FeeIncrementBps) * common.FeeDenominator / common.BasisPointMax
+	if limiter.ReferenceAmount == 0 || feeIncrementNumerator == 0 || inputAmount <= limiter.ReferenceAmount {
+		return cliffFeeNumerator, nil
+	}
+
+	maxIndex := (common.MaxFeeNumerator - cliffFeeNumerator) / feeIncrementNumerator
+	referenceAmount := uint128.From64(limiter.ReferenceAmount)
+	c := uint128.From64(cliffFeeNumerator)
+	i := uint128.From64(feeIncrementNumerator)
+
+	diff := inputAmount - limiter.ReferenceAmount
+	a := diff / limiter.ReferenceAmount
+	b := diff % limiter.ReferenceAmount
+
+	// c is below MAX_FEE_NUMERATOR and a below maxIndex where they multiply, which keeps
+	// the numerators under 2^59 and the products under 2^124
+	var tradingFeeNumerator uint128.Uint128
+	if a < maxIndex {
+		numerator1 := c.Add(c.Mul64(a)).Add(i.Mul64(a).Mul64(a + 1).Div64(2))
+		numerator2 := c.Add(i.Mul64(a + 1))
+		tradingFeeNumerator = referenceAmount.Mul(numerator1).Add(numerator2.Mul64(b))
+	} else {
+		numerator1 := c.Add(c.Mul64(maxIndex)).Add(i.Mul64(maxIndex).Mul64(maxIndex + 1).Div64(2))
+		// the amount past the last increment pays the maximum fee
+		leftAmount := uint128.From64(a - maxIndex).Mul(referenceAmount).Add64(b)
+		tradingFeeNumerator = referenceAmount.Mul(numerator1).Add(leftAmount.Mul64(common.MaxFeeNumerator))
+	}
+
+	tradingFee, err := MulDivU128(tradingFeeNumerator, uint128.From64(1), uint128.From64(common.FeeDenominator), common.Up)
+	if err != nil {
+		return 0, err
+	}
+	feeNumerator, err := MulDivU128(tradingFee, uint128.From64(common.FeeDenominator), uint128.From64(inputAmount), common.Up)
+	if err != nil {
+		return 0, err
+	}
+	if feeNumerator.Cmp64(common.MaxFeeNumerator) > 0 {
+		return common.MaxFeeNumerator, nil
+	}
+	return feeNumerator.Lo, nil
+}
+
+// gets the base fee numerator of a swap, from the input amount when the rate limiter applies
+func GetBaseFeeNumeratorFromInput(
+	baseFee *common.BaseFeeConfig,
+	tradeDirection common.TradeDirection,
+	inputAmount uint64,
+	currentPoint uint64,
+	activationPoint uint64,
+) (uint64, error) {
+	if IsRateLimiterApplied(baseFee, currentPoint, activationPoint, tradeDirection) {
+		limiter, _ := baseFee.RateLimiter()
+		return GetRateLimiterFeeNumerator(&limiter, inputAmount)
+	}
+	return GetCurrentBaseFeeNumeratorU64(baseFee, currentPoint, activationPoint)
+}
+
+// like GetFeeOnAmount for fees charged on the input, which the rate limiter depends on
+func GetFeeOnInputAmount(
+	poolFees *common.PoolFeesConfig,
+	volatilityTracker *common.VolatilityTracker,
+	amount uint64,
+	hasReferral bool,
+	tradeDirection common.TradeDirection,
+	currentPoint uint64,
+	activationPoint uint64,
+) (*common.FeeOnAmountResult, error) {
+	if !IsRateLimiterApplied(&poolFees.BaseFee, currentPoint, activationPoint, tradeDirection) {
+		return GetFeeOnAmount(poolFees, volatilityTracker, amount, hasReferral, currentPoint, activationPoint)
+	}
+	limiter, _ := poolFees.BaseFee.RateLimiter()
+	baseFeeNumerator, err := GetRateLimiterFeeNumerator(&limiter, amount)
+	if err != nil {
+		return nil, err
+	}
+	tradeFeeNumerator, err := addVariableFee(poolFees, volatilityTracker, new(big.Int).SetUint64(baseFeeNumerator))
+	if err != nil {
+		return nil, err
+	}
+	return splitFeeOnAmount(poolFees, amount, tradeFeeNumerator, hasReferral)
+}
+
+// fixed-width variant of GetFeeOnInputAmount
+func GetFeeOnInputAmountU64(
+	poolFees *common.PoolFeesConfig,
+	volatilityTracker *common.VolatilityTracker,
+	amount uint64,
+	hasReferral bool,
+	tradeDirection common.TradeDirection,
+	currentPoint uint64,
+	activationPoint uint64,
+) (common.FeeOnAmountResult, error) {
+	if !IsRateLimiterApplied(&poolFees.BaseFee, currentPoint, activationPoint, tradeDirection) {
+		return GetFeeOnAmountU64(poolFees, volatilityTracker, amount, hasReferral, currentPoint, activationPoint)
+	}
+	limiter, _ := poolFees.BaseFee.RateLimiter()
+	baseFeeNumerator, err := GetRateLimiterFeeNumerator(&limiter, amount)
+	if err != nil {
+		return common.FeeOnAmountResult{}, err
+	}
+	tradeFeeNumerator, err := addVariableFeeU64(poolFees, volatilityTracker, baseFeeNumerator)
+	if err != nil {
+		return common.FeeOnAmountResult{}, err
+	}
+	return splitFeeOnAmountU64(poolFees, amount, tradeFeeNumerator, hasReferral)
+}
@@ -0,0 +1,200 @@
+package math_test
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/math"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+)
+
+// rate limiter of a 1% cliff fee rising 1% per SOL bought above the first, for 100 points
+func rateLimiterFee() common.BaseFeeConfig {
+	return common.BaseFeeConfig{
+		CliffFeeNumerator: 10_000_000,
+		FeeSchedulerMode:  common.FeeSchedulerModeRateLimiter,
+		PeriodFrequency:   100,
+		NumberOfPeriod:    100,
+		ReductionFactor:   1_000_000_000,
+	}
+}
+
+func TestIsRateLimiterApplied(t *testing.T) {
+	const activationPoint = 1_000
+	tests := []struct {
+		name      string
+		baseFee   common.BaseFeeConfig
+		point     uint64
+		direction common.TradeDirection
+		want      bool
+	}{
+		{name: "buy at activation", baseFee: rateLimiterFee(), point: activationPoint, direction: common.QuoteToBase, want: true},
+		{name: "buy at the end of the window", baseFee: rateLimiterFee(), point: activationPoint + 100, direction: common.QuoteToBase, want: true},
+		{name: "buy after the window", baseFee: rateLimiterFee(), point: activationPoint + 101, direction: common.QuoteToBase},
+		{name: "buy before activation", baseFee: rateLimiterFee(), point: 10, direction: common.QuoteToBase, want: true},
+		{name: "sell", baseFee: rateLimiterFee(), point: activationPoint, direction: common.BaseToQuote},
+		{
+			name:      "fee scheduler",
+			baseFee:   common.BaseFeeConfig{CliffFeeNumerator: 10_000_000, PeriodFrequency: 100, NumberOfPeriod: 10},
+			point:     activationPoint,
+			direction: common.QuoteToBase,
+		},
+		{
+			name:      "unset limiter",
+			baseFee:   common.BaseFeeConfig{CliffFeeNumerator: 10_000_000, FeeSchedulerMode: common.FeeSchedulerModeRateLimiter},
+			point:     activationPoint,
+			direction: common.QuoteToBase,
+		},
+	}
+	for _, tt := range tests {
+		if got := math.IsRateLimiterApplied(&tt.baseFee, tt.point, activationPoint, tt.direction); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetRateLimiterFeeNumerator(t *testing.T) {
+	limiter := common.FeeRateLimiter{
+		CliffFeeNumerator:  10_000_000,
+		FeeIncrementBps:    100,
+		MaxLimiterDuration: 100,
+		ReferenceAmount:    1_000_000_000,
+	}
+	tests := []struct {
+		name    string
+		limiter common.FeeRateLimiter
+		amount  uint64
+		want    uint64
+	}{
+		{name: "below the reference", limiter: limiter, amount: 500_000_000, want: 10_000_000},
+		{name: "at the reference", limiter: limiter, amount: 1_000_000_000, want: 10_000_000},
+		// 1% on the first SOL and 2% on the next half: 20_000_000 / 1.5 SOL, rounded up
+		{name: "half a reference above", limiter: limiter, amount: 1_500_000_000, want: 13_333_334},
+		// 1% + 2% on two SOL
+		{name: "two references", limiter: limiter, amount: 2_000_000_000, want: 15_000_000},
+		{name: "three references", limiter: limiter, amount: 3_000_000_000, want: 20_000_000},
+		// 99 SOL from 1% to 99%, then 901 SOL at the 99% maximum
+		{name: "past the maximum fee", limiter: limiter, amount: 1_000_000_000_000, want: 941_490_000},
+		{name: "no reference amount", limiter: common.FeeRateLimiter{CliffFeeNumerator: 10_000_000, FeeIncrementBps: 100}, amount: 5_000_000_000, want: 10_000_000},
+		{name: "no increment", limiter: common.FeeRateLimiter{CliffFeeNumerator: 10_000_000, ReferenceAmount: 1}, amount: 5_000_000_000, want: 10_000_000},
+		{name: "cliff at the maximum", limiter: common.FeeRateLimiter{CliffFeeNumerator: common.MaxFeeNumerator + 1}, amount: 1, want: common.MaxFeeNumerator},
+	}
+	for _, tt := range tests {
+		got, err := math.GetRateLimiterFeeNumerator(&tt.limiter, tt.amount)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %d, %v, want %d", tt.name, got, err, tt.want)
+		}
+	}
+
+	// the fee paid never decreases with the amount and stays under the maximum
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1_000; i++ {
+		amount := uint64(rng.Int63n(200_000_000_000)) + 1
+		low, err := math.GetRateLimiterFeeNumerator(&limiter, amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		high, err := math.GetRateLimiterFeeNumerator(&limiter, amount+1_000_000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if high > common.MaxFeeNumerator || high < low {
+			t.Fatalf("fee numerator %d at %d, %d a bit above", low, amount, high)
+		}
+	}
+}
+
+func TestGetBaseFeeNumeratorFromInput(t *testing.T) {
+	baseFee := rateLimiterFee()
+	tests := []struct {
+		name      string
+		direction common.TradeDirection
+		point     uint64
+		want      uint64
+	}{
+		{name: "limited buy", direction: common.QuoteToBase, point: 50, want: 15_000_000},
+		{name: "sell", direction: common.BaseToQuote, point: 50, want: 10_000_000},
+		{name: "buy after the window", direction: common.QuoteToBase, point: 500, want: 10_000_000},
+	}
+	for _, tt := range tests {
+		got, err := math.GetBaseFeeNumeratorFromInput(&baseFee, tt.direction, 2_000_000_000, tt.point, 0)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %d, %v, want %d", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestGetFeeOnInputAmount(t *testing.T) {
+	config := rpctest.SamplePoolConfig()
+	config.PoolFees.BaseFee = rateLimiterFee()
+	var tracker common.VolatilityTracker
+	tests := []struct {
+		name     string
+		amount   uint64
+		referral bool
+		point    uint64
+		// total fee charged
+		want uint64
+	}{
+		{name: "one reference", amount: 1_000_000_000, want: 10_000_000},
+		{name: "two references", amount: 2_000_000_000, want: 30_000_000},
+		{name: "two references with referral", amount: 2_000_000_000, referral: true, want: 30_000_000},
+		{name: "after the window", amount: 2_000_000_000, point: 500, want: 20_000_000},
+	}
+	for _, tt := range tests {
+		fee, err := math.GetFeeOnInputAmount(&config.PoolFees, &tracker, tt.amount, tt.referral, common.QuoteToBase, tt.point, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if total := fee.TradingFee + fee.ProtocolFee + fee.ReferralFee; total != tt.want || fee.Amount != tt.amount-tt.want {
+			t.Errorf("%s: got %+v, want %d in fees", tt.name, fee, tt.want)
+		}
+		if tt.referral != (fee.ReferralFee != 0) {
+			t.Errorf("%s: got referral fee %d", tt.name, fee.ReferralFee)
+		}
+		feeU64, err := math.GetFeeOnInputAmountU64(&config.PoolFees, &tracker, tt.amount, tt.referral, common.QuoteToBase, tt.point, 0)
+		if err != nil || feeU64 != *fee {
+			t.Errorf("%s: fixed-width variant got %+v, %v, want %+v", tt.name, feeU64, err, fee)
+		}
+	}
+}
+
+func TestRateLimitedSwapModes(t *testing.T) {
+	config := rpctest.SamplePoolConfig()
+	config.PoolFees.BaseFee = rateLimiterFee()
+	pool := tradedSamplePool(t, config, 0)
+	tests := []struct {
+		name      string
+		direction common.TradeDirection
+		mode      common.SwapMode
+		point     uint64
+		limited   bool
+	}{
+		{name: "exact in buy", direction: common.QuoteToBase, mode: common.SwapModeExactIn},
+		{name: "partial fill buy", direction: common.QuoteToBase, mode: common.SwapModePartialFill, limited: true},
+		{name: "exact out buy", direction: common.QuoteToBase, mode: common.SwapModeExactOut, limited: true},
+		{name: "exact out buy after the window", direction: common.QuoteToBase, mode: common.SwapModeExactOut, point: 500},
+	}
+	for _, tt := range tests {
+		_, err := math.SwapQuote2(pool, config, tt.direction, tt.mode, 1_000_000_000, false, tt.point)
+		if limited := errors.Is(err, math.ErrRateLimiterExactInOnly); limited != tt.limited || (!tt.limited && err != nil) {
+			t.Errorf("%s: got %v", tt.name, err)
+		}
+	}
+
+	// a limited buy pays the rising fee, the same buy after the window only the cliff fee
+	limited, err := math.SwapQuote(pool, config, common.QuoteToBase, 2_000_000_000, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	later, err := math.SwapQuote(pool, config, common.QuoteToBase, 2_000_000_000, false, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limited.TradingFee+limited.ProtocolFee != 30_000_000 || later.TradingFee+later.ProtocolFee != 20_000_000 {
+		t.Fatalf("got fees %d and %d, want 30_000_000 and 20_000_000",
+			limited.TradingFee+limited.ProtocolFee, later.TradingFee+later.ProtocolFee)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"math/big"
 
 	"github.com/Luigi-1Combo/dbc-go/common"
-	"lukechampine.com/uint128"
 )
 
 // safe addition
@@ -34,6 +33,19 @@ func Div(a, b *big.Int) (*big.Int, error) {
 	return new(big.Int).Div(a, b), nil
 }
 
+// safe (x * y) / denominator with rounding - return error if denominator is zero
+func MulDiv(x, y, denominator *big.Int, round common.Rounding) (*big.Int, error) {
+	if denominator.Sign() == 0 {
+		return nil, errors.New("SafeMath: division by zero")
+	}
+	prod := Mul(x, y)
+	quotient, remainder := new(big.Int).QuoRem(prod, denominator, new(big.Int))
+	if round == common.Up && remainder.Sign() != 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	return quotient, nil
+}
+
 // safe modulo - return error if b is zero
 func Mod(a, b *big.Int) (*big.Int, error) {
 	if b.Sign() == 0 {
@@ -115,11 +127,3 @@ func Pow(base, exponent *big.Int, scaling bool) (*big.Int, error) {
 	}
 	return divResult, nil
 }
-
-// converts uint128.Uint128 to *big.Int
-func u128ToBig(val uint128.Uint128) *big.Int {
-	hi := new(big.Int).SetUint64(val.Hi)
-	lo := new(big.Int).SetUint64(val.Lo)
-	hi.Lsh(hi, 64)
-	return hi.Or(hi, lo)
-}
@@ -0,0 +1,255 @@
+package math
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+)
+
+// returned when the curve cannot absorb the whole input amount
+var ErrNotEnoughLiquidity = errors.New("not enough liquidity")
+
+var errInvalidSlippage = errors.New("slippage must not exceed 10000 bps")
+
+// quotes a swap on the bonding curve, mirroring the on-chain swap
+func SwapQuote(
+	pool *common.Pool,
+	config *common.PoolConfig,
+	tradeDirection common.TradeDirection,
+	amountIn uint64,
+	hasReferral bool,
+	currentPoint uint64,
+) (*common.SwapResult, error) {
+	feeMode, err := GetFeeMode(config.CollectFeeMode, tradeDirection, hasReferral)
+	if err != nil {
+		return nil, err
+	}
+	return GetSwapResult(pool, config, amountIn, feeMode, tradeDirection, currentPoint)
+}
+
+// gets the swap result for the given fee mode and trade direction
+func GetSwapResult(
+	pool *common.Pool,
+	config *common.PoolConfig,
+	amountIn uint64,
+	feeMode *common.FeeMode,
+	tradeDirection common.TradeDirection,
+	currentPoint uint64,
+) (*common.SwapResult, error) {
+	result := &common.SwapResult{}
+
+	actualAmountIn := amountIn
+	if feeMode.FeesOnInput {
+		fee, err := GetFeeOnInputAmount(&config.PoolFees, &pool.VolatilityTracker, amountIn, feeMode.HasReferral, tradeDirection, currentPoint, pool.ActivationPoint)
+		if err != nil {
+			return nil, err
+		}
+		result.TradingFee = fee.TradingFee
+		result.ProtocolFee = fee.ProtocolFee
+		result.ReferralFee = fee.ReferralFee
+		actualAmountIn = fee.Amount
+	}
+
+	var outputAmount, nextSqrtPrice *big.Int
+	var err error
+	if tradeDirection == common.BaseToQuote {
+		outputAmount, nextSqrtPrice, err = GetSwapAmountFromBaseToQuote(pool, config, actualAmountIn)
+	} else {
+		outputAmount, nextSqrtPrice, err = GetSwapAmountFromQuoteToBase(pool, config, actualAmountIn)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	actualAmountOut, err := BigToU64(outputAmount)
+	if err != nil {
+		return nil, err
+	}
+	if !feeMode.FeesOnInput {
+		fee, err := GetFeeOnAmount(&config.PoolFees, &pool.VolatilityTracker, actualAmountOut, feeMode.HasReferral, currentPoint, pool.ActivationPoint)
+		if err != nil {
+			return nil, err
+		}
+		result.TradingFee = fee.TradingFee
+		result.ProtocolFee = fee.ProtocolFee
+		result.ReferralFee = fee.ReferralFee
+		actualAmountOut = fee.Amount
+	}
+
+	result.NextSqrtPrice, err = BigToU128(nextSqrtPrice)
+	if err != nil {
+		return nil, err
+	}
+	result.InputAmount = amountIn
+	result.ActualInputAmount = actualAmountIn
+	result.OutputAmount = actualAmountOut
+
+	return result, nil
+}
+
+// gets the quote output and next sqrt price for selling base, walking the curve downwards
+func GetSwapAmountFromBaseToQuote(pool *common.Pool, config *common.PoolConfig, amountIn uint64) (*big.Int, *big.Int, error) {
+	totalOutputAmount := big.NewInt(0)
+	sqrtPrice := U128ToBig(pool.SqrtPrice)
+	amountLeft := new(big.Int).SetUint64(amountIn)
+
+	for i := common.MaxCurvePoint - 1; i >= 0; i-- {
+		if config.Curve[i].SqrtPrice.IsZero() || config.Curve[i].Liquidity.IsZero() {
+			continue
+		}
+
+		lowerSqrtPrice := U128ToBig(config.Curve[i].SqrtPrice)
+		if lowerSqrtPrice.Cmp(sqrtPrice) >= 0 {
+			continue
+		}
+
+		// the segment below the current price is priced with the next point's liquidity
+		liquidity := U128ToBig(config.Curve[i+1].Liquidity)
+		if liquidity.Sign() == 0 {
+			continue
+		}
+
+		maxAmountIn, err := GetDeltaAmountBaseUnsigned(lowerSqrtPrice, sqrtPrice, liquidity, common.Up)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if amountLeft.Cmp(maxAmountIn) < 0 {
+			nextSqrtPrice, err := GetNextSqrtPriceFromInput(sqrtPrice, liquidity, amountLeft, true)
+			if err != nil {
+				return nil, nil, err
+			}
+			outputAmount, err := GetDeltaAmountQuoteUnsigned(nextSqrtPrice, sqrtPrice, liquidity, common.Down)
+			if err != nil {
+				return nil, nil, err
+			}
+			totalOutputAmount = Add(totalOutputAmount, outputAmount)
+			sqrtPrice = nextSqrtPrice
+			amountLeft = big.NewInt(0)
+			break
+		}
+
+		outputAmount, err := GetDeltaAmountQuoteUnsigned(lowerSqrtPrice, sqrtPrice, liquidity, common.Down)
+		if err != nil {
+			return nil, nil, err
+		}
+		totalOutputAmount = Add(totalOutputAmount, outputAmount)
+		sqrtPrice = lowerSqrtPrice
+		amountLeft, err = Sub(amountLeft, maxAmountIn)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if amountLeft.Sign() != 0 {
+		liquidity := U128ToBig(config.Curve[0].Liquidity)
+		nextSqrtPrice, err := GetNextSqrtPriceFromInput(sqrtPrice, liquidity, amountLeft, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		if nextSqrtPrice.Cmp(U128ToBig(config.SqrtStartPrice)) < 0 {
+			return nil, nil, ErrNotEnoughLiquidity
+		}
+		outputAmount, err := GetDeltaAmountQuoteUnsigned(nextSqrtPrice, sqrtPrice, liquidity, common.Down)
+		if err != nil {
+			return nil, nil, err
+		}
+		totalOutputAmount = Add(totalOutputAmount, outputAmount)
+		sqrtPrice = nextSqrtPrice
+	}
+
+	return totalOutputAmount, sqrtPrice, nil
+}
+
+// gets the base output and next sqrt price for buying base, walking the curve upwards
+func GetSwapAmountFromQuoteToBase(pool *common.Pool, config *common.PoolConfig, amountIn uint64) (*big.Int, *big.Int, error) {
+	totalOutputAmount, sqrtPrice, amountLeft, err := getSwapAmountFromQuoteToBase(pool, config, amountIn, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if amountLeft.Sign() != 0 {
+		return nil, nil, ErrNotEnoughLiquidity
+	}
+	return totalOutputAmount, sqrtPrice, nil
+}
+
+// walks the curve upwards, stopping at maxSqrtPrice when set, and returns the
+// unspent amount alongside the output and next sqrt price
+func getSwapAmountFromQuoteToBase(
+	pool *common.Pool,
+	config *common.PoolConfig,
+	amountIn uint64,
+	maxSqrtPrice *big.Int,
+) (*big.Int, *big.Int, *big.Int, error) {
+	totalOutputAmount := big.NewInt(0)
+	sqrtPrice := U128ToBig(pool.SqrtPrice)
+	amountLeft := new(big.Int).SetUint64(amountIn)
+
+	for i := 0; i < common.MaxCurvePoint; i++ {
+		if config.Curve[i].SqrtPrice.IsZero() || config.Curve[i].Liquidity.IsZero() {
+			break
+		}
+		if maxSqrtPrice != nil && sqrtPrice.Cmp(maxSqrtPrice) >= 0 {
+			break
+		}
+
+		upperSqrtPrice := U128ToBig(config.Curve[i].SqrtPrice)
+		if maxSqrtPrice != nil && upperSqrtPrice.Cmp(maxSqrtPrice) > 0 {
+			upperSqrtPrice = maxSqrtPrice
+		}
+		if upperSqrtPrice.Cmp(sqrtPrice) <= 0 {
+			continue
+		}
+
+		liquidity := U128ToBig(config.Curve[i].Liquidity)
+		maxAmountIn, err := GetDeltaAmountQuoteUnsigned(sqrtPrice, upperSqrtPrice, liquidity, common.Up)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if amountLeft.Cmp(maxAmountIn) < 0 {
+			nextSqrtPrice, err := GetNextSqrtPriceFromInput(sqrtPrice, liquidity, amountLeft, false)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			outputAmount, err := GetDeltaAmountBaseUnsigned(sqrtPrice, nextSqrtPrice, liquidity, common.Down)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			totalOutputAmount = Add(totalOutputAmount, outputAmount)
+			sqrtPrice = nextSqrtPrice
+			amountLeft = big.NewInt(0)
+			break
+		}
+
+		outputAmount, err := GetDeltaAmountBaseUnsigned(sqrtPrice, upperSqrtPrice, liquidity, common.Down)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		totalOutputAmount = Add(totalOutputAmount, outputAmount)
+		sqrtPrice = upperSqrtPrice
+		amountLeft, err = Sub(amountLeft, maxAmountIn)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return totalOutputAmount, sqrtPrice, amountLeft, nil
+}
+
+// gets the minimum amount out accepted for a slippage tolerance in basis points
+func GetMinimumAmountOut(amountOut uint64, slippageBps uint64) (uint64, error) {
+	if slippageBps > common.BasisPointMax {
+		return 0, errInvalidSlippage
+	}
+	return mulDivU64(amountOut, common.BasisPointMax-slippageBps, common.BasisPointMax, common.Down)
+}
+
+// gets the maximum amount in accepted for a slippage tolerance in basis points
+func GetMaximumAmountIn(amountIn uint64, slippageBps uint64) (uint64, error) {
+	if slippageBps > common.BasisPointMax {
+		return 0, errInvalidSlippage
+	}
+	return mulDivU64(amountIn, common.BasisPointMax+slippageBps, common.BasisPointMax, common.Up)
+}
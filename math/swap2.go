@@ -0,0 +1,347 @@
+package math
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+)
+
+// quotes a swap2 in the given mode, mirroring the on-chain swap2. The amount is the
+// amount in for ExactIn and PartialFill and the amount out for ExactOut.
+func SwapQuote2(
+	pool *common.Pool,
+	config *common.PoolConfig,
+	tradeDirection common.TradeDirection,
+	swapMode common.SwapMode,
+	amount uint64,
+	hasReferral bool,
+	currentPoint uint64,
+) (*common.SwapResult, error) {
+	feeMode, err := GetFeeMode(config.CollectFeeMode, tradeDirection, hasReferral)
+	if err != nil {
+		return nil, err
+	}
+
+	if swapMode != common.SwapModeExactIn && IsRateLimiterApplied(&config.PoolFees.BaseFee, currentPoint, pool.ActivationPoint, tradeDirection) {
+		return nil, ErrRateLimiterExactInOnly
+	}
+
+	switch swapMode {
+	case common.SwapModeExactIn:
+		return GetSwapResult(pool, config, amount, feeMode, tradeDirection, currentPoint)
+	case common.SwapModePartialFill:
+		return GetSwapResultPartialFill(pool, config, amount, feeMode, tradeDirection, currentPoint)
+	case common.SwapModeExactOut:
+		return GetSwapResultExactOut(pool, config, amount, feeMode, tradeDirection, currentPoint)
+	default:
+		return nil, fmt.Errorf("unsupported swap mode: %d", swapMode)
+	}
+}
+
+// gets the swap result of a partial fill: buys stop at the migration sqrt price and
+// only the input needed to get there is consumed, sells behave like ExactIn
+func GetSwapResultPartialFill(
+	pool *common.Pool,
+	config *common.PoolConfig,
+	amountIn uint64,
+	feeMode *common.FeeMode,
+	tradeDirection common.TradeDirection,
+	currentPoint uint64,
+) (*common.SwapResult, error) {
+	if tradeDirection == common.BaseToQuote {
+		return GetSwapResult(pool, config, amountIn, feeMode, tradeDirection, currentPoint)
+	}
+
+	result := &common.SwapResult{InputAmount: amountIn}
+
+	actualAmountIn := amountIn
+	if feeMode.FeesOnInput {
+		fee, err := GetFeeOnAmount(&config.PoolFees, &pool.VolatilityTracker, amountIn, feeMode.HasReferral, currentPoint, pool.ActivationPoint)
+		if err != nil {
+			return nil, err
+		}
+		actualAmountIn = fee.Amount
+	}
+
+	var maxSqrtPrice *big.Int
+	if !config.MigrationSqrtPrice.IsZero() {
+		maxSqrtPrice = U128ToBig(config.MigrationSqrtPrice)
+	}
+	outputAmount, nextSqrtPrice, amountLeft, err := getSwapAmountFromQuoteToBase(pool, config, actualAmountIn, maxSqrtPrice)
+	if err != nil {
+		return nil, err
+	}
+	unspent, err := BigToU64(amountLeft)
+	if err != nil {
+		return nil, err
+	}
+	consumed := actualAmountIn - unspent
+
+	if feeMode.FeesOnInput {
+		// the fee is charged on the consumed amount only, grossed up as the program does
+		includedFeeAmount := amountIn
+		if unspent != 0 {
+			includedFeeAmount, err = getIncludedFeeAmount(pool, config, consumed, currentPoint)
+			if err != nil {
+				return nil, err
+			}
+		}
+		fee, err := GetFeeOnAmount(&config.PoolFees, &pool.VolatilityTracker, includedFeeAmount, feeMode.HasReferral, currentPoint, pool.ActivationPoint)
+		if err != nil {
+			return nil, err
+		}
+		result.TradingFee = fee.TradingFee
+		result.ProtocolFee = fee.ProtocolFee
+		result.ReferralFee = fee.ReferralFee
+		result.InputAmount = includedFeeAmount
+	} else {
+		result.InputAmount = consumed
+	}
+
+	actualAmountOut, err := BigToU64(outputAmount)
+	if err != nil {
+		return nil, err
+	}
+	if !feeMode.FeesOnInput {
+		fee, err := GetFeeOnAmount(&config.PoolFees, &pool.VolatilityTracker, actualAmountOut, feeMode.HasReferral, currentPoint, pool.ActivationPoint)
+		if err != nil {
+			return nil, err
+		}
+		result.TradingFee = fee.TradingFee
+		result.ProtocolFee = fee.ProtocolFee
+		result.ReferralFee = fee.ReferralFee
+		actualAmountOut = fee.Amount
+	}
+
+	result.NextSqrtPrice, err = BigToU128(nextSqrtPrice)
+	if err != nil {
+		return nil, err
+	}
+	result.ActualInputAmount = consumed
+	result.OutputAmount = actualAmountOut
+
+	return result, nil
+}
+
+// gets the swap result of receiving exactly amountOut, with the input it costs
+func GetSwapResultExactOut(
+	pool *common.Pool,
+	config *common.PoolConfig,
+	amountOut uint64,
+	feeMode *common.FeeMode,
+	tradeDirection common.TradeDirection,
+	currentPoint uint64,
+) (*common.SwapResult, error) {
+	result := &common.SwapResult{OutputAmount: amountOut}
+
+	// fees on output are added on top of the amount taken from the curve
+	curveAmountOut := amountOut
+	if !feeMode.FeesOnInput {
+		includedFeeAmount, err := getIncludedFeeAmount(pool, config, amountOut, currentPoint)
+		if err != nil {
+			return nil, err
+		}
+		fee, err := GetFeeOnAmount(&config.PoolFees, &pool.VolatilityTracker, includedFeeAmount, feeMode.HasReferral, currentPoint, pool.ActivationPoint)
+		if err != nil {
+			return nil, err
+		}
+		result.TradingFee = fee.TradingFee
+		result.ProtocolFee = fee.ProtocolFee
+		result.ReferralFee = fee.ReferralFee
+		curveAmountOut = includedFeeAmount
+	}
+
+	var inputAmount, nextSqrtPrice *big.Int
+	var err error
+	if tradeDirection == common.BaseToQuote {
+		inputAmount, nextSqrtPrice, err = GetSwapAmountFromBaseToQuoteExactOut(pool, config, curveAmountOut)
+	} else {
+		inputAmount, nextSqrtPrice, err = GetSwapAmountFromQuoteToBaseExactOut(pool, config, curveAmountOut)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	actualAmountIn, err := BigToU64(inputAmount)
+	if err != nil {
+		return nil, err
+	}
+	result.InputAmount = actualAmountIn
+	if feeMode.FeesOnInput {
+		includedFeeAmount, err := getIncludedFeeAmount(pool, config, actualAmountIn, currentPoint)
+		if err != nil {
+			return nil, err
+		}
+		fee, err := GetFeeOnAmount(&config.PoolFees, &pool.VolatilityTracker, includedFeeAmount, feeMode.HasReferral, currentPoint, pool.ActivationPoint)
+		if err != nil {
+			return nil, err
+		}
+		result.TradingFee = fee.TradingFee
+		result.ProtocolFee = fee.ProtocolFee
+		result.ReferralFee = fee.ReferralFee
+		result.InputAmount = includedFeeAmount
+	}
+
+	result.NextSqrtPrice, err = BigToU128(nextSqrtPrice)
+	if err != nil {
+		return nil, err
+	}
+	result.ActualInputAmount = actualAmountIn
+
+	return result, nil
+}
+
+// gets the base input and next sqrt price for receiving an exact quote amount, walking the curve downwards
+func GetSwapAmountFromBaseToQuoteExactOut(pool *common.Pool, config *common.PoolConfig, amountOut uint64) (*big.Int, *big.Int, error) {
+	totalInputAmount := big.NewInt(0)
+	sqrtPrice := U128ToBig(pool.SqrtPrice)
+	amountLeft := new(big.Int).SetUint64(amountOut)
+
+	for i := common.MaxCurvePoint - 1; i >= 0; i-- {
+		if config.Curve[i].SqrtPrice.IsZero() || config.Curve[i].Liquidity.IsZero() {
+			continue
+		}
+
+		lowerSqrtPrice := U128ToBig(config.Curve[i].SqrtPrice)
+		if lowerSqrtPrice.Cmp(sqrtPrice) >= 0 {
+			continue
+		}
+
+		// the segment below the current price is priced with the next point's liquidity
+		liquidity := U128ToBig(config.Curve[i+1].Liquidity)
+		if liquidity.Sign() == 0 {
+			continue
+		}
+
+		maxAmountOut, err := GetDeltaAmountQuoteUnsigned(lowerSqrtPrice, sqrtPrice, liquidity, common.Down)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if amountLeft.Cmp(maxAmountOut) < 0 {
+			nextSqrtPrice, err := GetNextSqrtPriceFromOutput(sqrtPrice, liquidity, amountLeft, true)
+			if err != nil {
+				return nil, nil, err
+			}
+			inputAmount, err := GetDeltaAmountBaseUnsigned(nextSqrtPrice, sqrtPrice, liquidity, common.Up)
+			if err != nil {
+				return nil, nil, err
+			}
+			totalInputAmount = Add(totalInputAmount, inputAmount)
+			sqrtPrice = nextSqrtPrice
+			amountLeft = big.NewInt(0)
+			break
+		}
+
+		inputAmount, err := GetDeltaAmountBaseUnsigned(lowerSqrtPrice, sqrtPrice, liquidity, common.Up)
+		if err != nil {
+			return nil, nil, err
+		}
+		totalInputAmount = Add(totalInputAmount, inputAmount)
+		sqrtPrice = lowerSqrtPrice
+		amountLeft, err = Sub(amountLeft, maxAmountOut)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if amountLeft.Sign() != 0 {
+		liquidity := U128ToBig(config.Curve[0].Liquidity)
+		nextSqrtPrice, err := GetNextSqrtPriceFromOutput(sqrtPrice, liquidity, amountLeft, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		if nextSqrtPrice.Cmp(U128ToBig(config.SqrtStartPrice)) < 0 {
+			return nil, nil, ErrNotEnoughLiquidity
+		}
+		inputAmount, err := GetDeltaAmountBaseUnsigned(nextSqrtPrice, sqrtPrice, liquidity, common.Up)
+		if err != nil {
+			return nil, nil, err
+		}
+		totalInputAmount = Add(totalInputAmount, inputAmount)
+		sqrtPrice = nextSqrtPrice
+	}
+
+	return totalInputAmount, sqrtPrice, nil
+}
+
+// gets the quote input and next sqrt price for receiving an exact base amount, walking the curve upwards
+func GetSwapAmountFromQuoteToBaseExactOut(pool *common.Pool, config *common.PoolConfig, amountOut uint64) (*big.Int, *big.Int, error) {
+	totalInputAmount := big.NewInt(0)
+	sqrtPrice := U128ToBig(pool.SqrtPrice)
+	amountLeft := new(big.Int).SetUint64(amountOut)
+
+	for i := 0; i < common.MaxCurvePoint; i++ {
+		if config.Curve[i].SqrtPrice.IsZero() || config.Curve[i].Liquidity.IsZero() {
+			break
+		}
+
+		upperSqrtPrice := U128ToBig(config.Curve[i].SqrtPrice)
+		if upperSqrtPrice.Cmp(sqrtPrice) <= 0 {
+			continue
+		}
+
+		liquidity := U128ToBig(config.Curve[i].Liquidity)
+		maxAmountOut, err := GetDeltaAmountBaseUnsigned(sqrtPrice, upperSqrtPrice, liquidity, common.Down)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if amountLeft.Cmp(maxAmountOut) < 0 {
+			nextSqrtPrice, err := GetNextSqrtPriceFromOutput(sqrtPrice, liquidity, amountLeft, false)
+			if err != nil {
+				return nil, nil, err
+			}
+			inputAmount, err := GetDeltaAmountQuoteUnsigned(sqrtPrice, nextSqrtPrice, liquidity, common.Up)
+			if err != nil {
+				return nil, nil, err
+			}
+			totalInputAmount = Add(totalInputAmount, inputAmount)
+			sqrtPrice = nextSqrtPrice
+			amountLeft = big.NewInt(0)
+			break
+		}
+
+		inputAmount, err := GetDeltaAmountQuoteUnsigned(sqrtPrice, upperSqrtPrice, liquidity, common.Up)
+		if err != nil {
+			return nil, nil, err
+		}
+		totalInputAmount = Add(totalInputAmount, inputAmount)
+		sqrtPrice = upperSqrtPrice
+		amountLeft, err = Sub(amountLeft, maxAmountOut)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if amountLeft.Sign() != 0 {
+		return nil, nil, ErrNotEnoughLiquidity
+	}
+
+	return totalInputAmount, sqrtPrice, nil
+}
+
+// GetIncludedFeeAmount at the current trading fee of the pool
+func getIncludedFeeAmount(pool *common.Pool, config *common.PoolConfig, amount uint64, currentPoint uint64) (uint64, error) {
+	tradeFeeNumerator, err := GetTotalTradingFeeNumerator(&config.PoolFees, &pool.VolatilityTracker, currentPoint, pool.ActivationPoint)
+	if err != nil {
+		return 0, err
+	}
+	return GetIncludedFeeAmount(tradeFeeNumerator, amount)
+}
+
+// gets the smallest amount that is left with excludedFeeAmount after the trading fee
+// Formula: included = excluded * FEE_DENOMINATOR / (FEE_DENOMINATOR - fee numerator)
+func GetIncludedFeeAmount(tradeFeeNumerator *big.Int, excludedFeeAmount uint64) (uint64, error) {
+	feeDenominator := big.NewInt(common.FeeDenominator)
+	denominator, err := Sub(feeDenominator, tradeFeeNumerator)
+	if err != nil {
+		return 0, err
+	}
+	included, err := MulDiv(new(big.Int).SetUint64(excludedFeeAmount), feeDenominator, denominator, common.Up)
+	if err != nil {
+		return 0, err
+	}
+	return BigToU64(included)
+}
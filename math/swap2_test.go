@@ -0,0 +1,256 @@
+package math_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/math"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+)
+
+// sample pool after a buy of the given quote amount, so sells have liquidity to take
+func tradedSamplePool(t *testing.T, config *common.PoolConfig, quoteIn uint64) *common.Pool {
+	t.Helper()
+	pool := rpctest.SamplePool(solana.PublicKey{1}, solana.PublicKey{2})
+	if quoteIn == 0 {
+		return pool
+	}
+	res, err := math.SwapQuote(pool, config, common.QuoteToBase, quoteIn, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.SqrtPrice = res.NextSqrtPrice
+	pool.QuoteReserve += res.ActualInputAmount
+	pool.BaseReserve -= res.OutputAmount
+	return pool
+}
+
+func TestGetIncludedFeeAmount(t *testing.T) {
+	tests := []struct {
+		name      string
+		numerator int64
+		excluded  uint64
+		want      uint64
+		wantErr   bool
+	}{
+		{name: "no fee", numerator: 0, excluded: 1_000, want: 1_000},
+		{name: "1%", numerator: 10_000_000, excluded: 990, want: 1_000},
+		{name: "rounded up", numerator: 10_000_000, excluded: 1, want: 2},
+		{name: "zero amount", numerator: 10_000_000, excluded: 0, want: 0},
+		{name: "50%", numerator: 500_000_000, excluded: 1_000_000_000, want: 2_000_000_000},
+		{name: "full fee", numerator: common.FeeDenominator, excluded: 1, wantErr: true},
+		{name: "over u64", numerator: 500_000_000, excluded: 1 << 63, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := math.GetIncludedFeeAmount(big.NewInt(tt.numerator), tt.excluded)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %d", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %d, %v, want %d", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestSwapQuote2ExactOutInvertsExactIn(t *testing.T) {
+	tests := []struct {
+		name string
+		// quote bought before the swap, 40 SOL when zero
+		quoteIn        uint64
+		direction      common.TradeDirection
+		collectFeeMode uint8
+		referral       bool
+		amountOut      uint64
+	}{
+		{name: "buy, fees in quote", direction: common.QuoteToBase, amountOut: 10_000_000_000_000},
+		{name: "buy, fees in output", direction: common.QuoteToBase, collectFeeMode: common.CollectFeeModeOutputToken, amountOut: 10_000_000_000_000},
+		{name: "buy with referral", direction: common.QuoteToBase, referral: true, amountOut: 123_456_789},
+		// from 20 SOL raised, crosses from the first curve segment into the second
+		{name: "buy across segments", quoteIn: 20_000_000_000, direction: common.QuoteToBase, amountOut: 150_000_000_000_000},
+		{name: "sell, fees in quote", direction: common.BaseToQuote, amountOut: 5_000_000_000},
+		{name: "sell, fees in output", direction: common.BaseToQuote, collectFeeMode: common.CollectFeeModeOutputToken, amountOut: 5_000_000_000},
+		{name: "sell with referral", direction: common.BaseToQuote, referral: true, amountOut: 1_000_000},
+		{name: "sell across segments", direction: common.BaseToQuote, amountOut: 15_000_000_000},
+	}
+	for _, tt := range tests {
+		config := rpctest.SamplePoolConfig()
+		config.CollectFeeMode = tt.collectFeeMode
+		// past the first segment by default, so sells walk down across it
+		quoteIn := tt.quoteIn
+		if quoteIn == 0 {
+			quoteIn = 40_000_000_000
+		}
+		pool := tradedSamplePool(t, config, quoteIn)
+
+		exactOut, err := math.SwapQuote2(pool, config, tt.direction, common.SwapModeExactOut, tt.amountOut, tt.referral, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if exactOut.OutputAmount != tt.amountOut {
+			t.Errorf("%s: exact out quotes %d out, want %d", tt.name, exactOut.OutputAmount, tt.amountOut)
+		}
+
+		// paying the quoted input gets at least the requested output, one unit less does not
+		exactIn, err := math.SwapQuote2(pool, config, tt.direction, common.SwapModeExactIn, exactOut.InputAmount, tt.referral, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if exactIn.OutputAmount < tt.amountOut {
+			t.Errorf("%s: %d in gets %d out, want at least %d", tt.name, exactOut.InputAmount, exactIn.OutputAmount, tt.amountOut)
+		}
+		less, err := math.SwapQuote2(pool, config, tt.direction, common.SwapModeExactIn, exactOut.InputAmount-1, tt.referral, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if less.OutputAmount >= tt.amountOut {
+			t.Errorf("%s: %d in already gets %d out, the exact out input is not minimal", tt.name, exactOut.InputAmount-1, less.OutputAmount)
+		}
+
+		// exact in moves the price at least as far
+		if exactIn.NextSqrtPrice.Cmp(exactOut.NextSqrtPrice) < 0 && tt.direction == common.QuoteToBase ||
+			exactIn.NextSqrtPrice.Cmp(exactOut.NextSqrtPrice) > 0 && tt.direction == common.BaseToQuote {
+			t.Errorf("%s: exact in moves the price less than exact out", tt.name)
+		}
+		// fees on input are charged on the same input, fees on output on at least the same output
+		feesOnInput := tt.direction == common.QuoteToBase && tt.collectFeeMode == common.CollectFeeModeQuoteToken
+		if diff := int64(exactIn.TradingFee) - int64(exactOut.TradingFee); diff < -1 || (feesOnInput && diff > 1) {
+			t.Errorf("%s: trading fees %d and %d", tt.name, exactIn.TradingFee, exactOut.TradingFee)
+		}
+		if tt.referral != (exactOut.ReferralFee != 0) {
+			t.Errorf("%s: got referral fee %d", tt.name, exactOut.ReferralFee)
+		}
+	}
+}
+
+func TestSwapQuote2PartialFill(t *testing.T) {
+	config := rpctest.SamplePoolConfig()
+	tests := []struct {
+		name      string
+		quoteIn   uint64
+		direction common.TradeDirection
+		amountIn  uint64
+		// whether the pool reaches the migration price and the input is cut
+		filled bool
+	}{
+		{name: "buy below the threshold", quoteIn: 10_000_000_000, direction: common.QuoteToBase, amountIn: 1_000_000_000},
+		{name: "buy past the threshold", quoteIn: 80_000_000_000, direction: common.QuoteToBase, amountIn: 20_000_000_000, filled: true},
+		{name: "buy far past the threshold", direction: common.QuoteToBase, amountIn: 1_000_000_000_000, filled: true},
+		{name: "sell", quoteIn: 10_000_000_000, direction: common.BaseToQuote, amountIn: 1_000_000_000_000},
+	}
+	for _, tt := range tests {
+		pool := tradedSamplePool(t, config, tt.quoteIn)
+		partial, err := math.SwapQuote2(pool, config, tt.direction, common.SwapModePartialFill, tt.amountIn, false, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		if !tt.filled {
+			exactIn, err := math.SwapQuote2(pool, config, tt.direction, common.SwapModeExactIn, tt.amountIn, false, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *partial != *exactIn {
+				t.Errorf("%s: partial fill %+v differs from exact in %+v", tt.name, partial, exactIn)
+			}
+			continue
+		}
+
+		if partial.NextSqrtPrice != config.MigrationSqrtPrice {
+			t.Errorf("%s: stopped at %s, want the migration price", tt.name, partial.NextSqrtPrice)
+		}
+		if partial.InputAmount >= tt.amountIn || partial.ActualInputAmount > partial.InputAmount {
+			t.Errorf("%s: consumed %d (%d on the curve) of %d", tt.name, partial.InputAmount, partial.ActualInputAmount, tt.amountIn)
+		}
+		// the pool ends at the threshold, the fee only applies to the consumed input
+		if raised := pool.QuoteReserve + partial.ActualInputAmount; raised < config.MigrationQuoteThreshold {
+			t.Errorf("%s: raised %d, below the threshold %d", tt.name, raised, config.MigrationQuoteThreshold)
+		}
+		fee := partial.TradingFee + partial.ProtocolFee + partial.ReferralFee
+		if want := partial.InputAmount / 100; fee < want-1 || fee > want+1 {
+			t.Errorf("%s: charged %d fee on %d", tt.name, fee, partial.InputAmount)
+		}
+		// the charged input is the consumed input grossed up by the fee, not capped
+		numerator, err := math.GetTotalTradingFeeNumerator(&config.PoolFees, &pool.VolatilityTracker, 0, pool.ActivationPoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if included, err := math.GetIncludedFeeAmount(numerator, partial.ActualInputAmount); err != nil || partial.InputAmount != included {
+			t.Errorf("%s: charged %d for %d on the curve, want %d", tt.name, partial.InputAmount, partial.ActualInputAmount, included)
+		}
+		// swapping the consumed input exactly gives the same output
+		exactIn, err := math.SwapQuote2(pool, config, tt.direction, common.SwapModeExactIn, partial.InputAmount, false, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := int64(exactIn.OutputAmount) - int64(partial.OutputAmount); diff < 0 || diff > 1 {
+			t.Errorf("%s: exact in of the consumed input gets %d, partial fill %d", tt.name, exactIn.OutputAmount, partial.OutputAmount)
+		}
+	}
+}
+
+// around the input that exactly reaches the migration price, the charged input is the
+// requested one below it and the consumed input grossed up by the fee above it
+func TestSwapQuote2PartialFillBoundary(t *testing.T) {
+	config := rpctest.SamplePoolConfig()
+	pool := tradedSamplePool(t, config, 80_000_000_000)
+	far, err := math.SwapQuote2(pool, config, common.QuoteToBase, common.SwapModePartialFill, 1_000_000_000_000, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	needed := far.InputAmount
+
+	tests := []struct {
+		name      string
+		amountIn  uint64
+		wantInput uint64
+		filled    bool
+	}{
+		{name: "one below", amountIn: needed - 1, wantInput: needed - 1},
+		{name: "exactly", amountIn: needed, wantInput: needed, filled: true},
+		{name: "one above", amountIn: needed + 1, wantInput: needed, filled: true},
+		{name: "far above", amountIn: needed + 1_000, wantInput: needed, filled: true},
+	}
+	for _, tt := range tests {
+		partial, err := math.SwapQuote2(pool, config, common.QuoteToBase, common.SwapModePartialFill, tt.amountIn, false, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if partial.InputAmount != tt.wantInput {
+			t.Errorf("%s: charged %d of %d, want %d", tt.name, partial.InputAmount, tt.amountIn, tt.wantInput)
+		}
+		if filled := partial.NextSqrtPrice == config.MigrationSqrtPrice; filled != tt.filled {
+			t.Errorf("%s: reached the migration price %v, want %v", tt.name, filled, tt.filled)
+		}
+	}
+}
+
+func TestSwapQuote2Errors(t *testing.T) {
+	config := rpctest.SamplePoolConfig()
+	pool := tradedSamplePool(t, config, 10_000_000_000)
+	tests := []struct {
+		name      string
+		direction common.TradeDirection
+		mode      common.SwapMode
+		amount    uint64
+		target    error
+	}{
+		{name: "buy more than the curve holds", direction: common.QuoteToBase, mode: common.SwapModeExactOut,
+			amount: rpctest.SampleSwapBaseAmount, target: math.ErrNotEnoughLiquidity},
+		{name: "sell for more than the reserve", direction: common.BaseToQuote, mode: common.SwapModeExactOut,
+			amount: 20_000_000_000, target: math.ErrNotEnoughLiquidity},
+		{name: "unknown mode", direction: common.QuoteToBase, mode: common.SwapMode(3), amount: 1},
+	}
+	for _, tt := range tests {
+		_, err := math.SwapQuote2(pool, config, tt.direction, tt.mode, tt.amount, false, 0)
+		if err == nil || (tt.target != nil && !errors.Is(err, tt.target)) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.target)
+		}
+	}
+}
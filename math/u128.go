@@ -0,0 +1,91 @@
+package math
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"lukechampine.com/uint128"
+)
+
+// preallocated so the u128 fast paths do not allocate on failure either
+var (
+	errDivisionByZero = errors.New("SafeMath: division by zero")
+	errU128Overflow   = errors.New("SafeMath: value overflows u128")
+	errU64Overflow    = errors.New("SafeMath: value overflows u64")
+	errSubOverflow    = errors.New("SafeMath: subtraction overflow")
+	errShiftOverflow  = errors.New("SafeMath: shift overflow")
+)
+
+// converts uint128.Uint128 to *big.Int
+func U128ToBig(val uint128.Uint128) *big.Int {
+	hi := new(big.Int).SetUint64(val.Hi)
+	lo := new(big.Int).SetUint64(val.Lo)
+	hi.Lsh(hi, 64)
+	return hi.Or(hi, lo)
+}
+
+// converts *big.Int to uint128.Uint128 - return error if val is negative or does not fit in 128 bits
+func BigToU128(val *big.Int) (uint128.Uint128, error) {
+	if val.Sign() < 0 {
+		return uint128.Zero, errors.New("SafeMath: negative value cannot be converted to u128")
+	}
+	if val.BitLen() > 128 {
+		return uint128.Zero, errU128Overflow
+	}
+	lo := new(big.Int).And(val, new(big.Int).SetUint64(^uint64(0))).Uint64()
+	hi := new(big.Int).Rsh(val, 64).Uint64()
+	return uint128.New(lo, hi), nil
+}
+
+// converts *big.Int to uint64 - return error if val is negative or does not fit in 64 bits
+func BigToU64(val *big.Int) (uint64, error) {
+	if val.Sign() < 0 {
+		return 0, errors.New("SafeMath: negative value cannot be converted to u64")
+	}
+	if !val.IsUint64() {
+		return 0, errU64Overflow
+	}
+	return val.Uint64(), nil
+}
+
+// (x * y) / denominator with rounding, computed without heap allocations
+func MulDivU128(x, y, denominator uint128.Uint128, round common.Rounding) (uint128.Uint128, error) {
+	if denominator.IsZero() {
+		return uint128.Zero, errDivisionByZero
+	}
+
+	prod := mul128(x, y)
+	quotient, remainder := prod.quoRem128(denominator)
+	if round == common.Up && !remainder.IsZero() {
+		quotient = quotient.add64(1)
+	}
+	return quotient.toU128()
+}
+
+// (x * y) >> offset with rounding, computed without heap allocations
+func MulShrU128(x, y uint128.Uint128, offset uint, round common.Rounding) (uint128.Uint128, error) {
+	prod := mul128(x, y)
+	result := prod.shr(offset)
+	if round == common.Up && !prod.lowBitsZero(offset) {
+		result = result.add64(1)
+	}
+	return result.toU128()
+}
+
+// (x << offset) / y with rounding, computed without heap allocations
+func ShlDivU128(x, y uint128.Uint128, offset uint, round common.Rounding) (uint128.Uint128, error) {
+	if y.IsZero() {
+		return uint128.Zero, errDivisionByZero
+	}
+	if offset > 128 {
+		return uint128.Zero, errShiftOverflow
+	}
+
+	numerator := u256{x.Lo, x.Hi, 0, 0}.shl(offset)
+	quotient, remainder := numerator.quoRem128(y)
+	if round == common.Up && !remainder.IsZero() {
+		quotient = quotient.add64(1)
+	}
+	return quotient.toU128()
+}
@@ -0,0 +1,171 @@
+package math_test
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"lukechampine.com/uint128"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/math"
+)
+
+var maxU128 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+
+func TestU128Conversions(t *testing.T) {
+	tests := []struct {
+		name  string
+		value *big.Int
+		valid bool
+	}{
+		{name: "zero", value: big.NewInt(0), valid: true},
+		{name: "u64 max", value: new(big.Int).SetUint64(^uint64(0)), valid: true},
+		{name: "2^64", value: new(big.Int).Lsh(big.NewInt(1), 64), valid: true},
+		{name: "u128 max", value: maxU128, valid: true},
+		{name: "2^128", value: new(big.Int).Add(maxU128, big.NewInt(1))},
+		{name: "negative", value: big.NewInt(-1)},
+	}
+	for _, tt := range tests {
+		got, err := math.BigToU128(tt.value)
+		if !tt.valid {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if back := math.U128ToBig(got); back.Cmp(tt.value) != 0 {
+			t.Errorf("%s: got %s back", tt.name, back)
+		}
+	}
+}
+
+func TestBigToU64(t *testing.T) {
+	tests := []struct {
+		name  string
+		value *big.Int
+		want  uint64
+		valid bool
+	}{
+		{name: "zero", value: big.NewInt(0), valid: true},
+		{name: "u64 max", value: new(big.Int).SetUint64(^uint64(0)), want: ^uint64(0), valid: true},
+		{name: "2^64", value: new(big.Int).Lsh(big.NewInt(1), 64)},
+		{name: "negative", value: big.NewInt(-1)},
+	}
+	for _, tt := range tests {
+		got, err := math.BigToU64(tt.value)
+		if tt.valid != (err == nil) {
+			t.Errorf("%s: got error %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// u128 of a random bit length, so both small and full-width operands are common
+func randomU128(rng *rand.Rand) *big.Int {
+	bits := rng.Intn(129)
+	if bits == 0 {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+}
+
+func randomRounding(rng *rand.Rand) common.Rounding {
+	if rng.Intn(2) == 0 {
+		return common.Up
+	}
+	return common.Down
+}
+
+// reference result: nil when it does not fit in a u128
+func fitU128(value *big.Int, err error) *big.Int {
+	if err != nil || value.Sign() < 0 || value.Cmp(maxU128) > 0 {
+		return nil
+	}
+	return value
+}
+
+func roundedShr(value *big.Int, offset uint, round common.Rounding) *big.Int {
+	result := new(big.Int).Rsh(value, offset)
+	if round == common.Up && new(big.Int).Lsh(result, offset).Cmp(value) != 0 {
+		result.Add(result, big.NewInt(1))
+	}
+	return result
+}
+
+func checkU128(t *testing.T, name string, want *big.Int, got uint128.Uint128, err error) {
+	t.Helper()
+	if want == nil {
+		if err == nil {
+			t.Fatalf("%s: got %s, want an error", name, math.U128ToBig(got))
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("%s: %v, want %s", name, err, want)
+	}
+	if math.U128ToBig(got).Cmp(want) != 0 {
+		t.Fatalf("%s: got %s, want %s", name, math.U128ToBig(got), want)
+	}
+}
+
+func mustU128(value *big.Int) uint128.Uint128 {
+	result, err := math.BigToU128(value)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+func TestU128MatchesBig(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20_000; i++ {
+		x, y, z := randomU128(rng), randomU128(rng), randomU128(rng)
+		round := randomRounding(rng)
+		offset := uint(rng.Intn(130))
+
+		want, err := math.MulDiv(x, y, z, round)
+		got, gotErr := math.MulDivU128(mustU128(x), mustU128(y), mustU128(z), round)
+		checkU128(t, "MulDivU128", fitU128(want, err), got, gotErr)
+
+		want = roundedShr(math.Mul(x, y), offset, round)
+		got, gotErr = math.MulShrU128(mustU128(x), mustU128(y), offset, round)
+		checkU128(t, "MulShrU128", fitU128(want, nil), got, gotErr)
+
+		var wantShlDiv *big.Int
+		if offset <= 128 {
+			wantShlDiv = fitU128(math.MulDiv(math.Shl(x, offset), big.NewInt(1), z, round))
+		}
+		got, gotErr = math.ShlDivU128(mustU128(x), mustU128(z), offset, round)
+		checkU128(t, "ShlDivU128", wantShlDiv, got, gotErr)
+
+		lower, upper := x, y
+		if rng.Intn(8) != 0 && lower.Cmp(upper) > 0 {
+			lower, upper = upper, lower
+		}
+		want, err = math.GetDeltaAmountQuoteUnsigned(lower, upper, z, round)
+		got, gotErr = math.GetDeltaAmountQuoteUnsignedU128(mustU128(lower), mustU128(upper), mustU128(z), round)
+		checkU128(t, "GetDeltaAmountQuoteUnsignedU128", fitU128(want, err), got, gotErr)
+	}
+}
+
+func TestU128DoesNotAllocate(t *testing.T) {
+	x := mustU128(new(big.Int).Lsh(big.NewInt(3), 100))
+	y := uint128.From64(1_000_000_007)
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = math.MulDivU128(x, y, y.Add64(1), common.Up)
+		_, _ = math.MulShrU128(x, y, 64, common.Up)
+		_, _ = math.ShlDivU128(y, x, 128, common.Up)
+		_, _ = math.MulDivU128(x, x, uint128.Zero, common.Down)
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocations", allocs)
+	}
+}
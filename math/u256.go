@@ -0,0 +1,211 @@
+package math
+
+import (
+	"math/bits"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"lukechampine.com/uint128"
+)
+
+// 256-bit unsigned integer, little-endian 64-bit limbs
+type u256 [4]uint64
+
+// full 256-bit product of two u128 values
+func mul128(x, y uint128.Uint128) u256 {
+	var z u256
+	var carry uint64
+
+	// x.Lo * y
+	h0, l0 := bits.Mul64(x.Lo, y.Lo)
+	h1, l1 := bits.Mul64(x.Lo, y.Hi)
+	z[0] = l0
+	z[1], carry = bits.Add64(h0, l1, 0)
+	z[2] = h1 + carry
+
+	// x.Hi * y, shifted by one limb
+	h2, l2 := bits.Mul64(x.Hi, y.Lo)
+	h3, l3 := bits.Mul64(x.Hi, y.Hi)
+	z[1], carry = bits.Add64(z[1], l2, 0)
+	z[2], carry = bits.Add64(z[2], h2, carry)
+	z[3] = h3 + carry
+	z[2], carry = bits.Add64(z[2], l3, 0)
+	z[3] += carry
+
+	return z
+}
+
+func u256FromU128(x uint128.Uint128) u256 {
+	return u256{x.Lo, x.Hi, 0, 0}
+}
+
+func (z u256) isZero() bool {
+	return z[0]|z[1]|z[2]|z[3] == 0
+}
+
+func (z u256) bitLen() int {
+	for i := 3; i >= 0; i-- {
+		if z[i] != 0 {
+			return i*64 + bits.Len64(z[i])
+		}
+	}
+	return 0
+}
+
+func (z u256) shr(n uint) u256 {
+	if n >= 256 {
+		return u256{}
+	}
+	limbs, rem := n/64, n%64
+	var r u256
+	for i := 0; i+int(limbs) < 4; i++ {
+		r[i] = z[i+int(limbs)] >> rem
+		if rem != 0 && i+int(limbs)+1 < 4 {
+			r[i] |= z[i+int(limbs)+1] << (64 - rem)
+		}
+	}
+	return r
+}
+
+func (z u256) shl(n uint) u256 {
+	if n >= 256 {
+		return u256{}
+	}
+	limbs, rem := n/64, n%64
+	var r u256
+	for i := 3; i >= int(limbs); i-- {
+		r[i] = z[i-int(limbs)] << rem
+		if rem != 0 && i-int(limbs)-1 >= 0 {
+			r[i] |= z[i-int(limbs)-1] >> (64 - rem)
+		}
+	}
+	return r
+}
+
+// reports whether the lowest n bits are all zero
+func (z u256) lowBitsZero(n uint) bool {
+	if n >= 256 {
+		return z.isZero()
+	}
+	return z.shl(256 - n).isZero()
+}
+
+func (z u256) add64(v uint64) u256 {
+	var carry uint64
+	z[0], carry = bits.Add64(z[0], v, 0)
+	z[1], carry = bits.Add64(z[1], 0, carry)
+	z[2], carry = bits.Add64(z[2], 0, carry)
+	z[3] += carry
+	return z
+}
+
+func (z u256) cmp(y u256) int {
+	for i := 3; i >= 0; i-- {
+		if z[i] != y[i] {
+			if z[i] > y[i] {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
+
+// returns z + y and whether the sum overflowed 256 bits
+func (z u256) add(y u256) (u256, bool) {
+	var carry uint64
+	z[0], carry = bits.Add64(z[0], y[0], 0)
+	z[1], carry = bits.Add64(z[1], y[1], carry)
+	z[2], carry = bits.Add64(z[2], y[2], carry)
+	z[3], carry = bits.Add64(z[3], y[3], carry)
+	return z, carry != 0
+}
+
+// returns z - y, wrapping on underflow
+func (z u256) sub(y u256) u256 {
+	var borrow uint64
+	z[0], borrow = bits.Sub64(z[0], y[0], 0)
+	z[1], borrow = bits.Sub64(z[1], y[1], borrow)
+	z[2], borrow = bits.Sub64(z[2], y[2], borrow)
+	z[3], _ = bits.Sub64(z[3], y[3], borrow)
+	return z
+}
+
+func (z u256) toU64() (uint64, error) {
+	if z[1] != 0 || z[2] != 0 || z[3] != 0 {
+		return 0, errU64Overflow
+	}
+	return z[0], nil
+}
+
+func (z u256) toU128() (uint128.Uint128, error) {
+	if z[2] != 0 || z[3] != 0 {
+		return uint128.Zero, errU128Overflow
+	}
+	return uint128.New(z[0], z[1]), nil
+}
+
+// divides z by a non-zero u128, returning the quotient and remainder
+func (z u256) quoRem128(d uint128.Uint128) (u256, uint128.Uint128) {
+	// fast path: 64-bit divisor, one hardware division per limb
+	if d.Hi == 0 {
+		var q u256
+		var r uint64
+		for i := 3; i >= 0; i-- {
+			q[i], r = bits.Div64(r, z[i], d.Lo)
+		}
+		return q, uint128.From64(r)
+	}
+
+	// shift-subtract long division over the significant bits of z
+	var q u256
+	var r uint128.Uint128
+	for i := z.bitLen() - 1; i >= 0; i-- {
+		overflow := r.Hi>>63 != 0
+		r = r.Lsh(1)
+		r.Lo |= (z[i/64] >> (uint(i) % 64)) & 1
+		if overflow || r.Cmp(d) >= 0 {
+			r = r.SubWrap(d)
+			q[i/64] |= 1 << (uint(i) % 64)
+		}
+	}
+	return q, r
+}
+
+// divides z by a non-zero u256, returning the quotient and remainder
+func (z u256) quoRem(d u256) (u256, u256) {
+	if d[2] == 0 && d[3] == 0 {
+		q, r := z.quoRem128(uint128.New(d[0], d[1]))
+		return q, u256FromU128(r)
+	}
+	if z.cmp(d) < 0 {
+		return u256{}, z
+	}
+
+	// the top bits of z below the length of d are already smaller than d
+	shift := uint(z.bitLen() - d.bitLen() + 1)
+	r := z.shr(shift)
+
+	var q u256
+	for i := int(shift) - 1; i >= 0; i-- {
+		overflow := r[3]>>63 != 0
+		r = r.shl(1)
+		r[0] |= (z[i/64] >> (uint(i) % 64)) & 1
+		if overflow || r.cmp(d) >= 0 {
+			r = r.sub(d)
+			q[i/64] |= 1 << (uint(i) % 64)
+		}
+	}
+	return q, r
+}
+
+// (x * y) / denominator with rounding over 256 bits
+func mulDiv256(x, y uint128.Uint128, denominator u256, round common.Rounding) (u256, error) {
+	if denominator.isZero() {
+		return u256{}, errDivisionByZero
+	}
+	quotient, remainder := mul128(x, y).quoRem(denominator)
+	if round == common.Up && !remainder.isZero() {
+		quotient = quotient.add64(1)
+	}
+	return quotient, nil
+}
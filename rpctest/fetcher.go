@@ -0,0 +1,183 @@
+package rpctest
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+)
+
+var (
+	_ instructions.AccountFetcher          = (*AccountFetcher)(nil)
+	_ instructions.MultipleAccountsFetcher = (*AccountFetcher)(nil)
+)
+
+// In-memory instructions.AccountFetcher loaded with account fixtures, so code built
+// on the fetch functions runs without a network. Missing accounts return
+// solRpc.ErrNotFound like the rpc client does.
+type AccountFetcher struct {
+	mu       sync.RWMutex
+	slot     uint64
+	accounts map[solana.PublicKey]*solRpc.Account
+	errs     map[solana.PublicKey]error
+	// owner of the accounts stored by SetPool and SetPoolConfig
+	dbcProgramID solana.PublicKey
+}
+
+func NewAccountFetcher() *AccountFetcher {
+	return NewProgramAccountFetcher(solana.MustPublicKeyFromBase58(common.DbcProgramID))
+}
+
+// Creates a fetcher whose pools and configs are owned by the given DBC deployment
+func NewProgramAccountFetcher(dbcProgramID solana.PublicKey) *AccountFetcher {
+	return &AccountFetcher{
+		dbcProgramID: dbcProgramID,
+		accounts:     make(map[solana.PublicKey]*solRpc.Account),
+		errs:         make(map[solana.PublicKey]error),
+	}
+}
+
+func (f *AccountFetcher) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*solRpc.GetAccountInfoResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err, ok := f.errs[account]; ok {
+		return nil, err
+	}
+	stored, ok := f.accounts[account]
+	if !ok {
+		return nil, solRpc.ErrNotFound
+	}
+
+	// callers get their own copy of the data
+	value := *stored
+	value.Data = solRpc.DataBytesOrJSONFromBytes(append([]byte(nil), stored.Data.GetBinary()...))
+	return &solRpc.GetAccountInfoResult{
+		RPCContext: solRpc.RPCContext{Context: solRpc.Context{Slot: f.slot}},
+		Value:      &value,
+	}, nil
+}
+
+// Gets the accounts in order, nil for missing ones like the rpc client
+func (f *AccountFetcher) GetMultipleAccounts(ctx context.Context, accounts ...solana.PublicKey) (*solRpc.GetMultipleAccountsResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	res := &solRpc.GetMultipleAccountsResult{
+		RPCContext: solRpc.RPCContext{Context: solRpc.Context{Slot: f.slot}},
+		Value:      make([]*solRpc.Account, len(accounts)),
+	}
+	for i, account := range accounts {
+		if err, ok := f.errs[account]; ok {
+			return nil, err
+		}
+		stored, ok := f.accounts[account]
+		if !ok {
+			continue
+		}
+		value := *stored
+		value.Data = solRpc.DataBytesOrJSONFromBytes(append([]byte(nil), stored.Data.GetBinary()...))
+		res.Value[i] = &value
+	}
+	return res, nil
+}
+
+// Sets the slot reported with every account
+func (f *AccountFetcher) SetSlot(slot uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.slot = slot
+}
+
+// Stores the raw account data owned by the program
+func (f *AccountFetcher) SetAccount(address, owner solana.PublicKey, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.accounts[address] = &solRpc.Account{
+		Owner: owner,
+		Data:  solRpc.DataBytesOrJSONFromBytes(append([]byte(nil), data...)),
+	}
+	delete(f.errs, address)
+}
+
+// Stores the pool as a DBC pool account
+func (f *AccountFetcher) SetPool(address solana.PublicKey, pool *common.Pool) error {
+	data, err := EncodeAccount(instructions.PoolAccountDiscriminator, pool)
+	if err != nil {
+		return err
+	}
+	f.SetAccount(address, f.dbcProgramID, data)
+	return nil
+}
+
+// Stores the config as a DBC pool config account
+func (f *AccountFetcher) SetPoolConfig(address solana.PublicKey, config *common.PoolConfig) error {
+	data, err := EncodeAccount(instructions.PoolConfigAccountDiscriminator, config)
+	if err != nil {
+		return err
+	}
+	f.SetAccount(address, f.dbcProgramID, data)
+	return nil
+}
+
+// Stores an initialized SPL token account holding amount of the mint
+func (f *AccountFetcher) SetTokenAccount(address, mint, owner solana.PublicKey, amount uint64) {
+	f.SetAccount(address, solana.TokenProgramID, EncodeTokenAccount(mint, owner, amount))
+}
+
+// Makes every fetch of the account fail with err, e.g. to test rpc failures
+func (f *AccountFetcher) SetError(address solana.PublicKey, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errs[address] = err
+}
+
+// Removes the account and any error set for it
+func (f *AccountFetcher) Delete(address solana.PublicKey) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.accounts, address)
+	delete(f.errs, address)
+}
+
+// Encodes an initialized SPL token account: mint, owner, amount, no delegate, the
+// initialized state and no close authority
+func EncodeTokenAccount(mint, owner solana.PublicKey, amount uint64) []byte {
+	data := make([]byte, tokenAccountSize)
+	copy(data[0:32], mint[:])
+	copy(data[32:64], owner[:])
+	binary.LittleEndian.PutUint64(data[64:72], amount)
+	data[108] = tokenAccountStateInitialized
+	return data
+}
+
+const (
+	tokenAccountSize             = 165
+	tokenAccountStateInitialized = 1
+)
+
+// Encodes account state the way the program lays it out: the anchor discriminator
+// followed by the fields in declaration order, little endian, without padding
+func EncodeAccount(discriminator [8]byte, state interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(discriminator[:])
+	if err := binary.Write(&buf, binary.LittleEndian, state); err != nil {
+		return nil, fmt.Errorf("failed to encode account: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,99 @@
+package rpctest_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+)
+
+func TestAccountFetcher(t *testing.T) {
+	fetcher := rpctest.NewAccountFetcher()
+	fetcher.SetSlot(7)
+	stored := solana.NewWallet().PublicKey()
+	failing := solana.NewWallet().PublicKey()
+	missing := solana.NewWallet().PublicKey()
+	owner := solana.NewWallet().PublicKey()
+	nodeErr := errors.New("node is behind")
+	fetcher.SetAccount(stored, owner, []byte{1, 2, 3})
+	fetcher.SetError(failing, nodeErr)
+
+	tests := []struct {
+		name    string
+		address solana.PublicKey
+		want    []byte
+		target  error
+	}{
+		{name: "stored", address: stored, want: []byte{1, 2, 3}},
+		{name: "missing", address: missing, target: solRpc.ErrNotFound},
+		{name: "failing", address: failing, target: nodeErr},
+	}
+	for _, tt := range tests {
+		res, err := fetcher.GetAccountInfo(context.Background(), tt.address)
+		if tt.target != nil {
+			if !errors.Is(err, tt.target) {
+				t.Errorf("%s: got %v, want %v", tt.name, err, tt.target)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if res.Context.Slot != 7 || !res.Value.Owner.Equals(owner) || string(res.Value.Data.GetBinary()) != string(tt.want) {
+			t.Errorf("%s: got %+v", tt.name, res.Value)
+		}
+		// callers get a copy of the data
+		res.Value.Data.GetBinary()[0] = 9
+	}
+
+	res, err := fetcher.GetMultipleAccounts(context.Background(), missing, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Value) != 2 || res.Value[0] != nil || res.Value[1].Data.GetBinary()[0] != 1 {
+		t.Fatalf("got %+v", res.Value)
+	}
+	if _, err := fetcher.GetMultipleAccounts(context.Background(), stored, failing); !errors.Is(err, nodeErr) {
+		t.Fatalf("got %v, want %v", err, nodeErr)
+	}
+
+	// deleting clears the error as well as the account
+	fetcher.Delete(failing)
+	if _, err := fetcher.GetAccountInfo(context.Background(), failing); !errors.Is(err, solRpc.ErrNotFound) {
+		t.Fatalf("got %v, want %v", err, solRpc.ErrNotFound)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fetcher.GetAccountInfo(ctx, stored); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestSetTokenAccount(t *testing.T) {
+	fetcher := rpctest.NewAccountFetcher()
+	address := solana.NewWallet().PublicKey()
+	mint := solana.NewWallet().PublicKey()
+	owner := solana.NewWallet().PublicKey()
+	fetcher.SetTokenAccount(address, mint, owner, 42)
+
+	res, err := fetcher.GetAccountInfo(context.Background(), address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Value.Owner.Equals(solana.TokenProgramID) {
+		t.Fatalf("token account owned by %s", res.Value.Owner)
+	}
+	// SPL token account layout: mint, owner, amount, delegate option and delegate, state
+	data := res.Value.Data.GetBinary()
+	if len(data) != 165 || !solana.PublicKeyFromBytes(data[0:32]).Equals(mint) ||
+		!solana.PublicKeyFromBytes(data[32:64]).Equals(owner) ||
+		binary.LittleEndian.Uint64(data[64:72]) != 42 || data[108] != 1 {
+		t.Fatalf("got token account %x", data)
+	}
+}
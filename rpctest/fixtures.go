@@ -0,0 +1,89 @@
+package rpctest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Account dumped by `solana account <address> --output json`
+type AccountDump struct {
+	Pubkey   solana.PublicKey
+	Owner    solana.PublicKey
+	Lamports uint64
+	Data     []byte
+}
+
+type accountDumpJSON struct {
+	Pubkey  solana.PublicKey `json:"pubkey"`
+	Account struct {
+		Lamports uint64           `json:"lamports"`
+		Data     []string         `json:"data"`
+		Owner    solana.PublicKey `json:"owner"`
+	} `json:"account"`
+}
+
+// Reads an account dump of `solana account <address> --output json`
+func LoadAccountDump(path string) (*AccountDump, error) {
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	return decodeAccountDump(path, encoded)
+}
+
+func decodeAccountDump(path string, encoded []byte) (*AccountDump, error) {
+	var dump accountDumpJSON
+	if err := json.Unmarshal(encoded, &dump); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture %s: %w", path, err)
+	}
+	if len(dump.Account.Data) != 2 || dump.Account.Data[1] != "base64" {
+		return nil, fmt.Errorf("failed to decode fixture %s: data is not base64 encoded", path)
+	}
+	data, err := base64.StdEncoding.DecodeString(dump.Account.Data[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode fixture %s: %w", path, err)
+	}
+	return &AccountDump{
+		Pubkey:   dump.Pubkey,
+		Owner:    dump.Account.Owner,
+		Lamports: dump.Account.Lamports,
+		Data:     data,
+	}, nil
+}
+
+// Reads the data of an account dump, either a `solana account <address> --output json`
+// dump or its base64 data field alone; surrounding whitespace is ignored
+func LoadAccountFixture(path string) ([]byte, error) {
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	encoded = bytes.TrimSpace(encoded)
+	if bytes.HasPrefix(encoded, []byte("{")) {
+		dump, err := decodeAccountDump(path, encoded)
+		if err != nil {
+			return nil, err
+		}
+		return dump.Data, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode fixture %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// Stores the account dump at path, see LoadAccountFixture
+func (f *AccountFetcher) SetAccountFromFixture(address, owner solana.PublicKey, path string) error {
+	data, err := LoadAccountFixture(path)
+	if err != nil {
+		return err
+	}
+	f.SetAccount(address, owner, data)
+	return nil
+}
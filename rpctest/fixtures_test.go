@@ -0,0 +1,64 @@
+package rpctest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+)
+
+func TestLoadAccountFixture(t *testing.T) {
+	dump := `{
+  "pubkey": "GqpxrPjRkSWa5BNuMufYHw67SmQQi4m6sUshovLLs4ia",
+  "account": {
+    "lamports": 1176000,
+    "data": ["AQID", "base64"],
+    "owner": "dbcij3LWUppWqq96dh6gJWwBifmcGfLSB5D4DuSMaqN",
+    "executable": false,
+    "rentEpoch": 18446744073709551615,
+    "space": 3
+  }
+}`
+	tests := []struct {
+		name    string
+		content string
+		want    []byte
+		wantErr bool
+	}{
+		{name: "json dump", content: dump, want: []byte{1, 2, 3}},
+		{name: "base64 data", content: "AQID\n", want: []byte{1, 2, 3}},
+		{name: "base58 data", content: `{"account": {"data": ["Ldp", "base58"]}}`, wantErr: true},
+		{name: "invalid json", content: `{"account": `, wantErr: true},
+		{name: "invalid base64", content: "AQI!", wantErr: true},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := filepath.Join(dir, "account")
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := rpctest.LoadAccountFixture(path)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %v", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || string(got) != string(tt.want) {
+			t.Errorf("%s: got %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+
+	path := filepath.Join(dir, "dump.json")
+	if err := os.WriteFile(path, []byte(dump), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	account, err := rpctest.LoadAccountDump(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Pubkey.String() != "GqpxrPjRkSWa5BNuMufYHw67SmQQi4m6sUshovLLs4ia" || account.Owner.String() != "dbcij3LWUppWqq96dh6gJWwBifmcGfLSB5D4DuSMaqN" || account.Lamports != 1_176_000 {
+		t.Errorf("got dump of %s owned by %s with %d lamports", account.Pubkey, account.Owner, account.Lamports)
+	}
+}
@@ -0,0 +1,174 @@
+package rpctest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/Luigi-1Combo/dbc-go/stream"
+)
+
+// capacity of the update queue of each stream
+const DefaultGeyserBufferSize = 64
+
+// In-process geyser feed streaming the accounts of an AccountFetcher. Dial is a
+// stream.GeyserDialer; Publish sends the current state of an account to every open
+// stream subscribed to it. GeyserServer serves the feed over gRPC.
+type GeyserFeed struct {
+	Fetcher *AccountFetcher
+
+	mu      sync.Mutex
+	streams map[*geyserStream]struct{}
+	dials   int
+	dialErr error
+}
+
+var _ stream.GeyserDialer = (*GeyserFeed)(nil).Dial
+
+type geyserStream struct {
+	feed     *GeyserFeed
+	accounts map[solana.PublicKey]bool
+	updates  chan *stream.GeyserAccountUpdate
+	closed   chan struct{}
+	once     sync.Once
+}
+
+func NewGeyserFeed(fetcher *AccountFetcher) *GeyserFeed {
+	return &GeyserFeed{
+		Fetcher: fetcher,
+		streams: make(map[*geyserStream]struct{}),
+	}
+}
+
+// Opens a stream of the accounts, failing with the error set by SetDialError
+func (g *GeyserFeed) Dial(ctx context.Context, accounts []solana.PublicKey) (stream.GeyserStream, error) {
+	s, err := g.open(ctx, accounts)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (g *GeyserFeed) open(ctx context.Context, accounts []solana.PublicKey) (*geyserStream, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.dials++
+	if g.dialErr != nil {
+		return nil, g.dialErr
+	}
+
+	s := &geyserStream{
+		feed:     g,
+		accounts: make(map[solana.PublicKey]bool, len(accounts)),
+		updates:  make(chan *stream.GeyserAccountUpdate, DefaultGeyserBufferSize),
+		closed:   make(chan struct{}),
+	}
+	for _, account := range accounts {
+		s.accounts[account] = true
+	}
+	g.streams[s] = struct{}{}
+	return s, nil
+}
+
+// Makes every following Dial fail with err, nil to accept dials again
+func (g *GeyserFeed) SetDialError(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.dialErr = err
+}
+
+// Number of Dial calls, failed ones included
+func (g *GeyserFeed) Dials() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.dials
+}
+
+// Number of open streams
+func (g *GeyserFeed) Streams() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.streams)
+}
+
+// Waits until at least n streams are open
+func (g *GeyserFeed) WaitStreams(ctx context.Context, n int) error {
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for g.Streams() < n {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to wait for %d geyser streams, have %d: %w", n, g.Streams(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Sends the current state of the account to the streams subscribed to it
+func (g *GeyserFeed) Publish(address solana.PublicKey) error {
+	res, err := g.Fetcher.GetAccountInfo(context.Background(), address)
+	if err != nil {
+		return fmt.Errorf("failed to get account %s: %w", address, err)
+	}
+	update := &stream.GeyserAccountUpdate{
+		Pubkey: address,
+		Slot:   res.Context.Slot,
+		Data:   res.Value.Data.GetBinary(),
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for s := range g.streams {
+		if !s.accounts[address] {
+			continue
+		}
+		select {
+		case s.updates <- update:
+		default:
+			return fmt.Errorf("geyser stream buffer full")
+		}
+	}
+	return nil
+}
+
+// Ends every open stream, as a dropped gRPC connection would
+func (g *GeyserFeed) DropStreams() {
+	g.mu.Lock()
+	streams := make([]*geyserStream, 0, len(g.streams))
+	for s := range g.streams {
+		streams = append(streams, s)
+	}
+	g.mu.Unlock()
+
+	for _, s := range streams {
+		s.Close()
+	}
+}
+
+func (s *geyserStream) Recv() (*stream.GeyserAccountUpdate, error) {
+	select {
+	case update := <-s.updates:
+		return update, nil
+	case <-s.closed:
+		return nil, io.EOF
+	}
+}
+
+func (s *geyserStream) Close() error {
+	s.once.Do(func() {
+		s.feed.mu.Lock()
+		delete(s.feed.streams, s)
+		s.feed.mu.Unlock()
+		close(s.closed)
+	})
+	return nil
+}
@@ -0,0 +1,230 @@
+package rpctest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/Luigi-1Combo/dbc-go/geyser"
+)
+
+const geyserListenerBufferSize = 1 << 20
+
+// In-process Yellowstone gRPC endpoint serving the streams of a GeyserFeed over a
+// bufconn listener, so geyser.Client runs its transport against fixtures. Subscribe
+// calls open a feed stream of the requested accounts; filters of later requests are
+// recorded but not applied.
+type GeyserServer struct {
+	Feed *GeyserFeed
+	// x-token required from the clients, none when empty
+	Token string
+
+	listener *bufconn.Listener
+	server   *grpc.Server
+
+	mu       sync.Mutex
+	requests []*geyser.SubscribeRequest
+	pings    map[chan struct{}]struct{}
+}
+
+var geyserServiceDesc = grpc.ServiceDesc{
+	ServiceName: "geyser.Geyser",
+	HandlerType: (*interface{})(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName: geyser.SubscribeStreamDesc.StreamName,
+		Handler: func(srv interface{}, serverStream grpc.ServerStream) error {
+			return srv.(*GeyserServer).subscribe(serverStream)
+		},
+		ServerStreams: true,
+		ClientStreams: true,
+	}},
+}
+
+func NewGeyserServer(feed *GeyserFeed, token string) *GeyserServer {
+	s := &GeyserServer{
+		Feed:     feed,
+		Token:    token,
+		listener: bufconn.Listen(geyserListenerBufferSize),
+		pings:    make(map[chan struct{}]struct{}),
+	}
+	s.server = grpc.NewServer(
+		grpc.ForceServerCodec(geyser.Codec{}),
+		// the client pings the connection more often than the grpc default allows
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{MinTime: time.Second, PermitWithoutStream: true}),
+	)
+	s.server.RegisterService(&geyserServiceDesc, s)
+	go s.server.Serve(s.listener)
+	return s
+}
+
+// target to pass to geyser.NewClient along with DialOptions
+func (s *GeyserServer) Target() string {
+	return "passthrough:///bufnet"
+}
+
+// Dial options connecting a client to the in-process listener
+func (s *GeyserServer) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.listener.DialContext(ctx)
+		}),
+	}
+}
+
+func (s *GeyserServer) Close() {
+	s.server.Stop()
+}
+
+// Requests received so far, subscriptions and pings
+func (s *GeyserServer) Requests() []*geyser.SubscribeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*geyser.SubscribeRequest(nil), s.requests...)
+}
+
+// Number of ping requests received so far
+func (s *GeyserServer) Pings() int {
+	pings := 0
+	for _, req := range s.Requests() {
+		if req.Ping != nil {
+			pings++
+		}
+	}
+	return pings
+}
+
+// Sends a ping update on every open stream, as endpoints do on idle streams
+func (s *GeyserServer) Ping() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ping := range s.pings {
+		select {
+		case ping <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *GeyserServer) record(req *geyser.SubscribeRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+}
+
+func (s *GeyserServer) subscribe(serverStream grpc.ServerStream) error {
+	ctx := serverStream.Context()
+	if s.Token != "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if tokens := md.Get("x-token"); len(tokens) != 1 || tokens[0] != s.Token {
+			return status.Error(codes.Unauthenticated, "invalid x-token")
+		}
+	}
+
+	req := new(geyser.SubscribeRequest)
+	if err := serverStream.RecvMsg(req); err != nil {
+		return err
+	}
+	s.record(req)
+	filters, accounts, err := subscribedAccounts(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	feedStream, err := s.Feed.open(ctx, accounts)
+	if err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	defer feedStream.Close()
+	if err := serverStream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	ping := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.pings[ping] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.pings, ping)
+		s.mu.Unlock()
+	}()
+
+	// later requests are read alongside the updates, pings are answered with a pong
+	requests := make(chan *geyser.SubscribeRequest)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req := new(geyser.SubscribeRequest)
+			if err := serverStream.RecvMsg(req); err != nil {
+				recvErr <- err
+				return
+			}
+			s.record(req)
+			select {
+			case requests <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		var update *geyser.SubscribeUpdate
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-feedStream.closed:
+			return status.Error(codes.Unavailable, "geyser stream dropped")
+		case err := <-recvErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case <-ping:
+			update = &geyser.SubscribeUpdate{Ping: &geyser.SubscribeUpdatePing{}}
+		case req := <-requests:
+			if req.Ping == nil {
+				continue
+			}
+			update = &geyser.SubscribeUpdate{Pong: &geyser.SubscribeUpdatePong{ID: req.Ping.ID}}
+		case account := <-feedStream.updates:
+			update = &geyser.SubscribeUpdate{
+				Filters: filters,
+				Account: &geyser.SubscribeUpdateAccount{
+					Account: &geyser.SubscribeUpdateAccountInfo{Pubkey: account.Pubkey.Bytes(), Data: account.Data},
+					Slot:    account.Slot,
+				},
+			}
+		}
+		if err := serverStream.SendMsg(update); err != nil {
+			return err
+		}
+	}
+}
+
+// names and accounts of the account filters of the request
+func subscribedAccounts(req *geyser.SubscribeRequest) ([]string, []solana.PublicKey, error) {
+	var filters []string
+	var accounts []solana.PublicKey
+	for name, filter := range req.Accounts {
+		filters = append(filters, name)
+		for _, address := range filter.Account {
+			account, err := solana.PublicKeyFromBase58(address)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid account %q: %w", address, err)
+			}
+			accounts = append(accounts, account)
+		}
+	}
+	return filters, accounts, nil
+}
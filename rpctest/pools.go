@@ -0,0 +1,70 @@
+package rpctest
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"lukechampine.com/uint128"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+)
+
+// Sample launch curve: a 6-decimal token against SOL migrating at 85 SOL, with a 1%
+// fee collected in quote, 20% protocol and 20% referral shares and slot activation.
+// The curve has two segments, raising 30 SOL from the start price to twice it and
+// 55 SOL from there to the migration price, four times the start price.
+var (
+	SampleSqrtStartPrice     = uint128.From64(97_600_000_000_000_000)
+	SampleSqrtMiddlePrice    = uint128.From64(195_200_000_000_000_000)
+	SampleSqrtMigrationPrice = uint128.From64(390_400_000_000_000_000)
+)
+
+const (
+	SampleMigrationQuoteThreshold = 85_000_000_000
+	SampleSwapBaseAmount          = 781_426_025_471_513
+	SampleMigrationBaseThreshold  = 189_774_891_900_224
+	SampleCliffFeeNumerator       = 10_000_000
+)
+
+// Creates the config of the sample launch curve
+func SamplePoolConfig() *common.PoolConfig {
+	config := &common.PoolConfig{
+		QuoteMint:               solana.SolMint,
+		CollectFeeMode:          common.CollectFeeModeQuoteToken,
+		ActivationType:          common.ActivationTypeSlot,
+		TokenDecimal:            6,
+		SwapBaseAmount:          SampleSwapBaseAmount,
+		MigrationQuoteThreshold: SampleMigrationQuoteThreshold,
+		MigrationBaseThreshold:  SampleMigrationBaseThreshold,
+		MigrationSqrtPrice:      SampleSqrtMigrationPrice,
+		SqrtStartPrice:          SampleSqrtStartPrice,
+	}
+	config.PoolFees.BaseFee.CliffFeeNumerator = SampleCliffFeeNumerator
+	config.PoolFees.ProtocolFeePercent = 20
+	config.PoolFees.ReferralFeePercent = 20
+	config.Curve[0] = common.LiquidityDistributionConfig{
+		SqrtPrice: SampleSqrtMiddlePrice,
+		Liquidity: mustUint128("104594989832255675244889735890912"),
+	}
+	config.Curve[1] = common.LiquidityDistributionConfig{
+		SqrtPrice: SampleSqrtMigrationPrice,
+		Liquidity: mustUint128("95878740679567702307815591233336"),
+	}
+	return config
+}
+
+// Creates a pool of the sample curve at its start price, before any trade
+func SamplePool(config, baseMint solana.PublicKey) *common.Pool {
+	return &common.Pool{
+		Config:      config,
+		BaseMint:    baseMint,
+		BaseReserve: SampleSwapBaseAmount + SampleMigrationBaseThreshold,
+		SqrtPrice:   SampleSqrtStartPrice,
+	}
+}
+
+func mustUint128(s string) uint128.Uint128 {
+	v, err := uint128.FromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
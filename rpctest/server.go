@@ -0,0 +1,473 @@
+package rpctest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/gorilla/websocket"
+)
+
+// Answers a JSON-RPC call from its raw params; the result is marshalled as is and an
+// error is returned to the client as a JSON-RPC error, a *jsonrpc.RPCError with its code
+// and data
+type Handler func(params json.RawMessage) (interface{}, error)
+
+// In-process RPC node serving an AccountFetcher over JSON-RPC and websocket account
+// and program subscriptions, so the rpc and ws clients run against fixtures.
+// Methods other than the account reads, getSlot and getLatestBlockhash are added
+// with Handle.
+type Server struct {
+	Fetcher *AccountFetcher
+
+	httpServer *httptest.Server
+	upgrader   websocket.Upgrader
+
+	mu        sync.Mutex
+	handlers  map[string]Handler
+	calls     map[string]int
+	conns     map[*wsConn]struct{}
+	nextSubID uint64
+}
+
+type wsConn struct {
+	server *Server
+	conn   *websocket.Conn
+	// gorilla connections allow a single concurrent writer
+	writeMu sync.Mutex
+	// subscriptions by id, guarded by the server lock
+	subs map[uint64]*wsSubscription
+}
+
+type wsSubscription struct {
+	id      uint64
+	method  string
+	address solana.PublicKey
+	filters []solRpc.RPCFilter
+}
+
+// Starts a server answering from the fetcher; Close stops it
+func NewServer(fetcher *AccountFetcher) *Server {
+	s := &Server{
+		Fetcher:  fetcher,
+		handlers: make(map[string]Handler),
+		calls:    make(map[string]int),
+		conns:    make(map[*wsConn]struct{}),
+	}
+	s.handlers["getAccountInfo"] = s.getAccountInfo
+	s.handlers["getMultipleAccounts"] = s.getMultipleAccounts
+	s.handlers["getSlot"] = s.getSlot
+	s.handlers["getLatestBlockhash"] = s.getLatestBlockhash
+	s.httpServer = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// http url of the JSON-RPC endpoint
+func (s *Server) URL() string {
+	return s.httpServer.URL
+}
+
+// ws url of the subscription endpoint
+func (s *Server) WsURL() string {
+	return "ws" + strings.TrimPrefix(s.httpServer.URL, "http")
+}
+
+// Creates an rpc client for the server
+func (s *Server) Client() *solRpc.Client {
+	return solRpc.New(s.URL())
+}
+
+// Sets the handler of a method, replacing any built-in one
+func (s *Server) Handle(method string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = handler
+}
+
+// Number of calls made to a JSON-RPC method
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// Number of open websocket connections
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Number of active subscriptions over all connections
+func (s *Server) Subscriptions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for conn := range s.conns {
+		count += len(conn.subs)
+	}
+	return count
+}
+
+// Waits until at least n subscriptions are active, so notifications sent afterwards
+// reach them
+func (s *Server) WaitSubscriptions(ctx context.Context, n int) error {
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for s.Subscriptions() < n {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to wait for %d subscriptions, have %d: %w", n, s.Subscriptions(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Sends the current state of the account to its account subscriptions and to the
+// program subscriptions of its owner whose filters match
+func (s *Server) NotifyAccount(address solana.PublicKey) error {
+	res, err := s.Fetcher.GetAccountInfo(context.Background(), address)
+	if err != nil {
+		return fmt.Errorf("failed to get account %s: %w", address, err)
+	}
+	account := res.Value
+	slot := res.Context.Slot
+
+	type notification struct {
+		conn    *wsConn
+		message interface{}
+	}
+	var notifications []notification
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		for _, sub := range conn.subs {
+			var value interface{}
+			switch {
+			case sub.method == "accountSubscribe" && sub.address == address:
+				value = account
+			case sub.method == "programSubscribe" && sub.address == account.Owner && matchesFilters(account.Data.GetBinary(), sub.filters):
+				value = solRpc.KeyedAccount{Pubkey: address, Account: account}
+			default:
+				continue
+			}
+			notifications = append(notifications, notification{conn: conn, message: map[string]interface{}{
+				"jsonrpc": "2.0",
+				"method":  strings.TrimSuffix(sub.method, "Subscribe") + "Notification",
+				"params": map[string]interface{}{
+					"result": map[string]interface{}{
+						"context": map[string]interface{}{"slot": slot},
+						"value":   value,
+					},
+					"subscription": sub.id,
+				},
+			}})
+		}
+	}
+	s.mu.Unlock()
+
+	for _, n := range notifications {
+		if err := n.conn.write(n.message); err != nil {
+			return fmt.Errorf("failed to notify subscription: %w", err)
+		}
+	}
+	return nil
+}
+
+// Closes every websocket connection, as a node restart would. Their subscriptions
+// are gone once it returns.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	conns := make([]*wsConn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+		delete(s.conns, conn)
+	}
+	s.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.conn.Close()
+	}
+}
+
+func (s *Server) Close() {
+	s.DropConnections()
+	s.httpServer.Close()
+}
+
+type rpcRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		s.serveWebsocket(w, r)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.calls[req.Method]++
+	handler, ok := s.handlers[req.Method]
+	s.mu.Unlock()
+
+	res := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	if !ok {
+		res["error"] = rpcError{Code: -32601, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	} else if result, err := handler(req.Params); err != nil {
+		var withData *jsonrpc.RPCError
+		if errors.As(err, &withData) {
+			res["error"] = withData
+		} else {
+			res["error"] = rpcError{Code: -32000, Message: err.Error()}
+		}
+	} else {
+		res["result"] = result
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &wsConn{server: s, conn: conn, subs: make(map[uint64]*wsSubscription)}
+
+	s.mu.Lock()
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req rpcRequest
+		if err := json.Unmarshal(message, &req); err != nil {
+			continue
+		}
+
+		result, err := c.handle(req)
+		res := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if err != nil {
+			res["error"] = rpcError{Code: -32602, Message: err.Error()}
+		} else {
+			res["result"] = result
+		}
+		if err := c.write(res); err != nil {
+			return
+		}
+	}
+}
+
+func (c *wsConn) handle(req rpcRequest) (interface{}, error) {
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 {
+		return nil, fmt.Errorf("invalid params")
+	}
+
+	switch req.Method {
+	case "accountSubscribe", "programSubscribe":
+		sub := &wsSubscription{method: req.Method}
+		if err := json.Unmarshal(params[0], &sub.address); err != nil {
+			return nil, fmt.Errorf("invalid address: %w", err)
+		}
+		if len(params) > 1 {
+			var config struct {
+				Filters []solRpc.RPCFilter `json:"filters"`
+			}
+			if err := json.Unmarshal(params[1], &config); err != nil {
+				return nil, fmt.Errorf("invalid config: %w", err)
+			}
+			sub.filters = config.Filters
+		}
+
+		c.server.mu.Lock()
+		defer c.server.mu.Unlock()
+		// ids start at 1, the client takes 0 for a failed subscription
+		c.server.nextSubID++
+		sub.id = c.server.nextSubID
+		c.subs[sub.id] = sub
+		return sub.id, nil
+	case "accountUnsubscribe", "programUnsubscribe":
+		var id uint64
+		if err := json.Unmarshal(params[0], &id); err != nil {
+			return nil, fmt.Errorf("invalid subscription id: %w", err)
+		}
+
+		c.server.mu.Lock()
+		defer c.server.mu.Unlock()
+		_, ok := c.subs[id]
+		delete(c.subs, id)
+		return ok, nil
+	default:
+		return nil, fmt.Errorf("method not found: %s", req.Method)
+	}
+}
+
+func (c *wsConn) write(message interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteJSON(message)
+}
+
+func matchesFilters(data []byte, filters []solRpc.RPCFilter) bool {
+	for _, filter := range filters {
+		if filter.DataSize != 0 && uint64(len(data)) != filter.DataSize {
+			return false
+		}
+		if memcmp := filter.Memcmp; memcmp != nil {
+			end := memcmp.Offset + uint64(len(memcmp.Bytes))
+			if end > uint64(len(data)) || !bytes.Equal(data[memcmp.Offset:end], memcmp.Bytes) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s *Server) getAccountInfo(params json.RawMessage) (interface{}, error) {
+	var args []json.RawMessage
+	var address solana.PublicKey
+	if err := json.Unmarshal(params, &args); err != nil || len(args) == 0 {
+		return nil, fmt.Errorf("invalid params")
+	}
+	if err := json.Unmarshal(args[0], &address); err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+
+	res, err := s.Fetcher.GetAccountInfo(context.Background(), address)
+	if errors.Is(err, solRpc.ErrNotFound) {
+		return map[string]interface{}{"context": map[string]interface{}{"slot": s.slot()}, "value": nil}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) getMultipleAccounts(params json.RawMessage) (interface{}, error) {
+	var args []json.RawMessage
+	var addresses []solana.PublicKey
+	if err := json.Unmarshal(params, &args); err != nil || len(args) == 0 {
+		return nil, fmt.Errorf("invalid params")
+	}
+	if err := json.Unmarshal(args[0], &addresses); err != nil {
+		return nil, fmt.Errorf("invalid addresses: %w", err)
+	}
+	return s.Fetcher.GetMultipleAccounts(context.Background(), addresses...)
+}
+
+func (s *Server) getSlot(json.RawMessage) (interface{}, error) {
+	return s.slot(), nil
+}
+
+// Answers with a blockhash derived from the slot, valid for 150 blocks
+func (s *Server) getLatestBlockhash(json.RawMessage) (interface{}, error) {
+	slot := s.slot()
+	var blockhash solana.Hash
+	copy(blockhash[:], fmt.Sprintf("blockhash-%d", slot))
+	return map[string]interface{}{
+		"context": map[string]interface{}{"slot": slot},
+		"value": map[string]interface{}{
+			"blockhash":            blockhash,
+			"lastValidBlockHeight": slot + 150,
+		},
+	}, nil
+}
+
+func (s *Server) slot() uint64 {
+	s.Fetcher.mu.RLock()
+	defer s.Fetcher.mu.RUnlock()
+	return s.Fetcher.slot
+}
+
+// Error of a sendTransaction rejected by the preflight simulation, carrying the
+// transaction error and program logs in its data as a node does
+func PreflightError(txErr interface{}, logs []string) error {
+	return &jsonrpc.RPCError{
+		Code:    -32002,
+		Message: "Transaction simulation failed",
+		Data: map[string]interface{}{
+			"err":           txErr,
+			"logs":          logs,
+			"accounts":      nil,
+			"unitsConsumed": 0,
+		},
+	}
+}
+
+// Answers simulateTransaction calls with a fixed outcome, see Handle
+type Simulation struct {
+	// state after the transaction, answering the accounts requested by the simulation
+	Post *AccountFetcher
+	// transaction error as returned by the node, e.g.
+	// map[string]interface{}{"InstructionError": []interface{}{0, map[string]interface{}{"Custom": 6000}}}
+	Err           interface{}
+	Logs          []string
+	UnitsConsumed uint64
+}
+
+func (s *Simulation) Handle(params json.RawMessage) (interface{}, error) {
+	var args []json.RawMessage
+	if err := json.Unmarshal(params, &args); err != nil || len(args) == 0 {
+		return nil, fmt.Errorf("invalid params")
+	}
+	var opts struct {
+		Accounts struct {
+			Addresses []solana.PublicKey `json:"addresses"`
+		} `json:"accounts"`
+	}
+	if len(args) > 1 {
+		if err := json.Unmarshal(args[1], &opts); err != nil {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+	}
+
+	unitsConsumed := s.UnitsConsumed
+	result := &solRpc.SimulateTransactionResult{Err: s.Err, Logs: s.Logs, UnitsConsumed: &unitsConsumed}
+	post, err := s.Post.GetMultipleAccounts(context.Background(), opts.Accounts.Addresses...)
+	if err != nil {
+		return nil, err
+	}
+	// accounts are not returned for failed transactions
+	if s.Err == nil {
+		result.Accounts = post.Value
+	}
+	return map[string]interface{}{
+		"context": post.Context,
+		"value":   result,
+	}, nil
+}
@@ -0,0 +1,224 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+)
+
+const DefaultPollInterval = 2 * time.Second
+
+// source of pool state updates, independent of the transport
+type PoolStateSource interface {
+	// streams snapshots of the pools until the context ends, then closes the channel
+	Subscribe(ctx context.Context, pools []solana.PublicKey) (<-chan PoolSnapshot, error)
+}
+
+var (
+	_ PoolStateSource = (*PollingSource)(nil)
+	_ PoolStateSource = (*WebsocketSource)(nil)
+	_ PoolStateSource = (*GeyserSource)(nil)
+)
+
+// polls the pool accounts over RPC, emitting a snapshot whenever the account data changes
+type PollingSource struct {
+	RpcClient  *solRpc.Client
+	Interval   time.Duration
+	Commitment solRpc.CommitmentType
+	// config of the pools, used to compute the curve progress
+	Config  *common.PoolConfig
+	OnError func(error)
+}
+
+func (s *PollingSource) Subscribe(ctx context.Context, pools []solana.PublicKey) (<-chan PoolSnapshot, error) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	commitment := s.Commitment
+	if commitment == "" {
+		commitment = solRpc.CommitmentConfirmed
+	}
+
+	out := make(chan PoolSnapshot, DefaultBufferSize)
+	go func() {
+		defer close(out)
+
+		last := make(map[solana.PublicKey][]byte, len(pools))
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			res, err := s.RpcClient.GetMultipleAccountsWithOpts(ctx, pools, &solRpc.GetMultipleAccountsOpts{
+				Encoding:   solana.EncodingBase64,
+				Commitment: commitment,
+			})
+			if err != nil {
+				s.reportError(fmt.Errorf("failed to poll pools: %w", err))
+			} else {
+				for i, account := range res.Value {
+					if account == nil || i >= len(pools) {
+						continue
+					}
+					data := account.Data.GetBinary()
+					if bytes.Equal(last[pools[i]], data) {
+						continue
+					}
+					last[pools[i]] = data
+
+					snapshot, err := decodeSnapshot(&accountUpdate{address: pools[i], slot: res.Context.Slot, data: data}, s.Config)
+					if err != nil {
+						s.reportError(err)
+						continue
+					}
+					select {
+					case out <- *snapshot:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (s *PollingSource) reportError(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// subscribes to every pool account over a single RPC websocket connection, see
+// SubscribePools
+type WebsocketSource struct {
+	URL  string
+	Opts *SubscribeOpts
+}
+
+func (s *WebsocketSource) Subscribe(ctx context.Context, pools []solana.PublicKey) (<-chan PoolSnapshot, error) {
+	return SubscribePools(ctx, s.URL, pools, s.Opts)
+}
+
+// account update of a Yellowstone-style geyser stream
+type GeyserAccountUpdate struct {
+	Pubkey solana.PublicKey
+	Slot   uint64
+	Data   []byte
+}
+
+// open geyser account subscription; Recv returns an error once the stream ends and
+// Close may be called more than once
+type GeyserStream interface {
+	Recv() (*GeyserAccountUpdate, error)
+	Close() error
+}
+
+// Opens a geyser account subscription for the accounts, e.g. the Subscribe method of a
+// geyser.Client connected to a Yellowstone gRPC endpoint
+type GeyserDialer func(ctx context.Context, accounts []solana.PublicKey) (GeyserStream, error)
+
+// streams pool accounts from a geyser gRPC subscription, redialing when the stream ends
+type GeyserSource struct {
+	Dial GeyserDialer
+	// config of the pools, used to compute the curve progress
+	Config            *common.PoolConfig
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+	OnError           func(error)
+}
+
+func (s *GeyserSource) Subscribe(ctx context.Context, pools []solana.PublicKey) (<-chan PoolSnapshot, error) {
+	opts := withSubscribeDefaults(&SubscribeOpts{
+		Config:            s.Config,
+		ReconnectDelay:    s.ReconnectDelay,
+		MaxReconnectDelay: s.MaxReconnectDelay,
+		OnError:           s.OnError,
+	})
+
+	// the first stream is opened up front so a bad endpoint fails immediately
+	geyserStream, err := s.Dial(ctx, pools)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open geyser stream: %w", err)
+	}
+
+	out := make(chan PoolSnapshot, opts.BufferSize)
+	go func() {
+		defer close(out)
+
+		delay := opts.ReconnectDelay
+		for {
+			if geyserStream == nil {
+				geyserStream, err = s.Dial(ctx, pools)
+				if err != nil {
+					reportError(opts, fmt.Errorf("failed to reopen geyser stream: %w", err))
+					if !sleep(ctx, delay) {
+						return
+					}
+					delay = nextDelay(delay, opts.MaxReconnectDelay)
+					continue
+				}
+				delay = opts.ReconnectDelay
+			}
+
+			err := forwardGeyser(ctx, geyserStream, opts, out)
+			geyserStream.Close()
+			geyserStream = nil
+			if ctx.Err() != nil {
+				return
+			}
+			reportError(opts, fmt.Errorf("geyser stream dropped: %w", err))
+			if !sleep(ctx, delay) {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func forwardGeyser(ctx context.Context, geyserStream GeyserStream, opts *SubscribeOpts, out chan<- PoolSnapshot) error {
+	// closing the stream unblocks Recv when the context ends
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			geyserStream.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		update, err := geyserStream.Recv()
+		if err != nil {
+			return err
+		}
+		if update == nil {
+			continue
+		}
+
+		snapshot, err := decodeSnapshot(&accountUpdate{address: update.Pubkey, slot: update.Slot, data: update.Data}, opts.Config)
+		if err != nil {
+			reportError(opts, err)
+			continue
+		}
+
+		select {
+		case out <- *snapshot:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
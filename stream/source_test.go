@@ -0,0 +1,350 @@
+package stream_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"lukechampine.com/uint128"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+	"github.com/Luigi-1Combo/dbc-go/stream"
+)
+
+const testTimeout = 5 * time.Second
+
+func testPool(quoteReserve uint64) *common.Pool {
+	return &common.Pool{
+		BaseReserve:  800_000_000_000_000,
+		QuoteReserve: quoteReserve,
+		SqrtPrice:    uint128.From64(1 << 62),
+	}
+}
+
+func setPools(t *testing.T, fetcher *rpctest.AccountFetcher, quoteReserve uint64, pools ...solana.PublicKey) {
+	t.Helper()
+	for _, pool := range pools {
+		if err := fetcher.SetPool(pool, testPool(quoteReserve)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// receives the next snapshot, failing the test when none arrives in time
+func next(t *testing.T, snapshots <-chan stream.PoolSnapshot) stream.PoolSnapshot {
+	t.Helper()
+	select {
+	case snapshot, ok := <-snapshots:
+		if !ok {
+			t.Fatal("snapshot channel closed")
+		}
+		return snapshot
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for a snapshot")
+	}
+	return stream.PoolSnapshot{}
+}
+
+// receives one snapshot per pool, keyed by address
+func nextOfEach(t *testing.T, snapshots <-chan stream.PoolSnapshot, n int) map[solana.PublicKey]stream.PoolSnapshot {
+	t.Helper()
+	received := make(map[solana.PublicKey]stream.PoolSnapshot, n)
+	for len(received) < n {
+		snapshot := next(t, snapshots)
+		received[snapshot.Address] = snapshot
+	}
+	return received
+}
+
+func waitClosed(t *testing.T, snapshots <-chan stream.PoolSnapshot) {
+	t.Helper()
+	timeout := time.After(testTimeout)
+	for {
+		select {
+		case _, ok := <-snapshots:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("snapshot channel not closed after the context ended")
+		}
+	}
+}
+
+func newPools(n int) []solana.PublicKey {
+	pools := make([]solana.PublicKey, n)
+	for i := range pools {
+		pools[i] = solana.NewWallet().PublicKey()
+	}
+	return pools
+}
+
+func TestPollingSourceEmitsChangedPools(t *testing.T) {
+	fetcher := rpctest.NewAccountFetcher()
+	server := rpctest.NewServer(fetcher)
+	defer server.Close()
+
+	pools := newPools(2)
+	fetcher.SetSlot(100)
+	setPools(t, fetcher, 1_000_000_000, pools...)
+
+	config := &common.PoolConfig{MigrationQuoteThreshold: 4_000_000_000}
+	source := &stream.PollingSource{RpcClient: server.Client(), Interval: 10 * time.Millisecond, Config: config}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	snapshots, err := source.Subscribe(ctx, pools)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initial := nextOfEach(t, snapshots, len(pools))
+	for _, pool := range pools {
+		snapshot := initial[pool]
+		if snapshot.Slot != 100 || snapshot.QuoteReserve != 1_000_000_000 || snapshot.Progress != 0.25 {
+			t.Fatalf("unexpected snapshot of %s: slot %d, quote reserve %d, progress %v", pool, snapshot.Slot, snapshot.QuoteReserve, snapshot.Progress)
+		}
+	}
+
+	// only the changed pool is emitted again
+	fetcher.SetSlot(101)
+	setPools(t, fetcher, 2_000_000_000, pools[1])
+	snapshot := next(t, snapshots)
+	if snapshot.Address != pools[1] || snapshot.Slot != 101 || snapshot.QuoteReserve != 2_000_000_000 {
+		t.Fatalf("unexpected snapshot after the change: %s at slot %d, quote reserve %d", snapshot.Address, snapshot.Slot, snapshot.QuoteReserve)
+	}
+
+	cancel()
+	waitClosed(t, snapshots)
+}
+
+func TestPollingSourceReportsErrors(t *testing.T) {
+	fetcher := rpctest.NewAccountFetcher()
+	server := rpctest.NewServer(fetcher)
+	defer server.Close()
+
+	pools := newPools(2)
+	setPools(t, fetcher, 1, pools[0])
+	// the second pool holds data that is not a pool
+	fetcher.SetAccount(pools[1], solana.MustPublicKeyFromBase58(common.DbcProgramID), []byte{1, 2, 3})
+
+	errs := make(chan error, 16)
+	source := &stream.PollingSource{
+		RpcClient: server.Client(),
+		Interval:  10 * time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	snapshots, err := source.Subscribe(ctx, pools)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if snapshot := next(t, snapshots); snapshot.Address != pools[0] {
+		t.Fatalf("got snapshot of %s, want %s", snapshot.Address, pools[0])
+	}
+	select {
+	case <-errs:
+	case <-time.After(testTimeout):
+		t.Fatal("decoding error not reported")
+	}
+}
+
+func TestWebsocketSourceMultiplexesPools(t *testing.T) {
+	fetcher := rpctest.NewAccountFetcher()
+	server := rpctest.NewServer(fetcher)
+	defer server.Close()
+
+	pools := newPools(3)
+	setPools(t, fetcher, 1_000_000_000, pools...)
+
+	source := &stream.WebsocketSource{URL: server.WsURL()}
+	ctx, cancel := context.WithCancel(context.Background())
+	snapshots, err := source.Subscribe(ctx, pools)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, testTimeout)
+	defer waitCancel()
+	if err := server.WaitSubscriptions(waitCtx, len(pools)); err != nil {
+		t.Fatal(err)
+	}
+	if connections := server.Connections(); connections != 1 {
+		t.Fatalf("got %d websocket connections for %d pools, want 1", connections, len(pools))
+	}
+
+	fetcher.SetSlot(200)
+	for i, pool := range pools {
+		setPools(t, fetcher, uint64(i+1)*1_000_000_000, pool)
+		if err := server.NotifyAccount(pool); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	received := nextOfEach(t, snapshots, len(pools))
+	for i, pool := range pools {
+		snapshot := received[pool]
+		if snapshot.Slot != 200 || snapshot.QuoteReserve != uint64(i+1)*1_000_000_000 {
+			t.Fatalf("unexpected snapshot of %s: slot %d, quote reserve %d", pool, snapshot.Slot, snapshot.QuoteReserve)
+		}
+	}
+
+	cancel()
+	waitClosed(t, snapshots)
+}
+
+func TestWebsocketSourceResubscribesAfterDrop(t *testing.T) {
+	fetcher := rpctest.NewAccountFetcher()
+	server := rpctest.NewServer(fetcher)
+	defer server.Close()
+
+	pools := newPools(2)
+	setPools(t, fetcher, 1, pools...)
+
+	errs := make(chan error, 16)
+	source := &stream.WebsocketSource{URL: server.WsURL(), Opts: &stream.SubscribeOpts{
+		ReconnectDelay: 10 * time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	snapshots, err := source.Subscribe(ctx, pools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.WaitSubscriptions(ctx, len(pools)); err != nil {
+		t.Fatal(err)
+	}
+
+	server.DropConnections()
+	select {
+	case <-errs:
+	case <-ctx.Done():
+		t.Fatal("dropped connection not reported")
+	}
+	if err := server.WaitSubscriptions(ctx, len(pools)); err != nil {
+		t.Fatal(err)
+	}
+	if connections := server.Connections(); connections != 1 {
+		t.Fatalf("got %d websocket connections after reconnecting, want 1", connections)
+	}
+
+	for _, pool := range pools {
+		if err := server.NotifyAccount(pool); err != nil {
+			t.Fatal(err)
+		}
+	}
+	nextOfEach(t, snapshots, len(pools))
+}
+
+func TestWebsocketSourceFailsOnBadURL(t *testing.T) {
+	server := rpctest.NewServer(rpctest.NewAccountFetcher())
+	url := server.WsURL()
+	server.Close()
+
+	source := &stream.WebsocketSource{URL: url}
+	if _, err := source.Subscribe(context.Background(), newPools(1)); err == nil {
+		t.Fatal("expected an error for an unreachable endpoint")
+	}
+}
+
+func TestGeyserSourceStreamsAndRedials(t *testing.T) {
+	fetcher := rpctest.NewAccountFetcher()
+	feed := rpctest.NewGeyserFeed(fetcher)
+
+	pools := newPools(2)
+	other := solana.NewWallet().PublicKey()
+	fetcher.SetSlot(300)
+	setPools(t, fetcher, 1_000_000_000, append(pools, other)...)
+
+	errs := make(chan error, 16)
+	source := &stream.GeyserSource{
+		Dial:           feed.Dial,
+		ReconnectDelay: 10 * time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	snapshots, err := source.Subscribe(ctx, pools)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// accounts outside the subscription are not streamed
+	for _, account := range []solana.PublicKey{other, pools[0], pools[1]} {
+		if err := feed.Publish(account); err != nil {
+			t.Fatal(err)
+		}
+	}
+	received := nextOfEach(t, snapshots, len(pools))
+	if _, ok := received[other]; ok {
+		t.Fatal("received a snapshot of an account outside the subscription")
+	}
+	if received[pools[0]].Slot != 300 {
+		t.Fatalf("got slot %d, want 300", received[pools[0]].Slot)
+	}
+
+	// the stream is redialed after it ends, retrying failed dials
+	feed.SetDialError(errors.New("unavailable"))
+	feed.DropStreams()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-errs:
+		case <-ctx.Done():
+			t.Fatal("stream drop and failed redial not reported")
+		}
+	}
+	feed.SetDialError(nil)
+	if err := feed.WaitStreams(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if dials := feed.Dials(); dials < 3 {
+		t.Fatalf("got %d dials, want at least 3", dials)
+	}
+
+	fetcher.SetSlot(301)
+	setPools(t, fetcher, 2_000_000_000, pools[1])
+	if err := feed.Publish(pools[1]); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := next(t, snapshots)
+	if snapshot.Address != pools[1] || snapshot.Slot != 301 || snapshot.QuoteReserve != 2_000_000_000 {
+		t.Fatalf("unexpected snapshot after redialing: %s at slot %d, quote reserve %d", snapshot.Address, snapshot.Slot, snapshot.QuoteReserve)
+	}
+
+	cancel()
+	waitClosed(t, snapshots)
+	if streams := feed.Streams(); streams != 0 {
+		t.Fatalf("%d geyser streams left open after the context ended", streams)
+	}
+}
+
+func TestGeyserSourceFailsOnFirstDial(t *testing.T) {
+	feed := rpctest.NewGeyserFeed(rpctest.NewAccountFetcher())
+	feed.SetDialError(errors.New("unavailable"))
+
+	source := &stream.GeyserSource{Dial: feed.Dial}
+	if _, err := source.Subscribe(context.Background(), newPools(1)); err == nil {
+		t.Fatal("expected the first dial error")
+	}
+}
@@ -0,0 +1,370 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+	"lukechampine.com/uint128"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/helpers"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/math"
+)
+
+const (
+	DefaultReconnectDelay    = time.Second
+	DefaultMaxReconnectDelay = 30 * time.Second
+	DefaultBufferSize        = 64
+
+	// pool account layout: discriminator (8), volatility tracker (64), config (32), ...
+	poolConfigOffset = 8 + 64
+)
+
+type PoolSnapshot struct {
+	Address      solana.PublicKey
+	Slot         uint64
+	Pool         *common.Pool
+	SqrtPrice    uint128.Uint128
+	BaseReserve  uint64
+	QuoteReserve uint64
+	// share of the migration quote threshold raised, only set when the config is known
+	Progress float64
+}
+
+type SubscribeOpts struct {
+	// defaults to confirmed
+	Commitment solRpc.CommitmentType
+	// config of the pools, used to compute the curve progress
+	Config *common.PoolConfig
+	// delay before the first reconnect, doubled on every failed attempt up to MaxReconnectDelay
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+	// capacity of the snapshot channel; snapshots are dropped only when the context ends
+	BufferSize int
+	// called with connection and decoding errors, which never stop the subscription
+	OnError func(error)
+	// DBC program the pools belong to, defaults to the mainnet deployment
+	ProgramID solana.PublicKey
+}
+
+func DefaultSubscribeOpts() *SubscribeOpts {
+	return &SubscribeOpts{
+		Commitment:        solRpc.CommitmentConfirmed,
+		ReconnectDelay:    DefaultReconnectDelay,
+		MaxReconnectDelay: DefaultMaxReconnectDelay,
+		BufferSize:        DefaultBufferSize,
+	}
+}
+
+// Subscribes to a pool account and emits a snapshot on every change. The connection is
+// re-established and the subscription renewed when it drops; the channel is closed
+// once the context ends.
+func SubscribePool(
+	ctx context.Context,
+	wsURL string,
+	poolAddress solana.PublicKey,
+	opts *SubscribeOpts,
+) (<-chan PoolSnapshot, error) {
+	return SubscribePools(ctx, wsURL, []solana.PublicKey{poolAddress}, opts)
+}
+
+// Subscribes to several pool accounts over a single connection, see SubscribePool
+func SubscribePools(
+	ctx context.Context,
+	wsURL string,
+	poolAddresses []solana.PublicKey,
+	opts *SubscribeOpts,
+) (<-chan PoolSnapshot, error) {
+	opts = withSubscribeDefaults(opts)
+	return subscribe(ctx, wsURL, opts, func(client *ws.Client) (*subscription, error) {
+		return subscribeAccounts(client, poolAddresses, opts.Commitment)
+	})
+}
+
+// Subscribes to every pool of a config through programSubscribe, filtering on the
+// pool discriminator and config address
+func SubscribeConfigPools(
+	ctx context.Context,
+	wsURL string,
+	configAddress solana.PublicKey,
+	opts *SubscribeOpts,
+) (<-chan PoolSnapshot, error) {
+	opts = withSubscribeDefaults(opts)
+	programID := opts.ProgramID
+	if programID.IsZero() {
+		programID = solana.MustPublicKeyFromBase58(common.DbcProgramID)
+	}
+	filters := []solRpc.RPCFilter{
+		{Memcmp: &solRpc.RPCFilterMemcmp{Offset: 0, Bytes: instructions.PoolAccountDiscriminator[:]}},
+		{Memcmp: &solRpc.RPCFilterMemcmp{Offset: poolConfigOffset, Bytes: configAddress[:]}},
+	}
+
+	return subscribe(ctx, wsURL, opts, func(client *ws.Client) (*subscription, error) {
+		sub, err := client.ProgramSubscribeWithOpts(programID, opts.Commitment, solana.EncodingBase64, filters)
+		if err != nil {
+			return nil, err
+		}
+		return &subscription{
+			recv: func() (*accountUpdate, error) {
+				res, err := sub.Recv()
+				if err != nil || res == nil {
+					return nil, err
+				}
+				if res.Value.Account == nil {
+					return &accountUpdate{address: res.Value.Pubkey, slot: res.Context.Slot}, nil
+				}
+				return &accountUpdate{address: res.Value.Pubkey, slot: res.Context.Slot, data: res.Value.Account.Data.GetBinary()}, nil
+			},
+			unsubscribe: sub.Unsubscribe,
+		}, nil
+	})
+}
+
+type accountUpdate struct {
+	address solana.PublicKey
+	slot    uint64
+	data    []byte
+}
+
+type subscription struct {
+	// returns a nil update once the subscription is closed
+	recv        func() (*accountUpdate, error)
+	unsubscribe func()
+}
+
+// Subscribes to every account on the client, merging their notifications into one
+// subscription
+func subscribeAccounts(client *ws.Client, accounts []solana.PublicKey, commitment solRpc.CommitmentType) (*subscription, error) {
+	subs := make([]*ws.AccountSubscription, 0, len(accounts))
+	unsubscribeAll := func() {
+		for _, sub := range subs {
+			sub.Unsubscribe()
+		}
+	}
+	for _, account := range accounts {
+		sub, err := client.AccountSubscribeWithOpts(account, commitment, solana.EncodingBase64)
+		if err != nil {
+			unsubscribeAll()
+			return nil, fmt.Errorf("failed to subscribe to %s: %w", account, err)
+		}
+		subs = append(subs, sub)
+	}
+
+	type received struct {
+		update *accountUpdate
+		err    error
+	}
+	updates := make(chan received)
+	closed := make(chan struct{})
+	for i, sub := range subs {
+		go func(address solana.PublicKey, sub *ws.AccountSubscription) {
+			for {
+				res, err := sub.Recv()
+				next := received{err: err}
+				if err == nil && res != nil {
+					next.update = &accountUpdate{address: address, slot: res.Context.Slot, data: res.Value.Data.GetBinary()}
+				}
+				select {
+				case updates <- next:
+				case <-closed:
+					return
+				}
+				// a nil update or an error ends the account subscription
+				if next.update == nil {
+					return
+				}
+			}
+		}(accounts[i], sub)
+	}
+
+	var once sync.Once
+	return &subscription{
+		recv: func() (*accountUpdate, error) {
+			select {
+			case next := <-updates:
+				return next.update, next.err
+			case <-closed:
+				return nil, nil
+			}
+		},
+		unsubscribe: func() {
+			once.Do(func() {
+				close(closed)
+				unsubscribeAll()
+			})
+		},
+	}, nil
+}
+
+type subscribeFunc func(client *ws.Client) (*subscription, error)
+
+func subscribe(ctx context.Context, wsURL string, opts *SubscribeOpts, subscribeFn subscribeFunc) (<-chan PoolSnapshot, error) {
+	// the first connection is made up front so a bad url fails immediately
+	client, err := ws.Connect(ctx, wsURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", wsURL, err)
+	}
+
+	out := make(chan PoolSnapshot, opts.BufferSize)
+	go run(ctx, wsURL, client, opts, subscribeFn, out)
+	return out, nil
+}
+
+func run(
+	ctx context.Context,
+	wsURL string,
+	client *ws.Client,
+	opts *SubscribeOpts,
+	subscribeFn subscribeFunc,
+	out chan<- PoolSnapshot,
+) {
+	defer close(out)
+
+	delay := opts.ReconnectDelay
+	for {
+		if client == nil {
+			var err error
+			client, err = ws.Connect(ctx, wsURL)
+			if err != nil {
+				reportError(opts, fmt.Errorf("failed to reconnect to %s: %w", wsURL, err))
+				if !sleep(ctx, delay) {
+					return
+				}
+				delay = nextDelay(delay, opts.MaxReconnectDelay)
+				continue
+			}
+		}
+
+		sub, err := subscribeFn(client)
+		if err != nil {
+			reportError(opts, fmt.Errorf("failed to subscribe: %w", err))
+		} else {
+			delay = opts.ReconnectDelay
+			err = forward(ctx, sub, opts, out)
+			if err != nil {
+				reportError(opts, fmt.Errorf("subscription dropped: %w", err))
+			}
+		}
+		client.Close()
+		client = nil
+
+		if !sleep(ctx, delay) {
+			return
+		}
+		delay = nextDelay(delay, opts.MaxReconnectDelay)
+	}
+}
+
+// forwards decoded updates until the subscription drops or the context ends
+func forward(ctx context.Context, sub *subscription, opts *SubscribeOpts, out chan<- PoolSnapshot) error {
+	// unsubscribing unblocks recv when the context ends
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			sub.unsubscribe()
+		case <-done:
+		}
+	}()
+	defer sub.unsubscribe()
+
+	for {
+		update, err := sub.recv()
+		if err != nil {
+			return err
+		}
+		if update == nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("subscription closed")
+		}
+
+		snapshot, err := decodeSnapshot(update, opts.Config)
+		if err != nil {
+			reportError(opts, err)
+			continue
+		}
+
+		select {
+		case out <- *snapshot:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func decodeSnapshot(update *accountUpdate, config *common.PoolConfig) (*PoolSnapshot, error) {
+	pool, err := helpers.DecodePool(update.data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pool %s at slot %d: %w", update.address, update.slot, err)
+	}
+
+	snapshot := &PoolSnapshot{
+		Address:      update.address,
+		Slot:         update.slot,
+		Pool:         pool,
+		SqrtPrice:    pool.SqrtPrice,
+		BaseReserve:  pool.BaseReserve,
+		QuoteReserve: pool.QuoteReserve,
+	}
+	if config != nil {
+		snapshot.Progress = math.GetCurveProgress(pool, config)
+	}
+	return snapshot, nil
+}
+
+func reportError(opts *SubscribeOpts, err error) {
+	if opts.OnError != nil {
+		opts.OnError(err)
+	}
+}
+
+// waits for the delay, returning false when the context ends first
+func sleep(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func nextDelay(delay, maxDelay time.Duration) time.Duration {
+	delay *= 2
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+func withSubscribeDefaults(opts *SubscribeOpts) *SubscribeOpts {
+	defaults := DefaultSubscribeOpts()
+	if opts == nil {
+		return defaults
+	}
+
+	merged := *opts
+	if merged.Commitment == "" {
+		merged.Commitment = defaults.Commitment
+	}
+	if merged.ReconnectDelay == 0 {
+		merged.ReconnectDelay = defaults.ReconnectDelay
+	}
+	if merged.MaxReconnectDelay == 0 {
+		merged.MaxReconnectDelay = defaults.MaxReconnectDelay
+	}
+	if merged.BufferSize == 0 {
+		merged.BufferSize = defaults.BufferSize
+	}
+	return &merged
+}
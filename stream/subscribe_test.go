@@ -0,0 +1,216 @@
+package stream_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+	"github.com/Luigi-1Combo/dbc-go/stream"
+)
+
+// pool of the config with the given quote reserve
+func configPool(config solana.PublicKey, quoteReserve uint64) *common.Pool {
+	pool := testPool(quoteReserve)
+	pool.Config = config
+	return pool
+}
+
+// receives nothing for a short while, failing the test on any snapshot
+func expectNone(t *testing.T, snapshots <-chan stream.PoolSnapshot) {
+	t.Helper()
+	select {
+	case snapshot := <-snapshots:
+		t.Fatalf("unexpected snapshot of %s", snapshot.Address)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	config := solana.NewWallet().PublicKey()
+	otherConfig := solana.NewWallet().PublicKey()
+	otherProgram := solana.NewWallet().PublicKey()
+	pools := newPools(3)
+
+	tests := []struct {
+		name string
+		// program owning the accounts stored by the fetcher
+		programID solana.PublicKey
+		subscribe func(ctx context.Context, wsURL string, opts *stream.SubscribeOpts) (<-chan stream.PoolSnapshot, error)
+		// subscriptions opened on the node
+		subscriptions int
+		// pools notified with the config of the same index, and the ones expected back
+		configs []solana.PublicKey
+		want    []solana.PublicKey
+	}{
+		{
+			name: "single pool",
+			subscribe: func(ctx context.Context, wsURL string, opts *stream.SubscribeOpts) (<-chan stream.PoolSnapshot, error) {
+				return stream.SubscribePool(ctx, wsURL, pools[0], opts)
+			},
+			subscriptions: 1,
+			configs:       []solana.PublicKey{config, config, config},
+			want:          pools[:1],
+		},
+		{
+			name: "several pools",
+			subscribe: func(ctx context.Context, wsURL string, opts *stream.SubscribeOpts) (<-chan stream.PoolSnapshot, error) {
+				return stream.SubscribePools(ctx, wsURL, pools[1:], opts)
+			},
+			subscriptions: 2,
+			configs:       []solana.PublicKey{config, config, otherConfig},
+			want:          pools[1:],
+		},
+		{
+			name: "pools of a config",
+			subscribe: func(ctx context.Context, wsURL string, opts *stream.SubscribeOpts) (<-chan stream.PoolSnapshot, error) {
+				return stream.SubscribeConfigPools(ctx, wsURL, config, opts)
+			},
+			subscriptions: 1,
+			configs:       []solana.PublicKey{config, otherConfig, config},
+			want:          []solana.PublicKey{pools[0], pools[2]},
+		},
+		{
+			name:      "pools of a config on another deployment",
+			programID: otherProgram,
+			subscribe: func(ctx context.Context, wsURL string, opts *stream.SubscribeOpts) (<-chan stream.PoolSnapshot, error) {
+				opts.ProgramID = otherProgram
+				return stream.SubscribeConfigPools(ctx, wsURL, config, opts)
+			},
+			subscriptions: 1,
+			configs:       []solana.PublicKey{config, config, otherConfig},
+			want:          pools[:2],
+		},
+		{
+			name: "pools owned by another program",
+			// the mainnet deployment is subscribed to, the pools belong to another one
+			programID: otherProgram,
+			subscribe: func(ctx context.Context, wsURL string, opts *stream.SubscribeOpts) (<-chan stream.PoolSnapshot, error) {
+				return stream.SubscribeConfigPools(ctx, wsURL, config, opts)
+			},
+			subscriptions: 1,
+			configs:       []solana.PublicKey{config, config, config},
+		},
+	}
+	for _, tt := range tests {
+		fetcher := rpctest.NewAccountFetcher()
+		if !tt.programID.IsZero() {
+			fetcher = rpctest.NewProgramAccountFetcher(tt.programID)
+		}
+		server := rpctest.NewServer(fetcher)
+
+		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+		opts := &stream.SubscribeOpts{Config: &common.PoolConfig{MigrationQuoteThreshold: 4_000_000_000}}
+		snapshots, err := tt.subscribe(ctx, server.WsURL(), opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if err := server.WaitSubscriptions(ctx, tt.subscriptions); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		fetcher.SetSlot(300)
+		for i, pool := range pools {
+			if err := fetcher.SetPool(pool, configPool(tt.configs[i], uint64(i+1)*1_000_000_000)); err != nil {
+				t.Fatal(err)
+			}
+			if err := server.NotifyAccount(pool); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+		}
+
+		received := nextOfEach(t, snapshots, len(tt.want))
+		for _, pool := range tt.want {
+			snapshot, ok := received[pool]
+			if !ok {
+				t.Errorf("%s: no snapshot of %s", tt.name, pool)
+				continue
+			}
+			i := indexOf(pools, pool)
+			wantReserve := uint64(i+1) * 1_000_000_000
+			if snapshot.Slot != 300 || snapshot.QuoteReserve != wantReserve || snapshot.Pool.Config != tt.configs[i] ||
+				snapshot.Progress != float64(wantReserve)/4_000_000_000 {
+				t.Errorf("%s: unexpected snapshot of %s: slot %d, quote reserve %d, progress %v", tt.name, pool,
+					snapshot.Slot, snapshot.QuoteReserve, snapshot.Progress)
+			}
+		}
+		expectNone(t, snapshots)
+
+		cancel()
+		waitClosed(t, snapshots)
+		server.Close()
+	}
+}
+
+func indexOf(pools []solana.PublicKey, pool solana.PublicKey) int {
+	for i, p := range pools {
+		if p == pool {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestSubscribePoolReportsDecodeErrors(t *testing.T) {
+	fetcher := rpctest.NewAccountFetcher()
+	server := rpctest.NewServer(fetcher)
+	defer server.Close()
+
+	pool := solana.NewWallet().PublicKey()
+	errs := make(chan error, 16)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	snapshots, err := stream.SubscribePool(ctx, server.WsURL(), pool, &stream.SubscribeOpts{
+		OnError: func(err error) { errs <- err },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.WaitSubscriptions(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// an undecodable update is reported and skipped, the subscription goes on
+	fetcher.SetAccount(pool, solana.MustPublicKeyFromBase58(common.DbcProgramID), []byte{1, 2, 3})
+	if err := server.NotifyAccount(pool); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-errs:
+	case <-ctx.Done():
+		t.Fatal("decoding error not reported")
+	}
+
+	setPools(t, fetcher, 1_000_000_000, pool)
+	if err := server.NotifyAccount(pool); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := next(t, snapshots)
+	if snapshot.Address != pool || snapshot.QuoteReserve != 1_000_000_000 || snapshot.Progress != 0 {
+		t.Fatalf("unexpected snapshot of %s: quote reserve %d, progress %v", snapshot.Address, snapshot.QuoteReserve, snapshot.Progress)
+	}
+	if server.Connections() != 1 {
+		t.Fatalf("got %d connections, the decoding error should not reconnect", server.Connections())
+	}
+}
+
+func TestDefaultSubscribeOpts(t *testing.T) {
+	opts := stream.DefaultSubscribeOpts()
+	if opts.Commitment != "confirmed" || opts.ReconnectDelay != stream.DefaultReconnectDelay ||
+		opts.MaxReconnectDelay != stream.DefaultMaxReconnectDelay || opts.BufferSize != stream.DefaultBufferSize {
+		t.Fatalf("got %+v", opts)
+	}
+}
+
+func TestSubscribePoolFailsOnBadURL(t *testing.T) {
+	server := rpctest.NewServer(rpctest.NewAccountFetcher())
+	url := server.WsURL()
+	server.Close()
+
+	if _, err := stream.SubscribeConfigPools(context.Background(), url, solana.NewWallet().PublicKey(), nil); err == nil {
+		t.Fatal("expected an error for an unreachable endpoint")
+	}
+}
@@ -0,0 +1,252 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+
+	dbcErrors "github.com/Luigi-1Combo/dbc-go/errors"
+)
+
+const (
+	// maximum compute units a transaction can request
+	MaxComputeUnitLimit = 1_400_000
+
+	maxPrioritizationFeeAccounts = 128
+
+	DefaultComputeUnitMargin     = 0.1
+	DefaultPriorityFeePercentile = 50
+)
+
+type BuildOpts struct {
+	// compute unit limit to request, estimated by simulation when zero
+	ComputeUnitLimit uint32
+	// extra fraction of the simulated compute units to request, e.g. 0.1 for +10%,
+	// DefaultComputeUnitMargin when zero
+	ComputeUnitMargin float64
+	// compute unit price in micro-lamports, picked from recent prioritization fees when zero
+	ComputeUnitPrice uint64
+	// percentile (0-100) of recent prioritization fees used as the compute unit price
+	PriorityFeePercentile float64
+	// upper bound for the picked compute unit price, no bound when zero
+	MaxComputeUnitPrice uint64
+	// commitment used for the blockhash, simulation and fee lookups, confirmed when empty
+	Commitment solRpc.CommitmentType
+}
+
+type BuiltTransaction struct {
+	Transaction          *solana.Transaction
+	LastValidBlockHeight uint64
+	ComputeUnitLimit     uint32
+	ComputeUnitPrice     uint64
+}
+
+func DefaultBuildOpts() *BuildOpts {
+	return &BuildOpts{
+		ComputeUnitMargin:     DefaultComputeUnitMargin,
+		PriorityFeePercentile: DefaultPriorityFeePercentile,
+		Commitment:            solRpc.CommitmentConfirmed,
+	}
+}
+
+// Builds a transaction with SetComputeUnitLimit and SetComputeUnitPrice prepended to the instructions
+func BuildTransaction(
+	ctx context.Context,
+	rpcClient *solRpc.Client,
+	instructions []solana.Instruction,
+	payer solana.PublicKey,
+	opts *BuildOpts,
+) (*BuiltTransaction, error) {
+	opts, err := withBuildDefaults(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	unitPrice := opts.ComputeUnitPrice
+	if unitPrice == 0 {
+		price, err := GetPriorityFee(ctx, rpcClient, GetWritableAccounts(instructions), opts.PriorityFeePercentile)
+		if err != nil {
+			return nil, err
+		}
+		unitPrice = price
+		if opts.MaxComputeUnitPrice != 0 && unitPrice > opts.MaxComputeUnitPrice {
+			unitPrice = opts.MaxComputeUnitPrice
+		}
+	}
+
+	bh, err := rpcClient.GetLatestBlockhash(ctx, opts.Commitment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+
+	unitLimit := opts.ComputeUnitLimit
+	if unitLimit == 0 {
+		unitsConsumed, err := EstimateComputeUnits(ctx, rpcClient, instructions, payer, bh.Value.Blockhash, opts.Commitment)
+		if err != nil {
+			return nil, err
+		}
+		unitLimit = applyComputeUnitMargin(unitsConsumed, opts.ComputeUnitMargin)
+	}
+
+	tx, err := solana.NewTransaction(
+		withComputeBudget(instructions, unitLimit, unitPrice),
+		bh.Value.Blockhash,
+		solana.TransactionPayer(payer),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	return &BuiltTransaction{
+		Transaction:          tx,
+		LastValidBlockHeight: bh.Value.LastValidBlockHeight,
+		ComputeUnitLimit:     unitLimit,
+		ComputeUnitPrice:     unitPrice,
+	}, nil
+}
+
+// Estimates the compute units consumed by the instructions by simulating them
+// with the maximum compute unit limit
+func EstimateComputeUnits(
+	ctx context.Context,
+	rpcClient *solRpc.Client,
+	instructions []solana.Instruction,
+	payer solana.PublicKey,
+	blockhash solana.Hash,
+	commitment solRpc.CommitmentType,
+) (uint64, error) {
+	tx, err := solana.NewTransaction(
+		withComputeBudget(instructions, MaxComputeUnitLimit, 0),
+		blockhash,
+		solana.TransactionPayer(payer),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create simulation transaction: %w", err)
+	}
+
+	// the rpc rejects transactions without a signature slot for every signer
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+
+	res, err := rpcClient.SimulateTransactionWithOpts(ctx, tx, &solRpc.SimulateTransactionOpts{
+		SigVerify:              false,
+		ReplaceRecentBlockhash: true,
+		Commitment:             commitment,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to simulate transaction: %w", err)
+	}
+	if res == nil || res.Value == nil {
+		return 0, fmt.Errorf("empty simulation result")
+	}
+	if res.Value.Err != nil {
+		if decoded, ok := dbcErrors.DecodeTransactionError(tx, res.Value.Err); ok {
+			return 0, fmt.Errorf("simulation failed: %w", decoded)
+		}
+		return 0, fmt.Errorf("simulation failed: %v", res.Value.Err)
+	}
+	if res.Value.UnitsConsumed == nil {
+		return 0, fmt.Errorf("simulation did not report consumed compute units")
+	}
+
+	return *res.Value.UnitsConsumed, nil
+}
+
+// Gets the compute unit price at the given percentile of the recent prioritization fees
+// paid by transactions locking the given accounts
+func GetPriorityFee(
+	ctx context.Context,
+	rpcClient *solRpc.Client,
+	writableAccounts solana.PublicKeySlice,
+	percentile float64,
+) (uint64, error) {
+	// the rpc accepts at most 128 accounts
+	if len(writableAccounts) > maxPrioritizationFeeAccounts {
+		writableAccounts = writableAccounts[:maxPrioritizationFeeAccounts]
+	}
+
+	fees, err := rpcClient.GetRecentPrioritizationFees(ctx, writableAccounts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get recent prioritization fees: %w", err)
+	}
+
+	values := make([]uint64, 0, len(fees))
+	for _, fee := range fees {
+		values = append(values, fee.PrioritizationFee)
+	}
+	return FeePercentile(values, percentile), nil
+}
+
+// Gets the nearest-rank percentile (0-100) of the fees, 0 when there are none
+func FeePercentile(fees []uint64, percentile float64) uint64 {
+	if len(fees) == 0 {
+		return 0
+	}
+
+	sorted := append([]uint64(nil), fees...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	percentile = math.Max(0, math.Min(100, percentile))
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Gets the writable non-signer accounts of the instructions, e.g. pool and vaults
+func GetWritableAccounts(instructions []solana.Instruction) solana.PublicKeySlice {
+	var accounts solana.PublicKeySlice
+	for _, ix := range instructions {
+		for _, meta := range ix.Accounts() {
+			if meta.IsWritable && !meta.IsSigner {
+				accounts.UniqueAppend(meta.PublicKey)
+			}
+		}
+	}
+	return accounts
+}
+
+// prepends the compute budget instructions
+func withComputeBudget(instructions []solana.Instruction, unitLimit uint32, unitPrice uint64) []solana.Instruction {
+	budget := []solana.Instruction{
+		computebudget.NewSetComputeUnitLimitInstruction(unitLimit).Build(),
+	}
+	if unitPrice != 0 {
+		budget = append(budget, computebudget.NewSetComputeUnitPriceInstruction(unitPrice).Build())
+	}
+	return append(budget, instructions...)
+}
+
+func withBuildDefaults(opts *BuildOpts) (*BuildOpts, error) {
+	defaults := DefaultBuildOpts()
+	if opts == nil {
+		return defaults, nil
+	}
+	if opts.ComputeUnitMargin < 0 || math.IsNaN(opts.ComputeUnitMargin) {
+		return nil, fmt.Errorf("compute unit margin must not be negative, got %v", opts.ComputeUnitMargin)
+	}
+
+	merged := *opts
+	if merged.ComputeUnitMargin == 0 {
+		merged.ComputeUnitMargin = defaults.ComputeUnitMargin
+	}
+	if merged.Commitment == "" {
+		merged.Commitment = defaults.Commitment
+	}
+	return &merged, nil
+}
+
+func applyComputeUnitMargin(unitsConsumed uint64, margin float64) uint32 {
+	// the margin is rounded on its own, (1 + margin) is inexact and would add a unit
+	// to round amounts, e.g. 100_000 * 1.1 = 110_000.00000000001
+	units := float64(unitsConsumed) + math.Ceil(float64(unitsConsumed)*margin)
+	if units > MaxComputeUnitLimit {
+		return MaxComputeUnitLimit
+	}
+	return uint32(units)
+}
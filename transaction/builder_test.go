@@ -0,0 +1,295 @@
+package transaction_test
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+
+	dbcErrors "github.com/Luigi-1Combo/dbc-go/errors"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+	"github.com/Luigi-1Combo/dbc-go/transaction"
+)
+
+func TestFeePercentile(t *testing.T) {
+	fees := []uint64{500, 100, 400, 0, 200, 300}
+	tests := []struct {
+		name       string
+		fees       []uint64
+		percentile float64
+		want       uint64
+	}{
+		{name: "no fees", percentile: 50, want: 0},
+		{name: "single fee", fees: []uint64{42}, percentile: 90, want: 42},
+		{name: "minimum", fees: fees, percentile: 0, want: 0},
+		{name: "median", fees: fees, percentile: 50, want: 200},
+		{name: "75th", fees: fees, percentile: 75, want: 400},
+		{name: "maximum", fees: fees, percentile: 100, want: 500},
+		{name: "below range", fees: fees, percentile: -10, want: 0},
+		{name: "above range", fees: fees, percentile: 150, want: 500},
+	}
+	for _, tt := range tests {
+		if got := transaction.FeePercentile(tt.fees, tt.percentile); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+	if fees[0] != 500 {
+		t.Error("FeePercentile sorted the caller's slice")
+	}
+}
+
+func TestGetWritableAccounts(t *testing.T) {
+	signer := solana.NewWallet().PublicKey()
+	pool := solana.NewWallet().PublicKey()
+	vault := solana.NewWallet().PublicKey()
+	mint := solana.NewWallet().PublicKey()
+	program := solana.NewWallet().PublicKey()
+
+	ixs := []solana.Instruction{
+		solana.NewInstruction(program, solana.AccountMetaSlice{
+			solana.Meta(signer).WRITE().SIGNER(),
+			solana.Meta(pool).WRITE(),
+			solana.Meta(mint),
+		}, nil),
+		solana.NewInstruction(program, solana.AccountMetaSlice{
+			solana.Meta(pool).WRITE(),
+			solana.Meta(vault).WRITE(),
+		}, nil),
+	}
+	got := transaction.GetWritableAccounts(ixs)
+	if len(got) != 2 || !got[0].Equals(pool) || !got[1].Equals(vault) {
+		t.Errorf("got %v, want [%s %s]", got, pool, vault)
+	}
+}
+
+// server answering recent prioritization fees with the given values, recording the
+// accounts of the last request
+func newBuilderServer(t *testing.T, fees ...uint64) (*rpctest.Server, *[]solana.PublicKey) {
+	t.Helper()
+	fetcher := rpctest.NewAccountFetcher()
+	fetcher.SetSlot(testSlot)
+	server := rpctest.NewServer(fetcher)
+	t.Cleanup(server.Close)
+
+	requested := new([]solana.PublicKey)
+	server.Handle("getRecentPrioritizationFees", func(params json.RawMessage) (interface{}, error) {
+		var args [][]solana.PublicKey
+		if err := json.Unmarshal(params, &args); err != nil || len(args) != 1 {
+			return nil, fmt.Errorf("invalid params")
+		}
+		*requested = args[0]
+		result := make([]map[string]uint64, 0, len(fees))
+		for i, fee := range fees {
+			result = append(result, map[string]uint64{"slot": testSlot - uint64(i), "prioritizationFee": fee})
+		}
+		return result, nil
+	})
+	return server, requested
+}
+
+// swap with random accounts, only built to be simulated
+func testSwapInstruction() solana.Instruction {
+	key := func() solana.PublicKey { return solana.NewWallet().PublicKey() }
+	return instructions.Swap(key(), key(), key(), key(), key(), key(), key(), solana.SolMint, key(),
+		solana.PublicKey{}, 1_000_000, 1)
+}
+
+// compute unit limit and price set by the transaction, price zero when not set
+func computeBudget(t *testing.T, tx *solana.Transaction) (uint32, uint64) {
+	t.Helper()
+	var limit uint32
+	var price uint64
+	for _, compiled := range tx.Message.Instructions {
+		programID, err := tx.Message.ResolveProgramIDIndex(compiled.ProgramIDIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !programID.Equals(computebudget.ProgramID) {
+			continue
+		}
+		switch compiled.Data[0] {
+		case computebudget.Instruction_SetComputeUnitLimit:
+			limit = binary.LittleEndian.Uint32(compiled.Data[1:])
+		case computebudget.Instruction_SetComputeUnitPrice:
+			price = binary.LittleEndian.Uint64(compiled.Data[1:])
+		}
+	}
+	return limit, price
+}
+
+func TestBuildTransaction(t *testing.T) {
+	ix := testSwapInstruction()
+	payer := solana.NewWallet().PublicKey()
+	tests := []struct {
+		name      string
+		fees      []uint64
+		opts      *transaction.BuildOpts
+		consumed  uint64
+		wantLimit uint32
+		wantPrice uint64
+		// whether fees and compute units are looked up
+		estimated bool
+	}{
+		{
+			name:      "default options",
+			fees:      []uint64{0, 1_000, 5_000, 20_000},
+			consumed:  100_000,
+			wantLimit: 110_000,
+			wantPrice: 1_000,
+			estimated: true,
+		},
+		{
+			name:      "capped price",
+			fees:      []uint64{50_000, 80_000},
+			opts:      &transaction.BuildOpts{ComputeUnitMargin: 0.25, PriorityFeePercentile: 100, MaxComputeUnitPrice: 60_000},
+			consumed:  60_000,
+			wantLimit: 75_000,
+			wantPrice: 60_000,
+			estimated: true,
+		},
+		{
+			name:      "zero margin and commitment defaulted",
+			fees:      []uint64{2_000},
+			opts:      &transaction.BuildOpts{PriorityFeePercentile: 50},
+			consumed:  100_000,
+			wantLimit: 110_000,
+			wantPrice: 2_000,
+			estimated: true,
+		},
+		{
+			name:      "limit capped at the maximum",
+			consumed:  1_350_000,
+			wantLimit: transaction.MaxComputeUnitLimit,
+			estimated: true,
+		},
+		{
+			name:      "fixed budget",
+			fees:      []uint64{1_000},
+			opts:      &transaction.BuildOpts{ComputeUnitLimit: 300_000, ComputeUnitPrice: 7_500},
+			wantLimit: 300_000,
+			wantPrice: 7_500,
+		},
+	}
+	for _, tt := range tests {
+		server, requested := newBuilderServer(t, tt.fees...)
+		server.Handle("simulateTransaction", (&rpctest.Simulation{Post: server.Fetcher, UnitsConsumed: tt.consumed}).Handle)
+
+		built, err := transaction.BuildTransaction(context.Background(), server.Client(), []solana.Instruction{ix}, payer, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if built.ComputeUnitLimit != tt.wantLimit || built.ComputeUnitPrice != tt.wantPrice {
+			t.Errorf("%s: got limit %d price %d, want %d and %d", tt.name,
+				built.ComputeUnitLimit, built.ComputeUnitPrice, tt.wantLimit, tt.wantPrice)
+		}
+		if limit, price := computeBudget(t, built.Transaction); limit != tt.wantLimit || price != tt.wantPrice {
+			t.Errorf("%s: transaction sets limit %d price %d, want %d and %d", tt.name, limit, price, tt.wantLimit, tt.wantPrice)
+		}
+		if n := len(built.Transaction.Message.Instructions); (tt.wantPrice == 0 && n != 2) || (tt.wantPrice != 0 && n != 3) {
+			t.Errorf("%s: got %d instructions", tt.name, n)
+		}
+		if !built.Transaction.Message.AccountKeys[0].Equals(payer) {
+			t.Errorf("%s: fee payer is %s", tt.name, built.Transaction.Message.AccountKeys[0])
+		}
+		if built.LastValidBlockHeight != testSlot+150 {
+			t.Errorf("%s: got last valid block height %d", tt.name, built.LastValidBlockHeight)
+		}
+
+		wantCalls := 0
+		if tt.estimated {
+			wantCalls = 1
+		}
+		for _, method := range []string{"getRecentPrioritizationFees", "simulateTransaction"} {
+			if calls := server.Calls(method); calls != wantCalls {
+				t.Errorf("%s: got %d %s calls, want %d", tt.name, calls, method, wantCalls)
+			}
+		}
+		if tt.estimated {
+			want := transaction.GetWritableAccounts([]solana.Instruction{ix})
+			if len(*requested) != len(want) {
+				t.Errorf("%s: fees requested for %d accounts, want %d", tt.name, len(*requested), len(want))
+			}
+		}
+	}
+}
+
+func TestBuildTransactionSimulationFailure(t *testing.T) {
+	server, _ := newBuilderServer(t)
+	// the swap follows the compute unit limit in the simulated transaction
+	server.Handle("simulateTransaction", (&rpctest.Simulation{
+		Post: server.Fetcher,
+		Err:  map[string]interface{}{"InstructionError": []interface{}{1, map[string]interface{}{"Custom": 6002}}},
+	}).Handle)
+
+	_, err := transaction.BuildTransaction(context.Background(), server.Client(),
+		[]solana.Instruction{testSwapInstruction()}, solana.NewWallet().PublicKey(), nil)
+	if !errors.Is(err, dbcErrors.ErrExceededSlippage) {
+		t.Fatalf("got %v, want %v", err, dbcErrors.ErrExceededSlippage)
+	}
+	var ixErr *dbcErrors.InstructionError
+	if !errors.As(err, &ixErr) || ixErr.Instruction != "Swap" {
+		t.Fatalf("got %v, want the failing Swap instruction", err)
+	}
+}
+
+func TestBuildTransactionDefaultsCommitment(t *testing.T) {
+	server, _ := newBuilderServer(t)
+	simulation := &rpctest.Simulation{Post: server.Fetcher, UnitsConsumed: 100_000}
+	var commitment string
+	server.Handle("simulateTransaction", func(params json.RawMessage) (interface{}, error) {
+		var args []json.RawMessage
+		var config struct {
+			Commitment string `json:"commitment"`
+		}
+		if err := json.Unmarshal(params, &args); err != nil || len(args) != 2 || json.Unmarshal(args[1], &config) != nil {
+			return nil, fmt.Errorf("invalid params")
+		}
+		commitment = config.Commitment
+		return simulation.Handle(params)
+	})
+
+	_, err := transaction.BuildTransaction(context.Background(), server.Client(),
+		[]solana.Instruction{testSwapInstruction()}, solana.NewWallet().PublicKey(), &transaction.BuildOpts{ComputeUnitPrice: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commitment != "confirmed" {
+		t.Fatalf("simulated at commitment %q, want confirmed", commitment)
+	}
+}
+
+func TestBuildTransactionNegativeMargin(t *testing.T) {
+	server, _ := newBuilderServer(t)
+	server.Handle("simulateTransaction", (&rpctest.Simulation{Post: server.Fetcher, UnitsConsumed: 100_000}).Handle)
+
+	_, err := transaction.BuildTransaction(context.Background(), server.Client(),
+		[]solana.Instruction{testSwapInstruction()}, solana.NewWallet().PublicKey(), &transaction.BuildOpts{ComputeUnitMargin: -0.5})
+	if err == nil {
+		t.Fatal("expected an error for a negative compute unit margin")
+	}
+	if server.Calls("simulateTransaction") != 0 || server.Calls("getRecentPrioritizationFees") != 0 {
+		t.Fatal("looked up fees or simulated with an invalid margin")
+	}
+}
+
+func TestBuildTransactionFeeLookupFailure(t *testing.T) {
+	server, _ := newBuilderServer(t)
+	server.Handle("getRecentPrioritizationFees", func(json.RawMessage) (interface{}, error) {
+		return nil, fmt.Errorf("node is behind")
+	})
+
+	_, err := transaction.BuildTransaction(context.Background(), server.Client(),
+		[]solana.Instruction{testSwapInstruction()}, solana.NewWallet().PublicKey(), nil)
+	if err == nil {
+		t.Fatal("expected the fee lookup error")
+	}
+	if server.Calls("simulateTransaction") != 0 {
+		t.Fatal("simulated after the fee lookup failed")
+	}
+}
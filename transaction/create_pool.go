@@ -0,0 +1,208 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/helpers"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/math"
+)
+
+type CreatePoolParams struct {
+	Config solana.PublicKey
+	// signs the pool creation
+	Creator solana.PublicKey
+	// pays the transaction fee and rents, defaults to Creator
+	Payer solana.PublicKey
+	// base mint keypair, e.g. a vanity address; generated when empty
+	BaseMint solana.PrivateKey
+	Name     string
+	Symbol   string
+	URI      string
+
+	// quote amount spent on the first buy, no buy when zero
+	FirstBuyAmountIn uint64
+	// signs the first buy and receives the base tokens, defaults to Creator
+	Buyer solana.PublicKey
+	// tolerated shortfall of the quoted first buy amount out, in basis points
+	SlippageBps uint64
+	// optional referral token account of the first buy, zero for none
+	ReferralTokenAccount solana.PublicKey
+	// deployment to create the pool on, defaults to instructions.DefaultProgram
+	Program *instructions.Program
+
+	// options of the built transaction, see BuildTransaction
+	BuildOpts *BuildOpts
+}
+
+type CreatePoolTransaction struct {
+	*BuiltTransaction
+	Pool     solana.PublicKey
+	BaseMint solana.PublicKey
+	// base mint keypair, must sign the transaction alongside the creator, buyer and payer
+	BaseMintKey solana.PrivateKey
+	// quote of the first buy on the fresh curve, nil without a first buy
+	Quote            *common.SwapResult
+	MinimumAmountOut uint64
+}
+
+// Builds a transaction creating a pool and, optionally, buying from it in the same
+// transaction, so the first buy lands atomically with the launch
+func CreatePoolWithFirstBuy(
+	ctx context.Context,
+	rpcClient *solRpc.Client,
+	params *CreatePoolParams,
+) (*CreatePoolTransaction, error) {
+	if err := helpers.ValidateTokenMetadata(params.Name, params.Symbol, params.URI); err != nil {
+		return nil, err
+	}
+
+	program := programOf(params.Program)
+	config, err := program.GetPoolConfig(ctx, params.Config, rpcClient)
+	if err != nil {
+		return nil, err
+	}
+	if config.TokenType != common.TokenTypeSplToken {
+		return nil, fmt.Errorf("unsupported token type: %d", config.TokenType)
+	}
+
+	baseMintKey := params.BaseMint
+	if len(baseMintKey) == 0 {
+		baseMintKey, err = solana.NewRandomPrivateKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate base mint: %w", err)
+		}
+	}
+	baseMint := baseMintKey.PublicKey()
+	quoteMint := config.QuoteMint
+
+	payer := params.Payer
+	if payer.IsZero() {
+		payer = params.Creator
+	}
+	buyer := params.Buyer
+	if buyer.IsZero() {
+		buyer = params.Creator
+	}
+
+	pool, err := program.DerivePool(quoteMint, baseMint, params.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive pool: %w", err)
+	}
+	baseVault, err := program.DeriveTokenVault(pool, baseMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive base vault: %w", err)
+	}
+	quoteVault, err := program.DeriveTokenVault(pool, quoteMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive quote vault: %w", err)
+	}
+	mintMetadata, err := program.DeriveMintMetadata(baseMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive mint metadata: %w", err)
+	}
+	ixs := []solana.Instruction{
+		program.InitializeVirtualPoolWithSplToken(
+			params.Config,
+			params.Creator,
+			baseMint,
+			quoteMint,
+			pool,
+			baseVault,
+			quoteVault,
+			mintMetadata,
+			payer,
+			params.Name,
+			params.Symbol,
+			params.URI,
+		),
+	}
+
+	result := &CreatePoolTransaction{
+		Pool:        pool,
+		BaseMint:    baseMint,
+		BaseMintKey: baseMintKey,
+	}
+
+	if params.FirstBuyAmountIn != 0 {
+		swapParams := &SwapParams{
+			Pool:                 pool,
+			Owner:                buyer,
+			Payer:                payer,
+			TradeDirection:       common.QuoteToBase,
+			AmountIn:             params.FirstBuyAmountIn,
+			SlippageBps:          params.SlippageBps,
+			ReferralTokenAccount: params.ReferralTokenAccount,
+			Program:              params.Program,
+		}
+
+		hasReferral := !params.ReferralTokenAccount.IsZero()
+		if hasReferral {
+			feeMint, err := GetFeeTokenMint(config.CollectFeeMode, common.QuoteToBase, baseMint, quoteMint)
+			if err != nil {
+				return nil, err
+			}
+			if err := ValidateReferralTokenAccount(ctx, rpcClient, params.ReferralTokenAccount, feeMint); err != nil {
+				return nil, err
+			}
+		}
+
+		slot, err := rpcClient.GetSlot(ctx, solRpc.CommitmentConfirmed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get slot: %w", err)
+		}
+		currentPoint, err := getCurrentPoint(config.ActivationType, slot)
+		if err != nil {
+			return nil, err
+		}
+
+		// the pool is activated at creation, so the first buy pays the cliff fee
+		initialPool := GetInitialPool(config, currentPoint)
+		quote, err := math.SwapQuote(
+			initialPool,
+			config,
+			common.QuoteToBase,
+			params.FirstBuyAmountIn,
+			hasReferral,
+			currentPoint,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to quote first buy: %w", err)
+		}
+		minOut, err := math.GetMinimumAmountOut(quote.OutputAmount, params.SlippageBps)
+		if err != nil {
+			return nil, err
+		}
+
+		rateLimited := math.IsRateLimiterApplied(&config.PoolFees.BaseFee, currentPoint, initialPool.ActivationPoint, common.QuoteToBase)
+		swapIxs, err := GetSwapInstructions(ctx, rpcClient, swapParams, params.Config, baseMint, quoteMint, minOut, rateLimited)
+		if err != nil {
+			return nil, err
+		}
+		ixs = append(ixs, swapIxs...)
+
+		result.Quote = quote
+		result.MinimumAmountOut = minOut
+	}
+
+	built, err := BuildTransaction(ctx, rpcClient, ixs, payer, params.BuildOpts)
+	if err != nil {
+		return nil, err
+	}
+	result.BuiltTransaction = built
+
+	return result, nil
+}
+
+// Gets the state of a pool right after its creation, priced at the config start price
+func GetInitialPool(config *common.PoolConfig, activationPoint uint64) *common.Pool {
+	return &common.Pool{
+		SqrtPrice:       config.SqrtStartPrice,
+		ActivationPoint: activationPoint,
+	}
+}
@@ -0,0 +1,192 @@
+package transaction_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/math"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+	"github.com/Luigi-1Combo/dbc-go/transaction"
+)
+
+// node holding the sample config at a random address
+func newCreatePoolNode(t *testing.T) (*rpctest.Server, solana.PublicKey, *common.PoolConfig) {
+	t.Helper()
+	server, _ := newBuilderServer(t)
+	server.Handle("simulateTransaction", (&rpctest.Simulation{Post: server.Fetcher, UnitsConsumed: 150_000}).Handle)
+	address := solana.NewWallet().PublicKey()
+	config := rpctest.SamplePoolConfig()
+	if err := server.Fetcher.SetPoolConfig(address, config); err != nil {
+		t.Fatal(err)
+	}
+	return server, address, config
+}
+
+func TestCreatePoolWithFirstBuy(t *testing.T) {
+	creator := solana.NewWallet().PublicKey()
+	buyer := solana.NewWallet().PublicKey()
+	tests := []struct {
+		name     string
+		firstBuy uint64
+		buyer    solana.PublicKey
+		want     []string
+	}{
+		{name: "without first buy", want: []string{"InitializeVirtualPoolWithSplToken"}},
+		{name: "with first buy", firstBuy: 5_000_000_000, want: []string{"InitializeVirtualPoolWithSplToken", "Swap"}},
+		{name: "first buy by another wallet", firstBuy: 5_000_000_000, buyer: buyer, want: []string{"InitializeVirtualPoolWithSplToken", "Swap"}},
+	}
+	for _, tt := range tests {
+		server, configAddress, config := newCreatePoolNode(t)
+		baseMint := solana.NewWallet().PrivateKey
+		params := &transaction.CreatePoolParams{
+			Config:           configAddress,
+			Creator:          creator,
+			BaseMint:         baseMint,
+			Name:             "Dynamic Bonding",
+			Symbol:           "DBC",
+			URI:              "https://arweave.net/abc123",
+			FirstBuyAmountIn: tt.firstBuy,
+			Buyer:            tt.buyer,
+			SlippageBps:      50,
+		}
+
+		created, err := transaction.CreatePoolWithFirstBuy(context.Background(), server.Client(), params)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		wantPool, err := instructions.DefaultProgram.DerivePool(config.QuoteMint, baseMint.PublicKey(), configAddress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !created.BaseMint.Equals(baseMint.PublicKey()) || !created.Pool.Equals(wantPool) {
+			t.Errorf("%s: got base mint %s pool %s, want %s and %s", tt.name, created.BaseMint, created.Pool, baseMint.PublicKey(), wantPool)
+		}
+		if got := dbcInstructionNames(t, created.Transaction); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		if !created.Transaction.Message.AccountKeys[0].Equals(creator) {
+			t.Errorf("%s: fee payer is %s", tt.name, created.Transaction.Message.AccountKeys[0])
+		}
+
+		if tt.firstBuy == 0 {
+			if created.Quote != nil || created.MinimumAmountOut != 0 {
+				t.Errorf("%s: got a quote without a first buy", tt.name)
+			}
+			continue
+		}
+		// the fresh pool sits at the start price and activates at the node's slot
+		quote, err := math.SwapQuote(transaction.GetInitialPool(config, testSlot), config, common.QuoteToBase, tt.firstBuy, false, testSlot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		minOut, _ := math.GetMinimumAmountOut(quote.OutputAmount, params.SlippageBps)
+		if created.Quote == nil || *created.Quote != *quote || created.MinimumAmountOut != minOut {
+			t.Errorf("%s: got quote %+v min out %d, want %+v and %d", tt.name, created.Quote, created.MinimumAmountOut, quote, minOut)
+		}
+
+		owner := creator
+		if !tt.buyer.IsZero() {
+			owner = tt.buyer
+		}
+		baseAccount, _, _ := solana.FindAssociatedTokenAddress(owner, baseMint.PublicKey())
+		if !hasAccount(created.Transaction, baseAccount) || !hasAccount(created.Transaction, owner) {
+			t.Errorf("%s: the first buy does not go to %s", tt.name, owner)
+		}
+	}
+}
+
+func TestCreatePoolWithFirstBuyGeneratesBaseMint(t *testing.T) {
+	server, configAddress, _ := newCreatePoolNode(t)
+	params := &transaction.CreatePoolParams{
+		Config:  configAddress,
+		Creator: solana.NewWallet().PublicKey(),
+		Name:    "Dynamic Bonding",
+		Symbol:  "DBC",
+	}
+	created, err := transaction.CreatePoolWithFirstBuy(context.Background(), server.Client(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created.BaseMintKey) == 0 || !created.BaseMintKey.PublicKey().Equals(created.BaseMint) {
+		t.Fatalf("got base mint %s with key %v", created.BaseMint, created.BaseMintKey)
+	}
+	if !hasAccount(created.Transaction, created.BaseMint) {
+		t.Fatal("the generated base mint is not part of the transaction")
+	}
+}
+
+func TestCreatePoolWithFirstBuyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(params *transaction.CreatePoolParams, config *common.PoolConfig)
+		target error
+		// whether the node is asked for anything before the error
+		queried bool
+	}{
+		{
+			name:   "invalid metadata",
+			modify: func(params *transaction.CreatePoolParams, _ *common.PoolConfig) { params.Symbol = "DYNAMICBOND" },
+		},
+		{
+			name: "token 2022 config",
+			modify: func(_ *transaction.CreatePoolParams, config *common.PoolConfig) {
+				config.TokenType = common.TokenTypeToken2022
+			},
+			queried: true,
+		},
+		{
+			name: "missing config",
+			modify: func(params *transaction.CreatePoolParams, _ *common.PoolConfig) {
+				params.Config = solana.NewWallet().PublicKey()
+			},
+			queried: true,
+		},
+		{
+			name: "referral in the base mint",
+			modify: func(params *transaction.CreatePoolParams, _ *common.PoolConfig) {
+				params.ReferralTokenAccount = solana.NewWallet().PublicKey()
+			},
+			target:  transaction.ErrInvalidReferralMint,
+			queried: true,
+		},
+	}
+	for _, tt := range tests {
+		server, configAddress, config := newCreatePoolNode(t)
+		params := &transaction.CreatePoolParams{
+			Config:           configAddress,
+			Creator:          solana.NewWallet().PublicKey(),
+			BaseMint:         solana.NewWallet().PrivateKey,
+			Name:             "Dynamic Bonding",
+			Symbol:           "DBC",
+			FirstBuyAmountIn: 1_000_000_000,
+		}
+		tt.modify(params, config)
+		if err := server.Fetcher.SetPoolConfig(configAddress, config); err != nil {
+			t.Fatal(err)
+		}
+		if !params.ReferralTokenAccount.IsZero() {
+			server.Fetcher.SetTokenAccount(params.ReferralTokenAccount, params.BaseMint.PublicKey(), solana.NewWallet().PublicKey(), 0)
+		}
+
+		_, err := transaction.CreatePoolWithFirstBuy(context.Background(), server.Client(), params)
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+			continue
+		}
+		if tt.target != nil && !errors.Is(err, tt.target) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.target)
+		}
+		if queried := server.Calls("getAccountInfo") > 0; queried != tt.queried {
+			t.Errorf("%s: queried the node: %v", tt.name, queried)
+		}
+		if server.Calls("simulateTransaction") != 0 {
+			t.Errorf("%s: simulated a failed build", tt.name)
+		}
+	}
+}
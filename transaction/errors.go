@@ -0,0 +1,103 @@
+package transaction
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+
+	dbcErrors "github.com/Luigi-1Combo/dbc-go/errors"
+)
+
+// returned when the transaction blockhash expired before the transaction landed
+var ErrTransactionExpired = errors.New("transaction expired: block height exceeded")
+
+// returned when the transaction landed but failed, or was rejected by the preflight
+// simulation before it was sent
+type TransactionFailedError struct {
+	Signature solana.Signature
+	// raw error as returned by the rpc, e.g. map[InstructionError:[5 map[Custom:6012]]]
+	Err interface{}
+	// decoded instruction error, nil when the failure is not an instruction error
+	Decoded *dbcErrors.InstructionError
+	// whether the preflight simulation rejected the transaction, it did not land then
+	Preflight bool
+	// program logs of the preflight simulation
+	Logs []string
+}
+
+func newTransactionFailedError(sig solana.Signature, tx *solana.Transaction, raw interface{}) *TransactionFailedError {
+	decoded, _ := dbcErrors.DecodeTransactionError(tx, raw)
+	return &TransactionFailedError{Signature: sig, Err: raw, Decoded: decoded}
+}
+
+// decodes a sendTransaction error of a transaction rejected by the preflight simulation,
+// the node returns the transaction error and logs in the JSON-RPC error data
+func newPreflightError(tx *solana.Transaction, err error) (*TransactionFailedError, bool) {
+	var rpcErr *jsonrpc.RPCError
+	if !errors.As(err, &rpcErr) {
+		return nil, false
+	}
+	data, ok := rpcErr.Data.(map[string]interface{})
+	if !ok || data["err"] == nil {
+		return nil, false
+	}
+
+	var sig solana.Signature
+	if len(tx.Signatures) > 0 {
+		sig = tx.Signatures[0]
+	}
+	failed := newTransactionFailedError(sig, tx, data["err"])
+	failed.Preflight = true
+	if logs, ok := data["logs"].([]interface{}); ok {
+		for _, log := range logs {
+			if line, ok := log.(string); ok {
+				failed.Logs = append(failed.Logs, line)
+			}
+		}
+	}
+	return failed, true
+}
+
+func (e *TransactionFailedError) Error() string {
+	failed := "failed"
+	if e.Preflight {
+		failed = "failed preflight"
+	}
+	if e.Decoded != nil {
+		return fmt.Sprintf("transaction %s %s: %v", e.Signature, failed, e.Decoded)
+	}
+	if index, code, ok := e.CustomError(); ok {
+		return fmt.Sprintf("transaction %s %s: instruction %d: custom program error %d", e.Signature, failed, index, code)
+	}
+	return fmt.Sprintf("transaction %s %s: %v", e.Signature, failed, e.Err)
+}
+
+// allows errors.Is(err, dbcErrors.ErrExceededSlippage) on failed transactions
+func (e *TransactionFailedError) Unwrap() error {
+	if e.Decoded == nil {
+		return nil
+	}
+	return e.Decoded
+}
+
+// Gets the index of the failing instruction, if the failure is an instruction error
+func (e *TransactionFailedError) InstructionIndex() (int, bool) {
+	index, _, ok := dbcErrors.ParseInstructionError(e.Err)
+	return index, ok
+}
+
+// Gets the failing instruction index and custom program error code, if the failure
+// is a custom program error
+func (e *TransactionFailedError) CustomError() (int, uint32, bool) {
+	index, detail, ok := dbcErrors.ParseInstructionError(e.Err)
+	if !ok {
+		return 0, 0, false
+	}
+	code, ok := dbcErrors.ParseCustomErrorCode(detail)
+	if !ok {
+		return 0, 0, false
+	}
+	return index, code, true
+}
@@ -0,0 +1,202 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+const (
+	DefaultRebroadcastInterval = 2 * time.Second
+	DefaultPollInterval        = 500 * time.Millisecond
+)
+
+type SendOpts struct {
+	// commitment the transaction must reach, defaults to confirmed
+	Commitment solRpc.CommitmentType
+	// last block height at which the transaction blockhash is valid, see BuiltTransaction;
+	// when zero the blockhash validity is queried instead
+	LastValidBlockHeight uint64
+	// how often the transaction is sent again until it lands
+	RebroadcastInterval time.Duration
+	// how often signature statuses are polled
+	PollInterval time.Duration
+	// skip the preflight simulation of the first send
+	SkipPreflight bool
+	// optional websocket client, signature notifications are used alongside polling
+	WsClient *ws.Client
+}
+
+func DefaultSendOpts() *SendOpts {
+	return &SendOpts{
+		Commitment:          solRpc.CommitmentConfirmed,
+		RebroadcastInterval: DefaultRebroadcastInterval,
+		PollInterval:        DefaultPollInterval,
+	}
+}
+
+// Sends a signed transaction and rebroadcasts it until it reaches the requested commitment
+// or its blockhash expires. Returns ErrTransactionExpired when the blockhash expired and
+// *TransactionFailedError when the transaction landed with an error or the preflight
+// simulation rejected it.
+func SendAndConfirm(
+	ctx context.Context,
+	rpcClient *solRpc.Client,
+	tx *solana.Transaction,
+	opts *SendOpts,
+) (solana.Signature, error) {
+	opts = withSendDefaults(opts)
+
+	txData, err := tx.MarshalBinary()
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	// we rebroadcast ourselves, so ask the rpc node not to retry
+	maxRetries := uint(0)
+	sig, err := rpcClient.SendRawTransactionWithOpts(ctx, txData, solRpc.TransactionOpts{
+		SkipPreflight:       opts.SkipPreflight,
+		PreflightCommitment: opts.Commitment,
+		MaxRetries:          &maxRetries,
+	})
+	if err != nil {
+		if failed, ok := newPreflightError(tx, err); ok {
+			return solana.Signature{}, failed
+		}
+		return solana.Signature{}, fmt.Errorf("failed to send transaction: %w", err)
+	}
+
+	notified := make(chan interface{}, 1)
+	if opts.WsClient != nil {
+		sub, err := opts.WsClient.SignatureSubscribe(sig, opts.Commitment)
+		if err == nil {
+			defer sub.Unsubscribe()
+			go func() {
+				res, err := sub.Recv()
+				if err == nil && res != nil {
+					notified <- res.Value.Err
+				}
+			}()
+		}
+	}
+
+	poll := time.NewTicker(opts.PollInterval)
+	defer poll.Stop()
+	rebroadcast := time.NewTicker(opts.RebroadcastInterval)
+	defer rebroadcast.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return sig, ctx.Err()
+
+		case txErr := <-notified:
+			if txErr != nil {
+				return sig, newTransactionFailedError(sig, tx, txErr)
+			}
+			return sig, nil
+
+		case <-rebroadcast.C:
+			// errors are expected once the transaction landed, the status poll decides
+			_, _ = rpcClient.SendRawTransactionWithOpts(ctx, txData, solRpc.TransactionOpts{
+				SkipPreflight: true,
+				MaxRetries:    &maxRetries,
+			})
+
+		case <-poll.C:
+			done, err := checkSignatureStatus(ctx, rpcClient, tx, sig, opts.Commitment)
+			if done || err != nil {
+				return sig, err
+			}
+
+			expired, err := isExpired(ctx, rpcClient, tx.Message.RecentBlockhash, opts.LastValidBlockHeight)
+			if err != nil || !expired {
+				continue
+			}
+
+			// the transaction may have landed between the status check and the expiry check
+			done, err = checkSignatureStatus(ctx, rpcClient, tx, sig, opts.Commitment)
+			if done || err != nil {
+				return sig, err
+			}
+			return sig, fmt.Errorf("%w: %s", ErrTransactionExpired, sig)
+		}
+	}
+}
+
+// reports whether the signature reached the commitment, or the error it failed with
+func checkSignatureStatus(
+	ctx context.Context,
+	rpcClient *solRpc.Client,
+	tx *solana.Transaction,
+	sig solana.Signature,
+	commitment solRpc.CommitmentType,
+) (bool, error) {
+	statuses, err := rpcClient.GetSignatureStatuses(ctx, false, sig)
+	if err != nil || statuses == nil || len(statuses.Value) == 0 || statuses.Value[0] == nil {
+		return false, nil
+	}
+
+	status := statuses.Value[0]
+	if status.Err != nil {
+		return true, newTransactionFailedError(sig, tx, status.Err)
+	}
+	return reachedCommitment(status.ConfirmationStatus, commitment), nil
+}
+
+func isExpired(
+	ctx context.Context,
+	rpcClient *solRpc.Client,
+	blockhash solana.Hash,
+	lastValidBlockHeight uint64,
+) (bool, error) {
+	if lastValidBlockHeight != 0 {
+		blockHeight, err := rpcClient.GetBlockHeight(ctx, solRpc.CommitmentConfirmed)
+		if err != nil {
+			return false, err
+		}
+		return blockHeight > lastValidBlockHeight, nil
+	}
+
+	valid, err := rpcClient.IsBlockhashValid(ctx, blockhash, solRpc.CommitmentProcessed)
+	if err != nil {
+		return false, err
+	}
+	return !valid.Value, nil
+}
+
+func reachedCommitment(status solRpc.ConfirmationStatusType, commitment solRpc.CommitmentType) bool {
+	rank := map[string]int{
+		string(solRpc.CommitmentProcessed): 0,
+		string(solRpc.CommitmentConfirmed): 1,
+		string(solRpc.CommitmentFinalized): 2,
+	}
+	got, ok := rank[string(status)]
+	if !ok {
+		return false
+	}
+	return got >= rank[string(commitment)]
+}
+
+func withSendDefaults(opts *SendOpts) *SendOpts {
+	defaults := DefaultSendOpts()
+	if opts == nil {
+		return defaults
+	}
+
+	merged := *opts
+	if merged.Commitment == "" {
+		merged.Commitment = defaults.Commitment
+	}
+	if merged.RebroadcastInterval == 0 {
+		merged.RebroadcastInterval = defaults.RebroadcastInterval
+	}
+	if merged.PollInterval == 0 {
+		merged.PollInterval = defaults.PollInterval
+	}
+	return &merged
+}
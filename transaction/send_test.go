@@ -0,0 +1,395 @@
+package transaction_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+
+	dbcErrors "github.com/Luigi-1Combo/dbc-go/errors"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+	"github.com/Luigi-1Combo/dbc-go/transaction"
+)
+
+// node that accepts transactions and answers signature statuses from a script,
+// repeating the last status once the script runs out
+type sendNode struct {
+	*rpctest.Server
+
+	mu sync.Mutex
+	// status per getSignatureStatuses call: nil when unknown, otherwise the confirmation
+	// status, e.g. "confirmed"
+	statuses []*string
+	// transaction error reported with the statuses
+	txErr interface{}
+	// options of every sendTransaction call
+	sends []map[string]interface{}
+}
+
+func newSendNode(t *testing.T, statuses ...*string) *sendNode {
+	t.Helper()
+	fetcher := rpctest.NewAccountFetcher()
+	fetcher.SetSlot(testSlot)
+	node := &sendNode{Server: rpctest.NewServer(fetcher), statuses: statuses}
+	t.Cleanup(node.Close)
+
+	node.Handle("sendTransaction", func(params json.RawMessage) (interface{}, error) {
+		var args []json.RawMessage
+		if err := json.Unmarshal(params, &args); err != nil || len(args) != 2 {
+			return nil, fmt.Errorf("invalid params")
+		}
+		var encoded string
+		var opts map[string]interface{}
+		if err := json.Unmarshal(args[0], &encoded); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(args[1], &opts); err != nil {
+			return nil, err
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+		// a single signature follows its compact length
+		if len(data) < 1+solana.SignatureLength || data[0] != 1 {
+			return nil, fmt.Errorf("expected a single signature")
+		}
+		node.mu.Lock()
+		node.sends = append(node.sends, opts)
+		node.mu.Unlock()
+		return solana.SignatureFromBytes(data[1 : 1+solana.SignatureLength]), nil
+	})
+	node.Handle("getSignatureStatuses", func(json.RawMessage) (interface{}, error) {
+		node.mu.Lock()
+		defer node.mu.Unlock()
+		var status *string
+		if len(node.statuses) > 0 {
+			status = node.statuses[0]
+			if len(node.statuses) > 1 {
+				node.statuses = node.statuses[1:]
+			}
+		}
+		value := []interface{}{nil}
+		if status != nil {
+			value[0] = map[string]interface{}{
+				"slot":               testSlot,
+				"confirmations":      nil,
+				"err":                node.txErr,
+				"confirmationStatus": *status,
+			}
+		}
+		return map[string]interface{}{"context": map[string]interface{}{"slot": testSlot}, "value": value}, nil
+	})
+	return node
+}
+
+func (n *sendNode) sendOpts() []map[string]interface{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]map[string]interface{}(nil), n.sends...)
+}
+
+func status(s string) *string {
+	return &s
+}
+
+// signed swap transaction, the swap being its only instruction
+func signedSwap(t *testing.T) *solana.Transaction {
+	t.Helper()
+	user := solana.NewWallet()
+	key := func() solana.PublicKey { return solana.NewWallet().PublicKey() }
+	ix := instructions.Swap(key(), key(), key(), key(), key(), key(), key(), solana.SolMint,
+		user.PublicKey(), solana.PublicKey{}, 1_000_000, 1)
+	tx, err := solana.NewTransaction([]solana.Instruction{ix}, solana.Hash{1}, solana.TransactionPayer(user.PublicKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Sign(func(solana.PublicKey) *solana.PrivateKey { return &user.PrivateKey }); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func fastSendOpts() *transaction.SendOpts {
+	return &transaction.SendOpts{
+		RebroadcastInterval: 3 * time.Millisecond,
+		PollInterval:        time.Millisecond,
+	}
+}
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestSendAndConfirm(t *testing.T) {
+	tests := []struct {
+		name       string
+		commitment solRpc.CommitmentType
+		statuses   []*string
+		// getSignatureStatuses calls until the commitment is reached
+		polls int
+	}{
+		{name: "confirmed", statuses: []*string{nil, status("processed"), status("confirmed")}, polls: 3},
+		{name: "processed", commitment: solRpc.CommitmentProcessed, statuses: []*string{status("processed")}, polls: 1},
+		{name: "finalized", commitment: solRpc.CommitmentFinalized,
+			statuses: []*string{status("confirmed"), status("confirmed"), status("finalized")}, polls: 3},
+	}
+	for _, tt := range tests {
+		node := newSendNode(t, tt.statuses...)
+		tx := signedSwap(t)
+		opts := fastSendOpts()
+		opts.Commitment = tt.commitment
+
+		sig, err := transaction.SendAndConfirm(testContext(t), node.Client(), tx, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !sig.Equals(tx.Signatures[0]) {
+			t.Errorf("%s: got signature %s, want %s", tt.name, sig, tx.Signatures[0])
+		}
+		if polls := node.Calls("getSignatureStatuses"); polls != tt.polls {
+			t.Errorf("%s: got %d status polls, want %d", tt.name, polls, tt.polls)
+		}
+	}
+}
+
+func TestSendAndConfirmRebroadcasts(t *testing.T) {
+	// unknown for longer than a few rebroadcast intervals
+	statuses := make([]*string, 20)
+	node := newSendNode(t, append(statuses, status("confirmed"))...)
+	opts := fastSendOpts()
+	opts.PollInterval = 2 * time.Millisecond
+
+	if _, err := transaction.SendAndConfirm(testContext(t), node.Client(), signedSwap(t), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	sends := node.sendOpts()
+	if len(sends) < 2 {
+		t.Fatalf("got %d sends, want rebroadcasts", len(sends))
+	}
+	for i, opts := range sends {
+		// the node must not retry on its own, and only the first send is preflighted
+		if opts["maxRetries"] != float64(0) {
+			t.Errorf("send %d: got max retries %v", i, opts["maxRetries"])
+		}
+		if skip, _ := opts["skipPreflight"].(bool); skip != (i > 0) {
+			t.Errorf("send %d: got skip preflight %v", i, skip)
+		}
+	}
+}
+
+func TestSendAndConfirmTransactionFailed(t *testing.T) {
+	node := newSendNode(t, status("confirmed"))
+	node.txErr = map[string]interface{}{"InstructionError": []interface{}{0, map[string]interface{}{"Custom": 6002}}}
+	tx := signedSwap(t)
+
+	_, err := transaction.SendAndConfirm(testContext(t), node.Client(), tx, fastSendOpts())
+	if !errors.Is(err, dbcErrors.ErrExceededSlippage) {
+		t.Fatalf("got %v, want %v", err, dbcErrors.ErrExceededSlippage)
+	}
+	var failed *transaction.TransactionFailedError
+	if !errors.As(err, &failed) {
+		t.Fatalf("got %T, want *TransactionFailedError", err)
+	}
+	if !failed.Signature.Equals(tx.Signatures[0]) || failed.Decoded == nil || failed.Decoded.Instruction != "Swap" {
+		t.Errorf("got %+v", failed)
+	}
+}
+
+func TestSendAndConfirmExpired(t *testing.T) {
+	tests := []struct {
+		name                 string
+		lastValidBlockHeight uint64
+		handle               func(node *sendNode)
+		method               string
+	}{
+		{
+			name:                 "block height past the last valid one",
+			lastValidBlockHeight: testSlot,
+			method:               "getBlockHeight",
+			handle: func(node *sendNode) {
+				node.Handle("getBlockHeight", func(json.RawMessage) (interface{}, error) {
+					return testSlot + 1, nil
+				})
+			},
+		},
+		{
+			name:   "blockhash no longer valid",
+			method: "isBlockhashValid",
+			handle: func(node *sendNode) {
+				node.Handle("isBlockhashValid", func(json.RawMessage) (interface{}, error) {
+					return map[string]interface{}{"context": map[string]interface{}{"slot": testSlot}, "value": false}, nil
+				})
+			},
+		},
+	}
+	for _, tt := range tests {
+		node := newSendNode(t, nil)
+		tt.handle(node)
+		opts := fastSendOpts()
+		opts.LastValidBlockHeight = tt.lastValidBlockHeight
+
+		_, err := transaction.SendAndConfirm(testContext(t), node.Client(), signedSwap(t), opts)
+		if !errors.Is(err, transaction.ErrTransactionExpired) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, transaction.ErrTransactionExpired)
+		}
+		if node.Calls(tt.method) == 0 {
+			t.Errorf("%s: expiry not checked with %s", tt.name, tt.method)
+		}
+		// the status is checked again after the expiry, in case the transaction just landed
+		if polls := node.Calls("getSignatureStatuses"); polls != 2 {
+			t.Errorf("%s: got %d status polls, want 2", tt.name, polls)
+		}
+	}
+}
+
+func TestSendAndConfirmLandedAtExpiry(t *testing.T) {
+	node := newSendNode(t, nil, status("confirmed"))
+	node.Handle("getBlockHeight", func(json.RawMessage) (interface{}, error) {
+		return testSlot + 1, nil
+	})
+	opts := fastSendOpts()
+	opts.LastValidBlockHeight = testSlot
+
+	if _, err := transaction.SendAndConfirm(testContext(t), node.Client(), signedSwap(t), opts); err != nil {
+		t.Fatalf("got %v, want the transaction confirmed", err)
+	}
+}
+
+func TestSendAndConfirmSendFailure(t *testing.T) {
+	node := newSendNode(t)
+	node.Handle("sendTransaction", func(json.RawMessage) (interface{}, error) {
+		return nil, fmt.Errorf("Transaction simulation failed: Blockhash not found")
+	})
+
+	if _, err := transaction.SendAndConfirm(testContext(t), node.Client(), signedSwap(t), fastSendOpts()); err == nil {
+		t.Fatal("expected the send error")
+	}
+	if node.Calls("getSignatureStatuses") != 0 {
+		t.Fatal("polled the status of a transaction that was not sent")
+	}
+}
+
+func TestSendAndConfirmPreflightFailure(t *testing.T) {
+	slippage := map[string]interface{}{"InstructionError": []interface{}{0, map[string]interface{}{"Custom": 6002}}}
+	tests := []struct {
+		name string
+		// error returned by sendTransaction
+		err    error
+		target error
+		// whether the send error is a *TransactionFailedError
+		failed bool
+		logs   []string
+	}{
+		{
+			name:   "program error",
+			err:    rpctest.PreflightError(slippage, []string{"Program log: Error: Exceeded slippage tolerance"}),
+			target: dbcErrors.ErrExceededSlippage,
+			failed: true,
+			logs:   []string{"Program log: Error: Exceeded slippage tolerance"},
+		},
+		{name: "transaction error", err: rpctest.PreflightError("BlockhashNotFound", nil), failed: true},
+		{name: "plain rpc error", err: fmt.Errorf("node is unhealthy")},
+	}
+	for _, tt := range tests {
+		node := newSendNode(t)
+		node.Handle("sendTransaction", func(json.RawMessage) (interface{}, error) {
+			return nil, tt.err
+		})
+		tx := signedSwap(t)
+
+		_, err := transaction.SendAndConfirm(testContext(t), node.Client(), tx, fastSendOpts())
+		if err == nil {
+			t.Fatalf("%s: expected the send error", tt.name)
+		}
+		var failed *transaction.TransactionFailedError
+		if errors.As(err, &failed) != tt.failed {
+			t.Fatalf("%s: got %v", tt.name, err)
+		}
+		if tt.target != nil && !errors.Is(err, tt.target) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.target)
+		}
+		if tt.failed {
+			if !failed.Preflight || failed.Signature != tx.Signatures[0] {
+				t.Errorf("%s: got %+v, want the preflight failure of %s", tt.name, failed, tx.Signatures[0])
+			}
+			if len(failed.Logs) != len(tt.logs) || (len(tt.logs) > 0 && failed.Logs[0] != tt.logs[0]) {
+				t.Errorf("%s: got logs %v, want %v", tt.name, failed.Logs, tt.logs)
+			}
+		}
+		if node.Calls("getSignatureStatuses") != 0 {
+			t.Errorf("%s: polled the status of a transaction that was not sent", tt.name)
+		}
+	}
+}
+
+func TestSendAndConfirmCanceled(t *testing.T) {
+	node := newSendNode(t, nil)
+	node.Handle("isBlockhashValid", func(json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{"context": map[string]interface{}{"slot": testSlot}, "value": true}, nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := transaction.SendAndConfirm(ctx, node.Client(), signedSwap(t), fastSendOpts()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestTransactionFailedError(t *testing.T) {
+	tx := signedSwap(t)
+	tests := []struct {
+		name      string
+		raw       interface{}
+		index     int
+		code      uint32
+		custom    bool
+		isIxError bool
+		target    error
+	}{
+		{
+			name:      "dbc custom error",
+			raw:       map[string]interface{}{"InstructionError": []interface{}{0, map[string]interface{}{"Custom": 6012}}},
+			code:      6012,
+			custom:    true,
+			isIxError: true,
+			target:    dbcErrors.ErrNotEnoughLiquidity,
+		},
+		{
+			name:      "builtin instruction error",
+			raw:       map[string]interface{}{"InstructionError": []interface{}{0, "InvalidAccountData"}},
+			isIxError: true,
+		},
+		{name: "transaction error", raw: "AccountInUse"},
+	}
+	for _, tt := range tests {
+		err := &transaction.TransactionFailedError{Signature: tx.Signatures[0], Err: tt.raw}
+		err.Decoded, _ = dbcErrors.DecodeTransactionError(tx, tt.raw)
+
+		index, code, custom := err.CustomError()
+		if custom != tt.custom || index != tt.index || code != tt.code {
+			t.Errorf("%s: got custom error %d %d %v", tt.name, index, code, custom)
+		}
+		if _, ok := err.InstructionIndex(); ok != tt.isIxError {
+			t.Errorf("%s: got instruction error %v", tt.name, ok)
+		}
+		if tt.target != nil && !errors.Is(err, tt.target) {
+			t.Errorf("%s: %v is not %v", tt.name, err, tt.target)
+		}
+		if err.Error() == "" {
+			t.Errorf("%s: empty message", tt.name)
+		}
+	}
+}
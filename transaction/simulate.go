@@ -0,0 +1,326 @@
+package transaction
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	dbcErrors "github.com/Luigi-1Combo/dbc-go/errors"
+	"github.com/Luigi-1Combo/dbc-go/helpers"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/math"
+)
+
+// SPL token account layout: mint (32), owner (32), amount (8), ...
+const tokenAccountAmountOffset = 64
+
+// accounts fetched before and simulated after the swap, in this order
+const (
+	trackedPool = iota
+	trackedInput
+	trackedOutput
+	trackedBaseVault
+	trackedQuoteVault
+	// only tracked when the swap has a referral
+	trackedReferral
+)
+
+type TokenBalanceChange struct {
+	Account solana.PublicKey
+	// zero when the account does not exist before the transaction
+	Pre uint64
+	// zero when the account does not exist after the transaction, e.g. closed wsol accounts
+	Post uint64
+}
+
+// signed change of the balance
+func (c TokenBalanceChange) Delta() int64 {
+	return int64(c.Post) - int64(c.Pre)
+}
+
+type SwapSimulation struct {
+	Pool           solana.PublicKey
+	TradeDirection common.TradeDirection
+	// ExactIn for swap instructions
+	SwapMode common.SwapMode
+	// amounts encoded in the swap or swap2 instruction: the amount in and minimum amount
+	// out for ExactIn and PartialFill, the amount out and maximum amount in for ExactOut
+	AmountIn         uint64
+	MinimumAmountOut uint64
+	AmountOut        uint64
+	MaximumAmountIn  uint64
+
+	// balances of the user token accounts and pool vaults
+	UserInput  TokenBalanceChange
+	UserOutput TokenBalanceChange
+	BaseVault  TokenBalanceChange
+	QuoteVault TokenBalanceChange
+	// zero when the swap has no referral
+	Referral TokenBalanceChange
+
+	// amount received by the user. When the output account is closed by the transaction,
+	// e.g. a wsol account unwrapped to lamports, it is what left the output vault less
+	// the referral fee paid from it.
+	ActualAmountOut uint64
+	// fees charged by the swap, read from the pool metrics
+	TradingFee  uint64
+	ProtocolFee uint64
+
+	PrePool  *common.Pool
+	PostPool *common.Pool
+	// off-chain quote for the same swap against the pre-state pool
+	Quote *common.SwapResult
+
+	UnitsConsumed uint64
+	Logs          []string
+}
+
+// difference between the simulated and the quoted amount out, false when there is no
+// quote, e.g. when the simulation failed
+func (s *SwapSimulation) AmountOutDiff() (int64, bool) {
+	if s.Quote == nil {
+		return 0, false
+	}
+	return int64(s.ActualAmountOut) - int64(s.Quote.OutputAmount), true
+}
+
+// Simulates a transaction containing a swap or swap2 instruction and decodes the token balance
+// changes and the post-state pool, alongside the off-chain quote for the same swap.
+// The pre-state is fetched right before the simulation, so both may be a slot apart.
+func SimulateSwap(
+	ctx context.Context,
+	rpcClient *solRpc.Client,
+	tx *solana.Transaction,
+) (*SwapSimulation, error) {
+	return SimulateProgramSwap(ctx, rpcClient, instructions.DefaultProgram, tx)
+}
+
+// Like SimulateSwap for a swap on the given deployment
+func SimulateProgramSwap(
+	ctx context.Context,
+	rpcClient *solRpc.Client,
+	program *instructions.Program,
+	tx *solana.Transaction,
+) (*SwapSimulation, error) {
+	swap, err := findSwapInstruction(tx, program)
+	if err != nil {
+		return nil, err
+	}
+	configAddress, _ := swap.Account("config")
+	referral, _ := swap.Account("referral_token_account")
+	hasReferral := !referral.Equals(program.Programs.Dbc)
+
+	sim := &SwapSimulation{}
+	sim.Pool, _ = swap.Account("pool")
+	// the quoted amount, in for ExactIn and PartialFill and out for ExactOut
+	var quoteAmount uint64
+	switch args := swap.Args.(type) {
+	case *instructions.SwapArgs:
+		sim.SwapMode = common.SwapModeExactIn
+		sim.AmountIn, sim.MinimumAmountOut = args.AmountIn, args.MinimumAmountOut
+		quoteAmount = args.AmountIn
+	case *instructions.Swap2Args:
+		sim.SwapMode = args.SwapMode
+		if args.SwapMode == common.SwapModeExactOut {
+			sim.AmountOut, sim.MaximumAmountIn = args.Amount0, args.Amount1
+		} else {
+			sim.AmountIn, sim.MinimumAmountOut = args.Amount0, args.Amount1
+		}
+		quoteAmount = args.Amount0
+	}
+	tracked := make([]solana.PublicKey, 0, trackedReferral+1)
+	for _, name := range []string{"pool", "input_token_account", "output_token_account", "base_vault", "quote_vault"} {
+		account, _ := swap.Account(name)
+		tracked = append(tracked, account)
+	}
+	if hasReferral {
+		tracked = append(tracked, referral)
+	}
+
+	pre, err := rpcClient.GetMultipleAccountsWithOpts(ctx, tracked, &solRpc.GetMultipleAccountsOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: solRpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pre-state accounts: %w", err)
+	}
+	if pre == nil || len(pre.Value) != len(tracked) {
+		return nil, fmt.Errorf("unexpected pre-state accounts response")
+	}
+	if pre.Value[trackedPool] == nil {
+		return nil, fmt.Errorf("pool account not found: %s", sim.Pool)
+	}
+	sim.PrePool, err = helpers.DecodePool(pre.Value[trackedPool].Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize pre-state pool: %w", err)
+	}
+
+	// unsigned transactions need a signature slot for every signer to be accepted
+	simTx := tx
+	if len(tx.Signatures) == 0 {
+		unsigned := *tx
+		unsigned.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+		simTx = &unsigned
+	}
+
+	res, err := rpcClient.SimulateTransactionWithOpts(ctx, simTx, &solRpc.SimulateTransactionOpts{
+		SigVerify:              false,
+		ReplaceRecentBlockhash: true,
+		Commitment:             solRpc.CommitmentConfirmed,
+		Accounts: &solRpc.SimulateTransactionAccountsOpts{
+			Encoding:  solana.EncodingBase64,
+			Addresses: tracked,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate transaction: %w", err)
+	}
+	if res == nil || res.Value == nil {
+		return nil, fmt.Errorf("empty simulation result")
+	}
+	sim.Logs = res.Value.Logs
+	if res.Value.UnitsConsumed != nil {
+		sim.UnitsConsumed = *res.Value.UnitsConsumed
+	}
+	if res.Value.Err != nil {
+		if decoded, ok := dbcErrors.DecodeProgramTransactionError(tx, res.Value.Err, program.Programs.Dbc); ok {
+			return sim, fmt.Errorf("simulation failed: %w", decoded)
+		}
+		return sim, fmt.Errorf("simulation failed: %v", res.Value.Err)
+	}
+	post := res.Value.Accounts
+	if len(post) != len(tracked) || post[trackedPool] == nil {
+		return sim, fmt.Errorf("simulation did not return the requested accounts")
+	}
+	sim.PostPool, err = helpers.DecodePool(post[trackedPool].Data.GetBinary())
+	if err != nil {
+		return sim, fmt.Errorf("failed to deserialize post-state pool: %w", err)
+	}
+
+	balances := make([]TokenBalanceChange, trackedReferral+1)
+	for i := trackedInput; i < len(tracked); i++ {
+		balances[i] = TokenBalanceChange{
+			Account: tracked[i],
+			Pre:     tokenAccountAmount(pre.Value[i]),
+			Post:    tokenAccountAmount(post[i]),
+		}
+	}
+	sim.UserInput, sim.UserOutput = balances[trackedInput], balances[trackedOutput]
+	sim.BaseVault, sim.QuoteVault = balances[trackedBaseVault], balances[trackedQuoteVault]
+	sim.Referral = balances[trackedReferral]
+
+	// selling base moves the base vault up, buying base moves it down
+	sim.TradeDirection = common.QuoteToBase
+	outputVault := trackedBaseVault
+	if sim.BaseVault.Post > sim.BaseVault.Pre {
+		sim.TradeDirection = common.BaseToQuote
+		outputVault = trackedQuoteVault
+	}
+
+	if !accountClosed(post[trackedOutput]) {
+		if sim.UserOutput.Post > sim.UserOutput.Pre {
+			sim.ActualAmountOut = sim.UserOutput.Post - sim.UserOutput.Pre
+		}
+	} else if vault := balances[outputVault]; vault.Pre > vault.Post {
+		sim.ActualAmountOut = vault.Pre - vault.Post
+		// a referral fee in the output token leaves the same vault
+		if hasReferral && sim.Referral.Post > sim.Referral.Pre &&
+			tokenAccountMint(post[trackedReferral]).Equals(tokenAccountMint(post[outputVault])) {
+			referralFee := sim.Referral.Post - sim.Referral.Pre
+			if referralFee > sim.ActualAmountOut {
+				referralFee = sim.ActualAmountOut
+			}
+			sim.ActualAmountOut -= referralFee
+		}
+	}
+
+	preMetrics, postMetrics := sim.PrePool.Metrics, sim.PostPool.Metrics
+	sim.TradingFee = (postMetrics.TotalTradingBaseFee - preMetrics.TotalTradingBaseFee) +
+		(postMetrics.TotalTradingQuoteFee - preMetrics.TotalTradingQuoteFee)
+	sim.ProtocolFee = (postMetrics.TotalProtocolBaseFee - preMetrics.TotalProtocolBaseFee) +
+		(postMetrics.TotalProtocolQuoteFee - preMetrics.TotalProtocolQuoteFee)
+
+	config, err := program.GetPoolConfig(ctx, configAddress, rpcClient)
+	if err != nil {
+		return sim, err
+	}
+	currentPoint, err := getCurrentPoint(config.ActivationType, res.Context.Slot)
+	if err != nil {
+		return sim, err
+	}
+	sim.Quote, err = math.SwapQuote2(sim.PrePool, config, sim.TradeDirection, sim.SwapMode, quoteAmount, hasReferral, currentPoint)
+	if err != nil {
+		return sim, fmt.Errorf("failed to quote swap: %w", err)
+	}
+
+	return sim, nil
+}
+
+// finds and decodes the first DBC swap or swap2 instruction
+func findSwapInstruction(tx *solana.Transaction, program *instructions.Program) (*instructions.DecodedInstruction, error) {
+	for _, ix := range tx.Message.Instructions {
+		ixProgram, err := tx.Message.ResolveProgramIDIndex(ix.ProgramIDIndex)
+		if err != nil || !ixProgram.Equals(program.Programs.Dbc) {
+			continue
+		}
+		decoded, err := program.DecodeCompiledInstruction(&tx.Message, ix)
+		if errors.Is(err, instructions.ErrUnknownInstruction) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode DBC instruction: %w", err)
+		}
+		switch decoded.Args.(type) {
+		case *instructions.SwapArgs, *instructions.Swap2Args:
+			return decoded, nil
+		}
+	}
+	return nil, fmt.Errorf("transaction has no swap instruction")
+}
+
+// reads the amount of an SPL token account, zero when the account does not exist
+func tokenAccountAmount(account *solRpc.Account) uint64 {
+	if account == nil {
+		return 0
+	}
+	data := account.Data.GetBinary()
+	if len(data) < tokenAccountAmountOffset+8 {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(data[tokenAccountAmountOffset:])
+}
+
+// reads the mint of an SPL token account, zero when the account does not exist
+func tokenAccountMint(account *solRpc.Account) solana.PublicKey {
+	if account == nil {
+		return solana.PublicKey{}
+	}
+	data := account.Data.GetBinary()
+	if len(data) < solana.PublicKeyLength {
+		return solana.PublicKey{}
+	}
+	return solana.PublicKeyFromBytes(data[:solana.PublicKeyLength])
+}
+
+// whether the account does not exist, e.g. after being closed by the transaction
+func accountClosed(account *solRpc.Account) bool {
+	return account == nil || len(account.Data.GetBinary()) == 0
+}
+
+// gets the current point used by the fee scheduler, a slot or a unix timestamp
+func getCurrentPoint(activationType uint8, slot uint64) (uint64, error) {
+	switch activationType {
+	case common.ActivationTypeSlot:
+		return slot, nil
+	case common.ActivationTypeTimestamp:
+		return uint64(time.Now().Unix()), nil
+	default:
+		return 0, fmt.Errorf("unsupported activation type: %d", activationType)
+	}
+}
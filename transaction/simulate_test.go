@@ -0,0 +1,342 @@
+package transaction_test
+
+import (
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/helpers"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/math"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+	"github.com/Luigi-1Combo/dbc-go/transaction"
+)
+
+const testSlot = 250_000_000
+
+// pool of the sample curve after a 10 SOL buy, with its accounts before and after a
+// simulated swap
+type swapFixture struct {
+	server *rpctest.Server
+	pre    *rpctest.AccountFetcher
+	post   *rpctest.AccountFetcher
+
+	config     solana.PublicKey
+	pool       solana.PublicKey
+	baseMint   solana.PublicKey
+	baseVault  solana.PublicKey
+	quoteVault solana.PublicKey
+	user       solana.PublicKey
+	// user token accounts
+	baseAccount  solana.PublicKey
+	quoteAccount solana.PublicKey
+	// referral token account in quote, owned by someone else
+	referral solana.PublicKey
+
+	poolConfig *common.PoolConfig
+	prePool    *common.Pool
+}
+
+func newSwapFixture(t *testing.T) *swapFixture {
+	t.Helper()
+	f := &swapFixture{
+		pre:          rpctest.NewAccountFetcher(),
+		post:         rpctest.NewAccountFetcher(),
+		config:       solana.NewWallet().PublicKey(),
+		pool:         solana.NewWallet().PublicKey(),
+		baseMint:     solana.NewWallet().PublicKey(),
+		baseVault:    solana.NewWallet().PublicKey(),
+		quoteVault:   solana.NewWallet().PublicKey(),
+		user:         solana.NewWallet().PublicKey(),
+		baseAccount:  solana.NewWallet().PublicKey(),
+		quoteAccount: solana.NewWallet().PublicKey(),
+		referral:     solana.NewWallet().PublicKey(),
+		poolConfig:   rpctest.SamplePoolConfig(),
+	}
+	f.pre.SetSlot(testSlot)
+	f.post.SetSlot(testSlot)
+
+	pool := rpctest.SamplePool(f.config, f.baseMint)
+	pool.BaseVault, pool.QuoteVault = f.baseVault, f.quoteVault
+	buy, err := math.SwapQuote(pool, f.poolConfig, common.QuoteToBase, 10_000_000_000, false, testSlot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.SqrtPrice = buy.NextSqrtPrice
+	pool.BaseReserve -= buy.OutputAmount
+	pool.QuoteReserve += buy.ActualInputAmount
+	pool.Metrics.TotalTradingQuoteFee = buy.TradingFee
+	pool.Metrics.TotalProtocolQuoteFee = buy.ProtocolFee
+	f.prePool = pool
+
+	if err := f.pre.SetPoolConfig(f.config, f.poolConfig); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.pre.SetPool(f.pool, pool); err != nil {
+		t.Fatal(err)
+	}
+	f.pre.SetTokenAccount(f.baseVault, f.baseMint, helpers.DerivePoolAuthorityPDA(), pool.BaseReserve)
+	f.pre.SetTokenAccount(f.quoteVault, solana.SolMint, helpers.DerivePoolAuthorityPDA(), pool.QuoteReserve+buy.TradingFee)
+	f.pre.SetTokenAccount(f.referral, solana.SolMint, solana.NewWallet().PublicKey(), 0)
+
+	f.server = rpctest.NewServer(f.pre)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// stores the post-state of a swap quoted off-chain: the pool moved to the quoted
+// price with the quoted fees, and the vaults and referral settled accordingly
+func (f *swapFixture) settle(t *testing.T, direction common.TradeDirection, quote *common.SwapResult, hasReferral bool) {
+	t.Helper()
+	pool := *f.prePool
+	pool.SqrtPrice = quote.NextSqrtPrice
+	pool.Metrics.TotalTradingQuoteFee += quote.TradingFee
+	pool.Metrics.TotalProtocolQuoteFee += quote.ProtocolFee
+	if err := f.post.SetPool(f.pool, &pool); err != nil {
+		t.Fatal(err)
+	}
+
+	baseVault := tokenAmount(t, f.pre, f.baseVault)
+	quoteVault := tokenAmount(t, f.pre, f.quoteVault)
+	referralFee := uint64(0)
+	if hasReferral {
+		referralFee = quote.ReferralFee
+		f.post.SetTokenAccount(f.referral, solana.SolMint, solana.NewWallet().PublicKey(), referralFee)
+	}
+	if direction == common.BaseToQuote {
+		baseVault += quote.InputAmount
+		quoteVault -= quote.OutputAmount + referralFee
+	} else {
+		baseVault -= quote.OutputAmount
+		quoteVault += quote.InputAmount - referralFee
+	}
+	f.post.SetTokenAccount(f.baseVault, f.baseMint, helpers.DerivePoolAuthorityPDA(), baseVault)
+	f.post.SetTokenAccount(f.quoteVault, solana.SolMint, helpers.DerivePoolAuthorityPDA(), quoteVault)
+}
+
+func (f *swapFixture) simulate(t *testing.T, ix solana.Instruction, sim *rpctest.Simulation) (*transaction.SwapSimulation, error) {
+	t.Helper()
+	f.server.Handle("simulateTransaction", sim.Handle)
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		computebudget.NewSetComputeUnitLimitInstruction(200_000).Build(),
+		ix,
+	}, solana.Hash{1}, solana.TransactionPayer(f.user))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transaction.SimulateSwap(context.Background(), f.server.Client(), tx)
+}
+
+func tokenAmount(t *testing.T, fetcher *rpctest.AccountFetcher, address solana.PublicKey) uint64 {
+	t.Helper()
+	res, err := fetcher.GetAccountInfo(context.Background(), address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return binary.LittleEndian.Uint64(res.Value.Data.GetBinary()[64:72])
+}
+
+func TestSimulateSwapSellIntoClosedWsolAccount(t *testing.T) {
+	for _, hasReferral := range []bool{false, true} {
+		f := newSwapFixture(t)
+		const amountIn = 20_000_000_000_000
+		quote, err := math.SwapQuote(f.prePool, f.poolConfig, common.BaseToQuote, amountIn, hasReferral, testSlot)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the wsol output account is created and closed by the transaction, so it
+		// exists on neither side of the simulation
+		f.pre.SetTokenAccount(f.baseAccount, f.baseMint, f.user, 50_000_000_000_000)
+		f.post.SetTokenAccount(f.baseAccount, f.baseMint, f.user, 50_000_000_000_000-amountIn)
+		f.settle(t, common.BaseToQuote, quote, hasReferral)
+
+		referral := solana.PublicKey{}
+		if hasReferral {
+			referral = f.referral
+		}
+		ix := instructions.Swap(f.config, f.pool, f.baseAccount, f.quoteAccount, f.baseVault, f.quoteVault,
+			f.baseMint, solana.SolMint, f.user, referral, amountIn, 0)
+		sim, err := f.simulate(t, ix, &rpctest.Simulation{Post: f.post, UnitsConsumed: 61_234})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if sim.TradeDirection != common.BaseToQuote {
+			t.Fatalf("referral %v: got direction %v, want BaseToQuote", hasReferral, sim.TradeDirection)
+		}
+		if sim.AmountIn != amountIn || sim.UnitsConsumed != 61_234 {
+			t.Fatalf("referral %v: got amount in %d and %d units", hasReferral, sim.AmountIn, sim.UnitsConsumed)
+		}
+		if sim.UserOutput.Post != 0 {
+			t.Fatalf("referral %v: got output balance %d for a closed account", hasReferral, sim.UserOutput.Post)
+		}
+		if sim.ActualAmountOut != quote.OutputAmount {
+			t.Fatalf("referral %v: got amount out %d, want %d", hasReferral, sim.ActualAmountOut, quote.OutputAmount)
+		}
+		if diff, ok := sim.AmountOutDiff(); !ok || diff != 0 {
+			t.Fatalf("referral %v: got amount out diff %d (%v), want 0", hasReferral, diff, ok)
+		}
+		if sim.TradingFee != quote.TradingFee || sim.ProtocolFee != quote.ProtocolFee {
+			t.Fatalf("referral %v: got fees %d/%d, want %d/%d", hasReferral, sim.TradingFee, sim.ProtocolFee, quote.TradingFee, quote.ProtocolFee)
+		}
+		if hasReferral && sim.Referral.Delta() != int64(quote.ReferralFee) {
+			t.Fatalf("got referral delta %d, want %d", sim.Referral.Delta(), quote.ReferralFee)
+		}
+	}
+}
+
+func TestSimulateSwapBuy(t *testing.T) {
+	f := newSwapFixture(t)
+	const amountIn = 5_000_000_000
+	quote, err := math.SwapQuote(f.prePool, f.poolConfig, common.QuoteToBase, amountIn, false, testSlot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.pre.SetTokenAccount(f.quoteAccount, solana.SolMint, f.user, amountIn)
+	f.pre.SetTokenAccount(f.baseAccount, f.baseMint, f.user, 1_000)
+	f.post.SetTokenAccount(f.quoteAccount, solana.SolMint, f.user, 0)
+	f.post.SetTokenAccount(f.baseAccount, f.baseMint, f.user, 1_000+quote.OutputAmount)
+	f.settle(t, common.QuoteToBase, quote, false)
+
+	ix := instructions.Swap(f.config, f.pool, f.quoteAccount, f.baseAccount, f.baseVault, f.quoteVault,
+		f.baseMint, solana.SolMint, f.user, solana.PublicKey{}, amountIn, 1)
+	sim, err := f.simulate(t, ix, &rpctest.Simulation{Post: f.post})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sim.TradeDirection != common.QuoteToBase {
+		t.Fatalf("got direction %v, want QuoteToBase", sim.TradeDirection)
+	}
+	if sim.ActualAmountOut != quote.OutputAmount || sim.UserOutput.Delta() != int64(quote.OutputAmount) {
+		t.Fatalf("got amount out %d, want %d", sim.ActualAmountOut, quote.OutputAmount)
+	}
+	if sim.UserInput.Delta() != -amountIn {
+		t.Fatalf("got input delta %d, want %d", sim.UserInput.Delta(), -amountIn)
+	}
+	if sim.MinimumAmountOut != 1 {
+		t.Fatalf("got minimum amount out %d, want 1", sim.MinimumAmountOut)
+	}
+	if sim.PostPool.SqrtPrice != quote.NextSqrtPrice {
+		t.Fatalf("got post sqrt price %s, want %s", sim.PostPool.SqrtPrice, quote.NextSqrtPrice)
+	}
+}
+
+func TestSimulateSwap2(t *testing.T) {
+	tests := []struct {
+		name      string
+		direction common.TradeDirection
+		mode      common.SwapMode
+		amount0   uint64
+		amount1   uint64
+	}{
+		{name: "exact out buy", direction: common.QuoteToBase, mode: common.SwapModeExactOut, amount0: 10_000_000_000_000, amount1: 2_000_000_000},
+		{name: "exact in sell", direction: common.BaseToQuote, mode: common.SwapModeExactIn, amount0: 10_000_000_000_000, amount1: 1},
+		{name: "partial fill buy", direction: common.QuoteToBase, mode: common.SwapModePartialFill, amount0: 3_000_000_000, amount1: 1},
+	}
+	for _, tt := range tests {
+		f := newSwapFixture(t)
+		quote, err := math.SwapQuote2(f.prePool, f.poolConfig, tt.direction, tt.mode, tt.amount0, false, testSlot)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		input, inputMint, output, outputMint := f.quoteAccount, solana.SolMint, f.baseAccount, f.baseMint
+		if tt.direction == common.BaseToQuote {
+			input, inputMint, output, outputMint = f.baseAccount, f.baseMint, f.quoteAccount, solana.SolMint
+		}
+		f.pre.SetTokenAccount(input, inputMint, f.user, quote.InputAmount)
+		f.pre.SetTokenAccount(output, outputMint, f.user, 0)
+		f.post.SetTokenAccount(input, inputMint, f.user, 0)
+		f.post.SetTokenAccount(output, outputMint, f.user, quote.OutputAmount)
+		f.settle(t, tt.direction, quote, false)
+
+		ix := instructions.Swap2(f.config, f.pool, input, output, f.baseVault, f.quoteVault,
+			f.baseMint, solana.SolMint, f.user, solana.PublicKey{}, tt.amount0, tt.amount1, tt.mode)
+		sim, err := f.simulate(t, ix, &rpctest.Simulation{Post: f.post})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		if sim.SwapMode != tt.mode || sim.TradeDirection != tt.direction {
+			t.Fatalf("%s: got mode %d and direction %v", tt.name, sim.SwapMode, sim.TradeDirection)
+		}
+		if tt.mode == common.SwapModeExactOut {
+			if sim.AmountOut != tt.amount0 || sim.MaximumAmountIn != tt.amount1 || sim.AmountIn != 0 {
+				t.Fatalf("%s: got amount out %d, maximum amount in %d, amount in %d", tt.name, sim.AmountOut, sim.MaximumAmountIn, sim.AmountIn)
+			}
+			if quote.OutputAmount < tt.amount0 {
+				t.Fatalf("%s: quoted %d below the exact amount out %d", tt.name, quote.OutputAmount, tt.amount0)
+			}
+		} else if sim.AmountIn != tt.amount0 || sim.MinimumAmountOut != tt.amount1 || sim.AmountOut != 0 {
+			t.Fatalf("%s: got amount in %d, minimum amount out %d, amount out %d", tt.name, sim.AmountIn, sim.MinimumAmountOut, sim.AmountOut)
+		}
+		if sim.Quote == nil || *sim.Quote != *quote {
+			t.Fatalf("%s: got quote %+v, want %+v", tt.name, sim.Quote, quote)
+		}
+		if diff, ok := sim.AmountOutDiff(); !ok || diff != 0 {
+			t.Fatalf("%s: got amount out diff %d (%v), want 0", tt.name, diff, ok)
+		}
+	}
+}
+
+func TestSimulateSwapFailure(t *testing.T) {
+	f := newSwapFixture(t)
+	f.pre.SetTokenAccount(f.quoteAccount, solana.SolMint, f.user, 1_000_000)
+
+	ix := instructions.Swap(f.config, f.pool, f.quoteAccount, f.baseAccount, f.baseVault, f.quoteVault,
+		f.baseMint, solana.SolMint, f.user, solana.PublicKey{}, 1_000_000, 1<<62)
+	sim, err := f.simulate(t, ix, &rpctest.Simulation{
+		Post: f.post,
+		// the swap is the second instruction, after the compute unit limit
+		Err:  map[string]interface{}{"InstructionError": []interface{}{1, map[string]interface{}{"Custom": 6002}}},
+		Logs: []string{"Program log: AnchorError occurred. Error Code: ExceededSlippage."},
+	})
+	if err == nil {
+		t.Fatal("expected the simulation error")
+	}
+	if !strings.Contains(err.Error(), "simulation failed") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sim == nil || len(sim.Logs) != 1 {
+		t.Fatal("expected the failed simulation with its logs")
+	}
+	if _, ok := sim.AmountOutDiff(); ok {
+		t.Fatal("AmountOutDiff reported a diff without a quote")
+	}
+}
+
+func TestSimulateSwapWithoutSwapInstruction(t *testing.T) {
+	f := newSwapFixture(t)
+	other := instructions.ClaimCreatorTradingFee(f.pool, f.baseAccount, f.quoteAccount,
+		f.baseVault, f.quoteVault, f.baseMint, solana.SolMint, f.user, 1, 1)
+	if _, err := f.simulate(t, other, &rpctest.Simulation{Post: f.post}); err == nil {
+		t.Fatal("expected an error for a transaction without a swap")
+	}
+}
+
+func TestAmountOutDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		sim    transaction.SwapSimulation
+		diff   int64
+		quoted bool
+	}{
+		{name: "no quote", sim: transaction.SwapSimulation{ActualAmountOut: 10}},
+		{name: "above quote", sim: transaction.SwapSimulation{ActualAmountOut: 110, Quote: &common.SwapResult{OutputAmount: 100}}, diff: 10, quoted: true},
+		{name: "below quote", sim: transaction.SwapSimulation{ActualAmountOut: 90, Quote: &common.SwapResult{OutputAmount: 100}}, diff: -10, quoted: true},
+	}
+	for _, tt := range tests {
+		diff, quoted := tt.sim.AmountOutDiff()
+		if diff != tt.diff || quoted != tt.quoted {
+			t.Errorf("%s: got (%d, %v), want (%d, %v)", tt.name, diff, quoted, tt.diff, tt.quoted)
+		}
+	}
+}
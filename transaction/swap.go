@@ -0,0 +1,326 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
+	system "github.com/gagliardetto/solana-go/programs/system"
+	token "github.com/gagliardetto/solana-go/programs/token"
+	solRpc "github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/math"
+)
+
+// instruction index of the associated token account program's CreateIdempotent
+const createIdempotentInstruction = 1
+
+// returned when the referral token account does not hold the token the fees are collected in
+var ErrInvalidReferralMint = errors.New("referral token account mint does not match the fee token")
+
+type SwapParams struct {
+	Pool solana.PublicKey
+	// signs the swap and owns the token accounts
+	Owner solana.PublicKey
+	// pays the transaction fee and the rent of created token accounts, defaults to Owner
+	Payer          solana.PublicKey
+	TradeDirection common.TradeDirection
+	// ExactIn builds the legacy swap, PartialFill and ExactOut build swap2
+	SwapMode common.SwapMode
+	// amount spent, at most for PartialFill; unused for ExactOut
+	AmountIn uint64
+	// amount received for ExactOut
+	AmountOut uint64
+	// tolerated shortfall of the quoted amount out, or excess of the quoted amount in
+	// for ExactOut, in basis points
+	SlippageBps uint64
+	// optional referral token account receiving part of the protocol fee, in the fee token
+	// given by GetFeeTokenMint; zero for none
+	ReferralTokenAccount solana.PublicKey
+	// deployment the pool belongs to, defaults to instructions.DefaultProgram
+	Program *instructions.Program
+	// options of the built transaction, see BuildTransaction
+	BuildOpts *BuildOpts
+}
+
+type SwapTransaction struct {
+	*BuiltTransaction
+	Quote            *common.SwapResult
+	MinimumAmountOut uint64
+	// only set for ExactOut
+	MaximumAmountIn uint64
+}
+
+// Builds a swap transaction for an existing pool: quotes the swap, creates the owner's
+// missing token accounts and wraps/unwraps SOL when the quote mint is the native mint
+func BuildSwapTransaction(
+	ctx context.Context,
+	rpcClient *solRpc.Client,
+	params *SwapParams,
+) (*SwapTransaction, error) {
+	program := programOf(params.Program)
+	pool, err := program.GetPool(ctx, params.Pool, rpcClient)
+	if err != nil {
+		return nil, err
+	}
+	config, err := program.GetPoolConfig(ctx, pool.Config, rpcClient)
+	if err != nil {
+		return nil, err
+	}
+
+	slot, err := rpcClient.GetSlot(ctx, solRpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slot: %w", err)
+	}
+	currentPoint, err := getCurrentPoint(config.ActivationType, slot)
+	if err != nil {
+		return nil, err
+	}
+
+	hasReferral := !params.ReferralTokenAccount.IsZero()
+	if hasReferral {
+		feeMint, err := GetFeeTokenMint(config.CollectFeeMode, params.TradeDirection, pool.BaseMint, config.QuoteMint)
+		if err != nil {
+			return nil, err
+		}
+		if err := ValidateReferralTokenAccount(ctx, rpcClient, params.ReferralTokenAccount, feeMint); err != nil {
+			return nil, err
+		}
+	}
+
+	quote, err := math.SwapQuote2(pool, config, params.TradeDirection, params.SwapMode, swapAmountOf(params), hasReferral, currentPoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quote swap: %w", err)
+	}
+	result := &SwapTransaction{Quote: quote}
+	var threshold uint64
+	if params.SwapMode == common.SwapModeExactOut {
+		result.MaximumAmountIn, err = math.GetMaximumAmountIn(quote.InputAmount, params.SlippageBps)
+		threshold = result.MaximumAmountIn
+	} else {
+		result.MinimumAmountOut, err = math.GetMinimumAmountOut(quote.OutputAmount, params.SlippageBps)
+		threshold = result.MinimumAmountOut
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimited := math.IsRateLimiterApplied(&config.PoolFees.BaseFee, currentPoint, pool.ActivationPoint, params.TradeDirection)
+	ixs, err := GetSwapInstructions(ctx, rpcClient, params, pool.Config, pool.BaseMint, config.QuoteMint, threshold, rateLimited)
+	if err != nil {
+		return nil, err
+	}
+
+	built, err := BuildTransaction(ctx, rpcClient, ixs, payerOf(params), params.BuildOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	result.BuiltTransaction = built
+	return result, nil
+}
+
+// Gets the swap instruction surrounded by the owner's token account creation and
+// SOL wrapping/unwrapping. Token accounts that do not exist yet are created idempotently
+// and wrapped SOL accounts created here are closed after the swap. The threshold is
+// the minimum amount out, or the maximum amount in for ExactOut; rateLimited adds the
+// account the program needs while the rate limiter applies, see math.IsRateLimiterApplied.
+func GetSwapInstructions(
+	ctx context.Context,
+	rpcClient *solRpc.Client,
+	params *SwapParams,
+	config solana.PublicKey,
+	baseMint solana.PublicKey,
+	quoteMint solana.PublicKey,
+	threshold uint64,
+	rateLimited bool,
+) ([]solana.Instruction, error) {
+	payer := payerOf(params)
+	nativeMint := solana.MustPublicKeyFromBase58(common.NativeMint)
+
+	inputMint, outputMint := quoteMint, baseMint
+	if params.TradeDirection == common.BaseToQuote {
+		inputMint, outputMint = baseMint, quoteMint
+	}
+
+	inputTokenAccount, _, err := solana.FindAssociatedTokenAddress(params.Owner, inputMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive input token account: %w", err)
+	}
+	outputTokenAccount, _, err := solana.FindAssociatedTokenAddress(params.Owner, outputMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive output token account: %w", err)
+	}
+
+	existing, err := rpcClient.GetMultipleAccounts(ctx, inputTokenAccount, outputTokenAccount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token accounts: %w", err)
+	}
+	if existing == nil || len(existing.Value) != 2 {
+		return nil, fmt.Errorf("unexpected token accounts response")
+	}
+	inputExists, outputExists := existing.Value[0] != nil, existing.Value[1] != nil
+
+	var ixs []solana.Instruction
+	if !inputExists {
+		ixs = append(ixs, CreateAssociatedTokenAccountIdempotent(payer, params.Owner, inputMint))
+	}
+	if inputMint.Equals(nativeMint) {
+		wrapAmount := params.AmountIn
+		if params.SwapMode == common.SwapModeExactOut {
+			wrapAmount = threshold
+		}
+		ixs = append(ixs,
+			system.NewTransferInstruction(wrapAmount, params.Owner, inputTokenAccount).Build(),
+			token.NewSyncNativeInstruction(inputTokenAccount).Build(),
+		)
+	}
+	if !outputExists {
+		ixs = append(ixs, CreateAssociatedTokenAccountIdempotent(payer, params.Owner, outputMint))
+	}
+
+	program := programOf(params.Program)
+	pool := params.Pool
+	baseVault, err := program.DeriveTokenVault(pool, baseMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive base vault: %w", err)
+	}
+	quoteVault, err := program.DeriveTokenVault(pool, quoteMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive quote vault: %w", err)
+	}
+	var swapIx solana.Instruction
+	if params.SwapMode == common.SwapModeExactIn {
+		swapIx = program.Swap(
+			config,
+			pool,
+			inputTokenAccount,
+			outputTokenAccount,
+			baseVault,
+			quoteVault,
+			baseMint,
+			quoteMint,
+			params.Owner,
+			params.ReferralTokenAccount,
+			params.AmountIn,
+			threshold,
+		)
+	} else {
+		swapIx = program.Swap2(
+			config,
+			pool,
+			inputTokenAccount,
+			outputTokenAccount,
+			baseVault,
+			quoteVault,
+			baseMint,
+			quoteMint,
+			params.Owner,
+			params.ReferralTokenAccount,
+			swapAmountOf(params),
+			threshold,
+			params.SwapMode,
+		)
+	}
+	if rateLimited {
+		swapIx, err = instructions.WithInstructionsSysvar(swapIx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ixs = append(ixs, swapIx)
+
+	// unwrap by closing the wrapped SOL accounts this transaction created
+	if inputMint.Equals(nativeMint) && !inputExists {
+		ixs = append(ixs, closeTokenAccount(inputTokenAccount, params.Owner))
+	}
+	if outputMint.Equals(nativeMint) && !outputExists {
+		ixs = append(ixs, closeTokenAccount(outputTokenAccount, params.Owner))
+	}
+
+	return ixs, nil
+}
+
+// Gets the mint of the token the swap fees, and so the referral fee, are collected in
+func GetFeeTokenMint(
+	collectFeeMode uint8,
+	tradeDirection common.TradeDirection,
+	baseMint solana.PublicKey,
+	quoteMint solana.PublicKey,
+) (solana.PublicKey, error) {
+	feeMode, err := math.GetFeeMode(collectFeeMode, tradeDirection, false)
+	if err != nil {
+		return solana.PublicKey{}, err
+	}
+	if feeMode.FeesOnBaseToken {
+		return baseMint, nil
+	}
+	return quoteMint, nil
+}
+
+// Checks that the referral token account exists and holds the fee token
+func ValidateReferralTokenAccount(
+	ctx context.Context,
+	rpcClient instructions.AccountFetcher,
+	referralTokenAccount solana.PublicKey,
+	feeMint solana.PublicKey,
+) error {
+	account, err := rpcClient.GetAccountInfo(ctx, referralTokenAccount)
+	if err != nil {
+		return fmt.Errorf("failed to get referral token account: %w", err)
+	}
+	if account == nil || account.Value == nil {
+		return fmt.Errorf("referral token account not found: %s", referralTokenAccount)
+	}
+
+	data := account.Value.Data.GetBinary()
+	if len(data) < tokenAccountAmountOffset+8 {
+		return fmt.Errorf("referral account %s is not a token account", referralTokenAccount)
+	}
+	if !bytes.Equal(data[:32], feeMint[:]) {
+		return fmt.Errorf("%w: expected %s", ErrInvalidReferralMint, feeMint)
+	}
+	return nil
+}
+
+// Creates the owner's associated token account, succeeding when it already exists
+func CreateAssociatedTokenAccountIdempotent(payer, owner, mint solana.PublicKey) solana.Instruction {
+	ix := associatedtokenaccount.NewCreateInstruction(payer, owner, mint).Build()
+	return solana.NewInstruction(
+		associatedtokenaccount.ProgramID,
+		ix.Accounts(),
+		[]byte{createIdempotentInstruction},
+	)
+}
+
+func closeTokenAccount(account, owner solana.PublicKey) solana.Instruction {
+	return token.NewCloseAccountInstruction(account, owner, owner, []solana.PublicKey{}).Build()
+}
+
+func payerOf(params *SwapParams) solana.PublicKey {
+	if params.Payer.IsZero() {
+		return params.Owner
+	}
+	return params.Payer
+}
+
+// Gets the program of the params, the mainnet deployment when unset
+func programOf(program *instructions.Program) *instructions.Program {
+	if program == nil {
+		return instructions.DefaultProgram
+	}
+	return program
+}
+
+// Gets the amount encoded first in the swap, the amount out for ExactOut and the amount in otherwise
+func swapAmountOf(params *SwapParams) uint64 {
+	if params.SwapMode == common.SwapModeExactOut {
+		return params.AmountOut
+	}
+	return params.AmountIn
+}
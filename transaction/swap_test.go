@@ -0,0 +1,419 @@
+package transaction_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
+
+	"github.com/Luigi-1Combo/dbc-go/common"
+	"github.com/Luigi-1Combo/dbc-go/instructions"
+	"github.com/Luigi-1Combo/dbc-go/math"
+	"github.com/Luigi-1Combo/dbc-go/rpctest"
+	"github.com/Luigi-1Combo/dbc-go/transaction"
+)
+
+// names the instructions of a swap transaction, e.g. "create <mint>" or "transfer <lamports>",
+// with accounts named by the labels
+func describe(t *testing.T, ixs []solana.Instruction, labels map[solana.PublicKey]string) []string {
+	t.Helper()
+	label := func(key solana.PublicKey) string {
+		if name, ok := labels[key]; ok {
+			return name
+		}
+		return key.String()
+	}
+
+	var names []string
+	for _, ix := range ixs {
+		data, err := ix.Data()
+		if err != nil {
+			t.Fatal(err)
+		}
+		accounts := ix.Accounts()
+		switch programID := ix.ProgramID(); {
+		case programID.Equals(associatedtokenaccount.ProgramID):
+			// payer, account, owner, mint
+			names = append(names, fmt.Sprintf("create %s for %s paid by %s",
+				label(accounts[3].PublicKey), label(accounts[2].PublicKey), label(accounts[0].PublicKey)))
+		case programID.Equals(solana.SystemProgramID):
+			names = append(names, fmt.Sprintf("transfer %d", binary.LittleEndian.Uint64(data[4:])))
+		case programID.Equals(solana.TokenProgramID) && data[0] == 17:
+			names = append(names, "sync "+label(accounts[0].PublicKey))
+		case programID.Equals(solana.TokenProgramID) && data[0] == 9:
+			names = append(names, "close "+label(accounts[0].PublicKey))
+		default:
+			name, ok := instructions.InstructionName(data)
+			if !ok {
+				t.Fatalf("unexpected instruction of %s", programID)
+			}
+			if accounts[len(accounts)-1].PublicKey.Equals(solana.SysVarInstructionsPubkey) {
+				name += " with instructions sysvar"
+			}
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// swap fixture of the sample curve with a SOL or USDC quote, the owner holding none of
+// the token accounts
+type swapBuilder struct {
+	server   *rpctest.Server
+	pool     solana.PublicKey
+	config   solana.PublicKey
+	baseMint solana.PublicKey
+	owner    solana.PublicKey
+	labels   map[solana.PublicKey]string
+
+	poolState   *common.Pool
+	configState *common.PoolConfig
+}
+
+func newSwapBuilder(t *testing.T, quoteMint solana.PublicKey) *swapBuilder {
+	t.Helper()
+	server, _ := newBuilderServer(t)
+	server.Handle("simulateTransaction", (&rpctest.Simulation{Post: server.Fetcher, UnitsConsumed: 80_000}).Handle)
+	b := &swapBuilder{
+		server:      server,
+		pool:        solana.NewWallet().PublicKey(),
+		config:      solana.NewWallet().PublicKey(),
+		baseMint:    solana.NewWallet().PublicKey(),
+		owner:       solana.NewWallet().PublicKey(),
+		configState: rpctest.SamplePoolConfig(),
+	}
+	b.configState.QuoteMint = quoteMint
+	b.poolState = rpctest.SamplePool(b.config, b.baseMint)
+	if err := server.Fetcher.SetPoolConfig(b.config, b.configState); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Fetcher.SetPool(b.pool, b.poolState); err != nil {
+		t.Fatal(err)
+	}
+	b.labels = map[solana.PublicKey]string{
+		b.owner:           "owner",
+		b.baseMint:        "base",
+		quoteMint:         "quote",
+		b.baseAccount(t):  "base account",
+		b.quoteAccount(t): "quote account",
+	}
+	return b
+}
+
+func (b *swapBuilder) account(t *testing.T, mint solana.PublicKey) solana.PublicKey {
+	t.Helper()
+	address, _, err := solana.FindAssociatedTokenAddress(b.owner, mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return address
+}
+
+func (b *swapBuilder) baseAccount(t *testing.T) solana.PublicKey {
+	return b.account(t, b.baseMint)
+}
+
+func (b *swapBuilder) quoteAccount(t *testing.T) solana.PublicKey {
+	return b.account(t, b.configState.QuoteMint)
+}
+
+// names of the DBC instructions of a transaction, in order
+func dbcInstructionNames(t *testing.T, tx *solana.Transaction) []string {
+	t.Helper()
+	dbc := solana.MustPublicKeyFromBase58(common.DbcProgramID)
+	var names []string
+	for _, compiled := range tx.Message.Instructions {
+		programID, err := tx.Message.ResolveProgramIDIndex(compiled.ProgramIDIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name, ok := instructions.InstructionName(compiled.Data); ok && programID.Equals(dbc) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func hasAccount(tx *solana.Transaction, account solana.PublicKey) bool {
+	for _, key := range tx.Message.AccountKeys {
+		if key.Equals(account) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGetSwapInstructions(t *testing.T) {
+	usdc := solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qZzEjRZuaXqHq8GtsN9Lrc8vwY")
+	payer := solana.NewWallet().PublicKey()
+	tests := []struct {
+		name      string
+		quoteMint solana.PublicKey
+		params    transaction.SwapParams
+		// token accounts the owner already holds
+		holdsBase, holdsQuote bool
+		threshold             uint64
+		rateLimited           bool
+		want                  []string
+	}{
+		{
+			name:      "buy with SOL, no token accounts",
+			quoteMint: solana.SolMint,
+			params:    transaction.SwapParams{TradeDirection: common.QuoteToBase, AmountIn: 1_000_000_000},
+			want: []string{
+				"create quote for owner paid by owner", "transfer 1000000000", "sync quote account",
+				"create base for owner paid by owner", "Swap", "close quote account",
+			},
+		},
+		{
+			name:      "buy with SOL, existing token accounts",
+			quoteMint: solana.SolMint,
+			params:    transaction.SwapParams{TradeDirection: common.QuoteToBase, AmountIn: 1_000_000_000},
+			holdsBase: true, holdsQuote: true,
+			want: []string{"transfer 1000000000", "sync quote account", "Swap"},
+		},
+		{
+			name:      "sell for SOL",
+			quoteMint: solana.SolMint,
+			params:    transaction.SwapParams{TradeDirection: common.BaseToQuote, AmountIn: 5_000_000},
+			holdsBase: true,
+			want:      []string{"create quote for owner paid by owner", "Swap", "close quote account"},
+		},
+		{
+			name:      "sell for SOL into an existing account",
+			quoteMint: solana.SolMint,
+			params:    transaction.SwapParams{TradeDirection: common.BaseToQuote, AmountIn: 5_000_000},
+			holdsBase: true, holdsQuote: true,
+			want: []string{"Swap"},
+		},
+		{
+			name:       "buy with USDC",
+			quoteMint:  usdc,
+			params:     transaction.SwapParams{TradeDirection: common.QuoteToBase, AmountIn: 1_000_000},
+			holdsQuote: true,
+			want:       []string{"create base for owner paid by owner", "Swap"},
+		},
+		{
+			name:      "separate payer",
+			quoteMint: usdc,
+			params:    transaction.SwapParams{TradeDirection: common.QuoteToBase, AmountIn: 1_000_000, Payer: payer},
+			want: []string{
+				"create quote for owner paid by payer", "create base for owner paid by payer", "Swap",
+			},
+		},
+		{
+			// the maximum amount in is wrapped
+			name:      "exact out buy with SOL",
+			quoteMint: solana.SolMint,
+			params:    transaction.SwapParams{TradeDirection: common.QuoteToBase, SwapMode: common.SwapModeExactOut, AmountOut: 1_000_000},
+			holdsBase: true, holdsQuote: true,
+			threshold: 2_000_000,
+			want:      []string{"transfer 2000000", "sync quote account", "Swap2"},
+		},
+		{
+			name:      "partial fill sell",
+			quoteMint: solana.SolMint,
+			params:    transaction.SwapParams{TradeDirection: common.BaseToQuote, SwapMode: common.SwapModePartialFill, AmountIn: 5_000_000},
+			holdsBase: true, holdsQuote: true,
+			want: []string{"Swap2"},
+		},
+		{
+			name:        "rate limited buy",
+			quoteMint:   usdc,
+			params:      transaction.SwapParams{TradeDirection: common.QuoteToBase, AmountIn: 1_000_000},
+			holdsBase:   true,
+			holdsQuote:  true,
+			rateLimited: true,
+			want:        []string{"Swap with instructions sysvar"},
+		},
+	}
+	for _, tt := range tests {
+		b := newSwapBuilder(t, tt.quoteMint)
+		b.labels[payer] = "payer"
+		if tt.holdsBase {
+			b.server.Fetcher.SetTokenAccount(b.baseAccount(t), b.baseMint, b.owner, 1)
+		}
+		if tt.holdsQuote {
+			b.server.Fetcher.SetTokenAccount(b.quoteAccount(t), tt.quoteMint, b.owner, 1)
+		}
+		params := tt.params
+		params.Pool, params.Owner = b.pool, b.owner
+
+		ixs, err := transaction.GetSwapInstructions(context.Background(), b.server.Client(), &params,
+			b.config, b.baseMint, tt.quoteMint, tt.threshold, tt.rateLimited)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := describe(t, ixs, b.labels); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildSwapTransaction(t *testing.T) {
+	tests := []struct {
+		name   string
+		params transaction.SwapParams
+		// amount quoted in
+		amount uint64
+	}{
+		{name: "buy", params: transaction.SwapParams{TradeDirection: common.QuoteToBase, AmountIn: 2_000_000_000, SlippageBps: 100}},
+		{name: "exact out buy", params: transaction.SwapParams{TradeDirection: common.QuoteToBase, SwapMode: common.SwapModeExactOut, AmountOut: 50_000_000_000, SlippageBps: 100}},
+	}
+	for _, tt := range tests {
+		b := newSwapBuilder(t, solana.SolMint)
+		params := tt.params
+		params.Pool, params.Owner = b.pool, b.owner
+
+		built, err := transaction.BuildSwapTransaction(context.Background(), b.server.Client(), &params)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		// the sample curve activates by slot, so the quote is at the node's slot
+		amount := params.AmountIn
+		if params.SwapMode == common.SwapModeExactOut {
+			amount = params.AmountOut
+		}
+		quote, err := math.SwapQuote2(b.poolState, b.configState, common.QuoteToBase, params.SwapMode, amount, false, testSlot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *built.Quote != *quote {
+			t.Errorf("%s: got quote %+v, want %+v", tt.name, built.Quote, quote)
+		}
+
+		swapName := "Swap"
+		if params.SwapMode == common.SwapModeExactOut {
+			maxIn, _ := math.GetMaximumAmountIn(quote.InputAmount, params.SlippageBps)
+			if built.MaximumAmountIn != maxIn || built.MinimumAmountOut != 0 {
+				t.Errorf("%s: got maximum in %d, minimum out %d, want %d", tt.name, built.MaximumAmountIn, built.MinimumAmountOut, maxIn)
+			}
+			swapName = "Swap2"
+		} else {
+			minOut, _ := math.GetMinimumAmountOut(quote.OutputAmount, params.SlippageBps)
+			if built.MinimumAmountOut != minOut || built.MaximumAmountIn != 0 {
+				t.Errorf("%s: got minimum out %d, maximum in %d, want %d", tt.name, built.MinimumAmountOut, built.MaximumAmountIn, minOut)
+			}
+		}
+
+		tx := built.Transaction
+		if names := dbcInstructionNames(t, tx); len(names) != 1 || names[0] != swapName {
+			t.Errorf("%s: got program instructions %v, want %s", tt.name, names, swapName)
+		}
+		if !tx.Message.AccountKeys[0].Equals(b.owner) || built.ComputeUnitLimit != 88_000 {
+			t.Errorf("%s: got payer %s and limit %d", tt.name, tx.Message.AccountKeys[0], built.ComputeUnitLimit)
+		}
+	}
+}
+
+func TestBuildSwapTransactionMissingPool(t *testing.T) {
+	b := newSwapBuilder(t, solana.SolMint)
+	params := &transaction.SwapParams{Pool: solana.NewWallet().PublicKey(), Owner: b.owner, AmountIn: 1}
+	if _, err := transaction.BuildSwapTransaction(context.Background(), b.server.Client(), params); err == nil {
+		t.Fatal("expected an error for a missing pool")
+	}
+}
+
+func TestGetFeeTokenMint(t *testing.T) {
+	base := solana.NewWallet().PublicKey()
+	quote := solana.NewWallet().PublicKey()
+	tests := []struct {
+		name           string
+		collectFeeMode uint8
+		direction      common.TradeDirection
+		want           solana.PublicKey
+		valid          bool
+	}{
+		{name: "quote mode buy", collectFeeMode: common.CollectFeeModeQuoteToken, direction: common.QuoteToBase, want: quote, valid: true},
+		{name: "quote mode sell", collectFeeMode: common.CollectFeeModeQuoteToken, direction: common.BaseToQuote, want: quote, valid: true},
+		{name: "output mode buy", collectFeeMode: common.CollectFeeModeOutputToken, direction: common.QuoteToBase, want: base, valid: true},
+		{name: "output mode sell", collectFeeMode: common.CollectFeeModeOutputToken, direction: common.BaseToQuote, want: quote, valid: true},
+		{name: "unknown mode", collectFeeMode: 2, direction: common.QuoteToBase},
+	}
+	for _, tt := range tests {
+		got, err := transaction.GetFeeTokenMint(tt.collectFeeMode, tt.direction, base, quote)
+		if tt.valid != (err == nil) {
+			t.Errorf("%s: got error %v", tt.name, err)
+			continue
+		}
+		if !got.Equals(tt.want) {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateReferralTokenAccount(t *testing.T) {
+	fetcher := rpctest.NewAccountFetcher()
+	feeMint := solana.NewWallet().PublicKey()
+	valid := solana.NewWallet().PublicKey()
+	otherMint := solana.NewWallet().PublicKey()
+	notTokenAccount := solana.NewWallet().PublicKey()
+	failing := solana.NewWallet().PublicKey()
+	fetcher.SetTokenAccount(valid, feeMint, solana.NewWallet().PublicKey(), 0)
+	fetcher.SetTokenAccount(otherMint, solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), 0)
+	fetcher.SetAccount(notTokenAccount, solana.SystemProgramID, nil)
+	fetcher.SetError(failing, fmt.Errorf("connection reset"))
+
+	tests := []struct {
+		name    string
+		account solana.PublicKey
+		valid   bool
+		target  error
+	}{
+		{name: "fee token account", account: valid, valid: true},
+		{name: "other mint", account: otherMint, target: transaction.ErrInvalidReferralMint},
+		{name: "not a token account", account: notTokenAccount},
+		{name: "missing", account: solana.NewWallet().PublicKey()},
+		{name: "rpc error", account: failing},
+	}
+	for _, tt := range tests {
+		err := transaction.ValidateReferralTokenAccount(context.Background(), fetcher, tt.account, feeMint)
+		if tt.valid != (err == nil) {
+			t.Errorf("%s: got error %v", tt.name, err)
+		}
+		if tt.target != nil && !errors.Is(err, tt.target) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.target)
+		}
+	}
+}
+
+func TestBuildSwapTransactionReferral(t *testing.T) {
+	b := newSwapBuilder(t, solana.SolMint)
+	referral := solana.NewWallet().PublicKey()
+	params := &transaction.SwapParams{
+		Pool:                 b.pool,
+		Owner:                b.owner,
+		TradeDirection:       common.QuoteToBase,
+		AmountIn:             1_000_000_000,
+		ReferralTokenAccount: referral,
+	}
+
+	// the sample curve collects fees in SOL, a base token account is rejected
+	b.server.Fetcher.SetTokenAccount(referral, b.baseMint, solana.NewWallet().PublicKey(), 0)
+	if _, err := transaction.BuildSwapTransaction(context.Background(), b.server.Client(), params); !errors.Is(err, transaction.ErrInvalidReferralMint) {
+		t.Fatalf("got %v, want %v", err, transaction.ErrInvalidReferralMint)
+	}
+
+	b.server.Fetcher.SetTokenAccount(referral, solana.SolMint, solana.NewWallet().PublicKey(), 0)
+	built, err := transaction.BuildSwapTransaction(context.Background(), b.server.Client(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	quote, err := math.SwapQuote(b.poolState, b.configState, common.QuoteToBase, params.AmountIn, true, testSlot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *built.Quote != *quote || quote.ReferralFee == 0 {
+		t.Errorf("got quote %+v, want %+v with a referral fee", built.Quote, quote)
+	}
+
+	if !hasAccount(built.Transaction, referral) {
+		t.Error("referral account missing from the transaction")
+	}
+}
@@ -0,0 +1,215 @@
+package vanity
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+	DefaultProgressInterval = time.Second
+
+	// attempts a worker makes between context checks
+	attemptsPerBatch = 256
+)
+
+var (
+	errEmptyPattern = errors.New("prefix or suffix is required")
+	errNilOptions   = errors.New("grind options are required")
+)
+
+type Options struct {
+	Prefix          string
+	Suffix          string
+	CaseInsensitive bool
+	// number of goroutines grinding keys, defaults to the number of CPUs
+	Workers int
+	// called every ProgressInterval while grinding
+	OnProgress       func(Progress)
+	ProgressInterval time.Duration
+}
+
+type Progress struct {
+	Attempts         uint64
+	Elapsed          time.Duration
+	ExpectedAttempts float64
+}
+
+// attempts per second so far
+func (p Progress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Attempts) / p.Elapsed.Seconds()
+}
+
+// Grinds keypairs until the base58 address matches the prefix and suffix, returning
+// a private key usable as a base mint. Returns the context error when cancelled.
+func Grind(ctx context.Context, opts *Options) (solana.PrivateKey, error) {
+	if opts == nil {
+		return nil, errNilOptions
+	}
+	if err := ValidatePattern(opts.Prefix, opts.Suffix, opts.CaseInsensitive); err != nil {
+		return nil, err
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	interval := opts.ProgressInterval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	expected := ExpectedAttempts(opts.Prefix, opts.Suffix, opts.CaseInsensitive)
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	var attempts uint64
+	found := make(chan solana.PrivateKey, 1)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, err := grind(ctx, opts.Prefix, opts.Suffix, opts.CaseInsensitive, &attempts)
+			switch {
+			case err != nil:
+				errs <- err
+			case key != nil:
+				select {
+				case found <- key:
+				default:
+				}
+			}
+		}()
+	}
+
+	// stop the remaining workers once a key is found or grinding is cancelled
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	start := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case key := <-found:
+			return key, nil
+		case err := <-errs:
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+			if opts.OnProgress != nil {
+				opts.OnProgress(Progress{
+					Attempts:         atomic.LoadUint64(&attempts),
+					Elapsed:          time.Since(start),
+					ExpectedAttempts: expected,
+				})
+			}
+		}
+	}
+}
+
+func grind(ctx context.Context, prefix, suffix string, caseInsensitive bool, attempts *uint64) (solana.PrivateKey, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		default:
+		}
+
+		for i := 0; i < attemptsPerBatch; i++ {
+			publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate keypair: %w", err)
+			}
+			if Matches(solana.PublicKeyFromBytes(publicKey).String(), prefix, suffix, caseInsensitive) {
+				atomic.AddUint64(attempts, uint64(i+1))
+				return solana.PrivateKey(privateKey), nil
+			}
+		}
+		atomic.AddUint64(attempts, attemptsPerBatch)
+	}
+}
+
+// Reports whether the address starts with the prefix and ends with the suffix
+func Matches(address, prefix, suffix string, caseInsensitive bool) bool {
+	if len(address) < len(prefix)+len(suffix) {
+		return false
+	}
+	head, tail := address[:len(prefix)], address[len(address)-len(suffix):]
+	if caseInsensitive {
+		return strings.EqualFold(head, prefix) && strings.EqualFold(tail, suffix)
+	}
+	return head == prefix && tail == suffix
+}
+
+// Checks that the pattern only uses characters a base58 address can contain
+func ValidatePattern(prefix, suffix string, caseInsensitive bool) error {
+	if prefix == "" && suffix == "" {
+		return errEmptyPattern
+	}
+	for _, c := range prefix + suffix {
+		if matchingChars(c, caseInsensitive) == 0 {
+			return fmt.Errorf("character %q is not in the base58 alphabet", c)
+		}
+	}
+	return nil
+}
+
+// Estimates the number of attempts needed to find a match, assuming uniformly
+// distributed base58 characters; leading characters of real addresses are skewed,
+// so long prefixes starting with high characters take longer
+func ExpectedAttempts(prefix, suffix string, caseInsensitive bool) float64 {
+	expected := 1.0
+	for _, c := range prefix + suffix {
+		n := matchingChars(c, caseInsensitive)
+		if n == 0 {
+			return 0
+		}
+		expected *= float64(len(base58Alphabet)) / float64(n)
+	}
+	return expected
+}
+
+// number of base58 characters matching c
+func matchingChars(c rune, caseInsensitive bool) int {
+	if !caseInsensitive {
+		if strings.ContainsRune(base58Alphabet, c) {
+			return 1
+		}
+		return 0
+	}
+
+	n := 0
+	for _, variant := range []string{strings.ToLower(string(c)), strings.ToUpper(string(c))} {
+		if strings.Contains(base58Alphabet, variant) {
+			n++
+		}
+	}
+	if strings.ToLower(string(c)) == strings.ToUpper(string(c)) && n == 2 {
+		// digits have a single case
+		n = 1
+	}
+	return n
+}
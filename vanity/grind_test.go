@@ -0,0 +1,157 @@
+package vanity_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Luigi-1Combo/dbc-go/vanity"
+)
+
+func TestMatches(t *testing.T) {
+	const address = "DBCxyz9Pq3kWvT7mNbR2sLhGfEdC8aZYuJtXoQnVpump"
+	tests := []struct {
+		name            string
+		prefix, suffix  string
+		caseInsensitive bool
+		want            bool
+	}{
+		{name: "prefix", prefix: "DBC", want: true},
+		{name: "suffix", suffix: "pump", want: true},
+		{name: "prefix and suffix", prefix: "DBCx", suffix: "ump", want: true},
+		{name: "wrong prefix", prefix: "DBX"},
+		{name: "wrong case", prefix: "dbc"},
+		{name: "case insensitive", prefix: "dbc", suffix: "PUMP", caseInsensitive: true, want: true},
+		{name: "longer than the address", prefix: address, suffix: "p"},
+		{name: "whole address", prefix: address, want: true},
+	}
+	for _, tt := range tests {
+		if got := vanity.Matches(address, tt.prefix, tt.suffix, tt.caseInsensitive); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	tests := []struct {
+		name            string
+		prefix, suffix  string
+		caseInsensitive bool
+		valid           bool
+	}{
+		{name: "prefix", prefix: "DBC", valid: true},
+		{name: "suffix", suffix: "pump", valid: true},
+		{name: "empty"},
+		{name: "zero", prefix: "D0"},
+		{name: "capital o", suffix: "Ok"},
+		{name: "capital i", prefix: "I"},
+		{name: "lowercase l", prefix: "l"},
+		// neither l nor I is base58, but L and i are
+		{name: "lowercase l case insensitive", prefix: "l", caseInsensitive: true, valid: true},
+		{name: "capital i case insensitive", prefix: "I", caseInsensitive: true, valid: true},
+		{name: "zero case insensitive", prefix: "0", caseInsensitive: true},
+		{name: "non ascii", prefix: "é"},
+	}
+	for _, tt := range tests {
+		err := vanity.ValidatePattern(tt.prefix, tt.suffix, tt.caseInsensitive)
+		if tt.valid != (err == nil) {
+			t.Errorf("%s: got error %v", tt.name, err)
+		}
+	}
+}
+
+func TestExpectedAttempts(t *testing.T) {
+	tests := []struct {
+		name            string
+		prefix, suffix  string
+		caseInsensitive bool
+		want            float64
+	}{
+		{name: "one character", prefix: "A", want: 58},
+		{name: "prefix and suffix", prefix: "A", suffix: "z", want: 58 * 58},
+		{name: "both cases match", prefix: "a", caseInsensitive: true, want: 29},
+		{name: "digit", prefix: "1", caseInsensitive: true, want: 58},
+		{name: "single case in base58", prefix: "L", caseInsensitive: true, want: 58},
+		{name: "invalid", prefix: "0", want: 0},
+	}
+	for _, tt := range tests {
+		got := vanity.ExpectedAttempts(tt.prefix, tt.suffix, tt.caseInsensitive)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGrind(t *testing.T) {
+	tests := []vanity.Options{
+		{Prefix: "A", Workers: 2},
+		{Suffix: "z", Workers: 1},
+		{Prefix: "b", CaseInsensitive: true},
+	}
+	for _, opts := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		key, err := vanity.Grind(ctx, &opts)
+		cancel()
+		if err != nil {
+			t.Fatalf("%+v: %v", opts, err)
+		}
+		address := key.PublicKey().String()
+		if !vanity.Matches(address, opts.Prefix, opts.Suffix, opts.CaseInsensitive) {
+			t.Errorf("%+v: got %s", opts, address)
+		}
+	}
+}
+
+func TestGrindCancelled(t *testing.T) {
+	var mu sync.Mutex
+	var progress []vanity.Progress
+	opts := &vanity.Options{
+		// about 10^14 attempts, never found within the test
+		Prefix:           "zzzzzzzz",
+		Workers:          2,
+		ProgressInterval: 20 * time.Millisecond,
+		OnProgress: func(p vanity.Progress) {
+			mu.Lock()
+			progress = append(progress, p)
+			mu.Unlock()
+		},
+	}
+	// long enough for a few batches of attempts, even under the race detector
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	key, err := vanity.Grind(ctx, opts)
+	if !errors.Is(err, context.DeadlineExceeded) || key != nil {
+		t.Fatalf("got %v, %v, want %v", key, err, context.DeadlineExceeded)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(progress) == 0 {
+		t.Fatal("no progress reported")
+	}
+	last := progress[len(progress)-1]
+	if last.Attempts == 0 || last.Elapsed <= 0 || last.Rate() <= 0 || last.ExpectedAttempts != math.Pow(58, 8) {
+		t.Errorf("got progress %+v", last)
+	}
+	for i := 1; i < len(progress); i++ {
+		if progress[i].Attempts < progress[i-1].Attempts {
+			t.Errorf("attempts went down from %d to %d", progress[i-1].Attempts, progress[i].Attempts)
+		}
+	}
+}
+
+func TestGrindInvalidPattern(t *testing.T) {
+	if _, err := vanity.Grind(context.Background(), &vanity.Options{Prefix: "0"}); err == nil {
+		t.Fatal("expected an error for a pattern outside the base58 alphabet")
+	}
+	if _, err := vanity.Grind(context.Background(), nil); err == nil {
+		t.Fatal("expected an error without options")
+	}
+	if (vanity.Progress{}).Rate() != 0 {
+		t.Fatal("got a rate without elapsed time")
+	}
+}